@@ -0,0 +1,2328 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/vaalley/totem/internal/backup"
+	"github.com/vaalley/totem/internal/fleet"
+	"github.com/vaalley/totem/internal/instances"
+	"github.com/vaalley/totem/internal/netguard"
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+// runCLI dispatches subcommands and returns the process exit code
+func runCLI(args []string) int {
+	switch args[0] {
+	case "backup":
+		return runBackup(args[1:])
+	case "restore":
+		return runRestore(args[1:])
+	case "consolidate":
+		return runConsolidate(args[1:])
+	case "join":
+		return runJoin(args[1:])
+	case "verify":
+		return runVerify(args[1:])
+	case "resume":
+		return runResume(args[1:])
+	case "watch":
+		return runWatch(args[1:])
+	case "quick":
+		return runQuick(args[1:])
+	case "tray":
+		return runTray(args[1:])
+	case "checkpoint":
+		return runCheckpoint(args[1:])
+	case "rollback":
+		return runRollback(args[1:])
+	case "archive-launcher":
+		return runArchiveLauncher(args[1:])
+	case "support-bundle":
+		return runSupportBundle(args[1:])
+	case "compare-pack":
+		return runComparePack(args[1:])
+	case "digest":
+		return runDigest(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "search":
+		return runSearch(args[1:])
+	case "new-pc":
+		return runNewPC(args[1:])
+	case "check-mods":
+		return runCheckMods(args[1:])
+	case "extract":
+		return runExtract(args[1:])
+	case "mount":
+		return runMount(args[1:])
+	case "browse":
+		return runBrowse(args[1:])
+	case "rules":
+		return runRules(args[1:])
+	case "analyze":
+		return runAnalyze(args[1:])
+	case "list":
+		return runList(args[1:])
+	case "prune":
+		return runPrune(args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	}
+	// Flags without a command: the TUI's backup, for scripts and cron jobs
+	if strings.HasPrefix(args[0], "-") {
+		return runHeadless(args)
+	}
+	fmt.Printf("%s unknown command %q\n\n", errorStyle.Render("✗"), args[0])
+	printUsage()
+	return 2
+}
+
+func printUsage() {
+	fmt.Println(`Usage:
+  totem                       Start the interactive TUI
+  totem --mc-path p --dest d  Run the TUI's backup without it, with plain text output
+  totem --config <dir> ...    Keep settings, keys and rules in <dir> (also $TOTEM_CONFIG)
+  totem --portable ...        Keep state and backups next to the binary (also totem.portable)
+  totem --offline ...         Turn off everything that uses the network (also $TOTEM_OFFLINE)
+  totem --profile-cpu <f> ... Write a pprof CPU profile of the run to <f>
+  totem --profile-mem <f> ... Write a pprof heap profile at the end of the run to <f>
+  totem backup [flags]        Back up one or more instances without the TUI
+  totem restore <backup>      Rebuild a backup (and its chain) into a folder
+  totem new-pc <backup>       Set up this PC from a backup: instance, settings, download checklist
+  totem check-mods <backup>   Check downloaded mods against the hashes a backup recorded
+  totem consolidate <backup>  Squash an incremental chain into a new full backup
+  totem join <part.001>       Put the parts of a split archive back into one file
+  totem verify <backup>       Re-hash a backup's files against its manifest; check its signature
+  totem resume [dest]         Continue backups interrupted by a crash
+  totem watch <instance>      Back up whenever a trigger file appears
+  totem quick                 Panic backup of saves and configs, no prompts
+  totem tray                  Tray icon with the last backup and "Back up now" (-tags tray builds)
+  totem checkpoint <label>    Snapshot mods, config and options before an update
+  totem rollback <label>      Put mods, config and options back from a checkpoint or backup
+  totem archive-launcher [l]  Archive a whole launcher folder, minus re-downloadable files
+  totem support-bundle        Zip logs, crash reports and the mod list for a bug report
+  totem compare-pack <pack>   List how an instance deviates from its .mrpack
+  totem digest                Summarize the last week of backups (markdown, HTML, email)
+  totem import <folder|zip>   Add manual copies or old backups to a destination
+  totem search <pattern>      Find which backups contain a file, folder or mod
+  totem extract <backup> <p>  Pull files or folders out of a backup without unpacking it
+  totem mount <backup> [dir]  Mount a backup read-only at dir (-tags fuse builds), or browse it in the web browser
+  totem browse                Browse backups in the TUI; extract or restore single items
+  totem rules                 Choose which instance folders are always or never copied
+  totem analyze [instance]    Show what takes up space in an instance and how it grew
+  totem list                  List backups with their size, game version and contents
+  totem prune                 Delete old backups by the retention policy in config.json
+
+Run "totem backup -h" for backup flags.`)
+}
+
+// globalFlags are the flags that apply to every command
+type globalFlags struct {
+	config     string // --config <folder>
+	portable   bool   // --portable
+	offline    bool   // --offline
+	profileCPU string // --profile-cpu <file>
+	profileMem string // --profile-mem <file>
+}
+
+// takeGlobalFlags removes --config <folder>, --portable, --offline and the --profile-* flags
+// from args, where they may come before the command or anywhere among headless flags (which
+// have no command). A command's own flags are left alone.
+func takeGlobalFlags(args []string) ([]string, globalFlags, error) {
+	var rest []string
+	var g globalFlags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		isFlag := strings.HasPrefix(arg, "-")
+		var target *string
+		switch {
+		case isFlag && name == "config":
+			target = &g.config
+		case isFlag && name == "profile-cpu":
+			target = &g.profileCPU
+		case isFlag && name == "profile-mem":
+			target = &g.profileMem
+		case isFlag && name == "portable" && !hasValue:
+			g.portable = true
+			continue
+		case isFlag && name == "offline" && !hasValue:
+			g.offline = true
+			continue
+		}
+		if target != nil {
+			if !hasValue {
+				if i+1 == len(args) {
+					what := "a file"
+					if name == "config" {
+						what = "a folder"
+					}
+					return nil, g, fmt.Errorf("--%s needs %s", name, what)
+				}
+				i++
+				value = args[i]
+			}
+			*target = value
+			continue
+		}
+		if len(rest) == 0 && !isFlag {
+			return append(rest, args[i:]...), g, nil
+		}
+		rest = append(rest, arg)
+	}
+	return rest, g, nil
+}
+
+// backupFlags are the backup options shared by headless runs (totem --mc-path ...) and
+// totem backup, so both commands take the same flags
+type backupFlags struct {
+	zipOutput, password, zstd, modMetadata              *bool
+	includeSaves, includeXaero, includeDH, includeCrash *bool
+	worldConfig, skipJunk, lowPriority, verify, network *bool
+	sign, parity, deterministic, redactSecrets, redact  *bool
+	incremental, linkDest, activeWorlds                 *bool
+	gpgRecipient, split, modpack, rateLimit, maxFile    *string
+	since, screenshotsSince, savesSince, xaeroSince     *string
+	zstdLevel, activeDays, parityBlocks                 *int
+	memoryLimit                                         *int64
+	zstdLong                                            zstdLongFlag
+	meta                                                stringList
+}
+
+// lowPriorityHelp describes --low-priority, which throttles copies the way the TUI's
+// Background mode does
+var lowPriorityHelp = fmt.Sprintf("lower CPU and I/O priority and copy at %s/s (unless --rate-limit is set) so the game stays smooth",
+	formatBytes(tui.DefaultRateLimit))
+
+// addBackupFlags registers the shared backup flags on fs
+func addBackupFlags(fs *flag.FlagSet) *backupFlags {
+	f := &backupFlags{}
+	f.zipOutput = fs.Bool("zip", false, "create a .zip archive")
+	f.password = fs.Bool("password", false, "encrypt the zip with AES-256 (password from "+backup.ZipPasswordEnv+", or asked for)")
+	f.gpgRecipient = fs.String("gpg", "", "pipe the zip through gpg to this key ID, fingerprint or email, into a .zip.gpg")
+	f.zstd = fs.Bool("zstd", false, "create a .tar.zst archive (needs the zstd command)")
+	f.zstdLevel = fs.Int("zstd-level", 0, fmt.Sprintf("zstd compression level, 1-%d (0 = zstd's default)", backup.MaxZstdLevel))
+	fs.Var(&f.zstdLong, "zstd-long", fmt.Sprintf("zstd long-distance matching; --zstd-long=N sets a 2^N byte window, %d-%d (default %d)",
+		backup.MinZstdLong, backup.MaxZstdLong, backup.DefaultZstdLong))
+	f.modMetadata = fs.Bool("mod-metadata", false, "look up mods on Modrinth and CurseForge by hash and save the results in mods.json")
+	f.split = fs.String("split", "", `split the archive into parts of this size, e.g. 2G, 700M or "fat32" (needs --zip or --zstd)`)
+	f.includeSaves = fs.Bool("saves", false, "include world saves")
+	f.includeXaero = fs.Bool("xaero", false, "include Xaero maps")
+	f.includeDH = fs.Bool("distant-horizons", false, "include Distant Horizons data")
+	f.includeCrash = fs.Bool("crash-reports", false, "include crash reports, summarized in info.md")
+	f.worldConfig = fs.Bool("world-config", false, "copy level.dat and datapacks when saves are skipped")
+	f.activeWorlds = fs.Bool("active-worlds", false, fmt.Sprintf("only back up worlds played in the last %d days (with --saves)", tui.DefaultActiveDays))
+	f.activeDays = fs.Int("active-days", 0, "only back up worlds played in the last N days (with --saves)")
+	f.skipJunk = fs.Bool("skip-junk", true, "skip lock files, OS metadata and Xaero's map caches")
+	f.lowPriority = fs.Bool("low-priority", false, lowPriorityHelp)
+	f.rateLimit = fs.String("rate-limit", "", "copy at most this many bytes per second, e.g. 20M (0 = no limit)")
+	f.maxFile = fs.String("max-file-size", "", "skip files larger than this, e.g. 2G, and list them in info.md (0 = no cap)")
+	f.memoryLimit = fs.Int64("memory-limit", 0, "soft memory limit in MB for low-RAM servers (0 = none)")
+	f.network = fs.Bool("network", false, "destination is a network share: retry I/O errors, fsync files, limit concurrency")
+	f.verify = fs.Bool("verify", false, "re-read every copied file and compare its hash with the source")
+	f.sign = fs.Bool("sign", false, "sign manifest.json with the local ed25519 key")
+	f.parity = fs.Bool("parity", false, fmt.Sprintf("write parity data next to the archive that rebuilds up to --parity-blocks damaged %d KB blocks in every %d (needs --zip or --zstd)",
+		backup.ParityBlockSize>>10, backup.ParityGroupBlocks))
+	f.parityBlocks = fs.Int("parity-blocks", 1, fmt.Sprintf("recovery blocks per group with --parity, 1-%d; each adds about %d%% of the archive's size",
+		backup.MaxParityBlocks, 100/backup.ParityGroupBlocks))
+	f.deterministic = fs.Bool("deterministic", false, "produce byte-identical archives for identical inputs (not with --password or --gpg)")
+	f.since = fs.String("since", "", `only copy screenshots, saves and xaero files changed since a date (YYYY-MM-DD) or "last" backup`)
+	f.screenshotsSince = fs.String("screenshots-since", "", "override --since for screenshots")
+	f.savesSince = fs.String("saves-since", "", "override --since for saves")
+	f.xaeroSince = fs.String("xaero-since", "", "override --since for xaero maps")
+	f.incremental = fs.Bool("incremental", false, "only copy files whose size or modification time changed since the last backup")
+	f.linkDest = fs.Bool("link-dest", false, "hard-link files unchanged since the last backup folder instead of copying them")
+	f.redactSecrets = fs.Bool("redact-secrets", false, "blank proxy forwarding secrets in the backup")
+	f.redact = fs.Bool("redact", false, "hide usernames, server addresses and absolute paths in info.md")
+	f.modpack = fs.String("modpack", "", "list deviations from this .mrpack in modpack.md")
+	fs.Var(&f.meta, "meta", "label the backup with key=value, e.g. seed, server or season (repeatable)")
+	return f
+}
+
+// config checks the flags against each other and returns the backup config they describe,
+// asking for the zip password if one is needed. The caller fills in the instance and
+// destination, then calls resolve.
+func (f *backupFlags) config() (*tui.Config, error) {
+	meta, err := parseMeta(f.meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLinkDest(*f.linkDest, *f.incremental, *f.zipOutput); err != nil {
+		return nil, err
+	}
+	if err := checkZstd(*f.zstd, *f.zstdLevel, int(f.zstdLong), *f.zipOutput, *f.linkDest); err != nil {
+		return nil, err
+	}
+	splitSize, err := parseSplit(*f.split, *f.zipOutput || *f.zstd)
+	if err != nil {
+		return nil, err
+	}
+	if *f.gpgRecipient != "" && !*f.zipOutput {
+		return nil, fmt.Errorf("--gpg needs --zip")
+	}
+	// gpg picks a random session key for every run
+	if *f.gpgRecipient != "" && *f.deterministic {
+		return nil, fmt.Errorf("--deterministic cannot be used with --gpg: encrypted archives differ on every run")
+	}
+	// AES zips salt every entry at random, so no two runs match
+	if *f.deterministic && *f.password {
+		return nil, fmt.Errorf("--deterministic cannot be used with --password: encrypted zips differ on every run")
+	}
+	if *f.parityBlocks < 1 || *f.parityBlocks > backup.MaxParityBlocks {
+		return nil, fmt.Errorf("--parity-blocks must be between 1 and %d", backup.MaxParityBlocks)
+	}
+	if *f.activeDays < 0 {
+		return nil, fmt.Errorf("--active-days cannot be negative")
+	}
+	rateLimit, err := parseRateLimit(*f.rateLimit, *f.lowPriority)
+	if err != nil {
+		return nil, err
+	}
+	maxFileSize, err := parseMaxFileSize(*f.maxFile)
+	if err != nil {
+		return nil, err
+	}
+	zipPassword, err := readZipPassword(*f.password, *f.zipOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tui.Config{
+		ZipOutput:       *f.zipOutput,
+		ProtectZip:      *f.password,
+		ZipPassword:     zipPassword,
+		GPGRecipient:    *f.gpgRecipient,
+		Zstd:            *f.zstd,
+		ZstdLevel:       *f.zstdLevel,
+		ZstdLong:        int(f.zstdLong),
+		ModMetadata:     *f.modMetadata,
+		SplitSize:       splitSize,
+		IncludeSaves:    *f.includeSaves,
+		IncludeXaero:    *f.includeXaero,
+		IncludeDH:       *f.includeDH,
+		IncludeCrashes:  *f.includeCrash,
+		WorldConfigOnly: *f.worldConfig && !*f.includeSaves,
+		SkipJunk:        *f.skipJunk,
+		MaxFileSize:     maxFileSize,
+		LowPriority:     *f.lowPriority,
+		RateLimit:       rateLimit,
+		MemoryLimit:     *f.memoryLimit << 20,
+		NetworkDest:     *f.network,
+		VerifyCopies:    *f.verify,
+		SignManifest:    *f.sign,
+		Parity:          *f.parity,
+		ParityBlocks:    *f.parityBlocks,
+		Deterministic:   *f.deterministic,
+		RedactSecrets:   *f.redactSecrets,
+		RedactReports:   *f.redact,
+		Meta:            meta,
+		Incremental:     *f.incremental,
+		LinkDest:        *f.linkDest,
+		Modpack:         *f.modpack,
+	}
+	if *f.activeDays > 0 {
+		config.ActiveWorldsDays = *f.activeDays
+	} else if *f.activeWorlds {
+		config.ActiveWorldsDays = tui.DefaultActiveDays
+	}
+	return config, nil
+}
+
+// resolve fills in what depends on config's destination: the --since cutoffs and, for
+// differential, incremental and snapshot backups, the newest backup as their parent
+func (f *backupFlags) resolve(config *tui.Config) error {
+	cutoffs := []struct {
+		flag string
+		dst  *time.Time
+	}{
+		{*f.screenshotsSince, &config.ScreenshotsSince},
+		{*f.savesSince, &config.SavesSince},
+		{*f.xaeroSince, &config.XaeroSince},
+	}
+	for _, c := range cutoffs {
+		value := c.flag
+		if value == "" {
+			value = *f.since
+		}
+		var err error
+		if *c.dst, err = parseSince(value, config.BackupDest); err != nil {
+			return err
+		}
+	}
+	if *f.incremental || *f.linkDest || *f.since != "" || *f.screenshotsSince != "" || *f.savesSince != "" || *f.xaeroSince != "" {
+		config.Parent, _, _ = backup.LatestBackup(config.BackupDest)
+	}
+	return nil
+}
+
+// remoteDestHelp ends the --dest help of the commands that can upload over SFTP. Uploads are
+// staged, not streamed, so the limit is spelled out where the flag is.
+const remoteDestHelp = ", or user@host:/path to upload over SFTP (the whole backup is staged in the temp folder, $TMPDIR, " +
+	"first, which needs room for all of it)"
+
+// runHeadless backs up one instance with the TUI's options given as flags. Output is
+// plain text without colors, and errors go to stderr, so it suits scripts and cron.
+func runHeadless(args []string) int {
+	fs := flag.NewFlagSet("totem", flag.ContinueOnError)
+	mcPath := fs.String("mc-path", "", "Minecraft folder to back up (required)")
+	dest := fs.String("dest", "", "backup destination folder"+remoteDestHelp+" (required)")
+	flags := addBackupFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *mcPath == "" || *dest == "" || fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: totem --mc-path <folder> --dest <folder> [flags]")
+		fs.PrintDefaults()
+		return 2
+	}
+	config, err := flags.config()
+	if err == nil {
+		config.MinecraftPath, config.BackupDest = *mcPath, *dest
+		err = flags.resolve(config)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+	for _, problem := range backup.Preflight(config) {
+		fmt.Fprintf(os.Stderr, "warning: unreadable: %s\n", problem)
+	}
+
+	fmt.Printf("Backing up %s to %s\n", config.MinecraftPath, config.BackupDest)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	result, err := backup.PerformContext(ctx, config, nil)
+	stop()
+	if errors.Is(err, backup.ErrCancelled) {
+		fmt.Fprintln(os.Stderr, `Backup cancelled. Run "totem resume" to finish it.`)
+		return 130
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Output: %s\n", result.OutputPath)
+	fmt.Printf("Files: %d in %s\n", result.TotalFiles, result.Duration.Round(time.Millisecond))
+	fmt.Printf("Health: %d/100\n", result.Health)
+	for _, name := range result.Pruned {
+		fmt.Printf("Pruned: %s\n", name)
+	}
+	for _, w := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "error: %s\n", e)
+	}
+	if !result.Success {
+		return 1
+	}
+	return 0
+}
+
+// Suffix of the file runWatch writes after each triggered backup
+const triggerResultSuffix = ".result"
+
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	dest := fs.String("dest", defaultBackupDest(), "backup destination folder")
+	trigger := fs.String("trigger", "", "trigger file to watch (default: <instance>/totem.trigger)")
+	interval := fs.Duration("interval", 2*time.Second, "how often to check for the trigger file")
+	includeXaero := fs.Bool("xaero", false, "include Xaero maps")
+	crashes := fs.Bool("crashes", true, "snapshot config, options and the latest world when a crash report appears")
+	digestEvery := fs.Duration("digest", 0, "write a digest of all backups in --dest this often, e.g. 168h (0 = never)")
+	mailTo := fs.String("mail", "", "email each digest to these comma-separated addresses (needs --smtp)")
+	smtpAddr := fs.String("smtp", "", "SMTP server as host:port for --mail")
+	lowPriority := fs.Bool("low-priority", false, lowPriorityHelp)
+	rateLimitFlag := fs.String("rate-limit", "", "copy at most this many bytes per second, e.g. 20M (0 = no limit)")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	rateLimit, err := parseRateLimit(*rateLimitFlag, *lowPriority)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Println("Usage: totem watch <instance> [--trigger file] [--dest folder] [--crashes=false]")
+		return 2
+	}
+	instance := positional[0]
+	if *trigger == "" {
+		*trigger = filepath.Join(instance, "totem.trigger")
+	}
+
+	fmt.Printf("%s %s\n", labelStyle.Render("Watching for"), valueStyle.Render(*trigger))
+	fmt.Println(labelStyle.Render("Create that file (from a mod, macro or script) to back up now. Ctrl+C to stop."))
+
+	// Reports already there when watching starts are old news
+	seenCrashes := map[string]bool{}
+	for _, report := range backup.CrashReports(instance) {
+		seenCrashes[report] = true
+	}
+
+	lastDigest := time.Now()
+	for {
+		time.Sleep(*interval)
+		if *digestEvery > 0 && time.Since(lastDigest) >= *digestEvery {
+			now := time.Now()
+			writeDigest(*dest, lastDigest, now, *mailTo, *smtpAddr)
+			lastDigest = now
+		}
+		if *crashes {
+			for _, report := range backup.CrashReports(instance) {
+				if seenCrashes[report] {
+					continue
+				}
+				seenCrashes[report] = true
+				fmt.Printf("  %s crash report %s at %s\n", errorStyle.Render("!"), filepath.Base(report), time.Now().Format("15:04:05"))
+				if out, count, err := backup.CrashSnapshot(instance, *dest, report); err != nil {
+					fmt.Printf("  %s crash snapshot: %v\n", errorStyle.Render("✗"), err)
+				} else {
+					fmt.Printf("  %s %d files → %s\n", successStyle.Render("✓"), count, out)
+				}
+			}
+		}
+		watchTrigger(*trigger, &tui.Config{
+			MinecraftPath: instance,
+			BackupDest:    *dest,
+			IncludeSaves:  true,
+			IncludeXaero:  *includeXaero,
+			SkipJunk:      true,
+			LowPriority:   *lowPriority,
+			RateLimit:     rateLimit,
+		})
+	}
+}
+
+// watchTrigger backs up with config if the trigger file exists, and writes how it went next
+// to the trigger for the requester. It reports whether a backup ran.
+func watchTrigger(trigger string, config *tui.Config) bool {
+	if _, err := os.Stat(trigger); err != nil {
+		return false
+	}
+	// Remove the trigger first so a request made during the backup is not lost
+	os.Remove(trigger)
+
+	fmt.Printf("  %s backup requested at %s\n", labelStyle.Render("→"), time.Now().Format("15:04:05"))
+	res, err := backup.PerformQuiet(config)
+
+	// The result file lets the requester see how it went
+	status := ""
+	switch {
+	case err != nil:
+		status = "error " + err.Error()
+		fmt.Printf("  %s %v\n", errorStyle.Render("✗"), err)
+	case !res.Success:
+		status = "error " + strings.Join(res.Errors, "; ")
+		fmt.Printf("  %s finished with errors: %s\n", errorStyle.Render("✗"), res.OutputPath)
+	default:
+		status = "ok " + res.OutputPath
+		fmt.Printf("  %s %s\n", successStyle.Render("✓"), res.OutputPath)
+	}
+	os.WriteFile(trigger+triggerResultSuffix, []byte(status+"\n"), 0644)
+	return true
+}
+
+func runQuick(args []string) int {
+	fs := flag.NewFlagSet("quick", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance to back up (default: from the last TUI backup)")
+	dest := fs.String("dest", "", "backup destination folder"+remoteDestHelp+" (default: from the last TUI backup)")
+	network := fs.Bool("network", false, "destination is a network share: retry I/O errors, fsync files, limit concurrency")
+	verify := fs.Bool("verify", false, "re-read every copied file and compare its hash with the source")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !applyQuickProfile(instance, dest) {
+		return 2
+	}
+
+	config := &tui.Config{
+		MinecraftPath: *instance,
+		BackupDest:    *dest,
+		IncludeSaves:  true,
+		SkipJunk:      true,
+		NetworkDest:   *network,
+		VerifyCopies:  *verify,
+	}
+	res, err := backup.PerformQuick(config)
+	switch {
+	case err != nil:
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Quick backup failed:"), err)
+		notify("Totem quick backup failed", err.Error())
+		return 1
+	case !res.Success:
+		fmt.Printf("%s %s\n", errorStyle.Render("✗ Quick backup finished with errors:"), strings.Join(res.Errors, "; "))
+		notify("Totem quick backup had errors", res.OutputPath)
+		return 1
+	}
+	fmt.Printf("%s %d files in %s → %s\n", successStyle.Render("✓ Quick backup:"),
+		res.TotalFiles, res.Duration.Round(time.Millisecond), valueStyle.Render(res.OutputPath))
+	notify("Totem quick backup done", fmt.Sprintf("%d files saved to %s", res.TotalFiles, res.OutputPath))
+	return 0
+}
+
+func runArchiveLauncher(args []string) int {
+	fs := flag.NewFlagSet("archive-launcher", flag.ContinueOnError)
+	dest := fs.String("dest", defaultBackupDest(), "backup destination folder")
+	zipOutput := fs.Bool("zip", false, "create a .zip archive")
+	keepDownloads := fs.Bool("keep-downloads", false, "also archive assets, libraries, game jars and bundled Java")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) > 1 {
+		fmt.Println("Usage: totem archive-launcher [launcher name or folder] [--dest folder] [--zip]")
+		return 2
+	}
+
+	// A name picks an installed launcher; anything else is taken as its data folder
+	var launcher instances.Launcher
+	switch {
+	case len(positional) == 0:
+		found := instances.Launchers()
+		if len(found) != 1 {
+			fmt.Println("Usage: totem archive-launcher [launcher name or folder] [--dest folder] [--zip]")
+			for _, l := range found {
+				fmt.Printf("  %s %s\n", valueStyle.Render(l.Name), labelStyle.Render(l.Path))
+			}
+			return 2
+		}
+		launcher = found[0]
+	default:
+		var ok bool
+		if launcher, ok = instances.FindLauncher(positional[0]); !ok {
+			launcher = instances.Launcher{Name: filepath.Base(positional[0]), Path: positional[0]}
+		}
+	}
+
+	if err := backup.LocalOnly("a launcher archive", *dest); err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("%s %s\n", labelStyle.Render("Archiving"), valueStyle.Render(launcher.Path))
+	archive, err := backup.ArchiveLauncher(ctx, launcher.Path, filepath.Join(*dest, safeName(launcher.Name)), *zipOutput, *keepDownloads)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Archive failed:"), err)
+		return 1
+	}
+	fmt.Printf("%s %d files → %s\n", successStyle.Render("✓ Archived:"), archive.Files, valueStyle.Render(archive.OutputPath))
+	if len(archive.Skipped) > 0 {
+		fmt.Printf("  %s %d items (%s), listed in skipped.md\n",
+			labelStyle.Render("Skipped:"), len(archive.Skipped), formatBytes(archive.SkippedSize))
+	}
+	return 0
+}
+
+func runSupportBundle(args []string) int {
+	fs := flag.NewFlagSet("support-bundle", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance to collect from (default: from the last TUI backup)")
+	dest := fs.String("dest", "", "folder to write the bundle to (default: from the last TUI backup)")
+	redact := fs.Bool("redact", false, "hide usernames, server addresses and absolute paths")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !applyQuickProfile(instance, dest) {
+		return 2
+	}
+
+	path, err := backup.SupportBundle(*instance, *dest, *redact)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Support bundle failed:"), err)
+		return 1
+	}
+	fmt.Printf("%s %s\n", successStyle.Render("✓ Support bundle:"), valueStyle.Render(path))
+	fmt.Printf("  %s\n", labelStyle.Render("Attach the zip to the issue and paste support.md into its description."))
+	return 0
+}
+
+func runComparePack(args []string) int {
+	fs := flag.NewFlagSet("compare-pack", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance to compare (default: from the last TUI backup)")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Println("Usage: totem compare-pack <pack.mrpack> [--instance folder]")
+		return 2
+	}
+	dest := "-" // Only the instance is needed from the profile
+	if !applyQuickProfile(instance, &dest) {
+		return 2
+	}
+
+	d, err := backup.ComparePack(positional[0], *instance)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	fmt.Printf("%s %s %s: %d unchanged, %d deviations\n",
+		labelStyle.Render("Modpack"), valueStyle.Render(d.Name), d.Version, d.Unchanged, d.Deviations())
+	printItems := func(mark string, items []string) {
+		for _, item := range items {
+			fmt.Printf("  %s %s\n", mark, item)
+		}
+	}
+	printItems("+", d.Added)
+	printItems("-", d.Removed)
+	for _, c := range d.Changed {
+		if c.Installed != "" {
+			fmt.Printf("  ~ %s → %s\n", c.Path, c.Installed)
+		} else {
+			fmt.Printf("  ~ %s\n", c.Path)
+		}
+	}
+	printItems("⏸", d.Disabled)
+	printItems("✎", d.OverridesChanged)
+	printItems("✗", d.OverridesRemoved)
+	return 0
+}
+
+func runDigest(args []string) int {
+	fs := flag.NewFlagSet("digest", flag.ContinueOnError)
+	dest := fs.String("dest", defaultBackupDest(), "backup destination folder")
+	days := fs.Int("days", 7, "how many days back to summarize")
+	mailTo := fs.String("mail", "", "email the digest to these comma-separated addresses (needs --smtp)")
+	smtpAddr := fs.String("smtp", "", "SMTP server as host:port for --mail")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	now := time.Now()
+	if !writeDigest(*dest, now.AddDate(0, 0, -*days), now, *mailTo, *smtpAddr) {
+		return 1
+	}
+	return 0
+}
+
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dest := fs.String("dest", defaultBackupDest(), "destination folder to add the backups to")
+	at := fs.String("time", "", `when the copy was made, as "YYYY-MM-DD" or "YYYY-MM-DD HH:MM" (default: from its name or newest file)`)
+	zipOutput := fs.Bool("zip", false, "store imported folders as .zip (zips always stay zipped)")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) == 0 {
+		fmt.Println("Usage: totem import <folder|zip|backups folder>... [--dest folder] [--time date] [--zip]")
+		return 2
+	}
+
+	var when time.Time
+	if *at != "" {
+		for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+			if when, err = time.ParseInLocation(layout, *at, time.Local); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			fmt.Printf("%s invalid --time %q (want YYYY-MM-DD or \"YYYY-MM-DD HH:MM\")\n", errorStyle.Render("✗"), *at)
+			return 2
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var sources []string
+	for _, arg := range positional {
+		sources = append(sources, backup.ImportSources(arg)...)
+	}
+	failed := 0
+	for _, source := range sources {
+		zipped := *zipOutput || strings.EqualFold(filepath.Ext(source), ".zip")
+		imported, err := backup.Import(ctx, source, *dest, when, zipped)
+		if err != nil {
+			fmt.Printf("%s %s: %v\n", errorStyle.Render("✗"), source, err)
+			failed++
+			if errors.Is(err, backup.ErrCancelled) {
+				return 130
+			}
+			continue
+		}
+		kind := "manual copy"
+		switch {
+		case imported.Totem:
+			kind = "Totem backup"
+		case len(imported.Worlds) > 0:
+			kind = "world backup: " + strings.Join(imported.Worlds, ", ")
+		}
+		fmt.Printf("%s %s (%s from %s, %d files) → %s\n", successStyle.Render("✓"), source, kind,
+			imported.Time.Format("2006-01-02 15:04"), imported.Files, valueStyle.Render(imported.OutputPath))
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Matching paths shown per backup before the rest are summarized
+const searchShowLimit = 5
+
+func runSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	dest := fs.String("dest", defaultBackupDest(), "backup destination folder")
+	mods := fs.Bool("mods", false, "also search mod lists and the names and ids inside backed up jars")
+	all := fs.Bool("all", false, "show every matching path, not just the first few per backup")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Println("Usage: totem search <pattern> [--dest folder] [--mods] [--all]")
+		return 2
+	}
+
+	matches, searched, err := backup.SearchBackups(*dest, positional[0], *mods)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	if len(matches) == 0 {
+		fmt.Printf("%s %q in %d backups\n", labelStyle.Render("No match for"), positional[0], searched)
+		return 1
+	}
+	for _, m := range matches {
+		fmt.Printf("%s %s\n", valueStyle.Render(m.Backup), labelStyle.Render(fmt.Sprintf("(%d matches)", len(m.Paths))))
+		shown := m.Paths
+		if !*all && len(shown) > searchShowLimit {
+			shown = shown[:searchShowLimit]
+		}
+		for _, p := range shown {
+			fmt.Printf("  %s\n", p)
+		}
+		if len(shown) < len(m.Paths) {
+			fmt.Printf("  %s\n", labelStyle.Render(fmt.Sprintf("… %d more (--all to list them)", len(m.Paths)-len(shown))))
+		}
+	}
+	newest := matches[len(matches)-1]
+	fmt.Printf("\n%s %d of %d backups; newest: %s\n", successStyle.Render("✓ Found in"), len(matches), searched, newest.Backup)
+	return 0
+}
+
+// writeDigest builds, writes and optionally emails the digest of backups in dest between from and to
+func writeDigest(dest string, from, to time.Time, mailTo, smtpAddr string) bool {
+	d, err := backup.BuildDigest(dest, from, to)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Digest failed:"), err)
+		return false
+	}
+	path, err := backup.WriteDigest(d)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Digest failed:"), err)
+		return false
+	}
+	backups, failures, size := d.Totals()
+	fmt.Printf("%s %d backups, %d failed, %s → %s\n", successStyle.Render("✓ Digest:"), backups, failures, formatBytes(size), valueStyle.Render(path))
+	if mailTo == "" {
+		return true
+	}
+	if smtpAddr == "" {
+		fmt.Printf("%s --mail needs --smtp host:port\n", errorStyle.Render("✗"))
+		return false
+	}
+	if err := backup.MailDigest(d, smtpAddr, mailTo); err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Digest email failed:"), err)
+		return false
+	}
+	fmt.Printf("  %s %s\n", labelStyle.Render("Emailed to"), mailTo)
+	return true
+}
+
+// applyQuickProfile fills an empty instance or destination from the saved quick profile
+func applyQuickProfile(instance, dest *string) bool {
+	if *instance != "" && *dest != "" {
+		return true
+	}
+	profile, err := backup.LoadQuickProfile()
+	if err != nil && *instance == "" {
+		fmt.Printf("%s no saved profile yet; run one backup from the TUI or pass --instance\n", errorStyle.Render("✗"))
+		return false
+	}
+	if profile != nil && *instance == "" {
+		*instance = profile.MinecraftPath
+	}
+	if *dest == "" {
+		*dest = defaultBackupDest()
+		if profile != nil && profile.BackupDest != "" {
+			*dest = profile.BackupDest
+		}
+	}
+	return true
+}
+
+func runCheckpoint(args []string) int {
+	fs := flag.NewFlagSet("checkpoint", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance to snapshot (default: from the last TUI backup)")
+	dest := fs.String("dest", "", "backup destination folder (default: from the last TUI backup)")
+	list := fs.Bool("list", false, "list checkpoints instead of creating one")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if !*list && len(positional) != 1 {
+		fmt.Println(`Usage: totem checkpoint "<label>" [--instance folder] [--dest folder]`)
+		fmt.Println("       totem checkpoint --list")
+		return 2
+	}
+	if !applyQuickProfile(instance, dest) {
+		return 2
+	}
+	if err := backup.LocalOnly("a checkpoint", *dest); err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 2
+	}
+	dir := checkpointDir(*instance, *dest)
+
+	if *list {
+		checkpoints, err := backup.ListCheckpoints(dir)
+		if err != nil {
+			fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+			return 1
+		}
+		if len(checkpoints) == 0 {
+			fmt.Printf("%s no checkpoints in %s\n", labelStyle.Render("–"), dir)
+		}
+		for _, c := range checkpoints {
+			fmt.Printf("  %s %s  %s\n", valueStyle.Render(fmt.Sprintf("%-36s", c.Label)),
+				labelStyle.Render(c.Created.Format("2006-01-02 15:04")), labelStyle.Render(fmt.Sprintf("%d files", c.Files)))
+		}
+		return 0
+	}
+
+	info, err := backup.Checkpoint(*instance, dir, safeName(positional[0]))
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Checkpoint failed:"), err)
+		return 1
+	}
+	fmt.Printf("%s %q (%d files). Undo an update with: totem rollback %q\n",
+		successStyle.Render("✓ Checkpoint"), info.Label, info.Files, info.Label)
+	return 0
+}
+
+func runRollback(args []string) int {
+	if kioskRefuses("rollback") {
+		return 1
+	}
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance to roll back (default: from the last TUI backup)")
+	dest := fs.String("dest", "", "backup destination folder (default: from the last TUI backup)")
+	preview := fs.Bool("preview", false, "only show what would change")
+	yes := fs.Bool("yes", false, "apply without asking for confirmation")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Println(`Usage: totem rollback <label|backup folder> [--instance folder] [--dest folder] [--preview] [--yes]`)
+		return 2
+	}
+	if !applyQuickProfile(instance, dest) {
+		return 2
+	}
+	dir := checkpointDir(*instance, *dest)
+
+	// Accept a backup folder as well as a checkpoint label
+	source := positional[0]
+	if info, err := os.Stat(source); err != nil || !info.IsDir() {
+		source = filepath.Join(dir, safeName(positional[0]))
+		if _, err := os.Stat(filepath.Join(source, "checkpoint.json")); err != nil {
+			fmt.Printf("%s no checkpoint or backup folder named %q\n", errorStyle.Render("✗"), positional[0])
+			return 1
+		}
+	}
+
+	changes, err := backup.RollbackChanges(*instance, source)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	if len(changes) == 0 {
+		fmt.Printf("%s mods, config and options already match %s\n", successStyle.Render("✓"), source)
+		return 0
+	}
+	marks := map[string]string{"restore": successStyle.Render("+"), "replace": labelStyle.Render("~"), "remove": errorStyle.Render("-")}
+	for _, c := range changes {
+		fmt.Printf("  %s %s\n", marks[c.Kind], c.Path)
+	}
+	fmt.Printf("\n  %d changes; saves are not touched\n", len(changes))
+	if *preview {
+		return 0
+	}
+	if !*yes {
+		fmt.Print("  Apply? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println(labelStyle.Render("  Nothing changed"))
+			return 0
+		}
+	}
+
+	// Keep the current state too, in case the rollback itself is regretted
+	safety := "before-rollback_" + time.Now().Format("2006-01-02_15-04-05")
+	if _, err := backup.Checkpoint(*instance, dir, safety); err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Could not save current state, nothing changed:"), err)
+		return 1
+	}
+
+	count, err := backup.Rollback(*instance, source)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Rollback failed:"), err)
+		fmt.Printf("  %s totem rollback %s\n", labelStyle.Render("Previous state saved; restore it with"), safety)
+		return 1
+	}
+	fmt.Printf("%s %d files restored from %s (previous state saved as %q)\n",
+		successStyle.Render("✓ Rolled back:"), count, positional[0], safety)
+	return 0
+}
+
+// checkpointDir is where checkpoints for an instance are kept
+func checkpointDir(instance, dest string) string {
+	abs, err := filepath.Abs(instance)
+	if err != nil {
+		abs = instance
+	}
+	return filepath.Join(dest, "checkpoints", safeName(filepath.Base(abs)))
+}
+
+// notify shows a desktop notification, ignoring failures (headless machines have none)
+func notify(title, message string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		script := fmt.Sprintf(`[reflection.assembly]::loadwithpartialname('System.Windows.Forms') | Out-Null;`+
+			`$n = New-Object System.Windows.Forms.NotifyIcon; $n.Icon = [System.Drawing.SystemIcons]::Information;`+
+			`$n.Visible = $true; $n.ShowBalloonTip(5000, '%s', '%s', 'Info'); Start-Sleep 5; $n.Dispose()`,
+			strings.ReplaceAll(title, "'", "''"), strings.ReplaceAll(message, "'", "''"))
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", message, title))
+	default:
+		cmd = exec.Command("notify-send", title, message)
+	}
+	cmd.Run()
+}
+
+// batchResult pairs an instance with the outcome of its backup
+type batchResult struct {
+	Instance instances.Instance
+	Result   *backup.Result
+	Err      error
+}
+
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	var paths stringList
+	allInstances := fs.Bool("all-instances", false, "back up every detected launcher instance")
+	fs.Var(&paths, "instance", "instance game directory to back up (repeatable)")
+	fleetFile := fs.String("fleet", "", "back up every server listed in a fleet config (JSON)")
+	dest := fs.String("dest", defaultBackupDest(), "backup destination folder"+remoteDestHelp)
+	parallel := fs.Bool("parallel", false, "back up instances concurrently")
+	combine := fs.Bool("combine", false, "put every instance into one folder with a combined report (one .zip with --zip)")
+	flags := addBackupFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*flags.incremental || *flags.linkDest) && *combine {
+		fmt.Printf("%s --incremental and --link-dest cannot be used with --combine\n", errorStyle.Render("✗"))
+		return 2
+	}
+	if (*flags.zstd || *flags.split != "" || *flags.password || *flags.gpgRecipient != "") && *combine {
+		fmt.Printf("%s --zstd, --split, --password and --gpg cannot be used with --combine\n", errorStyle.Render("✗"))
+		return 2
+	}
+	if *combine {
+		if err := backup.LocalOnly("--combine", *dest); err != nil {
+			fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+			return 2
+		}
+	}
+	base, err := flags.config()
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 2
+	}
+
+	var targets []instances.Instance
+	if *allInstances {
+		targets = append(targets, instances.Detect()...)
+	}
+	for _, p := range paths {
+		path := p
+		// A launcher folder or instance root with exactly one game folder inside is unambiguous
+		if fixed := backup.CorrectMinecraftPath(p); len(fixed) == 1 {
+			fmt.Printf("  %s %s has no Minecraft folders; using %s\n", labelStyle.Render("→"), p, fixed[0])
+			path = fixed[0]
+		}
+		targets = append(targets, instances.Instance{
+			Name:     filepath.Base(p),
+			Launcher: "Custom",
+			Path:     path,
+		})
+	}
+	// Fleet servers may live elsewhere; they are staged locally when their turn comes
+	servers := map[int]fleet.Server{}
+	if *fleetFile != "" {
+		cfg, err := fleet.Load(*fleetFile)
+		if err != nil {
+			fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+			return 2
+		}
+		for _, srv := range cfg.Servers {
+			servers[len(targets)] = srv
+			targets = append(targets, instances.Instance{
+				Name:     srv.Name,
+				Launcher: "Fleet",
+				Path:     srv.Source(),
+			})
+		}
+	}
+	if len(targets) == 0 {
+		fmt.Printf("%s no instances to back up (use --all-instances, --instance or --fleet)\n", errorStyle.Render("✗"))
+		return 2
+	}
+
+	fmt.Println(renderLogo())
+	fmt.Printf("\n  %s\n\n", titleStyle.Render(fmt.Sprintf("Backing up %d instances...", len(targets))))
+
+	start := time.Now()
+	results := make([]batchResult, len(targets))
+	// Ctrl+C stops the running backups cleanly; their journals are kept for `totem resume`
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// A combined backup stages each instance next to the others, then moves it into place
+	combinedRoot := ""
+	if *combine {
+		combinedRoot = backup.NewCombinedPath(*dest, start)
+		if err := os.MkdirAll(combinedRoot, 0755); err != nil {
+			fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+			return 1
+		}
+	}
+
+	run := func(i int) {
+		inst := targets[i]
+		sourcePath := inst.Path
+		if srv, ok := servers[i]; ok {
+			staged, cleanup, err := srv.Stage()
+			if err != nil {
+				results[i] = batchResult{Instance: inst, Err: err}
+				return
+			}
+			defer cleanup()
+			sourcePath = staged
+		}
+		config := new(tui.Config)
+		*config = *base
+		config.MinecraftPath = sourcePath
+		config.BackupDest = instanceDest(*dest, safeName(inst.Launcher+"_"+inst.Name))
+		if err := flags.resolve(config); err != nil {
+			results[i] = batchResult{Instance: inst, Err: err}
+			return
+		}
+
+		for _, problem := range backup.Preflight(config) {
+			fmt.Printf("  %s unreadable: %s\n", errorStyle.Render("!"), problem)
+		}
+
+		if *combine {
+			config.BackupDest = filepath.Join(combinedRoot, ".staging-"+safeName(inst.Launcher+"_"+inst.Name))
+			config.ZipOutput = false
+		}
+
+		res, err := backup.PerformContext(ctx, config, nil)
+		if *combine {
+			switch {
+			case err == nil:
+				folder, moveErr := backup.AdoptCombined(combinedRoot, res.OutputPath, safeName(inst.Launcher+"_"+inst.Name))
+				if moveErr != nil {
+					err = moveErr
+				} else {
+					res.OutputPath = folder
+				}
+			case !errors.Is(err, backup.ErrCancelled):
+				// Nothing to resume; keep the archive free of half-written folders
+				os.RemoveAll(config.BackupDest)
+			}
+		}
+		results[i] = batchResult{Instance: inst, Result: res, Err: err}
+	}
+
+	if *parallel {
+		limit := len(targets)
+		if backup.NetworkMode(&tui.Config{BackupDest: *dest, NetworkDest: base.NetworkDest}) {
+			limit = backup.NetworkParallelism
+		}
+		slots := make(chan struct{}, limit)
+		var wg sync.WaitGroup
+		for i := range targets {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				run(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range targets {
+			fmt.Printf("  %s %s\n", labelStyle.Render("→"), targets[i].Name)
+			run(i)
+		}
+	}
+
+	elapsed := time.Since(start)
+	failed := printBatchSummary(results, elapsed)
+	if *combine && ctx.Err() == nil {
+		var entries []backup.CombinedEntry
+		for _, r := range results {
+			entry := backup.CombinedEntry{Name: r.Instance.Name, Launcher: r.Instance.Launcher, Source: r.Instance.Path}
+			switch {
+			case r.Err != nil:
+				entry.Err = r.Err.Error()
+			case !r.Result.Success:
+				entry.Folder, entry.Files = filepath.Base(r.Result.OutputPath), r.Result.TotalFiles
+				entry.Err = strings.Join(r.Result.Errors, "; ")
+			default:
+				entry.Folder, entry.Files = filepath.Base(r.Result.OutputPath), r.Result.TotalFiles
+			}
+			entries = append(entries, entry)
+		}
+		if path, err := backup.FinishCombined(combinedRoot, entries, elapsed, base.ZipOutput); err != nil {
+			fmt.Printf("%s %v\n", errorStyle.Render("✗ Combined backup failed:"), err)
+			failed++
+		} else {
+			fmt.Printf("  %s %s\n\n", labelStyle.Render("Combined backup:"), valueStyle.Render(path))
+		}
+	}
+	if len(servers) > 0 {
+		var entries []fleet.ReportEntry
+		for i, r := range results {
+			srv, ok := servers[i]
+			if !ok {
+				continue
+			}
+			entry := fleet.ReportEntry{Server: srv}
+			switch {
+			case r.Err != nil:
+				entry.Err = r.Err.Error()
+			case !r.Result.Success:
+				entry.Output, entry.Files = r.Result.OutputPath, r.Result.TotalFiles
+				entry.Err = strings.Join(r.Result.Errors, "; ")
+			default:
+				entry.Output, entry.Files = r.Result.OutputPath, r.Result.TotalFiles
+			}
+			entries = append(entries, entry)
+		}
+		if path, err := fleet.WriteReport(*dest, entries, elapsed); err != nil {
+			fmt.Printf("%s %v\n", errorStyle.Render("✗ Fleet report failed:"), err)
+		} else {
+			fmt.Printf("  %s %s\n\n", labelStyle.Render("Fleet report:"), valueStyle.Render(path))
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// checkLinkDest rejects --link-dest with options it cannot work with: snapshots are
+// folders that link into each other, not zips or chains
+func checkLinkDest(linkDest, incremental, zipOutput bool) error {
+	switch {
+	case linkDest && incremental:
+		return fmt.Errorf("--link-dest and --incremental cannot be used together")
+	case linkDest && zipOutput:
+		return fmt.Errorf("--link-dest needs backup folders; it cannot be used with --zip")
+	}
+	return nil
+}
+
+// zstdLongFlag is --zstd-long, whose window log is optional: bare, it turns on zstd's
+// long-distance matching with zstd's default window
+type zstdLongFlag int
+
+func (f *zstdLongFlag) String() string { return strconv.Itoa(int(*f)) }
+
+func (f *zstdLongFlag) IsBoolFlag() bool { return true }
+
+func (f *zstdLongFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*f = backup.DefaultZstdLong
+		return nil
+	case "false":
+		*f = 0
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < backup.MinZstdLong || n > backup.MaxZstdLong {
+		return fmt.Errorf("window log must be between %d and %d", backup.MinZstdLong, backup.MaxZstdLong)
+	}
+	*f = zstdLongFlag(n)
+	return nil
+}
+
+// checkZstd rejects a zstd level out of range and --zstd with options that need another
+// output: a zip, or folders to hard-link into
+func checkZstd(zstd bool, level, long int, zipOutput, linkDest bool) error {
+	switch {
+	case level < 0 || level > backup.MaxZstdLevel:
+		return fmt.Errorf("--zstd-level must be between 1 and %d", backup.MaxZstdLevel)
+	case level > 0 && !zstd:
+		return fmt.Errorf("--zstd-level needs --zstd")
+	case long > 0 && !zstd:
+		return fmt.Errorf("--zstd-long needs --zstd")
+	case zstd && zipOutput:
+		return fmt.Errorf("--zip and --zstd cannot be used together")
+	case zstd && linkDest:
+		return fmt.Errorf("--link-dest needs backup folders; it cannot be used with --zstd")
+	}
+	return nil
+}
+
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	to := fs.String("to", "", "folder to restore into (required)")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 || *to == "" {
+		fmt.Println("Usage: totem restore <backup> --to <folder>")
+		return 2
+	}
+
+	chain, err := backup.ResolveChain(positional[0])
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Restore failed:"), err)
+		return 1
+	}
+	// Kiosk builds restore only into places that would not lose files
+	restore := backup.Restore
+	if kioskMode {
+		restore = func(backupPath, to string) (int, error) {
+			return backup.ExtractNew(backupPath, []string{"."}, to)
+		}
+	}
+	count, err := restore(positional[0], *to)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Restore failed:"), err)
+		return 1
+	}
+	fmt.Printf("%s %d files from %d backups into %s\n",
+		successStyle.Render("✓ Restored"), count, len(chain), valueStyle.Render(*to))
+	return 0
+}
+
+// runNewPC walks through restoring a backup onto a machine that has never run the
+// instance: picking or creating a launcher instance, restoring settings, configs and saves,
+// and leaving a checklist of the mods, shaders and packs to download again
+func runNewPC(args []string) int {
+	fs := flag.NewFlagSet("new-pc", flag.ContinueOnError)
+	to := fs.String("to", "", "instance folder to restore into (default: choose from this PC's launchers)")
+	name := fs.String("name", "", "name for a new launcher instance (default: from the Minecraft version)")
+	yes := fs.Bool("yes", false, "restore without asking for confirmation (needs --to)")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 || (*yes && *to == "") {
+		fmt.Println("Usage: totem new-pc <backup> [--to folder] [--name instance] [--yes]")
+		return 2
+	}
+	backupPath := positional[0]
+
+	plan, err := backup.PlanNewPC(backupPath)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	fmt.Printf("%s %s\n", labelStyle.Render("Backup:   "), valueStyle.Render(filepath.Base(backupPath)))
+	fmt.Printf("%s %s, %s\n", labelStyle.Render("Game:     "), plan.Minecraft, plan.Loader)
+	fmt.Printf("%s %s (%d files)\n", labelStyle.Render("Restores: "), strings.Join(plan.Items, ", "), plan.Files)
+	fmt.Printf("%s %d mods, %d shaders, %d resource packs\n\n", labelStyle.Render("Download: "),
+		len(plan.Mods), len(plan.Shaders), len(plan.ResourcePacks))
+
+	in := bufio.NewReader(os.Stdin)
+	instance := *to
+	if instance == "" {
+		if instance, err = chooseNewPCInstance(in, plan, *name); err != nil {
+			fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+			return 1
+		}
+	} else if err := os.MkdirAll(instance, 0755); err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+
+	if !*yes {
+		fmt.Printf("  Restore into %s? [y/N] ", instance)
+		answer, _ := in.ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println(labelStyle.Render("  Nothing restored"))
+			return 0
+		}
+	}
+	// Kiosk builds keep whatever the instance already has
+	count, err := backup.RestoreNewPC(backupPath, instance, !kioskMode)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Restore failed:"), err)
+		return 1
+	}
+	fmt.Printf("%s %d files into %s\n", successStyle.Render("✓ Restored"), count, valueStyle.Render(instance))
+
+	checklist, err := backup.WriteChecklist(instance, backupPath, plan)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Could not write the checklist:"), err)
+		return 1
+	}
+	fmt.Printf("\n  %s %s\n", labelStyle.Render("Still to do, also in"), valueStyle.Render(checklist))
+	if plan.Minecraft != "Unknown" {
+		fmt.Printf("  • Minecraft %s", plan.Minecraft)
+	} else {
+		fmt.Print("  • Minecraft (the backup does not record the version)")
+	}
+	if plan.Loader != "Unknown" {
+		fmt.Printf(" with %s", plan.Loader)
+	}
+	fmt.Println()
+	for _, mod := range plan.Mods {
+		fmt.Printf("  • %s %s\n", mod, labelStyle.Render(backup.DownloadSearchURL("mods", mod)))
+	}
+	if n := len(plan.Shaders) + len(plan.ResourcePacks); n > 0 {
+		fmt.Printf("  • %d shaders and resource packs, listed in the checklist\n", n)
+	}
+	if plan.ModHashes && len(plan.Mods) > 0 {
+		fmt.Printf("\n  %s totem check-mods %s --instance %s\n",
+			labelStyle.Render("Once the mods are downloaded, check them with"), backupPath, instance)
+	}
+	return 0
+}
+
+// chooseNewPCInstance asks which instance on this PC a new-PC restore goes into, offering the
+// instances found, a new instance in each launcher that Totem can create them in, and the
+// Minecraft Launcher's .minecraft when it does not exist yet
+func chooseNewPCInstance(in *bufio.Reader, plan *backup.NewPCPlan, name string) (string, error) {
+	type choice struct {
+		label  string
+		create func() (instances.Instance, error)
+	}
+	var choices []choice
+	vanilla := false
+	for _, inst := range instances.Detect() {
+		vanilla = vanilla || inst.Launcher == "Minecraft Launcher"
+		choices = append(choices, choice{
+			label:  fmt.Sprintf("%s / %s %s", inst.Launcher, inst.Name, labelStyle.Render(inst.Path)),
+			create: func() (instances.Instance, error) { return inst, nil },
+		})
+	}
+	minecraft := plan.Minecraft
+	if minecraft == "Unknown" {
+		minecraft = ""
+	}
+	if name == "" {
+		name = strings.TrimSpace("Restored " + minecraft)
+	}
+	for _, l := range instances.Launchers() {
+		if instances.CanCreate(l) {
+			choices = append(choices, choice{
+				label:  fmt.Sprintf("New %s instance %q", l.Name, name),
+				create: func() (instances.Instance, error) { return instances.Create(l, name, minecraft) },
+			})
+		}
+	}
+	if !vanilla {
+		choices = append(choices, choice{label: "Minecraft Launcher (.minecraft, created now)", create: instances.Vanilla})
+	}
+
+	for i, c := range choices {
+		fmt.Printf("  %s %s\n", valueStyle.Render(fmt.Sprintf("%d.", i+1)), c.label)
+	}
+	fmt.Printf("  Restore into [1-%d]: ", len(choices))
+	answer, _ := in.ReadString('\n')
+	var n int
+	if _, err := fmt.Sscan(strings.TrimSpace(answer), &n); err != nil || n < 1 || n > len(choices) {
+		return "", fmt.Errorf("no instance chosen")
+	}
+	inst, err := choices[n-1].create()
+	if err != nil {
+		return "", err
+	}
+	return inst.Path, nil
+}
+
+// runCheckMods checks the mods downloaded after a new-PC restore against the backup's hashes
+func runCheckMods(args []string) int {
+	fs := flag.NewFlagSet("check-mods", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance whose mods/ to check (required)")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 || *instance == "" {
+		fmt.Println("Usage: totem check-mods <backup> --instance <folder>")
+		return 2
+	}
+
+	checks, err := backup.CheckMods(positional[0], *instance)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	counts := map[string]int{}
+	for _, c := range checks {
+		counts[c.Status]++
+		switch c.Status {
+		case "rejected":
+			fmt.Printf("  %s %s %s\n", errorStyle.Render("✗"), c.File,
+				labelStyle.Render("does not match the backup; renamed to "+c.File+backup.RejectedSuffix))
+		case "missing":
+			fmt.Printf("  %s %s %s\n", labelStyle.Render("-"), c.File, labelStyle.Render("not downloaded yet"))
+		case "extra":
+			fmt.Printf("  %s %s %s\n", labelStyle.Render("?"), c.File, labelStyle.Render("not in the backup, not checked"))
+		}
+	}
+	fmt.Printf("\n%s %d match, %d rejected, %d missing, %d not in the backup\n", labelStyle.Render("Mods:"),
+		counts["ok"], counts["rejected"], counts["missing"], counts["extra"])
+	if counts["rejected"] > 0 {
+		fmt.Println(labelStyle.Render("  Download rejected mods again from their official page, or use the copy from the old PC."))
+		return 1
+	}
+	return 0
+}
+
+func runExtract(args []string) int {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	to := fs.String("to", ".", "folder to extract into")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) < 2 {
+		fmt.Println("Usage: totem extract <backup> <path>... [--to folder]")
+		return 2
+	}
+
+	count, err := extractFiles(positional[0], positional[1:], *to)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Extract failed:"), err)
+		if count == 0 {
+			return 1
+		}
+	}
+	fmt.Printf("%s %d files into %s\n", successStyle.Render("✓ Extracted"), count, valueStyle.Render(*to))
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runBrowse(args []string) int {
+	fs := flag.NewFlagSet("browse", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance that restores write into (default: from the last TUI backup)")
+	dest := fs.String("dest", "", "backup destination folder (default: from the last TUI backup)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if profile, err := backup.LoadQuickProfile(); err == nil {
+		if *instance == "" {
+			*instance = profile.MinecraftPath
+		}
+		if *dest == "" {
+			*dest = profile.BackupDest
+		}
+	}
+	if *dest == "" {
+		*dest = defaultBackupDest()
+	}
+
+	if err := tui.Browse(*backupBrowser(*instance, *dest)); err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	return 0
+}
+
+// backupBrowser wires the TUI's manage screen to the backups in dest
+func backupBrowser(instance, dest string) *tui.Browser {
+	homeDir, _ := os.UserHomeDir()
+	return &tui.Browser{
+		Dest:      dest,
+		Instance:  instance,
+		ExtractTo: filepath.Join(homeDir, "TotemExtract"),
+		List:      backup.ListBackups,
+		Files:     backup.BackupContents,
+		Extract:   extractFiles,
+		Read:      backup.ReadBackupFile,
+		Open:      backup.OpenFile,
+
+		KeepExisting: kioskMode,
+	}
+}
+
+func runRules(args []string) int {
+	fs := flag.NewFlagSet("rules", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance to edit rules for (default: from the last TUI backup)")
+	list := fs.Bool("list", false, "print the saved rules instead of editing them")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *instance == "" {
+		profile, err := backup.LoadQuickProfile()
+		if err != nil {
+			fmt.Printf("%s no saved profile yet; run one backup from the TUI or pass --instance\n", errorStyle.Render("✗"))
+			return 2
+		}
+		*instance = profile.MinecraftPath
+	}
+
+	if *list {
+		rules, err := backup.LoadRules(*instance)
+		if err != nil {
+			fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+			return 1
+		}
+		if len(rules.Include) == 0 && len(rules.Exclude) == 0 {
+			fmt.Printf("%s %s\n", labelStyle.Render("No rules for"), valueStyle.Render(*instance))
+			return 0
+		}
+		for _, p := range rules.Include {
+			fmt.Printf("%s %s\n", successStyle.Render("+ always"), p)
+		}
+		for _, p := range rules.Exclude {
+			fmt.Printf("%s %s\n", errorStyle.Render("- never "), p)
+		}
+		return 0
+	}
+
+	if err := tui.EditRules(*instance, *rulesEditor()); err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	return 0
+}
+
+// rulesEditor wires the TUI's rules screen to the saved rules
+func rulesEditor() *tui.RulesEditor {
+	return &tui.RulesEditor{
+		Folders: backup.InstanceFolders,
+		Load:    backup.LoadRules,
+		Save:    backup.SaveRules,
+	}
+}
+
+// parseMeta turns --meta key=value flags into labels
+func parseMeta(flags []string) (map[string]string, error) {
+	if len(flags) == 0 {
+		return nil, nil
+	}
+	meta := map[string]string{}
+	for _, f := range flags {
+		key, value, ok := strings.Cut(f, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("--meta needs key=value, got %q", f)
+		}
+		meta[key] = strings.TrimSpace(value)
+	}
+	return meta, nil
+}
+
+func runList(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	dest := fs.String("dest", defaultBackupDest(), "backup destination folder")
+	var filters stringList
+	fs.Var(&filters, "filter", "only backups labelled key=value, or having key (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	items, err := backup.ListBackups(*dest)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	shown := 0
+	for _, item := range items {
+		if !backup.MatchesMeta(item.Meta, filters) {
+			continue
+		}
+		shown++
+		var labels []string
+		for _, k := range slices.Sorted(maps.Keys(item.Meta)) {
+			labels = append(labels, k+"="+item.Meta[k])
+		}
+		game := strings.TrimSpace(item.Minecraft + " " + item.Loader)
+		if game == "" {
+			game = "?"
+		}
+		fmt.Printf("%s  %9s  %-14s  %s  %s\n", valueStyle.Render(item.Time.Format("2006-01-02 15:04")),
+			formatBytes(item.Size), game, item.Path, labelStyle.Render(strings.Join(labels, " ")))
+		if item.Contents != "" {
+			fmt.Printf("  %s\n", labelStyle.Render(item.Contents))
+		}
+		if item.Key != "" {
+			fmt.Printf("  %s\n", labelStyle.Render("encrypted with "+item.Key))
+		}
+	}
+	if shown == 0 {
+		fmt.Printf("%s %s\n", labelStyle.Render("No matching backups in"), *dest)
+		return 1
+	}
+	return 0
+}
+
+func runPrune(args []string) int {
+	if kioskRefuses("prune") {
+		return 1
+	}
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	dest := fs.String("dest", defaultBackupDest(), "backup destination folder (local only; sftp destinations are never pruned)")
+	dryRun := fs.Bool("dry-run", false, "only list what would be deleted")
+	var policy backup.Retention
+	fs.IntVar(&policy.KeepLast, "keep-last", 0, "keep the newest N backups (overrides config.json with any --keep flag)")
+	fs.IntVar(&policy.KeepDaily, "keep-daily", 0, "keep the newest backup of each of the last N days")
+	fs.IntVar(&policy.KeepWeekly, "keep-weekly", 0, "keep the newest backup of each of the last N weeks")
+	fs.IntVar(&policy.KeepMonthly, "keep-monthly", 0, "keep the newest backup of each of the last N months")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if remote, ok := backup.ParseRemoteDest(*dest); ok {
+		fmt.Printf("%s retention only applies to local destinations; delete old backups on %s on the server\n", errorStyle.Render("✗"), remote)
+		return 2
+	}
+	var override *backup.Retention
+	if policy.Enabled() {
+		override = &policy
+	}
+
+	pruned, err := backup.PruneAll(*dest, override, *dryRun)
+	verb := "Deleted"
+	if *dryRun {
+		verb = "Would delete"
+	}
+	total := 0
+	for _, dir := range slices.Sorted(maps.Keys(pruned)) {
+		for _, name := range pruned[dir] {
+			fmt.Printf("  %s %s\n", labelStyle.Render(verb), filepath.Join(dir, name))
+			total++
+		}
+	}
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Prune failed:"), err)
+		return 1
+	}
+	if total == 0 {
+		fmt.Println(labelStyle.Render("Nothing to prune (no retention policy, or every backup is kept)"))
+		return 0
+	}
+	fmt.Printf("%s %d backups\n", successStyle.Render("✓ "+verb), total)
+	return 0
+}
+
+// analyzeBarWidth is the width of the size bars drawn by totem analyze
+const analyzeBarWidth = 24
+
+func runAnalyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	top := fs.Int("top", 10, "top-level folders to show")
+	children := fs.Int("children", 3, "largest subfolders to show under each folder")
+	depth := fs.Int("depth", 2, "folder levels to break down")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) > 1 || *top < 1 || *depth < 1 {
+		fmt.Println("Usage: totem analyze [instance] [--top n] [--children n] [--depth n]")
+		return 2
+	}
+	instance := ""
+	if len(positional) == 1 {
+		instance = positional[0]
+	} else {
+		profile, err := backup.LoadQuickProfile()
+		if err != nil {
+			fmt.Printf("%s no saved profile yet; run one backup from the TUI or name the instance\n", errorStyle.Render("✗"))
+			return 2
+		}
+		instance = profile.MinecraftPath
+	}
+
+	a, err := backup.Analyze(instance, *top, *children, *depth)
+	if a == nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+
+	summary := fmt.Sprintf("%s in %d files", formatBytes(a.Total), a.Files)
+	if !a.Previous.IsZero() {
+		summary += fmt.Sprintf(", %s since %s", formatGrowth(a.Total-a.PrevTotal), a.Previous.Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("%s %s\n%s\n\n", labelStyle.Render("Analyzed"), valueStyle.Render(instance), summary)
+
+	names := make([]string, len(a.Dirs))
+	width := len("(loose files)")
+	for i, d := range a.Dirs {
+		names[i] = strings.Repeat("  ", d.Depth) + path.Base(d.Path)
+		width = max(width, len(names[i]))
+	}
+	row := func(name string, size int64, growth string) {
+		share := 0.0
+		if a.Total > 0 {
+			share = float64(size) / float64(a.Total)
+		}
+		filled := int(share*analyzeBarWidth + 0.5)
+		bar := valueStyle.Render(strings.Repeat("█", filled)) + labelStyle.Render(strings.Repeat("░", analyzeBarWidth-filled))
+		fmt.Printf("%-*s  %s %10s %4.0f%%  %s\n", width, name, bar, formatBytes(size), share*100, labelStyle.Render(growth))
+	}
+	for i, d := range a.Dirs {
+		growth := ""
+		switch {
+		case a.Previous.IsZero() || (d.Previous && d.Growth == 0):
+		case !d.Previous:
+			growth = "new"
+		default:
+			growth = formatGrowth(d.Growth)
+		}
+		row(names[i], d.Size, growth)
+	}
+	if a.Loose > 0 {
+		row("(loose files)", a.Loose, "")
+	}
+
+	fmt.Println()
+	if a.Previous.IsZero() {
+		fmt.Println(labelStyle.Render("Run totem analyze again later to see how each folder grew."))
+	}
+	fmt.Println(labelStyle.Render("Use totem rules to always or never copy a folder."))
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	return 0
+}
+
+// formatGrowth formats a size change with its sign
+func formatGrowth(delta int64) string {
+	if delta < 0 {
+		return "-" + formatBytes(-delta)
+	}
+	return "+" + formatBytes(delta)
+}
+
+func runMount(args []string) int {
+	fs := flag.NewFlagSet("mount", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:0", "address to serve on (port 0 picks a free one)")
+	open := fs.Bool("open", true, "open the browser")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 && len(positional) != 2 {
+		fmt.Println("Usage: totem mount <backup> [folder] [--addr host:port] [--open=false]")
+		return 2
+	}
+	if len(positional) == 2 && !backup.FUSEMount {
+		_, _, err := backup.MountFUSE(nil, positional[1])
+		fmt.Printf("%s %v; leave out the folder to browse it in the web browser\n", errorStyle.Render("✗ Mount failed:"), err)
+		return 2
+	}
+
+	fsys, closeFS, err := backup.BackupFS(positional[0])
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Mount failed:"), err)
+		return 1
+	}
+	defer closeFS()
+	if len(positional) == 2 {
+		return mountFolder(fsys, positional[0], positional[1])
+	}
+	listener, err := netguard.Listen("mount", "tcp", *addr)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Mount failed:"), err)
+		return 1
+	}
+
+	// Only GET and HEAD reach the file server, so the view stays read-only
+	files := http.FileServer(http.FS(fsys))
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "read-only", http.StatusMethodNotAllowed)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})}
+	url := "http://" + listener.Addr().String() + "/"
+	fmt.Printf("%s %s at %s\n", labelStyle.Render("Browsing"), valueStyle.Render(positional[0]), valueStyle.Render(url))
+	fmt.Println(labelStyle.Render("Open or save files from the browser. Ctrl+C to stop."))
+	if *open {
+		backup.OpenURL(url)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		return 1
+	}
+	return 0
+}
+
+// mountFolder mounts a backup read-only at folder with FUSE until Ctrl+C or an unmount
+func mountFolder(fsys fs.FS, name, folder string) int {
+	wait, unmount, err := backup.MountFUSE(fsys, folder)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Mount failed:"), err)
+		return 1
+	}
+	fmt.Printf("%s %s at %s\n", labelStyle.Render("Mounted"), valueStyle.Render(name), valueStyle.Render(folder))
+	fmt.Println(labelStyle.Render("Read-only. Ctrl+C or unmount the folder to stop."))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		unmount()
+	}()
+	wait()
+	return 0
+}
+
+func runConsolidate(args []string) int {
+	if len(args) != 1 {
+		fmt.Println("Usage: totem consolidate <backup>")
+		return 2
+	}
+	out, err := backup.Consolidate(args[0])
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Consolidate failed:"), err)
+		return 1
+	}
+	fmt.Printf("%s %s\n", successStyle.Render("✓ Full backup written to"), valueStyle.Render(out))
+	return 0
+}
+
+func runJoin(args []string) int {
+	if len(args) != 1 {
+		fmt.Println("Usage: totem join <backup.zip.001>")
+		return 2
+	}
+	out, err := backup.JoinParts(args[0])
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Join failed:"), err)
+		return 1
+	}
+	fmt.Printf("%s %s\n", successStyle.Render("✓ Archive written to"), valueStyle.Render(out))
+	return 0
+}
+
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	keyPath := fs.String("key", "", "trusted public key file (default: local signing.pub)")
+	requireSig := fs.Bool("require-signature", false, "fail if the backup is not signed")
+	repair := fs.Bool("repair", false, "rebuild damaged archive blocks from parity data")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Println("Usage: totem verify <backup> [--key file] [--require-signature] [--repair]")
+		return 2
+	}
+	if *repair && kioskRefuses("verify --repair") {
+		return 1
+	}
+	target := positional[0]
+
+	// Check parity first: a damaged archive may not even open. Split archives have it per part.
+	parts := backup.SplitParts(target)
+	if _, err := os.Stat(parts[0] + backup.ParitySuffix); err == nil {
+		for _, part := range parts {
+			where := ""
+			if len(parts) > 1 {
+				where = filepath.Base(part) + ": "
+			}
+			report, err := backup.CheckParity(part, *repair)
+			switch {
+			case err != nil:
+				fmt.Printf("%s %s%v\n", errorStyle.Render("✗ Parity check failed:"), where, err)
+				return 1
+			case report.Damaged == 0:
+				fmt.Printf("  %s %s%d blocks intact\n", successStyle.Render("✓"), where, report.Blocks)
+			case report.Repaired == report.Damaged:
+				fmt.Printf("  %s %srepaired %d damaged blocks\n", successStyle.Render("✓"), where, report.Repaired)
+			default:
+				fmt.Printf("  %s %s%d of %d blocks damaged, %d repaired\n",
+					errorStyle.Render("✗"), where, report.Damaged, report.Blocks, report.Repaired)
+				if !*repair {
+					fmt.Println(labelStyle.Render("    Run again with --repair to fix them"))
+				} else {
+					fmt.Println(labelStyle.Render(fmt.Sprintf("    Parity rebuilds at most %d damaged blocks in each group of %d; "+
+						"make backups with a higher --parity-blocks to survive more damage", report.Recovery, backup.ParityGroupBlocks)))
+				}
+				return 1
+			}
+		}
+	} else if *repair {
+		fmt.Printf("%s no parity data for %s\n", errorStyle.Render("✗"), target)
+		return 1
+	}
+
+	report, err := backup.VerifyFiles(target)
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗ Verify failed:"), err)
+		return 1
+	}
+	for _, f := range report.Missing {
+		fmt.Printf("  %s missing %s\n", errorStyle.Render("✗"), f)
+	}
+	for _, f := range report.Corrupted {
+		fmt.Printf("  %s corrupted %s\n", errorStyle.Render("✗"), f)
+	}
+	if report.Checked > 0 {
+		fmt.Printf("  %s %d files match their SHA-256\n", successStyle.Render("✓"), report.Checked)
+	}
+
+	failed := len(report.Missing) > 0 || len(report.Corrupted) > 0
+	// Without a trusted key a signature can't be checked, only found
+	trusted, keyErr := backup.LoadPublicKey(*keyPath)
+	if keyErr == nil {
+		err = backup.VerifySignature(target, trusted)
+	} else if signed, sigErr := backup.IsSigned(target); sigErr != nil {
+		err = sigErr
+	} else if signed {
+		err = keyErr
+	} else {
+		err = backup.ErrUnsigned
+	}
+	switch {
+	case err == nil:
+		fmt.Printf("  %s signature valid\n", successStyle.Render("✓"))
+	case errors.Is(err, backup.ErrUnsigned) && !*requireSig:
+		fmt.Printf("  %s\n", labelStyle.Render("• unsigned backup"))
+	case keyErr != nil && !errors.Is(err, backup.ErrUnsigned):
+		fmt.Printf("  %s no trusted key: %v\n", errorStyle.Render("✗"), keyErr)
+		failed = true
+	default:
+		fmt.Printf("  %s %v\n", errorStyle.Render("✗"), err)
+		failed = true
+	}
+
+	if failed {
+		fmt.Println(errorStyle.Render("✗ Backup failed verification"))
+		return 1
+	}
+	fmt.Println(successStyle.Render("✓ Backup verified"))
+	return 0
+}
+
+func runResume(args []string) int {
+	if len(args) > 1 {
+		fmt.Println("Usage: totem resume [dest]")
+		return 2
+	}
+
+	// Accept a destination folder, a batch destination, or a single journal file
+	var journals []string
+	target := defaultBackupDest()
+	if len(args) == 1 {
+		target = args[0]
+	} else {
+		// Backups for sftp destinations are staged in the temp folder
+		journals = backup.StagedJournals()
+	}
+	if strings.HasSuffix(target, backup.JournalSuffix) {
+		journals = []string{target}
+	} else {
+		found, _ := backup.FindJournals(target)
+		journals = append(journals, found...)
+		entries, _ := os.ReadDir(target)
+		for _, e := range entries {
+			if e.IsDir() {
+				found, _ := backup.FindJournals(filepath.Join(target, e.Name()))
+				journals = append(journals, found...)
+			}
+		}
+	}
+	if len(journals) == 0 {
+		fmt.Printf("%s no interrupted backups in %s\n", successStyle.Render("✓"), target)
+		return 0
+	}
+
+	start := time.Now()
+	results := make([]batchResult, len(journals))
+	for i, j := range journals {
+		name := strings.TrimSuffix(filepath.Base(j), backup.JournalSuffix)
+		fmt.Printf("  %s resuming %s\n", labelStyle.Render("→"), name)
+		res, err := backup.Resume(j, nil)
+		results[i] = batchResult{
+			Instance: instances.Instance{Name: name, Launcher: filepath.Base(filepath.Dir(j))},
+			Result:   res,
+			Err:      err,
+		}
+	}
+
+	if printBatchSummary(results, time.Since(start)) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// parseInterspersed parses flags that may appear before or after positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// printBatchSummary prints a combined table and returns the number of failed backups
+func printBatchSummary(results []batchResult, elapsed time.Duration) int {
+	var table strings.Builder
+	table.WriteString(fmt.Sprintf("%-28s %-18s %-8s %7s  %s\n", "Instance", "Launcher", "Status", "Files", "Output"))
+
+	failed := 0
+	for _, r := range results {
+		status, files, output := successStyle.Render("ok      "), 0, ""
+		switch {
+		case r.Err != nil:
+			status, output = errorStyle.Render("failed  "), r.Err.Error()
+			failed++
+		case !r.Result.Success:
+			status, files, output = errorStyle.Render("errors  "), r.Result.TotalFiles, r.Result.OutputPath
+			failed++
+		default:
+			files, output = r.Result.TotalFiles, r.Result.OutputPath
+		}
+		table.WriteString(fmt.Sprintf("%-28s %-18s %s %7d  %s\n",
+			truncate(r.Instance.Name, 28), truncate(r.Instance.Launcher, 18), status, files, output))
+	}
+	table.WriteString(fmt.Sprintf("\n%s %s", labelStyle.Render("Duration:"),
+		valueStyle.Render(elapsed.Round(time.Millisecond).String())))
+
+	box := successBoxStyle
+	if failed > 0 {
+		box = errorBoxStyle
+	}
+	fmt.Println(box.Render(table.String()))
+	fmt.Println()
+
+	for _, r := range results {
+		if r.Result == nil {
+			continue
+		}
+		for _, w := range r.Result.Warnings {
+			fmt.Printf("  %s %s: %s\n", errorStyle.Render("!"), r.Instance.Name, w)
+		}
+		if n := len(r.Result.Pruned); n > 0 {
+			fmt.Printf("  %s %s: pruned %d old backups\n", labelStyle.Render("→"), r.Instance.Name, n)
+		}
+	}
+	return failed
+}
+
+// parseSince turns a --since value into a cutoff time ("" means no cutoff)
+func parseSince(value, dest string) (time.Time, error) {
+	switch value {
+	case "":
+		return time.Time{}, nil
+	case "last":
+		return backup.LatestBackupTime(dest)
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(`invalid --since %q (want YYYY-MM-DD or "last")`, value)
+	}
+	return t, nil
+}
+
+// fat32Limit is the largest file a FAT32 drive holds
+const fat32Limit = 4<<30 - 1
+
+// readZipPassword returns the password for --password: the one in TOTEM_ZIP_PASSWORD, or
+// one typed twice on the terminal, without echo
+func readZipPassword(protect, zipOutput bool) (string, error) {
+	if !protect {
+		return "", nil
+	}
+	if !zipOutput {
+		return "", fmt.Errorf("--password needs --zip")
+	}
+	if password := os.Getenv(backup.ZipPasswordEnv); password != "" {
+		return password, nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return "", fmt.Errorf("--password needs %s when there is no terminal to ask on", backup.ZipPasswordEnv)
+	}
+	ask := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		password, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+	password, err := ask("Zip password: ")
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", fmt.Errorf("the zip password cannot be empty")
+	}
+	again, err := ask("Repeat it: ")
+	if err != nil {
+		return "", err
+	}
+	if again != password {
+		return "", fmt.Errorf("the passwords do not match")
+	}
+	return password, nil
+}
+
+// parseSplit turns a --split value such as 2G, 700M, 512K or "fat32" into bytes ("" means
+// no splitting). Sizes are binary: 2G is 2 GiB.
+func parseSplit(value string, archive bool) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if !archive {
+		return 0, fmt.Errorf("--split needs --zip or --zstd")
+	}
+	if strings.EqualFold(value, "fat32") {
+		return fat32Limit, nil
+	}
+	n, ok := parseSize(value)
+	if !ok || n < backup.MinSplitSize {
+		return 0, fmt.Errorf("invalid --split %q (want a size of at least 1M, e.g. 2G, or \"fat32\")", value)
+	}
+	return n, nil
+}
+
+// parseRateLimit reads --rate-limit in bytes per second. Left unset, --low-priority throttles
+// copies to the TUI's Background mode rate.
+func parseRateLimit(value string, lowPriority bool) (int64, error) {
+	if value == "" {
+		if lowPriority {
+			return tui.DefaultRateLimit, nil
+		}
+		return 0, nil
+	}
+	n, ok := parseSize(value)
+	if !ok || (n != 0 && n < 1<<10) {
+		return 0, fmt.Errorf("invalid --rate-limit %q (want bytes per second of at least 1K, e.g. 20M, or 0 for no limit)", value)
+	}
+	return n, nil
+}
+
+// parseMaxFileSize reads --max-file-size, the per-file cap of the TUI's "Skip huge files"
+func parseMaxFileSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, ok := parseSize(value)
+	if !ok {
+		return 0, fmt.Errorf("invalid --max-file-size %q (want a size, e.g. 2G, or 0 for no cap)", value)
+	}
+	return n, nil
+}
+
+// parseSize reads a byte count with an optional K, M or G suffix, reporting false for
+// anything else, negative counts included
+func parseSize(value string) (int64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	number, unit := value, int64(1)
+	switch suffix := strings.ToUpper(value[len(value)-1:]); suffix {
+	case "K", "M", "G":
+		number = value[:len(value)-1]
+		unit = map[string]int64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30}[suffix]
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	// Checked before multiplying, so a huge count can't wrap around to a plausible size
+	if err != nil || n < 0 || n > math.MaxInt64/unit {
+		return 0, false
+	}
+	return n * unit, true
+}
+
+func defaultBackupDest() string {
+	if backup.Portable() {
+		if dir, err := backup.PortableBackups(); err == nil {
+			return dir
+		}
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, "TotemBackups")
+}
+
+// instanceDest is the folder for one instance's backups inside dest. An sftp destination is
+// joined with remote path rules, since filepath.Join would turn sftp:// into sftp:/.
+func instanceDest(dest, name string) string {
+	if remote, ok := backup.ParseRemoteDest(dest); ok {
+		remote.Path = path.Join(remote.Path, name)
+		return remote.String()
+	}
+	return filepath.Join(dest, name)
+}
+
+// safeName makes a string usable as a folder name on every OS
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?* `, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/backup"
+	"github.com/vaalley/totem/internal/tui"
+)
+
+func TestParseSplit(t *testing.T) {
+	valid := map[string]int64{
+		"":      0,
+		"2G":    2 << 30,
+		"700m":  700 << 20,
+		"fat32": fat32Limit,
+	}
+	for value, want := range valid {
+		if got, err := parseSplit(value, true); err != nil || got != want {
+			t.Errorf("parseSplit(%q) = %d, %v; want %d", value, got, err, want)
+		}
+	}
+
+	// The first three overflow int64 when multiplied out; 17179869186G wraps to exactly 2 GiB
+	// and 18014398509483008K to 1 MiB
+	for _, value := range []string{"9000000000G", "17179869186G", "18014398509483008K", "512K", "-2G", "G", "2T"} {
+		if got, err := parseSplit(value, true); err == nil {
+			t.Errorf("parseSplit(%q) = %d; want an error", value, got)
+		}
+	}
+	if _, err := parseSplit("2G", false); err == nil {
+		t.Error("parseSplit without an archive: no error")
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	for _, c := range []struct {
+		value string
+		low   bool
+		want  int64
+	}{
+		{"", false, 0},
+		{"", true, tui.DefaultRateLimit},
+		{"0", true, 0},
+		{"5M", true, 5 << 20},
+		{"512K", false, 512 << 10},
+	} {
+		if got, err := parseRateLimit(c.value, c.low); err != nil || got != c.want {
+			t.Errorf("parseRateLimit(%q, %v) = %d, %v; want %d", c.value, c.low, got, err, c.want)
+		}
+	}
+	for _, value := range []string{"-1M", "fast", "100", "9000000000G"} {
+		if got, err := parseRateLimit(value, false); err == nil {
+			t.Errorf("parseRateLimit(%q) = %d; want an error", value, got)
+		}
+	}
+}
+
+// Headless runs reach the TUI's "Skip huge files" and Background mode through flags
+func TestBackupFlagsSizes(t *testing.T) {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	f := addBackupFlags(fs)
+	if err := fs.Parse([]string{"--max-file-size", "2G", "--low-priority"}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := f.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxFileSize != tui.DefaultMaxFileSize || config.RateLimit != tui.DefaultRateLimit {
+		t.Errorf("MaxFileSize = %d, RateLimit = %d; want the TUI's defaults", config.MaxFileSize, config.RateLimit)
+	}
+	if _, err := parseMaxFileSize("huge"); err == nil {
+		t.Error("parseMaxFileSize(huge): no error")
+	}
+}
+
+func TestParityBlocksFlag(t *testing.T) {
+	for _, c := range []struct {
+		value string
+		want  int
+		ok    bool
+	}{{"1", 1, true}, {"4", 4, true}, {"16", 16, true}, {"0", 0, false}, {"17", 0, false}} {
+		fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+		f := addBackupFlags(fs)
+		if err := fs.Parse([]string{"--zip", "--parity", "--parity-blocks", c.value}); err != nil {
+			t.Fatal(err)
+		}
+		config, err := f.config()
+		if (err == nil) != c.ok || (c.ok && config.ParityBlocks != c.want) {
+			t.Errorf("--parity-blocks %s: config %+v, error %v", c.value, config, err)
+		}
+	}
+}
+
+func TestDeterministicConflicts(t *testing.T) {
+	t.Setenv(backup.ZipPasswordEnv, "hunter2")
+	parse := func(args ...string) *backupFlags {
+		fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+		f := addBackupFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	if _, err := parse("--zip", "--deterministic").config(); err != nil {
+		t.Errorf("--zip --deterministic: %v", err)
+	}
+	if _, err := parse("--zip", "--password").config(); err != nil {
+		t.Errorf("--zip --password: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"--zip", "--deterministic", "--password"},
+		{"--zip", "--deterministic", "--gpg", "alex@example.com"},
+	} {
+		if _, err := parse(args...).config(); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+}
+
+func TestParseMeta(t *testing.T) {
+	meta, err := parseMeta([]string{"season=3", " server = play.example.net ", "note="})
+	if err != nil || len(meta) != 3 || meta["server"] != "play.example.net" || meta["note"] != "" {
+		t.Errorf("parseMeta = %v, %v", meta, err)
+	}
+	for _, bad := range []string{"season", "=3"} {
+		if _, err := parseMeta([]string{bad}); err == nil {
+			t.Errorf("parseMeta(%q): no error", bad)
+		}
+	}
+	if meta, err := parseMeta(nil); meta != nil || err != nil {
+		t.Errorf("parseMeta(nil) = %v, %v", meta, err)
+	}
+}
+
+// Flags without a command run the TUI's backup headless, for scripts and cron
+func TestHeadless(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	mc := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(mc, "saves", "World"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mc, "saves", "World", "level.dat"), []byte("level"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+
+	if code := runCLI([]string{"--mc-path", mc, "--dest", dest, "--zip", "--saves"}); code != 0 {
+		t.Fatalf("totem --mc-path ... exited %d", code)
+	}
+	zips, _ := filepath.Glob(filepath.Join(dest, "backup_*.zip"))
+	if len(zips) != 1 {
+		t.Fatalf("found %v, want one zip", zips)
+	}
+	if _, err := backup.Extract(zips[0], []string{"saves/World/level.dat"}, t.TempDir()); err != nil {
+		t.Errorf("the zip lacks the world: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"--mc-path", mc},
+		{"--dest", dest},
+		{"--mc-path", mc, "--dest", dest, "extra"},
+		{"--mc-path", mc, "--dest", dest, "--no-such-flag"},
+	} {
+		if code := runCLI(args); code != 2 {
+			t.Errorf("totem %v exited %d, want 2", args, code)
+		}
+	}
+}
+
+// --config and the other global flags may come before a command or among headless flags
+func TestTakeGlobalFlags(t *testing.T) {
+	rest, g, err := takeGlobalFlags([]string{"--config", "alex", "--offline", "backup", "--config", "x", "--zip"})
+	if err != nil || g.config != "alex" || !g.offline || strings.Join(rest, " ") != "backup --config x --zip" {
+		t.Errorf("before a command: %q, %+v, %v; want the command's own flags left alone", rest, g, err)
+	}
+	rest, g, err = takeGlobalFlags([]string{"--mc-path", "mc", "--config=shared/alex", "--portable", "--dest", "d"})
+	if err != nil || g.config != "shared/alex" || !g.portable || strings.Join(rest, " ") != "--mc-path mc --dest d" {
+		t.Errorf("headless: %q, %+v, %v", rest, g, err)
+	}
+	if _, _, err := takeGlobalFlags([]string{"--config"}); err == nil {
+		t.Error("--config without a folder: no error")
+	}
+}
+
+// fakeSFTP runs sftp batch files against the folder in FAKE_SFTP_ROOT, like the one in
+// internal/backup's remote_test.go
+const fakeSFTP = `#!/bin/sh
+while IFS= read -r line; do
+	eval "set -- $line"
+	cmd=$1; shift
+	case $cmd in
+	-mkdir) mkdir -p "$FAKE_SFTP_ROOT/$1" ;;
+	mkdir) mkdir "$FAKE_SFTP_ROOT/$1" || exit 1 ;;
+	put) [ "$1" = -r ] && shift; cp -R "$1" "$FAKE_SFTP_ROOT/$2" || exit 1 ;;
+	-get) cp "$FAKE_SFTP_ROOT/$1" "$2" 2>/dev/null || true ;;
+	get) cp "$FAKE_SFTP_ROOT/$1" "$2" || exit 1 ;;
+	rename) mv "$FAKE_SFTP_ROOT/$1" "$FAKE_SFTP_ROOT/$2" || exit 1 ;;
+	-rm|rm) rm -f "$FAKE_SFTP_ROOT/$1" ;;
+	-rmdir) rmdir "$FAKE_SFTP_ROOT/$1" 2>/dev/null || true ;;
+	rmdir) rmdir "$FAKE_SFTP_ROOT/$1" || exit 1 ;;
+	-ls|ls)
+		case $1 in
+		-1) shift; ls -1 "$FAKE_SFTP_ROOT/$1" 2>/dev/null | sed "s|^|$1/|" ;;
+		-lna) shift; ls -A "$FAKE_SFTP_ROOT/$1" 2>/dev/null | while IFS= read -r n; do
+			f="$FAKE_SFTP_ROOT/$1/$n"
+			if [ -d "$f" ]; then echo "drwxr-xr-x 2 0 0 4096 Jan 1 00:00 $1/$n"
+			else echo "-rw-r--r-- 1 0 0 $(wc -c <"$f") Jan 1 00:00 $1/$n"; fi
+		done ;;
+		esac ;;
+	esac
+done
+`
+
+// totem backup into an sftp destination uploads each instance under the remote path
+func TestBackupSFTPDest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sftp is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "sftp"), []byte(fakeSFTP), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	root := t.TempDir()
+	t.Setenv("FAKE_SFTP_ROOT", root)
+	t.Chdir(t.TempDir())
+
+	mc := filepath.Join(t.TempDir(), "mc")
+	if err := os.MkdirAll(mc, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mc, "options.txt"), []byte("fov:0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runBackup([]string{"--instance", mc, "--dest", "sftp://me@host:2222/srv"}); code != 0 {
+		t.Fatalf("runBackup exited %d", code)
+	}
+	uploaded, _ := filepath.Glob(filepath.Join(root, "srv", "Custom_mc", "backup_*", "options.txt"))
+	if len(uploaded) != 1 {
+		t.Fatalf("found %d uploaded backups under srv/Custom_mc, want 1", len(uploaded))
+	}
+	if entries, _ := os.ReadDir("."); len(entries) != 0 {
+		t.Errorf("wrote %s into the working folder instead of uploading", entries[0].Name())
+	}
+	if got := instanceDest("me@host:srv", "Custom_mc"); got != "me@host:srv/Custom_mc" {
+		t.Errorf("instanceDest(me@host:srv) = %q", got)
+	}
+	if got := instanceDest("sftp://me@host:2222/srv", "Custom_mc"); got != "sftp://me@host:2222/srv/Custom_mc" {
+		t.Errorf("instanceDest(sftp://...) = %q", got)
+	}
+}
+
+// A trigger file starts one backup, is removed, and leaves the outcome beside it
+func TestWatchTrigger(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	mc := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mc, "options.txt"), []byte("fov:0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	trigger := filepath.Join(mc, "totem.trigger")
+	config := &tui.Config{MinecraftPath: mc, BackupDest: dest, SkipJunk: true}
+
+	if watchTrigger(trigger, config) {
+		t.Fatal("backed up without a trigger file")
+	}
+	os.WriteFile(trigger, nil, 0644)
+	if !watchTrigger(trigger, config) {
+		t.Fatal("the trigger file did not start a backup")
+	}
+	if _, err := os.Stat(trigger); err == nil {
+		t.Error("the trigger file was left in place")
+	}
+	status, _ := os.ReadFile(trigger + triggerResultSuffix)
+	if !strings.HasPrefix(string(status), "ok "+filepath.Join(dest, "backup_")) {
+		t.Errorf("result file = %q, want ok and the backup's path", status)
+	}
+}
+
+// --combine puts each instance's backup in one folder with a combined report, or one zip
+func TestBackupCombine(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	var instanceArgs []string
+	for _, name := range []string{"fabric", "vanilla"} {
+		mc := filepath.Join(t.TempDir(), name)
+		if err := os.MkdirAll(mc, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(mc, "options.txt"), []byte("fov:0.0\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		instanceArgs = append(instanceArgs, "--instance", mc)
+	}
+
+	dest := t.TempDir()
+	if code := runBackup(append(instanceArgs, "--dest", dest, "--combine")); code != 0 {
+		t.Fatalf("runBackup exited %d", code)
+	}
+	combined, _ := filepath.Glob(filepath.Join(dest, "combined_*"))
+	if len(combined) != 1 {
+		t.Fatalf("found %v, want one combined folder", combined)
+	}
+	for _, name := range []string{"Custom_fabric", "Custom_vanilla"} {
+		if _, err := os.Stat(filepath.Join(combined[0], name, "options.txt")); err != nil {
+			t.Errorf("%s is missing from the combined folder: %v", name, err)
+		}
+	}
+	report, _ := os.ReadFile(filepath.Join(combined[0], "combined.md"))
+	if !strings.Contains(string(report), "**2 instances**, 2 ok, 0 failed") {
+		t.Errorf("combined.md = %s", report)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(combined[0], ".staging-*")); len(leftovers) > 0 {
+		t.Errorf("left %v in the combined folder", leftovers)
+	}
+
+	zipDest := t.TempDir()
+	if code := runBackup(append(instanceArgs, "--dest", zipDest, "--combine", "--zip")); code != 0 {
+		t.Fatalf("runBackup --zip exited %d", code)
+	}
+	if entries, _ := os.ReadDir(zipDest); len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".zip") {
+		t.Errorf("--combine --zip left %v, want one zip", entries)
+	}
+}
+
+// totem list exits 1 when no backup matches, so scripts can test for one
+func TestList(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	mc := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mc, "options.txt"), []byte("fov:0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	if code := runList([]string{"--dest", dest}); code != 1 {
+		t.Errorf("list of an empty destination exited %d, want 1", code)
+	}
+	if code := runBackup([]string{"--instance", mc, "--dest", dest, "--meta", "season=3"}); code != 0 {
+		t.Fatalf("runBackup exited %d", code)
+	}
+	for _, c := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"--dest", dest}, 0},
+		{[]string{"--dest", dest, "--filter", "season=3"}, 0},
+		{[]string{"--dest", dest, "--filter", "season"}, 0},
+		{[]string{"--dest", dest, "--filter", "season=4"}, 1},
+		{[]string{"--dest", dest, "--no-such-flag"}, 2},
+	} {
+		if code := runList(c.args); code != c.want {
+			t.Errorf("totem list %v exited %d, want %d", c.args, code, c.want)
+		}
+	}
+}
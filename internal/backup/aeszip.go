@@ -0,0 +1,317 @@
+package backup
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// ZipPasswordEnv holds the password of password-protected zips, for scripts and for reading
+// such backups back
+const ZipPasswordEnv = "TOTEM_ZIP_PASSWORD"
+
+// Password-protected zips use WinZip's AES format (AE-2), which 7-Zip, WinRAR, WinZip and
+// macOS's Archive Utility open. Each entry gets its own salt, AES-256 in CTR mode with a
+// little-endian counter, and a truncated HMAC-SHA1 of the ciphertext.
+const (
+	aesMethod     = 99     // Compression method of every encrypted entry
+	aesExtraID    = 0x9901 // Extra field holding the strength and the real method
+	aesStrength   = 3      // AES-256
+	aesKeyLen     = 32
+	aesSaltLen    = 16
+	aesVerifyLen  = 2
+	aesMACLen     = 10
+	aesIterations = 1000
+	aesVendorAE2  = 2 // AE-2 leaves the CRC out; the MAC covers integrity
+
+	// aesReadMethod + the real method is what an entry's method is rewritten to when read,
+	// so its decompressor knows whether to inflate after decrypting
+	aesReadMethod = 0xAE00
+)
+
+// ErrZipPassword is returned when a password-protected zip is read without the right password
+var ErrZipPassword = errors.New("wrong or missing zip password (set " + ZipPasswordEnv + ")")
+
+var errZipAuth = errors.New("zip: encrypted entry failed authentication")
+
+// aesKeys derives the encryption key, MAC key and password check value from a password
+func aesKeys(password string, salt []byte) (encKey, macKey, verify []byte, err error) {
+	keys, err := pbkdf2.Key(sha1.New, password, salt, aesIterations, 2*aesKeyLen+aesVerifyLen)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return keys[:aesKeyLen], keys[aesKeyLen : 2*aesKeyLen], keys[2*aesKeyLen:], nil
+}
+
+// aesCTR is AES in counter mode as WinZip does it: the counter is a little-endian integer
+// starting at 1, unlike the big-endian counter of crypto/cipher's CTR
+type aesCTR struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int
+}
+
+func newAESCTR(key []byte) (*aesCTR, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesCTR{block: block, used: aes.BlockSize}, nil
+}
+
+func (c *aesCTR) xor(dst, src []byte) {
+	for i := range src {
+		if c.used == aes.BlockSize {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+			c.block.Encrypt(c.stream[:], c.counter[:])
+			c.used = 0
+		}
+		dst[i] = src[i] ^ c.stream[c.used]
+		c.used++
+	}
+}
+
+// aesEntryWriter compresses and encrypts one zip entry. The sizes are only known once it is
+// closed, so the entry is written with a data descriptor and its header filled in then.
+type aesEntryWriter struct {
+	fh     *zip.FileHeader
+	raw    io.Writer
+	comp   io.WriteCloser // Compresses into the encrypter; nil when stored
+	ctr    *aesCTR
+	mac    hash.Hash
+	buf    []byte
+	plain  uint64
+	cipher uint64
+}
+
+// createAESEntry adds an entry to w that is compressed with method, then encrypted with
+// password. Close the returned writer before starting the next entry.
+func createAESEntry(w *zip.Writer, name string, method uint16, password string) (io.WriteCloser, error) {
+	salt := make([]byte, aesSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	encKey, macKey, verify, err := aesKeys(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := newAESCTR(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	extra := make([]byte, 11)
+	binary.LittleEndian.PutUint16(extra[0:], aesExtraID)
+	binary.LittleEndian.PutUint16(extra[2:], 7)
+	binary.LittleEndian.PutUint16(extra[4:], aesVendorAE2)
+	copy(extra[6:], "AE")
+	extra[8] = aesStrength
+	binary.LittleEndian.PutUint16(extra[9:], method)
+	fh := &zip.FileHeader{
+		Name:   name,
+		Method: aesMethod,
+		Flags:  0x1 | 0x8, // Encrypted, sizes in a data descriptor
+		Extra:  extra,
+	}
+	raw, err := w.CreateRaw(fh)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := raw.Write(append(salt, verify...)); err != nil {
+		return nil, err
+	}
+
+	e := &aesEntryWriter{fh: fh, raw: raw, ctr: ctr, mac: hmac.New(sha1.New, macKey)}
+	if method == zip.Deflate {
+		if e.comp, err = flate.NewWriter(encrypter{e}, flate.DefaultCompression); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// encrypter is where compressed bytes go to be encrypted
+type encrypter struct{ e *aesEntryWriter }
+
+func (w encrypter) Write(p []byte) (int, error) {
+	e := w.e
+	if cap(e.buf) < len(p) {
+		e.buf = make([]byte, len(p))
+	}
+	out := e.buf[:len(p)]
+	e.ctr.xor(out, p)
+	e.mac.Write(out)
+	e.cipher += uint64(len(p))
+	return e.raw.Write(out)
+}
+
+func (e *aesEntryWriter) Write(p []byte) (int, error) {
+	e.plain += uint64(len(p))
+	if e.comp != nil {
+		return e.comp.Write(p)
+	}
+	return encrypter{e}.Write(p)
+}
+
+// Close finishes the entry: the MAC goes after the ciphertext and the sizes into its header
+func (e *aesEntryWriter) Close() error {
+	if e.comp != nil {
+		if err := e.comp.Close(); err != nil {
+			return err
+		}
+	}
+	if _, err := e.raw.Write(e.mac.Sum(nil)[:aesMACLen]); err != nil {
+		return err
+	}
+	e.fh.CompressedSize64 = aesSaltLen + aesVerifyLen + e.cipher + aesMACLen
+	e.fh.UncompressedSize64 = e.plain
+	e.fh.CompressedSize = uint32(min(e.fh.CompressedSize64, 1<<32-1))
+	e.fh.UncompressedSize = uint32(min(e.fh.UncompressedSize64, 1<<32-1))
+	return nil
+}
+
+// readAESEntries lets zr read password-protected entries with the password from
+// ZipPasswordEnv; a wrong or missing password fails when an entry is opened
+func readAESEntries(zr *zip.Reader) {
+	found := false
+	for _, f := range zr.File {
+		if f.Method != aesMethod {
+			continue
+		}
+		if method, ok := aesRealMethod(f.Extra); ok {
+			f.Method = aesReadMethod + method
+			// AE-2 has no CRC for archive/zip to check the data descriptor against; the
+			// MAC checks the data instead
+			f.Flags &^= 0x8
+			found = true
+		}
+	}
+	if !found {
+		return
+	}
+	password := os.Getenv(ZipPasswordEnv)
+	zr.RegisterDecompressor(aesReadMethod+zip.Store, func(r io.Reader) io.ReadCloser {
+		return io.NopCloser(newAESReader(r, password))
+	})
+	zr.RegisterDecompressor(aesReadMethod+zip.Deflate, func(r io.Reader) io.ReadCloser {
+		ar := newAESReader(r, password)
+		return aesInflater{flate.NewReader(ar), ar}
+	})
+}
+
+// aesInflater inflates a decrypted entry. Deflate stops at its last block, so the rest is
+// read through to reach the MAC check. A tampered entry usually trips deflate first, and
+// the MAC check then reports it as what it is.
+type aesInflater struct {
+	io.ReadCloser
+	ar *aesReader
+}
+
+func (r aesInflater) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil {
+		if _, drainErr := io.Copy(io.Discard, r.ar); drainErr != nil {
+			return n, drainErr
+		}
+	}
+	return n, err
+}
+
+// aesRealMethod reads the method an encrypted entry was compressed with from its extra field
+func aesRealMethod(extra []byte) (uint16, bool) {
+	for len(extra) >= 4 {
+		id, size := binary.LittleEndian.Uint16(extra), int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			return 0, false
+		}
+		if id == aesExtraID && size >= 7 && extra[4] == aesStrength {
+			method := binary.LittleEndian.Uint16(extra[5:])
+			return method, method == zip.Store || method == zip.Deflate
+		}
+		extra = extra[size:]
+	}
+	return 0, false
+}
+
+// aesReader decrypts an entry's data, checking the MAC at the end. The last aesMACLen bytes
+// read may be the MAC, so they are held back until the data runs out.
+type aesReader struct {
+	src     io.Reader
+	ctr     *aesCTR
+	mac     hash.Hash
+	err     error // Set when the header does not check out
+	pending []byte
+	scratch []byte
+	eof     bool
+}
+
+func newAESReader(src io.Reader, password string) *aesReader {
+	r := &aesReader{src: src}
+	header := make([]byte, aesSaltLen+aesVerifyLen)
+	if _, err := io.ReadFull(src, header); err != nil {
+		r.err = fmt.Errorf("zip: encrypted entry: %w", err)
+		return r
+	}
+	encKey, macKey, verify, err := aesKeys(password, header[:aesSaltLen])
+	if err != nil {
+		r.err = err
+		return r
+	}
+	if password == "" || !hmac.Equal(verify, header[aesSaltLen:]) {
+		r.err = ErrZipPassword
+		return r
+	}
+	if r.ctr, r.err = newAESCTR(encKey); r.err != nil {
+		return r
+	}
+	r.mac = hmac.New(sha1.New, macKey)
+	r.scratch = make([]byte, 32<<10)
+	return r
+}
+
+func (r *aesReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	for len(r.pending) <= aesMACLen && !r.eof {
+		buf := append(r.scratch[:0], r.pending...)
+		n, err := r.src.Read(buf[len(buf):cap(buf)])
+		r.pending = buf[:len(buf)+n]
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	avail := len(r.pending) - aesMACLen
+	if avail <= 0 {
+		if avail < 0 || !hmac.Equal(r.mac.Sum(nil)[:aesMACLen], r.pending) {
+			r.err = errZipAuth
+			return 0, r.err
+		}
+		return 0, io.EOF
+	}
+	n := min(len(p), avail)
+	r.mac.Write(r.pending[:n])
+	r.ctr.xor(p[:n], r.pending[:n])
+	r.pending = r.pending[n:]
+	return n, nil
+}
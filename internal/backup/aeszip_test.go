@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// aesTestFiles are entries of every kind: stored, deflated over many CTR blocks, and empty
+func aesTestFiles() map[string][]byte {
+	random := make([]byte, 100<<10)
+	rand.Read(random)
+	return map[string][]byte{
+		"saves/World/region/r.0.0.mca": random,
+		"logs/latest.log":              bytes.Repeat([]byte("[Server thread/INFO]: Saving chunks\n"), 5000),
+		"empty.txt":                    nil,
+	}
+}
+
+// writeAESZip writes files to a password-protected zip and returns its bytes
+func writeAESZip(t *testing.T, files map[string][]byte, password string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		method := zip.Deflate
+		if strings.HasSuffix(name, ".mca") {
+			method = zip.Store
+		}
+		w, err := createAESEntry(zw, name, method, password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readAESZip reads every entry of a password-protected zip with the password in the
+// environment, returning the first error
+func readAESZip(t *testing.T, data []byte) (map[string][]byte, error) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAESEntries(zr)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return files, err
+		}
+		contents, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return files, err
+		}
+		files[f.Name] = contents
+	}
+	return files, nil
+}
+
+func TestAESZipRoundTrip(t *testing.T) {
+	files := aesTestFiles()
+	data := writeAESZip(t, files, "correct horse")
+
+	t.Setenv(ZipPasswordEnv, "correct horse")
+	got, err := readAESZip(t, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range files {
+		if !bytes.Equal(got[name], want) {
+			t.Errorf("%s: read %d bytes back, want the %d written", name, len(got[name]), len(want))
+		}
+	}
+	// The plaintext must not be in the archive
+	if bytes.Contains(data, []byte("Saving chunks")) {
+		t.Error("the log is readable without the password")
+	}
+}
+
+func TestAESZipWrongPassword(t *testing.T) {
+	data := writeAESZip(t, aesTestFiles(), "correct horse")
+	for _, password := range []string{"", "battery staple"} {
+		t.Setenv(ZipPasswordEnv, password)
+		if _, err := readAESZip(t, data); !errors.Is(err, ErrZipPassword) {
+			t.Errorf("password %q: got %v, want ErrZipPassword", password, err)
+		}
+	}
+}
+
+// A changed ciphertext or MAC byte must fail the entry rather than return altered data
+func TestAESZipTampered(t *testing.T) {
+	files := map[string][]byte{"logs/latest.log": bytes.Repeat([]byte("tamper test\n"), 1000)}
+	data := writeAESZip(t, files, "correct horse")
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := zr.File[0].DataOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := start + int64(zr.File[0].CompressedSize64)
+
+	t.Setenv(ZipPasswordEnv, "correct horse")
+	for what, offset := range map[string]int64{
+		"ciphertext": start + aesSaltLen + aesVerifyLen + 3,
+		"MAC":        end - 1,
+	} {
+		tampered := bytes.Clone(data)
+		tampered[offset] ^= 0x01
+		if _, err := readAESZip(t, tampered); !errors.Is(err, errZipAuth) {
+			t.Errorf("changed %s byte: got %v, want errZipAuth", what, err)
+		}
+	}
+}
+
+// aesKeys must match PBKDF2-HMAC-SHA1 with 1000 iterations, as WinZip specifies; the
+// expected keys were derived with Python's hashlib.pbkdf2_hmac
+func TestAESKeysVector(t *testing.T) {
+	salt := make([]byte, aesSaltLen)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+	encKey, macKey, verify, err := aesKeys("password", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0309e2fe4e0bdfe7d0fe4828d41c234416e2d9bfb61cdd8f643a11cfbfdfc119" +
+		"e78b0eb3d9243415743b2fe4f5e67c6689bd2c3e512d0fda622dd7d1b0565b83" + "256b"
+	if got := hex.EncodeToString(encKey) + hex.EncodeToString(macKey) + hex.EncodeToString(verify); got != want {
+		t.Errorf("aesKeys = %s, want %s", got, want)
+	}
+}
+
+// testdata/aes256-libarchive.zip was made by libarchive's bsdtar with
+// --options zip:encryption=aes256 --passphrase totem-test
+func TestAESZipReadsLibarchive(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "aes256-libarchive.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ZipPasswordEnv, "totem-test")
+	got, err := readAESZip(t, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var repeat strings.Builder
+	for i := range 2000 {
+		fmt.Fprintf(&repeat, "line %d of a compressible file\n", i)
+	}
+	if string(got["hello.txt"]) != "hello from libarchive\n" || string(got["repeat.txt"]) != repeat.String() {
+		t.Errorf("read hello.txt = %q and %d bytes of repeat.txt", got["hello.txt"], len(got["repeat.txt"]))
+	}
+}
+
+// Other tools must open what Totem writes; libarchive stands in for 7-Zip and WinZip
+func TestAESZipReadByLibarchive(t *testing.T) {
+	bsdtar, err := exec.LookPath("bsdtar")
+	if err != nil {
+		t.Skip("bsdtar not installed")
+	}
+	files := aesTestFiles()
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup.zip")
+	if err := os.WriteFile(archive, writeAESZip(t, files, "correct horse"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	os.Mkdir(out, 0755)
+	if msg, err := exec.Command(bsdtar, "--passphrase", "correct horse", "-xf", archive, "-C", out).CombinedOutput(); err != nil {
+		t.Fatalf("bsdtar: %v: %s", err, msg)
+	}
+	for name, want := range files {
+		if got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name))); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s extracted by bsdtar: %d bytes, %v; want %d", name, len(got), err, len(want))
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DirUsage is one folder in an analysis, relative to the instance, with its growth since
+// the last analysis
+type DirUsage struct {
+	Path     string // Slash-separated
+	Depth    int    // 0 for top-level folders
+	Size     int64
+	Files    int
+	Growth   int64 // Size change since the previous analysis
+	Previous bool  // The previous analysis measured this folder
+}
+
+// Analysis is a breakdown of an instance's disk use
+type Analysis struct {
+	Instance  string
+	Time      time.Time
+	Total     int64
+	Files     int
+	Loose     int64      // Files directly in the instance folder
+	Dirs      []DirUsage // Largest top-level folders, each followed by its largest subfolders
+	Previous  time.Time  // When the previous analysis ran; zero for the first
+	PrevTotal int64      // Total size at the previous analysis
+}
+
+// analysisSnapshot is what analysis.json keeps of an instance's last analysis
+type analysisSnapshot struct {
+	Time  time.Time        `json:"time"`
+	Total int64            `json:"total"`
+	Sizes map[string]int64 `json:"sizes"`
+}
+
+// Subfolders under this share of the total are left out of an analysis
+const analyzeMinShare = 0.01
+
+// Analyze measures an instance without backing anything up: the top folders by size, and
+// under each up to children of its largest subfolders, depth levels deep. Growth is against
+// the previous analysis of the same instance, which this one then replaces.
+func Analyze(instance string, top, children, depth int) (*Analysis, error) {
+	info, err := os.Stat(instance)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("minecraft path does not exist: %s", instance)
+	}
+
+	a := &Analysis{Instance: instance, Time: time.Now()}
+	sizes := map[string]int64{}
+	files := map[string]int{}
+	err = walkDirFollow(instance, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		a.Total += info.Size()
+		a.Files++
+		rel, _ := filepath.Rel(instance, path)
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) == 1 {
+			a.Loose += info.Size()
+			return nil
+		}
+		// Count the file in each enclosing folder, down to depth levels
+		for i := 1; i < len(parts) && i <= depth; i++ {
+			dir := strings.Join(parts[:i], "/")
+			sizes[dir] += info.Size()
+			files[dir]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	all, _ := loadAnalyses()
+	key := rulesKey(instance)
+	prev, hadPrev := all[key]
+	if hadPrev {
+		a.Previous, a.PrevTotal = prev.Time, prev.Total
+	}
+	usage := func(dir string, level int) DirUsage {
+		u := DirUsage{Path: dir, Depth: level, Size: sizes[dir], Files: files[dir]}
+		if old, ok := prev.Sizes[dir]; ok {
+			u.Growth, u.Previous = u.Size-old, true
+		} else if hadPrev {
+			u.Growth = u.Size
+		}
+		return u
+	}
+
+	// Children of each folder, largest first
+	kids := map[string][]string{}
+	for dir := range sizes {
+		parent := ""
+		if i := strings.LastIndex(dir, "/"); i >= 0 {
+			parent = dir[:i]
+		}
+		kids[parent] = append(kids[parent], dir)
+	}
+	for _, list := range kids {
+		sort.Slice(list, func(i, j int) bool {
+			if sizes[list[i]] != sizes[list[j]] {
+				return sizes[list[i]] > sizes[list[j]]
+			}
+			return list[i] < list[j]
+		})
+	}
+	var add func(dir string, level int)
+	add = func(dir string, level int) {
+		a.Dirs = append(a.Dirs, usage(dir, level))
+		shown := 0
+		for _, kid := range kids[dir] {
+			if shown == children || float64(sizes[kid]) < float64(a.Total)*analyzeMinShare {
+				break
+			}
+			add(kid, level+1)
+			shown++
+		}
+	}
+	for i, dir := range kids[""] {
+		if i == top {
+			break
+		}
+		add(dir, 0)
+	}
+
+	all[key] = analysisSnapshot{Time: a.Time, Total: a.Total, Sizes: sizes}
+	if err := saveAnalyses(all); err != nil {
+		return a, fmt.Errorf("could not save the analysis for next time: %w", err)
+	}
+	return a, nil
+}
+
+func analysesPath() (string, error) {
+	dir, err := KeyDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "analysis.json"), nil
+}
+
+func loadAnalyses() (map[string]analysisSnapshot, error) {
+	all := map[string]analysisSnapshot{}
+	path, err := analysesPath()
+	if err != nil {
+		return all, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return all, err
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return map[string]analysisSnapshot{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return all, nil
+}
+
+func saveAnalyses(all map[string]analysisSnapshot) error {
+	path, err := analysesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,59 @@
+package backup
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", strings.Repeat("o", 10))
+	writeTestFile(t, mc, "saves/A/region/r.0.0.mca", strings.Repeat("r", 5000))
+	writeTestFile(t, mc, "saves/B/level.dat", strings.Repeat("l", 1000))
+	writeTestFile(t, mc, "mods/sodium.jar", strings.Repeat("m", 3000))
+	writeTestFile(t, mc, "logs/latest.log", strings.Repeat("g", 100))
+
+	a, err := Analyze(mc, 2, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Total != 9110 || a.Files != 5 || a.Loose != 10 || !a.Previous.IsZero() {
+		t.Errorf("Analysis = %+v", a)
+	}
+	var got []string
+	for _, d := range a.Dirs {
+		got = append(got, strings.Repeat("  ", d.Depth)+d.Path)
+	}
+	// The top 2 folders, each with its largest subfolder; saves/A/region is past depth 2
+	if want := []string{"saves", "  saves/A", "mods"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Dirs = %q, want %q", got, want)
+	}
+	if a.Dirs[0].Size != 6000 || a.Dirs[0].Files != 2 || a.Dirs[0].Previous {
+		t.Errorf("saves = %+v", a.Dirs[0])
+	}
+
+	// The next analysis shows growth since this one
+	writeTestFile(t, mc, "mods/lithium.jar", strings.Repeat("m", 2000))
+	b, err := Analyze(mc, 2, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.PrevTotal != 9110 || !b.Previous.Equal(a.Time) {
+		t.Errorf("previous analysis: total %d at %v", b.PrevTotal, b.Previous)
+	}
+	for _, d := range b.Dirs {
+		want := int64(0)
+		if d.Path == "mods" {
+			want = 2000
+		}
+		if !d.Previous || d.Growth != want {
+			t.Errorf("%s grew %d (measured before: %v), want %d", d.Path, d.Growth, d.Previous, want)
+		}
+	}
+
+	if _, err := Analyze(filepath.Join(mc, "options.txt"), 2, 1, 2); err == nil {
+		t.Error("analyzed a file")
+	}
+}
@@ -2,6 +2,8 @@ package backup
 
 import (
 	"archive/zip"
+	"compress/flate"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,8 +12,10 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/vaalley/totem/internal/tui"
@@ -26,18 +30,53 @@ type Result struct {
 	Errors     []string
 	Stats      Stats
 	Duration   time.Duration
+	Datapacks  []WorldDatapacks
+	Skipped    []FileInfo // Files skipped for exceeding the size cap
+	Health     int        // 0-100, lowered by each warning
+	Warnings   []string   // Likely misconfigurations that did not fail the backup
+	Pack       *PackDiff  // Deviations from config.Modpack, nil without one
+	Pruned     []string   // Old backups deleted by the retention policy
 }
 
 // Stats tracks backup statistics
 type Stats struct {
-	ScreenshotsCopied    int
-	ModsListed           int
-	ShadersListed        int
-	ShaderConfigsCopied  int
-	ResourcepacksListed  int
-	SavesCopied          int
-	XaeroCopied          int
+	ScreenshotsCopied     int
+	ModsListed            int
+	ModsIdentified        int    // Mods found on Modrinth or CurseForge, with --mod-metadata
+	ModLookupError        string // Why looking mods up online failed, if it did
+	ShadersListed         int
+	ShaderConfigsCopied   int
+	ResourcepacksListed   int
+	SavesCopied           int
+	XaeroCopied           int
 	DistantHorizonsCopied int
+	DatapacksListed       int
+	WorldConfigsCopied    int
+	ServerFilesCopied     int
+	ProxyFilesCopied      int
+	CrashReportsCopied    int
+	ConfigsCopied         int // Files from config/ (quick backups and checkpoints)
+	IncludedCopied        int // Files from folders added by include rules
+	UnchangedSkipped      int // Files left to the parent by an incremental backup
+	FilesLinked           int // Files hard-linked to the parent snapshot
+	FilesVerified         int // Copies re-read and matched against the source hash
+	JunkSkipped           int
+	StoredUncompressed    int           // Already-compressed files zipped with Store
+	StoreTimeSaved        time.Duration // Estimated time saved by not deflating them
+	StoreSizeDelta        int64         // Estimated size cost of not deflating them
+	Timings               []StepTiming  // Duration of each step that ran, in order
+}
+
+// StepTiming records how long one backup step took
+type StepTiming struct {
+	Step     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// timeStep records the time elapsed since start for a step
+func (s *Stats) timeStep(step string, start time.Time) {
+	s.Timings = append(s.Timings, StepTiming{Step: step, Start: start, Duration: time.Since(start)})
 }
 
 // MinecraftInfo holds detected MC version info
@@ -80,8 +119,30 @@ func buildPaths(root string) MinecraftPaths {
 	}
 }
 
-// Perform performs the backup
-func Perform(config *tui.Config) (*Result, error) {
+// PerformQuiet performs the backup without console output (for spinner compatibility)
+func PerformQuiet(config *tui.Config) (*Result, error) {
+	return PerformWithProgress(config, nil)
+}
+
+// PerformWithProgress performs a quiet backup, reporting bytes processed to progress
+func PerformWithProgress(config *tui.Config, progress *Progress) (*Result, error) {
+	return PerformContext(context.Background(), config, progress)
+}
+
+// PerformContext is PerformWithProgress stopped by ctx. A cancelled backup keeps its journal,
+// so `totem resume` can finish it later.
+func PerformContext(ctx context.Context, config *tui.Config, progress *Progress) (*Result, error) {
+	return perform(ctx, config, progress, nil)
+}
+
+// perform runs a quiet backup. With a journal it resumes that backup, skipping finished steps;
+// without one it starts a new backup and journals it as it goes.
+func perform(ctx context.Context, config *tui.Config, progress *Progress, j *journal) (*Result, error) {
+	if remote, ok := ParseRemoteDest(config.BackupDest); ok && j == nil {
+		return performRemote(config, remote, func(local *tui.Config) (*Result, error) {
+			return perform(ctx, local, progress, nil)
+		})
+	}
 	startTime := time.Now()
 
 	result := &Result{
@@ -89,278 +150,470 @@ func Perform(config *tui.Config) (*Result, error) {
 		Errors:  []string{},
 		Stats:   Stats{},
 	}
+	if j != nil {
+		*result = j.Result
+		startTime = time.Now().Add(-result.Duration)
+	}
 
-	// Build paths
+	// Build paths; the instance's saved include/exclude rules apply on top of the config
+	config = withRules(config)
 	paths := buildPaths(config.MinecraftPath)
+	opts := newCopyOptions(config, progress)
+	opts.ctx = ctx
 
 	// Validate MC path exists
 	if _, err := os.Stat(paths.Root); os.IsNotExist(err) {
 		return nil, fmt.Errorf("minecraft path does not exist: %s", paths.Root)
 	}
+	if err := checkSource(paths.Root); err != nil {
+		return nil, err
+	}
+	if err := checkDestination(config); err != nil {
+		return nil, err
+	}
+	// Encrypting destinations only take archives
+	enc, err := encryptionFor(config.BackupDest)
+	if err != nil {
+		return nil, err
+	}
+	if enc != nil && !config.Zstd {
+		config.ZipOutput = true
+	}
+	if config.ProtectZip {
+		if config.ZipPassword == "" {
+			return nil, fmt.Errorf("a password-protected zip needs a password")
+		}
+		config.ZipOutput = true
+	}
+	if config.GPGRecipient != "" {
+		if err := checkGPG(enc); err != nil {
+			return nil, err
+		}
+		config.ZipOutput = true
+	}
+	previous, linkFrom, err := incrementalBase(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create backup folder with timestamp, or reuse the interrupted one
+	if j == nil {
+		backupPath := newBackupPath(config.BackupDest, time.Now())
+		if err := os.MkdirAll(backupPath, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create backup folder: %w", err)
+		}
+		j = &journal{Config: *config, BackupPath: backupPath, Started: time.Now()}
+		j.save()
+	} else {
+		opts.Resume = true
+	}
+	backupPath := j.BackupPath
+	opts.previous, opts.linkFrom, opts.backupRoot = previous, linkFrom, backupPath
+
+	// finishStep records a step's timing and checkpoints it in the journal
+	finishStep := func(step string, start time.Time) {
+		result.Stats.timeStep(step, start)
+		result.Duration = time.Since(startTime)
+		// An interrupted step is left unfinished so resume runs it again
+		if opts.space.full != nil || ctx.Err() != nil {
+			return
+		}
+		j.complete(step, result)
+	}
+
+	progress.setTotal(estimateBackupBytes(config, paths, opts))
 
-	// Create backup folder with timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04")
-	backupPath := filepath.Join(config.BackupDest, "backup_"+timestamp)
-	if err := os.MkdirAll(backupPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create backup folder: %w", err)
+	// Best effort: a failed priority change should not stop the backup
+	if config.LowPriority {
+		lowerPriority()
 	}
 
-	fmt.Printf("  → Creating backup: %s\n", backupPath)
+	// Soft limit: the GC works harder instead of letting the heap grow past it
+	if config.MemoryLimit > 0 {
+		defer limitMemory(config.MemoryLimit)()
+	}
 
 	// 1. Copy screenshots
-	if exists(paths.Screenshots) {
-		fmt.Println("  → Copying screenshots...")
-		count, err := copyDir(paths.Screenshots, filepath.Join(backupPath, "screenshots"))
+	if !j.completed("Screenshots") && exists(paths.Screenshots) {
+		stepStart := time.Now()
+		count, err := copyDirSince(paths.Screenshots, filepath.Join(backupPath, "screenshots"), opts, config.ScreenshotsSince)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("screenshots: %v", err))
 		} else {
 			result.Stats.ScreenshotsCopied = count
 			result.TotalFiles += count
-			fmt.Printf("    Copied %d files\n", count)
 		}
+		finishStep("Screenshots", stepStart)
 	}
 
 	// 2. List mods
-	if exists(paths.Mods) {
-		fmt.Println("  → Listing mods...")
+	if !j.completed("Mods") && exists(paths.Mods) && !opts.ruledOut(paths.Mods) {
+		stepStart := time.Now()
 		mods, err := listFiles(paths.Mods)
 		if err == nil {
 			result.Stats.ModsListed = len(mods)
 			content := strings.Join(mods, "\n")
 			os.WriteFile(filepath.Join(backupPath, "mods.txt"), []byte(content), 0644)
-			fmt.Printf("    Listed %d mods\n", len(mods))
+			if err := writeModHashes(paths.Mods, backupPath, mods); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("mod hashes: %v", err))
+			}
 		}
+		finishStep("Mods", stepStart)
+	}
+
+	// 2b. Look mods up in the background while everything else is copied
+	var enrichment *modEnrichment
+	if !j.completed("Mod metadata") && config.ModMetadata && exists(paths.Mods) && !opts.ruledOut(paths.Mods) {
+		enrichment = startModMetadata(ctx, paths.Mods, backupPath, progress)
 	}
 
 	// 3. Process shaderpacks
-	if exists(paths.Shaderpacks) {
-		fmt.Println("  → Processing shaderpacks...")
+	if !j.completed("Shaders") && exists(paths.Shaderpacks) && !opts.ruledOut(paths.Shaderpacks) {
+		stepStart := time.Now()
 		shaders, configs, err := processShaderpacks(paths.Shaderpacks, backupPath)
 		if err == nil {
 			result.Stats.ShadersListed = len(shaders)
 			result.Stats.ShaderConfigsCopied = configs
-			fmt.Printf("    Listed %d shaders, copied %d configs\n", len(shaders), configs)
 		}
+		finishStep("Shaders", stepStart)
 	}
 
 	// 4. List resource packs
-	if exists(paths.Resourcepacks) {
-		fmt.Println("  → Listing resource packs...")
+	if !j.completed("Resource packs") && exists(paths.Resourcepacks) && !opts.ruledOut(paths.Resourcepacks) {
+		stepStart := time.Now()
 		packs, err := listFiles(paths.Resourcepacks)
 		if err == nil {
 			result.Stats.ResourcepacksListed = len(packs)
 			content := strings.Join(packs, "\n")
 			os.WriteFile(filepath.Join(backupPath, "resourcepacks.txt"), []byte(content), 0644)
-			fmt.Printf("    Listed %d packs\n", len(packs))
 		}
+		finishStep("Resource packs", stepStart)
 	}
 
 	// 5. Copy options.txt
-	if exists(paths.Options) {
-		fmt.Println("  → Copying options.txt...")
-		copyFile(paths.Options, filepath.Join(backupPath, "options.txt"))
+	if !j.completed("Options") && exists(paths.Options) {
+		stepStart := time.Now()
+		if err := opts.copyFile(paths.Options, filepath.Join(backupPath, "options.txt")); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("options: %v", err))
+		}
+		finishStep("Options", stepStart)
 	}
 
 	// 6. Optional: saves
-	if config.IncludeSaves && exists(paths.Saves) {
-		fmt.Println("  → Copying saves (this may take a while)...")
-		count, err := copyDir(paths.Saves, filepath.Join(backupPath, "saves"))
+	if !j.completed("Saves") && config.IncludeSaves && exists(paths.Saves) {
+		stepStart := time.Now()
+		count, err := copySaves(paths.Saves, filepath.Join(backupPath, "saves"), opts, config)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("saves: %v", err))
 		} else {
 			result.Stats.SavesCopied = count
 			result.TotalFiles += count
-			fmt.Printf("    Copied %d files\n", count)
 		}
+		finishStep("Saves", stepStart)
+	}
+
+	// 6b. Datapacks per world, plus level.dat/datapacks when saves are skipped
+	if !j.completed("Datapacks") && exists(paths.Saves) {
+		stepStart := time.Now()
+		worlds, err := listWorldDatapacks(paths.Saves)
+		if err == nil && len(worlds) > 0 {
+			result.Datapacks = worlds
+			result.Stats.DatapacksListed = countDatapacks(worlds)
+			writeDatapacksList(backupPath, worlds)
+		}
+		if config.WorldConfigOnly && !config.IncludeSaves {
+			count, err := copyWorldConfigs(paths.Saves, filepath.Join(backupPath, "saves"), opts)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("world configs: %v", err))
+			} else {
+				result.Stats.WorldConfigsCopied = count
+				result.TotalFiles += count
+			}
+		}
+		finishStep("Datapacks", stepStart)
 	}
 
 	// 7. Optional: xaero
-	if config.IncludeXaero && exists(paths.Xaero) {
-		fmt.Println("  → Copying Xaero maps...")
-		count, err := copyDir(paths.Xaero, filepath.Join(backupPath, "xaero"))
+	if !j.completed("Xaero maps") && config.IncludeXaero && exists(paths.Xaero) {
+		stepStart := time.Now()
+		count, err := copyDirSince(paths.Xaero, filepath.Join(backupPath, "xaero"), opts, config.XaeroSince)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("xaero: %v", err))
 		} else {
 			result.Stats.XaeroCopied = count
 			result.TotalFiles += count
-			fmt.Printf("    Copied %d files\n", count)
 		}
+		finishStep("Xaero maps", stepStart)
 	}
 
 	// 8. Optional: Distant Horizons
-	if config.IncludeDH && exists(paths.DistantHorizons) {
-		fmt.Println("  → Copying Distant Horizons data...")
-		count, err := copyDir(paths.DistantHorizons, filepath.Join(backupPath, "distant_horizons_server_data"))
+	if !j.completed("Distant Horizons") && config.IncludeDH && exists(paths.DistantHorizons) {
+		stepStart := time.Now()
+		count, err := copyDir(paths.DistantHorizons, filepath.Join(backupPath, "distant_horizons_server_data"), opts)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("distant_horizons: %v", err))
 		} else {
 			result.Stats.DistantHorizonsCopied = count
 			result.TotalFiles += count
-			fmt.Printf("    Copied %d files\n", count)
 		}
+		finishStep("Distant Horizons", stepStart)
 	}
 
-	// Record duration before generating info
-	result.Duration = time.Since(startTime)
-
-	// 9. Generate info.md
-	fmt.Println("  → Generating info.md...")
-	generateInfoMD(backupPath, config, result, paths)
+	// 8b. Server admin files (whitelist, ops, bans)
+	if !j.completed("Server files") && isServer(paths.Root) {
+		stepStart := time.Now()
+		count, err := copyServerFiles(paths.Root, backupPath)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("server: %v", err))
+		} else {
+			result.Stats.ServerFilesCopied = count
+			result.TotalFiles += count
+		}
+		finishStep("Server files", stepStart)
+	}
 
-	result.OutputPath = backupPath
+	// 8c. Proxy and plugin configs (Velocity, BungeeCord)
+	if !j.completed("Proxy configs") && proxyKind(paths.Root) != "" {
+		stepStart := time.Now()
+		count, err := copyProxyConfig(paths.Root, backupPath, config.RedactSecrets)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("proxy: %v", err))
+		} else {
+			result.Stats.ProxyFilesCopied = count
+			result.TotalFiles += count
+		}
+		finishStep("Proxy configs", stepStart)
+	}
 
-	// 10. Zip if requested
-	if config.ZipOutput {
-		fmt.Println("  → Creating zip archive...")
-		zipPath := backupPath + ".zip"
-		if err := createZip(backupPath, zipPath); err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("zip: %v", err))
+	// 8d. Crash reports
+	crashDir := filepath.Join(paths.Root, CrashReportsDir)
+	if !j.completed("Crash reports") && config.IncludeCrashes && exists(crashDir) {
+		stepStart := time.Now()
+		count, err := copyDir(crashDir, filepath.Join(backupPath, CrashReportsDir), opts)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("crash reports: %v", err))
 		} else {
-			// Remove the unzipped folder
-			os.RemoveAll(backupPath)
-			result.OutputPath = zipPath
-			fmt.Println("    Zip created successfully")
+			result.Stats.CrashReportsCopied = count
+			result.TotalFiles += count
 		}
+		finishStep("Crash reports", stepStart)
 	}
 
-	// 11. Open folder if requested
-	if config.OpenWhenDone {
-		openFolder(filepath.Dir(result.OutputPath))
+	// 8e. Modpack deviations (not journaled: it is quick and info.md needs the result on resume)
+	if config.Modpack != "" {
+		stepStart := time.Now()
+		if err := comparePackStep(config, paths, backupPath, result); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("modpack: %v", err))
+		}
+		result.Stats.timeStep("Modpack", stepStart)
 	}
 
-	result.Success = len(result.Errors) == 0
-	return result, nil
-}
+	// 8f. Folders added by include rules
+	if !j.completed("Included folders") && len(config.Include) > 0 {
+		stepStart := time.Now()
+		count, err := copyIncluded(paths.Root, backupPath, config.Include, opts)
+		result.Stats.IncludedCopied = count
+		result.TotalFiles += count
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("included folders: %v", err))
+		}
+		finishStep("Included folders", stepStart)
+	}
 
-// PerformQuiet performs the backup without console output (for spinner compatibility)
-func PerformQuiet(config *tui.Config) (*Result, error) {
-	startTime := time.Now()
+	// 8g. Wait for the mod lookups; only the time spent waiting is timed
+	if enrichment != nil {
+		stepStart := time.Now()
+		enrichment.wait(result)
+		finishStep("Mod metadata", stepStart)
+	}
 
-	result := &Result{
-		Success: true,
-		Errors:  []string{},
-		Stats:   Stats{},
+	// A full destination stops the backup here rather than failing every later step
+	if opts.space.full != nil {
+		return nil, opts.space.full
+	}
+	if ctx.Err() != nil {
+		return nil, ErrCancelled
 	}
 
-	// Build paths
-	paths := buildPaths(config.MinecraftPath)
+	result.Stats.JunkSkipped = opts.JunkSkipped
+	result.Stats.UnchangedSkipped = opts.Unchanged
+	result.Stats.FilesLinked = opts.Linked
+	result.Stats.FilesVerified = opts.Verified
+	result.Skipped = opts.LargeSkipped
 
-	// Validate MC path exists
-	if _, err := os.Stat(paths.Root); os.IsNotExist(err) {
-		return nil, fmt.Errorf("minecraft path does not exist: %s", paths.Root)
+	// Record duration before generating info
+	result.Duration = time.Since(startTime)
+
+	// Decide which files to store uncompressed before the report is written
+	var plan *compressionPlan
+	if config.ZipOutput {
+		plan = planCompression(backupPath)
+		result.Stats.StoredUncompressed = plan.StoredFiles
+		result.Stats.StoreTimeSaved = plan.TimeSaved
+		result.Stats.StoreSizeDelta = plan.SizeDelta
 	}
 
-	// Create backup folder with timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04")
-	backupPath := filepath.Join(config.BackupDest, "backup_"+timestamp)
-	if err := os.MkdirAll(backupPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create backup folder: %w", err)
+	// 9. Generate info.md (its size scans stop early on Ctrl+C)
+	result.Health, result.Warnings = assessHealth(backupPath, config, result, paths)
+	generateInfoMD(backupPath, config, result, paths, opts)
+	if ctx.Err() != nil {
+		return nil, ErrCancelled
 	}
 
-	// 1. Copy screenshots
-	if exists(paths.Screenshots) {
-		count, err := copyDir(paths.Screenshots, filepath.Join(backupPath, "screenshots"))
+	// 9b. Write manifest (file list and chain link)
+	if err := writeManifest(backupPath, config, opts.hashes); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("manifest: %v", err))
+	} else if config.SignManifest {
+		if err := signManifest(backupPath); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("signature: %v", err))
+		}
+	}
+
+	result.OutputPath = backupPath
+
+	// 10. Zip if requested, or tar with zstd. A staged upload that streams the archive to
+	// the server makes it there instead.
+	makeArchive := !j.completed("Zip") && !config.StreamArchive
+	if makeArchive && config.Zstd {
+		stepStart := time.Now()
+		archivePath := backupPath + ZstdSuffix
+		if err := createTarZst(backupPath, archivePath, config.ZstdLevel, config.ZstdLong, config.Deterministic, progress); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("zstd: %v", err))
+		} else {
+			os.RemoveAll(backupPath)
+			result.OutputPath = archivePath
+		}
+		finishStep("Zip", stepStart)
+	} else if makeArchive && config.ZipOutput {
+		stepStart := time.Now()
+		zipPath := backupPath + ".zip"
+		var err error
+		if config.GPGRecipient != "" {
+			zipPath += encryptSuffixes["gpg"]
+			err = encryptZipGPG(backupPath, zipPath, plan, config.ZipPassword, config.GPGRecipient, progress)
+		} else {
+			err = createZip(backupPath, zipPath, plan, config.ZipPassword, progress)
+		}
 		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("screenshots: %v", err))
+			result.Errors = append(result.Errors, fmt.Sprintf("zip: %v", err))
 		} else {
-			result.Stats.ScreenshotsCopied = count
-			result.TotalFiles += count
+			os.RemoveAll(backupPath)
+			result.OutputPath = zipPath
 		}
+		finishStep("Zip", stepStart)
 	}
 
-	// 2. List mods
-	if exists(paths.Mods) {
-		mods, err := listFiles(paths.Mods)
-		if err == nil {
-			result.Stats.ModsListed = len(mods)
-			content := strings.Join(mods, "\n")
-			os.WriteFile(filepath.Join(backupPath, "mods.txt"), []byte(content), 0644)
-		}
+	// 10a-c. Encrypt, split and add parity to the archive
+	if result.OutputPath != backupPath {
+		finishArchive(config, enc, result, j, finishStep)
 	}
 
-	// 3. Process shaderpacks
-	if exists(paths.Shaderpacks) {
-		shaders, configs, err := processShaderpacks(paths.Shaderpacks, backupPath)
-		if err == nil {
-			result.Stats.ShadersListed = len(shaders)
-			result.Stats.ShaderConfigsCopied = configs
+	// The backup is complete; without its journal it counts for retention
+	j.remove()
+
+	// 10d. Prune old backups, only once this one is known good
+	if len(result.Errors) == 0 {
+		pruned, err := Prune(config.BackupDest, RetentionFor(config.BackupDest), false)
+		result.Pruned = pruned
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("prune: %v", err))
 		}
 	}
 
-	// 4. List resource packs
-	if exists(paths.Resourcepacks) {
-		packs, err := listFiles(paths.Resourcepacks)
-		if err == nil {
-			result.Stats.ResourcepacksListed = len(packs)
-			content := strings.Join(packs, "\n")
-			os.WriteFile(filepath.Join(backupPath, "resourcepacks.txt"), []byte(content), 0644)
-		}
+	// 10e. Add the backup to its destination's catalog
+	if err := recordBackup(config, result, j.Started); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Could not update the catalog: %v", err))
 	}
 
-	// 5. Copy options.txt
-	if exists(paths.Options) {
-		copyFile(paths.Options, filepath.Join(backupPath, "options.txt"))
+	// 10f. Send the backup's spans to an OpenTelemetry collector, if one is configured
+	if err := exportTrace(config, result, j.Started); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Could not export the trace: %v", err))
 	}
 
-	// 6. Optional: saves
-	if config.IncludeSaves && exists(paths.Saves) {
-		count, err := copyDir(paths.Saves, filepath.Join(backupPath, "saves"))
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("saves: %v", err))
-		} else {
-			result.Stats.SavesCopied = count
-			result.TotalFiles += count
-		}
+	// 11. Open folder if requested
+	if config.OpenWhenDone {
+		openFolder(filepath.Dir(result.OutputPath))
 	}
 
-	// 7. Optional: xaero
-	if config.IncludeXaero && exists(paths.Xaero) {
-		count, err := copyDir(paths.Xaero, filepath.Join(backupPath, "xaero"))
+	progress.finish()
+	result.Success = len(result.Errors) == 0
+	return result, nil
+}
+
+// finishArchive runs the steps after Zip on the archive at result.OutputPath: encryption for
+// the destination, splitting and parity. Steps the journal has already finished are skipped.
+func finishArchive(config *tui.Config, enc *Destination, result *Result, j *journal, finishStep func(string, time.Time)) {
+	// Encrypt for the destination
+	if !j.completed("Encrypt") && enc != nil {
+		stepStart := time.Now()
+		out, err := encryptArchive(result.OutputPath, enc)
 		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("xaero: %v", err))
-		} else {
-			result.Stats.XaeroCopied = count
-			result.TotalFiles += count
+			result.Errors = append(result.Errors, fmt.Sprintf("encrypt: %v", err))
 		}
+		result.OutputPath = out
+		finishStep("Encrypt", stepStart)
 	}
 
-	// 8. Optional: Distant Horizons
-	if config.IncludeDH && exists(paths.DistantHorizons) {
-		count, err := copyDir(paths.DistantHorizons, filepath.Join(backupPath, "distant_horizons_server_data"))
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("distant_horizons: %v", err))
+	// Split for drives and uploads with a file size limit
+	if !j.completed("Split") && config.SplitSize > 0 {
+		stepStart := time.Now()
+		if first, err := splitArchive(result.OutputPath, config.SplitSize); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("split: %v", err))
 		} else {
-			result.Stats.DistantHorizonsCopied = count
-			result.TotalFiles += count
+			result.OutputPath = first
 		}
+		finishStep("Split", stepStart)
 	}
 
-	// Record duration before generating info
-	result.Duration = time.Since(startTime)
-
-	// 9. Generate info.md
-	generateInfoMD(backupPath, config, result, paths)
+	// Parity for cold storage, per part for a split archive
+	if !j.completed("Parity") && config.Parity {
+		stepStart := time.Now()
+		for _, part := range SplitParts(result.OutputPath) {
+			if err := writeParity(part, max(config.ParityBlocks, 1)); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("parity: %v", err))
+			}
+		}
+		finishStep("Parity", stepStart)
+	}
+}
 
-	result.OutputPath = backupPath
+// memoryLimits tracks the backups running under a soft memory limit. The limit is process-wide,
+// so backups running in parallel share the lowest one, and the process's own limit comes back
+// once the last of them finishes.
+var memoryLimits struct {
+	sync.Mutex
+	active   int
+	previous int64
+}
 
-	// 10. Zip if requested
-	if config.ZipOutput {
-		zipPath := backupPath + ".zip"
-		if err := createZip(backupPath, zipPath); err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("zip: %v", err))
-		} else {
-			os.RemoveAll(backupPath)
-			result.OutputPath = zipPath
+// limitMemory lowers the process's soft memory limit to limit for one backup and returns
+// the function that lifts it again
+func limitMemory(limit int64) func() {
+	memoryLimits.Lock()
+	defer memoryLimits.Unlock()
+	current := debug.SetMemoryLimit(-1)
+	if memoryLimits.active == 0 {
+		memoryLimits.previous = current
+	}
+	memoryLimits.active++
+	debug.SetMemoryLimit(min(limit, current))
+	return func() {
+		memoryLimits.Lock()
+		defer memoryLimits.Unlock()
+		if memoryLimits.active--; memoryLimits.active == 0 {
+			debug.SetMemoryLimit(memoryLimits.previous)
 		}
 	}
+}
 
-	// 11. Open folder if requested
-	if config.OpenWhenDone {
-		openFolder(filepath.Dir(result.OutputPath))
+// newBackupPath picks a backup folder name for t, adding a suffix if that minute is taken
+func newBackupPath(dest string, t time.Time) string {
+	base := filepath.Join(dest, "backup_"+t.Format(backupTimeLayout))
+	path := base
+	for i := 2; archiveExists(path); i++ {
+		path = fmt.Sprintf("%s_%d", base, i)
 	}
-
-	result.Success = len(result.Errors) == 0
-	return result, nil
+	return path
 }
 
 func exists(path string) bool {
@@ -383,6 +636,11 @@ func listFiles(dir string) ([]string, error) {
 }
 
 func copyFile(src, dst string) error {
+	return copyFileWrapped(src, dst, nil)
+}
+
+// copyFileWrapped copies a file, passing the source through wrap (if set) first
+func copyFileWrapped(src, dst string, wrap func(io.Reader) io.Reader) error {
 	source, err := os.Open(src)
 	if err != nil {
 		return err
@@ -395,16 +653,47 @@ func copyFile(src, dst string) error {
 	}
 	defer dest.Close()
 
-	_, err = io.Copy(dest, source)
+	var r io.Reader = source
+	if wrap != nil {
+		r = wrap(r)
+	}
+	_, err = io.Copy(dest, r)
 	return err
 }
 
-func copyDir(src, dst string) (int, error) {
+func copyDir(src, dst string, opts *copyOptions) (int, error) {
+	return copyDirSince(src, dst, opts, time.Time{})
+}
+
+// copyDirSince copies only files modified after since (a zero time copies everything)
+func copyDirSince(src, dst string, opts *copyOptions, since time.Time) (int, error) {
 	count := 0
-	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+	err := walkDirFollow(src, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if opts.cancelled() {
+			return ErrCancelled
+		}
+
+		if opts.ruledOut(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if opts.SkipJunk && path != src && opts.junk(path, d) {
+			opts.JunkSkipped++
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if opts.tooLarge(path, d) {
+			return nil
+		}
 
 		relPath, _ := filepath.Rel(src, path)
 		destPath := filepath.Join(dst, relPath)
@@ -413,7 +702,43 @@ func copyDir(src, dst string) (int, error) {
 			return os.MkdirAll(destPath, 0755)
 		}
 
-		if err := copyFile(path, destPath); err != nil {
+		if !since.IsZero() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !info.ModTime().After(since) {
+				return nil
+			}
+		}
+
+		// Unchanged since the parent: incremental backups leave the file to it, snapshots
+		// hard-link it (and copy it when linking fails, e.g. across filesystems)
+		if opts.unchanged(destPath, d) {
+			if opts.linkFrom == "" {
+				opts.progress.skip(path)
+				opts.Unchanged++
+				return nil
+			}
+			if opts.linkUnchanged(destPath) == nil {
+				opts.progress.skip(path)
+				opts.Linked++
+				count++
+				return nil
+			}
+		}
+
+		// Files finished before a crash are kept once their size and hash check out
+		if opts.Resume && sameFile(path, destPath) {
+			if info, err := d.Info(); err == nil {
+				opts.hashes.recordModTime(destPath, info.ModTime())
+			}
+			opts.progress.skip(path)
+			count++
+			return nil
+		}
+
+		if err := opts.copyFile(path, destPath); err != nil {
 			return err
 		}
 		count++
@@ -525,10 +850,23 @@ func getMinecraftInfo(mcRoot string) MinecraftInfo {
 	return info
 }
 
-// getDirSize calculates directory size in bytes
-func getDirSize(path string) int64 {
+// getDirSize calculates directory size in bytes, leaving out what opts would not copy
+// (nil counts everything). It stops early, returning a partial size, once opts is cancelled.
+func getDirSize(path string, opts *copyOptions) int64 {
 	var size int64
-	filepath.WalkDir(path, func(_ string, d fs.DirEntry, _ error) error {
+	walkDirFollow(path, func(p string, d fs.DirEntry, err error) error {
+		if opts.cancelled() {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return nil
+		}
+		if (p != path && opts.excluded(p, d)) || opts.ruledOut(p) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if !d.IsDir() {
 			info, err := d.Info()
 			if err == nil {
@@ -567,8 +905,10 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dm %ds", mins, secsRem)
 }
 
-// getLargestItems gets the largest files/folders in a directory
-func getLargestItems(dirPath string, limit int) []FileInfo {
+// getLargestItems gets the largest files/folders in a directory.
+// Only the top limit entries are kept, so huge folders don't build a huge list.
+// Entries opts would not copy are left out, matching what was backed up.
+func getLargestItems(dirPath string, limit int, opts *copyOptions) []FileInfo {
 	var items []FileInfo
 
 	entries, err := os.ReadDir(dirPath)
@@ -577,27 +917,38 @@ func getLargestItems(dirPath string, limit int) []FileInfo {
 	}
 
 	for _, e := range entries {
+		if opts.cancelled() {
+			break
+		}
 		path := filepath.Join(dirPath, e.Name())
+		if opts.excluded(path, e) {
+			continue
+		}
 		var size int64
 		if e.IsDir() {
-			size = getDirSize(path)
+			size = getDirSize(path, opts)
 		} else {
 			info, err := e.Info()
 			if err == nil {
 				size = info.Size()
 			}
 		}
-		items = append(items, FileInfo{Name: e.Name(), Size: size})
+		items = insertLargest(items, FileInfo{Name: e.Name(), Size: size}, limit)
 	}
+	return items
+}
 
-	// Sort by size descending
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].Size > items[j].Size
-	})
-
-	if len(items) > limit {
-		return items[:limit]
+// insertLargest adds item to a list kept sorted by size (descending) and capped at limit
+func insertLargest(items []FileInfo, item FileInfo, limit int) []FileInfo {
+	i := sort.Search(len(items), func(i int) bool { return items[i].Size < item.Size })
+	if i >= limit {
+		return items
+	}
+	if len(items) < limit {
+		items = append(items, FileInfo{})
 	}
+	copy(items[i+1:], items[i:])
+	items[i] = item
 	return items
 }
 
@@ -615,20 +966,20 @@ func getOSInfo() string {
 	return fmt.Sprintf("%s (%s)", osName, runtime.GOARCH)
 }
 
-func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths MinecraftPaths) {
+func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths MinecraftPaths, opts *copyOptions) {
 	// Get Minecraft info
 	mcInfo := getMinecraftInfo(config.MinecraftPath)
 
 	// Get sizes
-	backupSize := getDirSize(backupPath)
-	modsSize := getDirSize(paths.Mods)
+	backupSize := getDirSize(backupPath, opts.scanOnly())
+	modsSize := getDirSize(paths.Mods, opts)
 	savesSize := int64(0)
 	if config.IncludeSaves {
-		savesSize = getDirSize(paths.Saves)
+		savesSize = getDirSize(paths.Saves, opts)
 	}
 
 	// Get largest mods
-	largestMods := getLargestItems(paths.Mods, 3)
+	largestMods := getLargestItems(paths.Mods, 3, opts)
 	largestModsStr := ""
 	if len(largestMods) > 0 {
 		for _, m := range largestMods {
@@ -637,11 +988,17 @@ func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths
 	} else {
 		largestModsStr = "  - None found\n"
 	}
+	if config.ModMetadata {
+		largestModsStr += renderModMetadataLines(backupPath, result.Stats)
+	}
+	installedMods := listModInfo(paths.Mods)
+	largestModsStr += renderDuplicateModsSection(findDuplicateMods(installedMods))
+	largestModsStr += renderModDependenciesSection(installedMods)
 
 	// Get largest saves if included
 	largestSavesStr := ""
 	if config.IncludeSaves && exists(paths.Saves) {
-		largestSaves := getLargestItems(paths.Saves, 3)
+		largestSaves := getLargestItems(paths.Saves, largestListLimit, opts)
 		if len(largestSaves) > 0 {
 			largestSavesStr = fmt.Sprintf(`
 ## 🌍 Save Statistics
@@ -654,11 +1011,26 @@ func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths
 				largestSavesStr += fmt.Sprintf("  - %s (%s)\n", s.Name, formatBytes(s.Size))
 			}
 		}
+		if worlds, err := listWorlds(paths.Saves); err == nil && len(worlds) > 0 {
+			largestSavesStr += "- **Recently played:**\n"
+			for _, w := range worlds {
+				played := "never"
+				if !w.LastPlayed.IsZero() {
+					played = w.LastPlayed.Format("2006-01-02")
+				}
+				largestSavesStr += fmt.Sprintf("  - %s (%s)\n", w.Name, played)
+			}
+			if config.ActiveWorldsDays > 0 {
+				largestSavesStr += fmt.Sprintf("- **Backed up:** only worlds played in the last %d days\n", config.ActiveWorldsDays)
+			}
+		}
 	}
 
 	// Calculate total files
 	totalFiles := result.Stats.ScreenshotsCopied + result.Stats.ShaderConfigsCopied +
-		result.Stats.SavesCopied + result.Stats.XaeroCopied + result.Stats.DistantHorizonsCopied
+		result.Stats.SavesCopied + result.Stats.XaeroCopied + result.Stats.DistantHorizonsCopied +
+		result.Stats.WorldConfigsCopied + result.Stats.ServerFilesCopied + result.Stats.ProxyFilesCopied +
+		result.Stats.ConfigsCopied + result.Stats.CrashReportsCopied + result.Stats.IncludedCopied
 
 	// Loader version string
 	loaderStr := mcInfo.Loader
@@ -675,6 +1047,21 @@ func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths
 		}
 	}
 
+	crashSection := ""
+	if config.IncludeCrashes {
+		crashSection = renderCrashSection(paths.Root)
+	}
+
+	// Reproducible archives leave out anything that changes from run to run
+	generatedAt := time.Now().Format("2006-01-02 15:04:05")
+	durationStr := formatDuration(result.Duration)
+	compressionStr := renderCompressionSection(result.Stats)
+	timingStr := renderTimingSection(result.Stats.Timings)
+	if config.Deterministic {
+		generatedAt, durationStr = "reproducible mode (no timestamp)", "n/a"
+		compressionStr, timingStr = "", ""
+	}
+
 	content := fmt.Sprintf(`# 🗿 Totem Backup
 
 > Generated on %s
@@ -688,7 +1075,7 @@ func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths
 | Minecraft Version | %s |
 | Mod Loader | %s |
 | Operating System | %s |
-| Totem Version | v` + version.Version + ` |
+| Totem Version | v`+version.Version+` |
 
 ---
 
@@ -700,7 +1087,7 @@ func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths
 | Backup Duration | %s |
 | Total Backup Size | %s |
 | Total Files Copied | %d files |
-
+%s
 ---
 
 ## 📁 Contents
@@ -715,6 +1102,12 @@ func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths
 | Saves | %d files |
 | Xaero Maps | %d files |
 | Distant Horizons | %d files |
+| Datapacks | %d datapacks |
+| World Configs | %d files |
+| Crash Reports | %d files |
+| Included Folders | %d files |
+| Junk Skipped | %d files |
+| Verified Copies | %d files |
 
 ---
 
@@ -723,16 +1116,19 @@ func generateInfoMD(backupPath string, config *tui.Config, result *Result, paths
 - **Total Mods:** %d
 - **Total Size:** %s
 - **Largest Mods:**
-%s%s
+%s%s%s%s%s%s%s%s%s%s%s%s%s%s
 ---
 
 ## 🔧 Restoration Guide
 
+On a new PC, `+"`totem new-pc <backup>`"+` does the steps below and lists the downloads in a checklist.
+
 ### 1. Screenshots
 Copy the `+"`screenshots/`"+` folder back to your minecraft folder.
 
 ### 2. Mods
 Re-download mods listed in `+"`mods.txt`"+` from [Modrinth](https://modrinth.com) or [CurseForge](https://curseforge.com).
+`+"`mods.sha256`"+` holds the hash of each jar at backup time; `+"`totem check-mods`"+` checks downloads against it.
 
 ### 3. Shaders
 - Re-download shaders listed in `+"`shaders.txt`"+`
@@ -744,8 +1140,13 @@ Re-download packs listed in `+"`resourcepacks.txt`"+`.
 ### 5. Options
 Copy `+"`options.txt`"+` to your minecraft folder.
 
-### 6. Saves (if included)
+### 6. Incremental backups
+If `+"`manifest.json`"+` says this is an incremental backup, run `+"`totem restore <backup> --to <folder>`"+`
+to rebuild the full state from its chain.
+
+### 7. Saves (if included)
 Copy the `+"`saves/`"+` folder back to your minecraft folder.
+If only world configs were backed up, copy each world's `+"`level.dat`"+` and `+"`datapacks/`"+` into the matching world.
 
 ---
 
@@ -755,14 +1156,15 @@ Copy the `+"`saves/`"+` folder back to your minecraft folder.
 
 *Generated by [Totem](https://github.com/vaalley/totem) - Minecraft Backup Utility*
 `,
-		time.Now().Format("2006-01-02 15:04:05"),
+		generatedAt,
 		mcInfo.Version,
 		loaderStr,
 		getOSInfo(),
 		config.MinecraftPath,
-		formatDuration(result.Duration),
+		durationStr,
 		formatBytes(backupSize),
 		totalFiles,
+		renderHealthSection(result),
 		result.Stats.ScreenshotsCopied,
 		result.Stats.ModsListed, formatBytes(modsSize),
 		result.Stats.ShadersListed,
@@ -771,27 +1173,106 @@ Copy the `+"`saves/`"+` folder back to your minecraft folder.
 		result.Stats.SavesCopied,
 		result.Stats.XaeroCopied,
 		result.Stats.DistantHorizonsCopied,
+		result.Stats.DatapacksListed,
+		result.Stats.WorldConfigsCopied,
+		result.Stats.CrashReportsCopied,
+		result.Stats.IncludedCopied,
+		result.Stats.JunkSkipped,
+		result.Stats.FilesVerified,
 		result.Stats.ModsListed,
 		formatBytes(modsSize),
 		largestModsStr,
 		largestSavesStr,
+		renderCleanupSection(paths, config, opts),
+		crashSection,
+		renderPackSection(result.Pack),
+		renderDatapacksSection(result.Datapacks),
+		renderServerPropertiesSection(paths.Root),
+		renderProxySection(paths.Root, config.RedactSecrets),
+		renderSkippedSection(result.Skipped),
+		renderRulesSection(config),
+		renderMetaSection(config.Meta),
+		renderSinceSection(config)+renderIncrementalSection(config, result.Stats),
+		compressionStr,
+		timingStr,
 		statusStr,
 	)
 
+	if config.RedactReports {
+		content = newRedactor(config.MinecraftPath).Redact(content)
+	}
+
 	os.WriteFile(filepath.Join(backupPath, "info.md"), []byte(content), 0644)
 }
 
-func createZip(srcDir, destZip string) error {
+// renderTimingSection renders a table of how long each step took
+func renderTimingSection(timings []StepTiming) string {
+	if len(timings) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n## ⏱️ Timing\n\n| Step | Duration |\n|------|----------|\n")
+	for _, t := range timings {
+		b.WriteString(fmt.Sprintf("| %s | %s |\n", t.Step, t.Duration.Round(time.Millisecond)))
+	}
+	return b.String()
+}
+
+// renderCompressionSection summarizes files stored without compression
+func renderCompressionSection(stats Stats) string {
+	if stats.StoredUncompressed == 0 {
+		return ""
+	}
+	sizeCost := "none, deflate would not have shrunk them"
+	if stats.StoreSizeDelta > 0 {
+		sizeCost = formatBytes(stats.StoreSizeDelta) + " larger than deflating"
+	}
+	return fmt.Sprintf(`
+## 🗜️ Compression
+
+- **Stored without compression:** %d already-compressed files
+- **Estimated time saved:** %s
+- **Estimated size cost:** %s
+`, stats.StoredUncompressed, stats.StoreTimeSaved.Round(time.Millisecond), sizeCost)
+}
+
+// renderSkippedSection lists files skipped for exceeding the size cap
+func renderSkippedSection(skipped []FileInfo) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n## 🐘 Skipped Large Files\n\n")
+	b.WriteString("These files exceeded the size cap and were not backed up:\n\n")
+	for _, f := range skipped {
+		b.WriteString(fmt.Sprintf("- `%s` (%s)\n", f.Name, formatBytes(f.Size)))
+	}
+	return b.String()
+}
+
+// createZip zips srcDir into destZip. With a password, every entry is encrypted with AES-256.
+func createZip(srcDir, destZip string, plan *compressionPlan, password string, progress *Progress) error {
 	zipFile, err := os.Create(destZip)
 	if err != nil {
 		return err
 	}
-	defer zipFile.Close()
+	err = writeZip(zipFile, srcDir, plan, password, progress)
+	if closeErr := zipFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
 
-	w := zip.NewWriter(zipFile)
-	defer w.Close()
+// writeZip writes srcDir as a zip to out
+func writeZip(out io.Writer, srcDir string, plan *compressionPlan, password string, progress *Progress) error {
+	w := zip.NewWriter(out)
 
-	return filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+	// Pin the deflate level so identical inputs always produce identical bytes
+	w.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, flate.DefaultCompression)
+	})
+
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -801,20 +1282,34 @@ func createZip(srcDir, destZip string) error {
 		}
 
 		relPath, _ := filepath.Rel(srcDir, path)
-		f, err := w.Create(relPath)
+		name := filepath.ToSlash(relPath)
+		source, err := os.Open(path)
 		if err != nil {
 			return err
 		}
+		defer source.Close()
 
-		source, err := os.Open(path)
+		if password != "" {
+			f, err := createAESEntry(w, name, plan.method(name), password)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, progress.track(source)); err != nil {
+				return err
+			}
+			return f.Close()
+		}
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: plan.method(name)})
 		if err != nil {
 			return err
 		}
-		defer source.Close()
-
-		_, err = io.Copy(f, source)
+		_, err = io.Copy(f, progress.track(source))
 		return err
 	})
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
 func openFolder(path string) {
@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A backup's memory limit is lifted when it finishes, so later backups and the TUI don't keep it
+func TestMemoryLimitRestored(t *testing.T) {
+	before := debug.SetMemoryLimit(-1)
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+
+	if _, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), MemoryLimit: 256 << 20}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if after := debug.SetMemoryLimit(-1); after != before {
+		t.Errorf("memory limit after the backup = %d, want %d", after, before)
+	}
+
+	// Overlapping backups keep the lowest limit until the last one ends
+	lift1 := limitMemory(512 << 20)
+	lift2 := limitMemory(256 << 20)
+	lift2()
+	if got := debug.SetMemoryLimit(-1); got != 256<<20 {
+		t.Errorf("limit with one backup still running = %d, want %d", got, 256<<20)
+	}
+	lift1()
+	if got := debug.SetMemoryLimit(-1); got != before {
+		t.Errorf("limit after both backups = %d, want %d", got, before)
+	}
+}
+
+// Files over the size cap are left out of the backup and listed in the result and info.md
+func TestMaxFileSizeSkips(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "saves/World/huge.bin", strings.Repeat("x", 4096))
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), IncludeSaves: true, MaxFileSize: 1024}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	if len(result.Skipped) != 1 || filepath.Base(result.Skipped[0].Name) != "huge.bin" || result.Skipped[0].Size != 4096 {
+		t.Fatalf("Skipped = %+v, want huge.bin", result.Skipped)
+	}
+	if exists(filepath.Join(result.OutputPath, "saves", "World", "huge.bin")) {
+		t.Error("huge.bin was backed up")
+	}
+	if !exists(filepath.Join(result.OutputPath, "saves", "World", "level.dat")) {
+		t.Error("level.dat was not backed up")
+	}
+	info, _ := os.ReadFile(filepath.Join(result.OutputPath, "info.md"))
+	if !strings.Contains(string(info), "Skipped Large Files") || !strings.Contains(string(info), "huge.bin") {
+		t.Error("info.md does not list the skipped file")
+	}
+}
+
+// Each step that ran is timed, in the order it ran, and listed in the report
+func TestStepTimings(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "screenshots/shot.png", "png")
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), ZipOutput: true}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	var steps []string
+	for _, s := range result.Stats.Timings {
+		steps = append(steps, s.Step)
+		if s.Start.IsZero() || s.Duration < 0 {
+			t.Errorf("step %s: %+v", s.Step, s)
+		}
+	}
+	if got := strings.Join(steps, ","); !strings.HasPrefix(got, "Screenshots,") || !strings.HasSuffix(got, ",Zip") || !strings.Contains(got, "Options") {
+		t.Errorf("timed steps %s, want Screenshots first and Zip last", got)
+	}
+	if s := renderTimingSection(result.Stats.Timings); !strings.Contains(s, "| Zip | ") {
+		t.Errorf("timing section %q has no Zip row", s)
+	}
+}
+
+// Two deterministic zips of the same files are byte-identical, even with the files touched
+// in between
+func TestDeterministicZip(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "screenshots/shot.png", "png")
+
+	var zips [][]byte
+	for i := range 2 {
+		if i == 1 {
+			later := time.Now().Add(time.Hour)
+			os.Chtimes(filepath.Join(mc, "options.txt"), later, later)
+			os.Chtimes(filepath.Join(mc, "screenshots", "shot.png"), later, later)
+		}
+		result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), ZipOutput: true, Deterministic: true}, nil)
+		if err != nil || !result.Success {
+			t.Fatalf("PerformContext: %v", err)
+		}
+		data, err := os.ReadFile(result.OutputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zips = append(zips, data)
+	}
+	if !bytes.Equal(zips[0], zips[1]) {
+		t.Error("the two deterministic zips differ")
+	}
+}
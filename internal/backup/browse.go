@@ -0,0 +1,157 @@
+package backup
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// ListBackups returns the backups in dest and its instance subfolders for the backup
+// browser, newest first
+func ListBackups(dest string) ([]tui.BackupItem, error) {
+	if _, err := os.Stat(dest); err != nil {
+		return nil, err
+	}
+	var items []tui.BackupItem
+	for _, dir := range backupFolders(dest) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		catalog, _ := LoadCatalog(dir)
+		for _, e := range entries {
+			t, ok := parseBackupName(e.Name())
+			if !ok || !isBackupEntry(e.Name()) {
+				continue
+			}
+			item := tui.BackupItem{Path: filepath.Join(dir, e.Name()), Name: e.Name(), Time: t}
+			item.Instance, _ = filepath.Rel(dest, dir)
+			// Catalogued backups need not be opened
+			if c := catalog.Entry(e.Name()); c != nil {
+				item.Size, item.Incremental, item.Meta = c.Size, c.Type == TypeIncremental, c.Meta
+				item.Minecraft, item.Loader = knownValue(c.Minecraft), knownValue(c.Loader)
+				item.Contents = contentsSummary(statsCounts(c.Stats))
+				item.Key = c.Key
+				items = append(items, item)
+				continue
+			}
+			if e.IsDir() {
+				item.Size = getDirSize(item.Path, nil)
+			} else {
+				item.Size = archiveSize(item.Path)
+			}
+			if m, err := ReadManifest(item.Path); err == nil {
+				item.Incremental = m.Type == TypeIncremental
+				item.Meta = m.Meta
+			}
+			if info, err := ReadBackupFile(item.Path, "info.md"); err == nil {
+				item.Minecraft = knownValue(infoTableValue(string(info), "Minecraft Version", ""))
+				item.Loader = knownValue(infoTableValue(string(info), "Mod Loader", ""))
+				item.Contents = contentsSummary(infoCounts(string(info)))
+			}
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.After(items[j].Time) })
+	return items, nil
+}
+
+// contentRows are the rows of info.md's contents table that summaries mention, in order,
+// with the Stats counter behind each
+var contentRows = []struct {
+	row, label string
+	count      func(Stats) int
+}{
+	{"Saves", "save files", func(s Stats) int { return s.SavesCopied }},
+	{"World Configs", "world configs", func(s Stats) int { return s.WorldConfigsCopied }},
+	{"Screenshots", "screenshots", func(s Stats) int { return s.ScreenshotsCopied }},
+	{"Mods", "mods", func(s Stats) int { return s.ModsListed }},
+	{"Shaders", "shaders", func(s Stats) int { return s.ShadersListed }},
+	{"Resource Packs", "resource packs", func(s Stats) int { return s.ResourcepacksListed }},
+	{"Xaero Maps", "map files", func(s Stats) int { return s.XaeroCopied }},
+	{"Distant Horizons", "Distant Horizons files", func(s Stats) int { return s.DistantHorizonsCopied }},
+	{"Crash Reports", "crash reports", func(s Stats) int { return s.CrashReportsCopied }},
+	{"Included Folders", "included files", func(s Stats) int { return s.IncludedCopied }},
+}
+
+// statsCounts returns the contents counts of a catalogued backup, by contents row
+func statsCounts(s Stats) map[string]int {
+	counts := map[string]int{}
+	for _, r := range contentRows {
+		counts[r.row] = r.count(s)
+	}
+	return counts
+}
+
+// infoCounts reads the contents counts from a backup's info.md, by contents row
+func infoCounts(info string) map[string]int {
+	counts := map[string]int{}
+	for _, r := range contentRows {
+		var n int
+		fmt.Sscan(infoTableValue(info, r.row, "0"), &n)
+		counts[r.row] = n
+	}
+	return counts
+}
+
+// contentsSummary describes what a backup holds, leaving out what it has none of
+func contentsSummary(counts map[string]int) string {
+	var parts []string
+	for _, r := range contentRows {
+		if n := counts[r.row]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, r.label))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// knownValue returns v, or "" for the "Unknown" info.md writes when detection failed
+func knownValue(v string) string {
+	if v == "Unknown" {
+		return ""
+	}
+	return v
+}
+
+// BackupContents lists the files of a backup with their sizes, as its chain adds up to
+func BackupContents(backupPath string) ([]tui.BackupFile, error) {
+	fsys, closeFS, err := BackupFS(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFS()
+
+	var files []tui.BackupFile
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || p == ManifestName || p == SignatureName {
+			return err
+		}
+		file := tui.BackupFile{Path: p}
+		if info, err := d.Info(); err == nil {
+			file.Size = info.Size()
+		}
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
+
+// ReadBackupFile returns one file of a backup, resolving its chain
+func ReadBackupFile(backupPath, file string) ([]byte, error) {
+	fsys, closeFS, err := BackupFS(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFS()
+	return fs.ReadFile(fsys, file)
+}
+
+// OpenFile opens a file in its default app
+func OpenFile(path string) {
+	openFolder(path)
+}
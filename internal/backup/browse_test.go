@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// The manage screen lists backups newest first, catalogued or not, and their contents
+func TestListBackups(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	dest := t.TempDir()
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, IncludeSaves: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Copied in by hand, so not in the catalog
+	writeTestFile(t, dest, "Vanilla/backup_2020-01-01_00-00/info.md", "| Saves | 4 files |\n| Minecraft Version | Unknown |\n")
+
+	items, err := ListBackups(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Path != result.OutputPath || items[1].Instance != "Vanilla" {
+		t.Fatalf("ListBackups = %+v, want the new backup, then Vanilla's", items)
+	}
+	if items[0].Contents != "1 save files" || items[0].Size == 0 {
+		t.Errorf("catalogued backup: contents %q, size %d", items[0].Contents, items[0].Size)
+	}
+	if items[1].Contents != "4 save files" || items[1].Minecraft != "" {
+		t.Errorf("uncatalogued backup: contents %q, Minecraft %q", items[1].Contents, items[1].Minecraft)
+	}
+
+	files, err := BackupContents(result.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+		if f.Path == ManifestName {
+			t.Error("the manifest is listed as backup content")
+		}
+	}
+	if !slices.Contains(paths, "saves/World/level.dat") || !slices.Contains(paths, "options.txt") {
+		t.Errorf("BackupContents = %q", paths)
+	}
+}
@@ -0,0 +1,272 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// CatalogName is the index Totem keeps in each folder it writes backups to
+const CatalogName = "totem-catalog.json"
+
+// CatalogEntry describes one backup in a catalog
+type CatalogEntry struct {
+	Name      string            `json:"name"` // Folder or archive in the destination
+	Time      time.Time         `json:"time"`
+	Size      int64             `json:"size"`
+	Type      string            `json:"type"`
+	Parent    string            `json:"parent,omitempty"`
+	Source    string            `json:"source,omitempty"` // Left out with redacted reports
+	Minecraft string            `json:"minecraft,omitempty"`
+	Loader    string            `json:"loader,omitempty"`
+	Options   []string          `json:"options,omitempty"`
+	Files     int               `json:"files"`
+	Health    int               `json:"health"`
+	Duration  time.Duration     `json:"duration"`
+	Stats     Stats             `json:"stats"`
+	Meta      map[string]string `json:"meta,omitempty"`
+	Key       string            `json:"key,omitempty"`    // What the backup is encrypted with; see backupKey
+	Upload    *UploadCheck      `json:"upload,omitempty"` // Only in a remote destination's catalog
+}
+
+// Catalog lists the backups in a destination folder, so listing and pruning need not open
+// each one. The folder itself stays authoritative: backups missing from the catalog are
+// read directly, and entries whose backup is gone are dropped on the next write.
+type Catalog struct {
+	Version int            `json:"version"`
+	Backups []CatalogEntry `json:"backups"`
+}
+
+// LoadCatalog reads the catalog in dir; a folder without one has an empty catalog
+func LoadCatalog(dir string) (*Catalog, error) {
+	c := &Catalog{Version: 1}
+	data, err := os.ReadFile(filepath.Join(dir, CatalogName))
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return &Catalog{Version: 1}, fmt.Errorf("%s: %w", filepath.Join(dir, CatalogName), err)
+	}
+	return c, nil
+}
+
+// Entry returns the catalog entry for a backup folder or archive name, or nil
+func (c *Catalog) Entry(name string) *CatalogEntry {
+	for i := range c.Backups {
+		if c.Backups[i].Name == name {
+			return &c.Backups[i]
+		}
+	}
+	return nil
+}
+
+// save writes the catalog to dir atomically, dropping entries whose backup is gone. Callers
+// hold the catalog lock; see updateCatalog.
+func (c *Catalog) save(dir string) error {
+	c.Backups = slices.DeleteFunc(c.Backups, func(e CatalogEntry) bool {
+		return !exists(filepath.Join(dir, e.Name))
+	})
+	data, err := c.encode()
+	if err != nil {
+		return err
+	}
+	// A temp file of its own, so two writers never rename each other's half-written one
+	tmp, err := os.CreateTemp(dir, CatalogName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, CatalogName)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// encode returns the catalog as written to disk, oldest backup first
+func (c *Catalog) encode() ([]byte, error) {
+	slices.SortFunc(c.Backups, func(a, b CatalogEntry) int { return a.Time.Compare(b.Time) })
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// merge adds the entries of other, replacing those of the same name
+func (c *Catalog) merge(other *Catalog) {
+	for _, e := range other.Backups {
+		c.Backups = slices.DeleteFunc(c.Backups, func(old CatalogEntry) bool { return old.Name == e.Name })
+		c.Backups = append(c.Backups, e)
+	}
+}
+
+// updateCatalog changes the catalog in dir under its lock. The catalog is read again once the
+// lock is held, so entries another machine added in the meantime are kept.
+func updateCatalog(dir string, change func(*Catalog)) error {
+	unlock, err := lockCatalog(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	c, err := LoadCatalog(dir)
+	if err != nil {
+		return err
+	}
+	change(c)
+	return c.save(dir)
+}
+
+// CatalogLockName is the folder a writer creates next to the catalog while it changes it.
+// Creating a folder is atomic on local disks, network shares and over sftp alike.
+const CatalogLockName = CatalogName + ".lock"
+
+// catalogLeaseName is the file in the lock folder that names its holder
+const catalogLeaseName = "lease.json"
+
+const (
+	// catalogLeaseTime is how long a lock is honoured; one left behind by a crashed run is
+	// taken over once it runs out
+	catalogLeaseTime = 10 * time.Minute
+	// catalogLockWait is how long a writer waits for another one to finish
+	catalogLockWait = time.Minute
+	// catalogLockRetry is how often a held lock is tried again
+	catalogLockRetry = 200 * time.Millisecond
+)
+
+// catalogLease is written into the lock folder to say who holds the lock and until when
+type catalogLease struct {
+	Host    string    `json:"host"`
+	PID     int       `json:"pid"`
+	Expires time.Time `json:"expires"`
+}
+
+func (l *catalogLease) String() string {
+	return fmt.Sprintf("%s (pid %d, until %s)", l.Host, l.PID, l.Expires.Format("15:04:05"))
+}
+
+// newCatalogLease is a lease for this process, starting now
+func newCatalogLease() *catalogLease {
+	host, _ := os.Hostname()
+	return &catalogLease{Host: host, PID: os.Getpid(), Expires: time.Now().Add(catalogLeaseTime)}
+}
+
+// lockCatalog takes the lock on the catalog in dir, waiting while another writer holds it,
+// and returns the function that releases it
+func lockCatalog(dir string) (func(), error) {
+	lock := filepath.Join(dir, CatalogLockName)
+	deadline := time.Now().Add(catalogLockWait)
+	for {
+		err := os.Mkdir(lock, 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		holder := readCatalogLease(lock)
+		if time.Now().After(holder.Expires) {
+			os.RemoveAll(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("the catalog in %s is locked by %s", dir, holder)
+		}
+		time.Sleep(catalogLockRetry)
+	}
+
+	data, _ := json.Marshal(newCatalogLease())
+	if err := os.WriteFile(filepath.Join(lock, catalogLeaseName), data, 0644); err != nil {
+		os.RemoveAll(lock)
+		return nil, err
+	}
+	return func() { os.RemoveAll(lock) }, nil
+}
+
+// readCatalogLease reads the lease in a lock folder. A lock whose lease was never written,
+// because its holder stopped right after taking it, lasts a lease from the folder's creation.
+func readCatalogLease(lock string) *catalogLease {
+	var l catalogLease
+	if data, err := os.ReadFile(filepath.Join(lock, catalogLeaseName)); err == nil && json.Unmarshal(data, &l) == nil {
+		return &l
+	}
+	l.Host = "an unknown writer"
+	if info, err := os.Stat(lock); err == nil {
+		l.Expires = info.ModTime().Add(catalogLeaseTime)
+	}
+	return &l
+}
+
+// recordBackup adds a finished backup to the catalog of its destination
+func recordBackup(config *tui.Config, result *Result, started time.Time) error {
+	dir := filepath.Dir(result.OutputPath)
+	info := getMinecraftInfo(config.MinecraftPath)
+	entry := CatalogEntry{
+		Name:      filepath.Base(result.OutputPath),
+		Time:      started,
+		Minecraft: info.Version,
+		Loader:    info.Loader,
+		Options:   catalogOptions(config),
+		Files:     result.TotalFiles,
+		Health:    result.Health,
+		Duration:  result.Duration,
+		Stats:     result.Stats,
+		Meta:      config.Meta,
+	}
+	entry.Type, entry.Parent = chainLink(config)
+	entry.Key = backupKey(config, result.OutputPath)
+	if !config.RedactReports {
+		entry.Source = config.MinecraftPath
+	}
+	if fi, err := os.Stat(result.OutputPath); err == nil && !fi.IsDir() {
+		entry.Size = archiveSize(result.OutputPath)
+	} else {
+		entry.Size = getDirSize(result.OutputPath, nil)
+	}
+
+	return updateCatalog(dir, func(c *Catalog) {
+		c.Backups = slices.DeleteFunc(c.Backups, func(e CatalogEntry) bool { return e.Name == entry.Name })
+		c.Backups = append(c.Backups, entry)
+	})
+}
+
+// catalogOptions names the options a backup ran with
+func catalogOptions(config *tui.Config) []string {
+	var opts []string
+	for _, o := range []struct {
+		on   bool
+		name string
+	}{
+		{config.ZipOutput, "zip"},
+		{config.Zstd, "zstd"},
+		{config.ModMetadata, "mod-metadata"},
+		{config.IncludeSaves, "saves"},
+		{config.WorldConfigOnly, "world-config"},
+		{config.IncludeXaero, "xaero"},
+		{config.IncludeDH, "distant-horizons"},
+		{config.IncludeCrashes, "crash-reports"},
+		{config.Incremental, "incremental"},
+		{config.LinkDest, "link-dest"},
+		{config.SignManifest, "sign"},
+		{config.Parity, "parity"},
+		{config.Deterministic, "deterministic"},
+		{config.VerifyCopies, "verify"},
+		{config.ActiveWorldsDays > 0, "active-worlds"},
+	} {
+		if o.on {
+			opts = append(opts, o.name)
+		}
+	}
+	return opts
+}
@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Writers sharing a destination, as two machines on one NAS folder do, keep each other's entries
+func TestCatalogConcurrentWriters(t *testing.T) {
+	dir := t.TempDir()
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := range writers {
+		name := fmt.Sprintf("backup_2026-01-01_00-%02d", i)
+		writeTestFile(t, dir, name+"/options.txt", "fov:0.0\n")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := updateCatalog(dir, func(c *Catalog) {
+				c.Backups = append(c.Backups, CatalogEntry{Name: name, Type: TypeFull})
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c, err := LoadCatalog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Backups) != writers {
+		t.Errorf("catalog has %d entries, want %d", len(c.Backups), writers)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if e.Name() == CatalogLockName || filepath.Ext(e.Name()) == ".tmp" {
+			t.Errorf("%s was left behind", e.Name())
+		}
+	}
+}
+
+// A lock left by a run that crashed is taken over once its lease runs out
+func TestCatalogExpiredLease(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "backup_2026-01-01_00-00/options.txt", "fov:0.0\n")
+	lease, _ := json.Marshal(catalogLease{Host: "laptop", PID: 1, Expires: time.Now().Add(-time.Minute)})
+	writeTestFile(t, dir, CatalogLockName+"/lease.json", string(lease))
+
+	err := updateCatalog(dir, func(c *Catalog) {
+		c.Backups = append(c.Backups, CatalogEntry{Name: "backup_2026-01-01_00-00", Type: TypeFull})
+	})
+	if err != nil {
+		t.Fatalf("updateCatalog over an expired lease: %v", err)
+	}
+	c, _ := LoadCatalog(dir)
+	if !slices.ContainsFunc(c.Backups, func(e CatalogEntry) bool { return e.Name == "backup_2026-01-01_00-00" }) {
+		t.Error("the entry was not recorded")
+	}
+	if exists(filepath.Join(dir, CatalogLockName)) {
+		t.Error("the lock was kept after the update")
+	}
+}
+
+// The catalog records which key each encrypted backup needs
+func TestBackupKey(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // No gpg: fingerprints fall back to the recipient
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	cloud := t.TempDir()
+	settings := fmt.Sprintf(`{"destinations":[{"path":%q,"encrypt":"age","recipient":"age1xyz"}]}`, cloud)
+	writeTestFile(t, os.Getenv("TOTEM_CONFIG"), "config.json", settings)
+
+	cases := []struct {
+		config tui.Config
+		output string
+		want   string
+	}{
+		{tui.Config{BackupDest: cloud}, filepath.Join(cloud, "backup_2026-01-01_00-00.zip.age"), "age:age1xyz"},
+		{tui.Config{BackupDest: "d", GPGRecipient: "ABCD1234"}, "d/backup_2026-01-01_00-00.zip.gpg", "gpg:ABCD1234"},
+		{tui.Config{BackupDest: "d", ProtectZip: true, ZipPassword: "hunter2"}, "d/backup_2026-01-01_00-00.zip", "zip-password"},
+		{tui.Config{BackupDest: "d"}, "d/backup_2026-01-01_00-00", ""},
+	}
+	for _, c := range cases {
+		if got := backupKey(&c.config, c.output); got != c.want {
+			t.Errorf("backupKey(%s) = %q, want %q", c.output, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+	"github.com/vaalley/totem/internal/version"
+)
+
+// ManifestName is the file describing a backup and its place in a chain
+const ManifestName = "manifest.json"
+
+// Backup types in a chain
+const (
+	TypeFull        = "full"
+	TypeIncremental = "incremental"
+)
+
+// Manifest describes a backup: a full backup, or an incremental on top of a parent
+type Manifest struct {
+	Version int       `json:"version"`
+	Totem   string    `json:"totem"`
+	Type    string    `json:"type"`
+	Parent  string    `json:"parent,omitempty"` // Name of the parent backup in the same folder
+	Created time.Time `json:"created,omitzero"` // Left out in reproducible mode
+
+	Meta  map[string]string `json:"meta,omitempty"` // Labels given with --meta
+	Files []string          `json:"files"`          // Slash-separated paths stored in this backup
+
+	SHA256   map[string]string    `json:"sha256,omitempty"` // Hex digest per entry in Files
+	Sizes    map[string]int64     `json:"sizes,omitempty"`  // Size per entry in Files
+	ModTimes map[string]time.Time `json:"mtimes,omitempty"` // Source modification time of copied files
+}
+
+// chainLink returns the type a backup made with config has, and its parent if it has one.
+// A cutoff with no earlier backup to fall back on still copies everything it can restore,
+// so it stays a full backup rather than a chain link with nothing before it.
+func chainLink(config *tui.Config) (typ, parent string) {
+	cutoff := !config.ScreenshotsSince.IsZero() || !config.SavesSince.IsZero() || !config.XaeroSince.IsZero()
+	if (cutoff || config.Incremental) && config.Parent != "" {
+		return TypeIncremental, config.Parent
+	}
+	return TypeFull, ""
+}
+
+// manifestChunk is how many files are hashed and written at a time, which bounds
+// memory on instances with millions of files
+const manifestChunk = 1024
+
+// writeManifest records the backup's files, their hashes and the chain link.
+// Hashes already taken while copying are reused; the rest are computed in parallel.
+// The JSON is streamed to disk so the file list is never held in memory.
+func writeManifest(backupPath string, config *tui.Config, known *hashRecorder) (err error) {
+	m := Manifest{
+		Version: 1,
+		Totem:   version.Version,
+		Meta:    config.Meta,
+	}
+	if !config.Deterministic {
+		m.Created = time.Now()
+	}
+	m.Type, m.Parent = chainLink(config)
+
+	f, err := os.Create(filepath.Join(backupPath, ManifestName))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	w := bufio.NewWriter(f)
+
+	// Header fields come from the struct so the layout matches json.MarshalIndent
+	header, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	header = bytes.TrimSuffix(header, []byte(",\n  \"files\": null\n}"))
+	w.Write(header)
+
+	// Pass 1: file names
+	w.WriteString(",\n  \"files\": [")
+	n := 0
+	err = walkManifestFiles(backupPath, func(rel, _ string) error {
+		name, _ := json.Marshal(rel)
+		if n > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString("\n    ")
+		w.Write(name)
+		n++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		w.WriteString("\n  ")
+	}
+	w.WriteString("]")
+
+	// Pass 2: hashes, a chunk at a time
+	if n > 0 {
+		w.WriteString(",\n  \"sha256\": {")
+		first := true
+		var rels, paths []string
+		flush := func() error {
+			sums, err := known.hashAll(paths)
+			if err != nil {
+				return err
+			}
+			for i, rel := range rels {
+				name, _ := json.Marshal(rel)
+				if !first {
+					w.WriteString(",")
+				}
+				first = false
+				fmt.Fprintf(w, "\n    %s: %q", name, sums[paths[i]])
+			}
+			rels, paths = rels[:0], paths[:0]
+			return nil
+		}
+		err = walkManifestFiles(backupPath, func(rel, path string) error {
+			rels, paths = append(rels, rel), append(paths, path)
+			if len(paths) == manifestChunk {
+				return flush()
+			}
+			return nil
+		})
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			return err
+		}
+		w.WriteString("\n  }")
+	}
+
+	// Pass 3: sizes
+	if n > 0 {
+		w.WriteString(",\n  \"sizes\": {")
+		first := true
+		err = walkManifestFiles(backupPath, func(rel, path string) error {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			name, _ := json.Marshal(rel)
+			if !first {
+				w.WriteString(",")
+			}
+			first = false
+			fmt.Fprintf(w, "\n    %s: %d", name, info.Size())
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		w.WriteString("\n  }")
+	}
+
+	// Pass 4: source modification times of copied files, which incremental backups compare.
+	// Reproducible archives leave them out, so backups on top of one copy every file again.
+	first := true
+	if !config.Deterministic {
+		err = walkManifestFiles(backupPath, func(rel, path string) error {
+			t, ok := known.takeModTime(path)
+			if !ok {
+				return nil
+			}
+			name, _ := json.Marshal(rel)
+			stamp, _ := t.MarshalJSON()
+			if first {
+				w.WriteString(",\n  \"mtimes\": {")
+			} else {
+				w.WriteString(",")
+			}
+			first = false
+			fmt.Fprintf(w, "\n    %s: %s", name, stamp)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !first {
+		w.WriteString("\n  }")
+	}
+	w.WriteString("\n}")
+	return w.Flush()
+}
+
+// walkManifestFiles calls fn for each file in a backup folder, except the manifest itself
+func walkManifestFiles(backupPath string, fn func(rel, path string) error) error {
+	return filepath.WalkDir(backupPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(backupPath, path)
+		rel = filepath.ToSlash(rel)
+		if rel == ManifestName || rel == SignatureName {
+			return nil
+		}
+		return fn(rel, path)
+	})
+}
+
+// errManifestFound stops a walk of a tar backup once its manifest has been read
+var errManifestFound = errors.New("manifest found")
+
+// ReadManifest reads the manifest of a backup folder or archive. Only the manifest is read:
+// a folder's is opened directly, a zip's is found in its central directory, and a tar is
+// read no further than the manifest (which Totem writes first).
+func ReadManifest(backupPath string) (*Manifest, error) {
+	m := &Manifest{}
+	missing := fmt.Errorf("%s has no %s", filepath.Base(backupPath), ManifestName)
+	switch {
+	case isZipBackup(backupPath):
+		zr, closeZip, err := openZipBackup(backupPath)
+		if err != nil {
+			return nil, err
+		}
+		defer closeZip()
+		for _, f := range zr.File {
+			if filepath.ToSlash(f.Name) != ManifestName {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			if err := json.NewDecoder(rc).Decode(m); err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+		return nil, missing
+
+	case isTarBackup(backupPath):
+		err := forEachMatchingFile(backupPath, func(rel string) bool { return rel == ManifestName }, func(_ string, r io.Reader) error {
+			if err := json.NewDecoder(r).Decode(m); err != nil {
+				return err
+			}
+			return errManifestFound
+		})
+		if errors.Is(err, errManifestFound) {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, missing
+	}
+
+	f, err := os.Open(filepath.Join(backupPath, ManifestName))
+	if os.IsNotExist(err) {
+		return nil, missing
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// backupName strips the archive extension from a backup path
+func backupName(backupPath string) string {
+	return trimArchiveSuffix(filepath.Base(backupPath))
+}
+
+// findBackup locates a named backup as a folder or archive inside dir
+func findBackup(dir, name string) (string, error) {
+	for _, candidate := range []string{name, name + ".zip", name + ZstdSuffix, name + ".zip" + firstPart, name + ZstdSuffix + firstPart} {
+		path := filepath.Join(dir, candidate)
+		if exists(path) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("backup %s not found in %s", name, dir)
+}
+
+// ResolveChain returns the backups needed to materialize backupPath, oldest (full) first
+func ResolveChain(backupPath string) ([]string, error) {
+	dir := filepath.Dir(backupPath)
+	chain := []string{backupPath}
+	seen := map[string]bool{backupName(backupPath): true}
+
+	for current := backupPath; ; {
+		m, err := ReadManifest(current)
+		if err != nil {
+			return nil, err
+		}
+		if m.Type == TypeFull {
+			break
+		}
+		if m.Parent == "" {
+			return nil, fmt.Errorf("%s is incremental but has no parent backup", backupName(current))
+		}
+		if seen[m.Parent] {
+			return nil, fmt.Errorf("backup chain loops at %s", m.Parent)
+		}
+		seen[m.Parent] = true
+
+		parent, err := findBackup(dir, m.Parent)
+		if err != nil {
+			return nil, err
+		}
+		chain = append([]string{parent}, chain...)
+		current = parent
+	}
+	return chain, nil
+}
+
+// Restore materializes backupPath into dest by applying its chain from the full backup up.
+// Files deleted between backups are not tracked, so they reappear from older links.
+func Restore(backupPath, dest string) (int, error) {
+	chain, err := ResolveChain(backupPath)
+	if err != nil {
+		return 0, err
+	}
+
+	written := map[string]bool{}
+	for _, link := range chain {
+		err := forEachFile(link, func(rel string, r io.Reader) error {
+			// The signature covers the link's own manifest, not the restored files
+			if rel == ManifestName || rel == SignatureName {
+				return nil
+			}
+			target, ok := joinInside(dest, rel)
+			if !ok {
+				return fmt.Errorf("unsafe path in backup: %s", rel)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			if _, err := io.Copy(out, r); err != nil {
+				return err
+			}
+			written[rel] = true
+			return nil
+		})
+		if err != nil {
+			return len(written), fmt.Errorf("%s: %w", backupName(link), err)
+		}
+	}
+	return len(written), nil
+}
+
+// Consolidate squashes a chain into a new full backup next to it and returns its path
+func Consolidate(backupPath string) (string, error) {
+	dest := filepath.Join(filepath.Dir(backupPath), backupName(backupPath)+"_full")
+	if exists(dest) {
+		return "", fmt.Errorf("%s already exists", dest)
+	}
+	if _, err := Restore(backupPath, dest); err != nil {
+		os.RemoveAll(dest)
+		return "", err
+	}
+	// info.md is the last link's report and counts only what that link copied
+	os.Remove(filepath.Join(dest, "info.md"))
+	if err := writeManifest(dest, &tui.Config{}, nil); err != nil {
+		return "", err
+	}
+	if err := recordConsolidated(dest, backupPath); err != nil {
+		return dest, fmt.Errorf("%s was written but not added to the catalog: %w", filepath.Base(dest), err)
+	}
+	return dest, nil
+}
+
+// consolidatedFolders are the backup folders whose files a Stats counter counts
+var consolidatedFolders = map[string]func(*Stats) *int{
+	"screenshots":                  func(s *Stats) *int { return &s.ScreenshotsCopied },
+	"saves":                        func(s *Stats) *int { return &s.SavesCopied },
+	"xaero":                        func(s *Stats) *int { return &s.XaeroCopied },
+	"distant_horizons_server_data": func(s *Stats) *int { return &s.DistantHorizonsCopied },
+	CrashReportsDir:                func(s *Stats) *int { return &s.CrashReportsCopied },
+}
+
+// recordConsolidated adds the full backup Consolidate made from the chain ending at last to
+// the catalog. Its contents are counted from its files; the mod, shader and resource pack
+// lists are complete in every link, so they and the instance details carry over from last.
+func recordConsolidated(dest, last string) error {
+	m, err := ReadManifest(dest)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(dest)
+	entry := CatalogEntry{Name: filepath.Base(dest), Type: TypeFull, Files: len(m.Files), Size: getDirSize(dest, nil)}
+	entry.Time, _ = parseBackupName(filepath.Base(last))
+
+	if c, err := LoadCatalog(dir); err == nil {
+		if prev := c.Entry(filepath.Base(last)); prev != nil {
+			entry.Source, entry.Minecraft, entry.Loader, entry.Meta = prev.Source, prev.Minecraft, prev.Loader, prev.Meta
+			entry.Options = slices.DeleteFunc(slices.Clone(prev.Options), func(o string) bool { return o == "incremental" })
+			entry.Stats = Stats{
+				ModsListed:          prev.Stats.ModsListed,
+				ShadersListed:       prev.Stats.ShadersListed,
+				ResourcepacksListed: prev.Stats.ResourcepacksListed,
+			}
+		}
+	}
+	for _, f := range m.Files {
+		top, _, _ := strings.Cut(f, "/")
+		if counter, ok := consolidatedFolders[top]; ok {
+			*counter(&entry.Stats)++
+		}
+	}
+
+	return updateCatalog(dir, func(c *Catalog) {
+		c.Backups = slices.DeleteFunc(c.Backups, func(e CatalogEntry) bool { return e.Name == entry.Name })
+		c.Backups = append(c.Backups, entry)
+	})
+}
+
+// forEachFile calls fn for every file in a backup folder, zip or tar, with slash-separated paths
+func forEachFile(backupPath string, fn func(rel string, r io.Reader) error) error {
+	if isTarBackup(backupPath) {
+		return forEachMatchingFile(backupPath, func(string) bool { return true }, fn)
+	}
+	if isZipBackup(backupPath) {
+		zr, closeZip, err := openZipBackup(backupPath)
+		if err != nil {
+			return err
+		}
+		defer closeZip()
+
+		files := make([]*zip.File, 0, len(zr.File))
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() {
+				files = append(files, f)
+			}
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+		for _, f := range files {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(filepath.ToSlash(f.Name), rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return filepath.WalkDir(backupPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(backupPath, path)
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return fn(filepath.ToSlash(rel), f)
+	})
+}
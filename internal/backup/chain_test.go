@@ -0,0 +1,196 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// writeTestZip writes a zip backup holding files, by slash-separated name
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(contents))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A crafted entry name must not let Restore, or Consolidate through it, write outside dest
+func TestRestoreUnsafePath(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backups", "backup_2026-01-01_00-00.zip")
+	os.MkdirAll(filepath.Dir(archive), 0755)
+	writeTestZip(t, archive, map[string]string{
+		ManifestName:        `{"version":1,"type":"full","files":["../../escaped.txt"]}`,
+		"../../escaped.txt": "gotcha",
+	})
+
+	dest := filepath.Join(dir, "a", "b", "restored")
+	if _, err := Restore(archive, dest); err == nil {
+		t.Error("Restore of ../../escaped.txt: no error")
+	}
+	if _, err := Consolidate(archive); err == nil {
+		t.Error("Consolidate of ../../escaped.txt: no error")
+	}
+	for _, p := range []string{filepath.Join(dir, "a", "escaped.txt"), filepath.Join(dir, "escaped.txt")} {
+		if exists(p) {
+			t.Errorf("%s was written outside the restore folder", p)
+		}
+	}
+}
+
+func TestRestoreSkipsSignature(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, src, ManifestName, `{"version":1,"type":"full","files":["options.txt"]}`)
+	writeTestFile(t, src, SignatureName, `{}`)
+	writeTestFile(t, src, "options.txt", "fov:0.0\n")
+
+	dest := t.TempDir()
+	if n, err := Restore(src, dest); err != nil || n != 1 {
+		t.Fatalf("Restore = %d, %v; want 1 file", n, err)
+	}
+	if exists(filepath.Join(dest, SignatureName)) {
+		t.Error("the signature was restored with the files")
+	}
+}
+
+// totem restore <chain> --to . restores into the current folder
+func TestRestoreIntoCurrentFolder(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, src, ManifestName, `{"version":1,"type":"full","files":["options.txt","saves/World/level.dat"]}`)
+	writeTestFile(t, src, "options.txt", "fov:0.0\n")
+	writeTestFile(t, src, "saves/World/level.dat", "12345")
+
+	dest := t.TempDir()
+	t.Chdir(dest)
+	if n, err := Restore(src, "."); err != nil || n != 2 {
+		t.Fatalf("Restore into . = %d, %v; want 2 files", n, err)
+	}
+	for _, p := range []string{"options.txt", "saves/World/level.dat"} {
+		if !exists(filepath.Join(dest, filepath.FromSlash(p))) {
+			t.Errorf("%s was not restored", p)
+		}
+	}
+}
+
+// A --since cutoff into an empty destination has no parent, so it must be a full backup
+// that restores on its own
+func TestCutoffWithoutParentIsFull(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	config := &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), SavesSince: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	result, err := PerformContext(context.Background(), config, nil)
+	if err != nil {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	m, err := ReadManifest(result.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != TypeFull || m.Parent != "" {
+		t.Errorf("manifest is %s with parent %q, want a full backup", m.Type, m.Parent)
+	}
+	if _, err := Restore(result.OutputPath, t.TempDir()); err != nil {
+		t.Errorf("Restore: %v", err)
+	}
+}
+
+// ReadManifest finds the manifest of each backup format, and a .tar.zst starts with it
+func TestReadManifestFormats(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "config/a.toml", "a = 1\n")
+
+	for _, config := range []*tui.Config{
+		{MinecraftPath: mc, BackupDest: t.TempDir()},
+		{MinecraftPath: mc, BackupDest: t.TempDir(), ZipOutput: true},
+		{MinecraftPath: mc, BackupDest: t.TempDir(), Zstd: true},
+	} {
+		if config.Zstd {
+			if _, err := zstdCommand(); err != nil {
+				continue
+			}
+		}
+		result, err := PerformContext(context.Background(), config, nil)
+		if err != nil {
+			t.Fatalf("PerformContext: %v", err)
+		}
+		m, err := ReadManifest(result.OutputPath)
+		if err != nil || m.Type != TypeFull || !slices.Contains(m.Files, "options.txt") {
+			t.Errorf("ReadManifest(%s) = %+v, %v", filepath.Base(result.OutputPath), m, err)
+		}
+		if config.Zstd {
+			var first string
+			forEachMatchingFile(result.OutputPath, func(string) bool { return true }, func(rel string, _ io.Reader) error {
+				first = rel
+				return errManifestFound
+			})
+			if first != ManifestName {
+				t.Errorf("the .tar.zst starts with %s, want %s", first, ManifestName)
+			}
+		}
+	}
+
+	if _, err := ReadManifest(t.TempDir()); err == nil {
+		t.Error("ReadManifest of a folder without one: no error")
+	}
+}
+
+// A consolidated backup is catalogued with counts for the whole chain, not the last link's
+func TestConsolidateCatalogued(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "saves/World/region/r.0.0.mca", "region")
+	dest := t.TempDir()
+	if _, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, IncludeSaves: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	parent, _, _ := LatestBackup(dest)
+	future := time.Now().Add(time.Hour)
+	writeTestFile(t, mc, "saves/World/level.dat", "level 2")
+	os.Chtimes(filepath.Join(mc, "saves/World/level.dat"), future, future)
+	link, err := PerformContext(context.Background(), &tui.Config{
+		MinecraftPath: mc, BackupDest: dest, IncludeSaves: true, Incremental: true, Parent: parent,
+	}, nil)
+	if err != nil || link.Stats.SavesCopied != 1 {
+		t.Fatalf("incremental backup copied %d saves, %v; want 1", link.Stats.SavesCopied, err)
+	}
+
+	full, err := Consolidate(link.OutputPath)
+	if err != nil {
+		t.Fatalf("Consolidate: %v", err)
+	}
+	if exists(filepath.Join(full, "info.md")) {
+		t.Error("the last link's info.md was kept")
+	}
+	c, _ := LoadCatalog(dest)
+	e := c.Entry(filepath.Base(full))
+	if e == nil {
+		t.Fatal("the consolidated backup is not in the catalog")
+	}
+	if e.Type != TypeFull || e.Parent != "" || e.Stats.SavesCopied != 2 {
+		t.Errorf("catalog entry = %s, parent %q, %d saves; want full with 2 saves", e.Type, e.Parent, e.Stats.SavesCopied)
+	}
+}
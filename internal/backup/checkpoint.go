@@ -0,0 +1,207 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Folders and files a checkpoint captures; worlds are left out to keep it fast
+var checkpointItems = []string{"mods", "config", "options.txt"}
+
+// CheckpointInfo describes a saved checkpoint
+type CheckpointInfo struct {
+	Label    string    `json:"label"`
+	Instance string    `json:"instance"`
+	Created  time.Time `json:"created"`
+	Files    int       `json:"files"`
+}
+
+// Checkpoint snapshots mods/, config/ and options.txt of instance into dir/label
+func Checkpoint(instance, dir, label string) (*CheckpointInfo, error) {
+	if label == "" {
+		return nil, fmt.Errorf("checkpoint label is empty")
+	}
+	if err := LocalOnly("a checkpoint", dir); err != nil {
+		return nil, err
+	}
+	if !exists(instance) {
+		return nil, fmt.Errorf("minecraft path does not exist: %s", instance)
+	}
+	target := filepath.Join(dir, label)
+	if exists(target) {
+		return nil, fmt.Errorf("checkpoint %q already exists", label)
+	}
+
+	// Write into a temporary folder so a failed checkpoint never looks complete
+	tmp := target + ".partial"
+	os.RemoveAll(tmp)
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return nil, err
+	}
+	count, err := copyItems(instance, tmp, checkpointItems)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+
+	info := &CheckpointInfo{Label: label, Instance: instance, Created: time.Now(), Files: count}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "checkpoint.json"), data, 0644); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	return info, nil
+}
+
+// FileChange is one difference a rollback would make, relative to the instance
+type FileChange struct {
+	Path string // Relative to the instance folder
+	Kind string // "restore", "replace" or "remove"
+}
+
+// rollbackItems returns the checkpoint items present in source; only those are rolled back
+func rollbackItems(source string) []string {
+	var items []string
+	for _, item := range checkpointItems {
+		if exists(filepath.Join(source, item)) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RollbackChanges previews what Rollback would do, without touching anything
+func RollbackChanges(instance, source string) ([]FileChange, error) {
+	items := rollbackItems(source)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s has no mods/, config/ or options.txt to roll back to", source)
+	}
+
+	var changes []FileChange
+	for _, item := range items {
+		from, to := listTree(filepath.Join(source, item)), listTree(filepath.Join(instance, item))
+		for rel := range from {
+			path := filepath.Join(item, rel)
+			switch {
+			case !to[rel]:
+				changes = append(changes, FileChange{Path: path, Kind: "restore"})
+			case !sameFile(filepath.Join(source, path), filepath.Join(instance, path)):
+				changes = append(changes, FileChange{Path: path, Kind: "replace"})
+			}
+		}
+		for rel := range to {
+			if !from[rel] {
+				changes = append(changes, FileChange{Path: filepath.Join(item, rel), Kind: "remove"})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Rollback replaces the instance's mods/, config/ and options.txt with the copies in source,
+// a checkpoint or backup folder; saves are never touched
+func Rollback(instance, source string) (int, error) {
+	items := rollbackItems(source)
+	if len(items) == 0 {
+		return 0, fmt.Errorf("%s has no mods/, config/ or options.txt to roll back to", source)
+	}
+	for _, item := range items {
+		if err := os.RemoveAll(filepath.Join(instance, item)); err != nil {
+			return 0, err
+		}
+	}
+	return copyItems(source, instance, items)
+}
+
+// listTree returns the relative paths of all files under root (root itself if it is a file)
+func listTree(root string) map[string]bool {
+	files := map[string]bool{}
+	info, err := os.Stat(root)
+	if err != nil {
+		return files
+	}
+	if !info.IsDir() {
+		files["."] = true
+		return files
+	}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(root, path); err == nil {
+			files[rel] = true
+		}
+		return nil
+	})
+	return files
+}
+
+// ListCheckpoints returns the checkpoints in dir, newest first
+func ListCheckpoints(dir string) ([]CheckpointInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []CheckpointInfo
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), "checkpoint.json"))
+		if err != nil {
+			continue
+		}
+		var info CheckpointInfo
+		if json.Unmarshal(data, &info) == nil {
+			list = append(list, info)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.After(list[j].Created) })
+	return list, nil
+}
+
+// copyItems copies the named files and folders from src to dst, skipping missing ones
+func copyItems(src, dst string, items []string) (int, error) {
+	opts := newCopyOptions(&tui.Config{}, nil)
+	count := 0
+	for _, item := range items {
+		from := filepath.Join(src, item)
+		info, err := os.Stat(from)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		if !info.IsDir() {
+			if err := copyFile(from, filepath.Join(dst, item)); err != nil {
+				return count, err
+			}
+			count++
+			continue
+		}
+		n, err := copyDir(from, filepath.Join(dst, item), opts)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
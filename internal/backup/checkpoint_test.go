@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A checkpoint keeps mods, config and options but no worlds, and rolling back to it undoes a
+// modpack update while leaving saves alone
+func TestCheckpointRollback(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "mods/sodium-0.5.jar", "old")
+	writeTestFile(t, mc, "config/sodium.json", `{"v":1}`)
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	dir := t.TempDir()
+
+	info, err := Checkpoint(mc, dir, "before-update")
+	if err != nil || info.Files != 3 {
+		t.Fatalf("Checkpoint = %+v, %v; want 3 files", info, err)
+	}
+	if exists(filepath.Join(dir, "before-update", "saves")) || exists(filepath.Join(dir, "before-update.partial")) {
+		t.Error("the checkpoint holds saves or left its partial folder")
+	}
+	if _, err := Checkpoint(mc, dir, "before-update"); err == nil {
+		t.Error("a second checkpoint with the same label: no error")
+	}
+	if list, err := ListCheckpoints(dir); err != nil || len(list) != 1 || list[0].Label != "before-update" {
+		t.Errorf("ListCheckpoints = %+v, %v", list, err)
+	}
+
+	// The update swaps a mod and changes a config; the world is played on
+	os.Remove(filepath.Join(mc, "mods", "sodium-0.5.jar"))
+	writeTestFile(t, mc, "mods/sodium-0.6.jar", "new")
+	writeTestFile(t, mc, "config/sodium.json", `{"v":2}`)
+	writeTestFile(t, mc, "saves/World/level.dat", "played on")
+
+	if _, err := Rollback(mc, filepath.Join(dir, "before-update")); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"mods/sodium-0.5.jar":   "old",
+		"config/sodium.json":    `{"v":1}`,
+		"saves/World/level.dat": "played on",
+	} {
+		if data, _ := os.ReadFile(filepath.Join(mc, path)); string(data) != want {
+			t.Errorf("%s = %q after the rollback, want %q", path, data, want)
+		}
+	}
+	if exists(filepath.Join(mc, "mods", "sodium-0.6.jar")) {
+		t.Error("the updated mod was left in mods/")
+	}
+}
+
+// The preview lists what a rollback to a backup folder would restore, replace and remove,
+// and changes nothing
+func TestRollbackChanges(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "mods/same.jar", "same")
+	writeTestFile(t, mc, "mods/added.jar", "added")
+	writeTestFile(t, mc, "options.txt", "fov:1.0\n")
+	backupDir := t.TempDir()
+	writeTestFile(t, backupDir, "mods/same.jar", "same")
+	writeTestFile(t, backupDir, "mods/removed.jar", "removed")
+	writeTestFile(t, backupDir, "options.txt", "fov:0.0\n")
+	writeTestFile(t, backupDir, "info.md", "# Totem Backup")
+
+	changes, err := RollbackChanges(mc, backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileChange{
+		{filepath.Join("mods", "added.jar"), "remove"},
+		{filepath.Join("mods", "removed.jar"), "restore"},
+		{"options.txt", "replace"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("RollbackChanges = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+	if !exists(filepath.Join(mc, "mods", "added.jar")) {
+		t.Error("the preview removed a file")
+	}
+
+	if _, err := RollbackChanges(mc, t.TempDir()); err == nil {
+		t.Error("previewing a rollback to an empty folder: no error")
+	}
+}
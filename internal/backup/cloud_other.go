@@ -0,0 +1,11 @@
+//go:build !windows
+
+package backup
+
+import "io/fs"
+
+// isCloudOnly reports whether a file is an online-only placeholder.
+// Only OneDrive on Windows leaves placeholders in the game folder.
+func isCloudOnly(info fs.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,31 @@
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Folders under any of OneDrive's roots count as synced, a sibling with a longer name does not,
+// and the size estimate says so
+func TestInOneDrive(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("OneDrive", "")
+	t.Setenv("OneDriveConsumer", "")
+	t.Setenv("OneDriveCommercial", filepath.Join(root, "OneDrive - Work"))
+
+	if !inOneDrive(filepath.Join(root, "OneDrive - Work", "Documents", ".minecraft")) {
+		t.Error("a folder inside OneDriveCommercial is not reported")
+	}
+	if inOneDrive(filepath.Join(root, "OneDrive - Work2", ".minecraft")) || inOneDrive(root) {
+		t.Error("a folder outside OneDrive is reported")
+	}
+
+	mc := filepath.Join(root, "OneDrive - Work", ".minecraft")
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	est, err := EstimateSize(&tui.Config{MinecraftPath: mc, BackupDest: t.TempDir()})
+	if err != nil || !est.OneDrive {
+		t.Errorf("EstimateSize = %+v, %v; want OneDrive set", est, err)
+	}
+}
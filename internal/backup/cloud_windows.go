@@ -0,0 +1,19 @@
+package backup
+
+import (
+	"io/fs"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// Attributes OneDrive (and other cloud filter drivers) set on files that are not on disk
+const cloudOnlyAttrs = windows.FILE_ATTRIBUTE_RECALL_ON_DATA_ACCESS |
+	windows.FILE_ATTRIBUTE_RECALL_ON_OPEN |
+	windows.FILE_ATTRIBUTE_OFFLINE
+
+// isCloudOnly reports whether a file is an online-only placeholder that reading would download
+func isCloudOnly(info fs.FileInfo) bool {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	return ok && attrs.FileAttributes&cloudOnlyAttrs != 0
+}
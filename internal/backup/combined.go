@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/version"
+)
+
+// Prefix of the folder (and zip) holding a combined multi-instance backup
+const combinedPrefix = "combined_"
+
+// NewCombinedPath returns a fresh folder path in dest for a combined backup started at t
+func NewCombinedPath(dest string, t time.Time) string {
+	base := filepath.Join(dest, combinedPrefix+t.Format(backupTimeLayout))
+	path := base
+	for i := 2; archiveExists(path); i++ {
+		path = fmt.Sprintf("%s_%d", base, i)
+	}
+	return path
+}
+
+// CombinedEntry is one instance's part of a combined backup
+type CombinedEntry struct {
+	Name     string
+	Launcher string
+	Source   string
+	Folder   string // Subtree inside the combined folder, empty if the backup failed
+	Files    int
+	Err      string // Empty on success
+}
+
+// AdoptCombined moves a finished instance backup from its staging folder to root/name,
+// so the combined folder has one plain subtree per instance
+func AdoptCombined(root, output, name string) (string, error) {
+	target := filepath.Join(root, name)
+	for i := 2; exists(target); i++ {
+		target = fmt.Sprintf("%s_%d", filepath.Join(root, name), i)
+	}
+	if err := os.Rename(output, target); err != nil {
+		return "", err
+	}
+	// The staging folder only held this backup and the catalog listing it; its journal is
+	// gone once it finished, and combined.md stands in for the catalog
+	os.RemoveAll(filepath.Dir(output))
+	return target, nil
+}
+
+// FinishCombined writes combined.md into root and, when zipping, replaces root with one archive.
+// It returns the path of the finished folder or zip.
+func FinishCombined(root string, entries []CombinedEntry, elapsed time.Duration, zipOutput bool) (string, error) {
+	var b strings.Builder
+	b.WriteString("# 🗿 Totem Combined Backup\n\n")
+	b.WriteString(fmt.Sprintf("> Generated on %s in %s by Totem v%s\n\n", time.Now().Format("2006-01-02 15:04:05"), elapsed.Round(time.Millisecond), version.Version))
+
+	failed, files := 0, 0
+	for _, e := range entries {
+		if e.Err != "" {
+			failed++
+		}
+		files += e.Files
+	}
+	b.WriteString(fmt.Sprintf("**%d instances**, %d ok, %d failed, %d files\n\n", len(entries), len(entries)-failed, failed, files))
+
+	b.WriteString("| Instance | Launcher | Source | Status | Files | Folder |\n|----------|----------|--------|--------|-------|--------|\n")
+	for _, e := range entries {
+		status, folder := "✅ ok", "`"+e.Folder+"/`"
+		if e.Err != "" {
+			status, folder = "❌ failed", e.Err
+		}
+		b.WriteString(fmt.Sprintf("| %s | %s | `%s` | %s | %d | %s |\n", e.Name, e.Launcher, e.Source, status, e.Files, folder))
+	}
+	b.WriteString("\nEach folder is a complete backup with its own `info.md` and `manifest.json`.\n")
+
+	if err := os.WriteFile(filepath.Join(root, "combined.md"), []byte(b.String()), 0644); err != nil {
+		return "", err
+	}
+	if !zipOutput {
+		return root, nil
+	}
+
+	zipPath := root + ".zip"
+	if err := createZip(root, zipPath, nil, "", nil); err != nil {
+		os.Remove(zipPath)
+		return "", fmt.Errorf("zip: %w", err)
+	}
+	os.RemoveAll(root)
+	return zipPath, nil
+}
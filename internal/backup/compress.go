@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Extensions whose contents are already compressed
+var incompressibleExts = map[string]bool{
+	".mca": true, ".mcr": true, ".png": true, ".jpg": true, ".jpeg": true,
+	".jar": true, ".zip": true, ".gz": true, ".ogg": true, ".mp3": true,
+	".mp4": true, ".webp": true, ".xz": true, ".zst": true, ".7z": true,
+}
+
+const (
+	compressionSampleSize = 64 << 10 // Bytes read to judge a file
+	highEntropyThreshold  = 7.5      // Bits per byte above which deflate rarely helps
+)
+
+// compressionPlan records which files are stored without compression
+type compressionPlan struct {
+	store map[string]bool // Slash-separated relative paths
+
+	StoredFiles int
+	TimeSaved   time.Duration // Estimated deflate time avoided
+	SizeDelta   int64         // Estimated extra bytes from not compressing
+}
+
+// planCompression samples every file under srcDir and decides whether to store it as-is
+func planCompression(srcDir string) *compressionPlan {
+	plan := &compressionPlan{store: map[string]bool{}}
+	sample := make([]byte, compressionSampleSize)
+
+	filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 {
+			return nil
+		}
+
+		store, compressed, elapsed := sampleFile(path, info.Size(), sample)
+		if !store {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(srcDir, path)
+		plan.store[filepath.ToSlash(rel)] = true
+		plan.StoredFiles++
+		plan.TimeSaved += elapsed
+		plan.SizeDelta += info.Size() - compressed
+		return nil
+	})
+	return plan
+}
+
+// sampleFile judges a file from its first bytes: whether to store it as-is, and the
+// deflated size and deflate time extrapolated to the whole file
+func sampleFile(path string, size int64, sample []byte) (store bool, compressed int64, elapsed time.Duration) {
+	ext := strings.ToLower(filepath.Ext(path))
+	f, err := os.Open(path)
+	if err != nil {
+		return incompressibleExts[ext], size, 0
+	}
+	n, _ := io.ReadFull(f, sample)
+	f.Close()
+	if n == 0 {
+		return false, 0, 0
+	}
+
+	store = incompressibleExts[ext] || entropy(sample[:n]) >= highEntropyThreshold
+	took, deflated := deflateSample(sample[:n])
+	scale := float64(size) / float64(n)
+	return store, int64(float64(deflated) * scale), time.Duration(float64(took) * scale)
+}
+
+// EstimateSize predicts the raw and zipped size of a backup from a sampling pass
+func EstimateSize(config *tui.Config) (tui.SizeEstimate, error) {
+	config = withRules(config)
+	exclude := excludePaths(config)
+	paths := buildPaths(config.MinecraftPath)
+	if !exists(paths.Root) {
+		return tui.SizeEstimate{}, fmt.Errorf("minecraft path does not exist: %s", paths.Root)
+	}
+	if err := checkSource(paths.Root); err != nil {
+		return tui.SizeEstimate{}, err
+	}
+	if err := checkDestination(config); err != nil {
+		return tui.SizeEstimate{}, err
+	}
+
+	var est tui.SizeEstimate
+	sample := make([]byte, compressionSampleSize)
+	for _, root := range copyRoots(config, paths) {
+		walkDirFollow(root, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && underAny(exclude, path) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			// Online-only files would be downloaded just to be measured
+			if isCloudOnly(info) {
+				est.CloudOnly++
+				return nil
+			}
+			est.Raw += info.Size()
+
+			// Region files and images are stored as-is, no need to read them
+			if incompressibleExts[strings.ToLower(filepath.Ext(path))] {
+				est.Compressed += info.Size()
+				return nil
+			}
+			store, compressed, _ := sampleFile(path, info.Size(), sample)
+			if store {
+				compressed = info.Size()
+			}
+			est.Compressed += compressed
+			return nil
+		})
+	}
+	est.Unreadable = Preflight(config)
+	est.OneDrive = inOneDrive(paths.Root)
+	if policy := RetentionFor(config.BackupDest); policy.Enabled() {
+		est.Retention = policy.String()
+	}
+	return est, nil
+}
+
+// method returns the zip method for a relative path
+func (p *compressionPlan) method(rel string) uint16 {
+	if p != nil && p.store[rel] {
+		return 0 // zip.Store
+	}
+	return 8 // zip.Deflate
+}
+
+// entropy returns the Shannon entropy of data in bits per byte
+func entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	var h float64
+	total := float64(len(data))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / total
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// deflateSample times compressing data and returns the compressed size
+func deflateSample(data []byte) (time.Duration, int) {
+	var buf bytes.Buffer
+	start := time.Now()
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	w.Write(data)
+	w.Close()
+	return time.Since(start), buf.Len()
+}
@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Region files, images and random-looking data are stored as-is, while text is deflated,
+// both in the plan and in the zip written from it
+func TestPlanCompression(t *testing.T) {
+	src := t.TempDir()
+	noise := make([]byte, 32<<10)
+	rand.Read(noise)
+	writeTestFile(t, src, "saves/World/region/r.0.0.mca", "looks compressible but is a region file")
+	writeTestFile(t, src, "screenshots/shot.PNG", "png")
+	writeTestFile(t, src, "config/blob.dat", string(noise))
+	writeTestFile(t, src, "options.txt", strings.Repeat("fov:0.0\n", 1000))
+	writeTestFile(t, src, "empty.txt", "")
+
+	plan := planCompression(src)
+	stored := map[string]bool{"saves/World/region/r.0.0.mca": true, "screenshots/shot.PNG": true, "config/blob.dat": true}
+	for _, rel := range []string{"saves/World/region/r.0.0.mca", "screenshots/shot.PNG", "config/blob.dat", "options.txt", "empty.txt"} {
+		want := zip.Deflate
+		if stored[rel] {
+			want = zip.Store
+		}
+		if got := plan.method(rel); got != want {
+			t.Errorf("method(%s) = %d, want %d", rel, got, want)
+		}
+	}
+	if plan.StoredFiles != 3 {
+		t.Errorf("StoredFiles = %d, want 3", plan.StoredFiles)
+	}
+
+	dest := filepath.Join(t.TempDir(), "backup.zip")
+	if err := createZip(src, dest, plan, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if want := plan.method(f.Name); !f.FileInfo().IsDir() && f.Method != want {
+			t.Errorf("%s is written with method %d, want %d", f.Name, f.Method, want)
+		}
+	}
+}
+
+// The estimate counts every file it will copy as raw, and as compressed counts region files
+// whole and text at roughly its deflated size
+func TestEstimateSize(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", strings.Repeat("fov:0.0\n", 1000))
+	writeTestFile(t, mc, "saves/World/region/r.0.0.mca", strings.Repeat("r", 5000))
+
+	est, err := EstimateSize(&tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), IncludeSaves: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Raw != 13000 {
+		t.Errorf("Raw = %d, want 13000", est.Raw)
+	}
+	if est.Compressed < 5000 || est.Compressed > 6000 {
+		t.Errorf("Compressed = %d, want the region file plus a little deflated text", est.Compressed)
+	}
+
+	if _, err := EstimateSize(&tui.Config{MinecraftPath: filepath.Join(mc, "missing"), BackupDest: t.TempDir()}); err == nil {
+		t.Error("estimating a missing folder: no error")
+	}
+}
@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// copyOptions controls how copyDir treats individual files and tracks what it skipped
+type copyOptions struct {
+	SkipJunk    bool
+	MaxFileSize int64 // 0 means no cap
+	limiter     *rateLimiter
+	progress    *Progress
+	Resume      bool // Keep destination files that already match the source
+	network     bool // Retry I/O errors and write files durably for network shares
+	verify      bool // Re-read each copy and compare hashes
+	space       *spaceMonitor
+	hashes      *hashRecorder // SHA-256 of each copy, taken from the stream while copying
+	exclude     []string      // Absolute paths skipped by exclude rules
+	root        string        // Instance folder, which junk rules see paths relative to
+	ctx         context.Context
+
+	// Incremental backups and snapshots: files of the parent by path in the backup, the
+	// backup folder those paths are relative to, and for snapshots the parent's folder
+	previous   map[string]fileStamp
+	backupRoot string
+	linkFrom   string
+
+	JunkSkipped  int
+	Verified     int        // Copies whose read-back hash matched
+	LargeSkipped []FileInfo // Files over MaxFileSize, by source path
+	Unchanged    int        // Files left to the parent backup as unchanged
+	Linked       int        // Unchanged files hard-linked to the parent snapshot
+}
+
+func newCopyOptions(config *tui.Config, progress *Progress) *copyOptions {
+	return &copyOptions{
+		SkipJunk:    config.SkipJunk,
+		MaxFileSize: config.MaxFileSize,
+		limiter:     newRateLimiter(config.RateLimit),
+		progress:    progress,
+		network:     NetworkMode(config),
+		verify:      config.VerifyCopies,
+		space:       newSpaceMonitor(config.BackupDest),
+		hashes:      newHashRecorder(),
+		exclude:     excludePaths(config),
+		root:        config.MinecraftPath,
+		ctx:         context.Background(),
+	}
+}
+
+// cancelled reports whether the backup was stopped (Ctrl+C); nil options are never cancelled
+func (o *copyOptions) cancelled() bool {
+	return o != nil && o.ctx.Err() != nil
+}
+
+// junk reports whether the entry at path is junk, going by its path in the instance
+func (o *copyOptions) junk(path string, d fs.DirEntry) bool {
+	rel, err := filepath.Rel(o.root, path)
+	if err != nil {
+		rel = path
+	}
+	return isJunk(rel, d)
+}
+
+// excluded reports whether a copy would skip the entry at path as junk or as over the size
+// cap, without recording it. Size scans use it so the report matches what was backed up.
+func (o *copyOptions) excluded(path string, d fs.DirEntry) bool {
+	if o == nil {
+		return false
+	}
+	if o.SkipJunk && o.junk(path, d) {
+		return true
+	}
+	if o.MaxFileSize <= 0 || d.IsDir() {
+		return false
+	}
+	info, err := d.Info()
+	return err == nil && info.Size() > o.MaxFileSize
+}
+
+// scanOnly returns options that keep o's cancellation but exclude nothing,
+// for scanning the backup itself
+func (o *copyOptions) scanOnly() *copyOptions {
+	if o == nil {
+		return nil
+	}
+	return &copyOptions{ctx: o.ctx}
+}
+
+// copyFile copies one file, applying the rate limit, reporting progress and retrying locked files
+// (and, for network destinations, dropped connections)
+func (o *copyOptions) copyFile(src, dst string) error {
+	info, statErr := os.Stat(src)
+	if statErr == nil {
+		if err := o.space.reserve(info.Size()); err != nil {
+			return err
+		}
+	}
+
+	// The hash is taken from the bytes as they are copied, so the source is never read twice
+	var srcHash hash.Hash
+	wrap := func(r io.Reader) io.Reader {
+		srcHash = sha256.New()
+		return io.TeeReader(o.progress.track(o.limiter.throttle(r)), srcHash)
+	}
+
+	var err error
+	if o.network {
+		err = retryNetwork(func() error {
+			return retryLocked(src, func() error { return copyFileDurable(src, dst, wrap) })
+		})
+	} else {
+		err = retryLocked(src, func() error {
+			return copyFileWrapped(src, dst, wrap)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	o.hashes.record(dst, srcHash.Sum(nil))
+	if statErr == nil {
+		o.hashes.recordModTime(dst, info.ModTime())
+	}
+	if !o.verify {
+		return nil
+	}
+
+	dstSum, err := hashFile(dst)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", dst, err)
+	}
+	if !bytes.Equal(srcHash.Sum(nil), dstSum) {
+		return &VerifyError{Path: dst}
+	}
+	o.Verified++
+	return nil
+}
+
+// VerifyError is a copy whose read-back hash does not match the source
+type VerifyError struct {
+	Path string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s does not match its source after copying; the destination may be failing", e.Path)
+}
+
+// tooLarge reports whether a file exceeds the size cap, recording it if so
+func (o *copyOptions) tooLarge(path string, d fs.DirEntry) bool {
+	if o.MaxFileSize <= 0 || d.IsDir() {
+		return false
+	}
+	info, err := d.Info()
+	if err != nil || info.Size() <= o.MaxFileSize {
+		return false
+	}
+	o.LargeSkipped = append(o.LargeSkipped, FileInfo{Name: path, Size: info.Size()})
+	return true
+}
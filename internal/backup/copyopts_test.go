@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// With verify-copies every copied file is read back and counted once its hash matches
+func TestVerifyCopies(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "screenshots/a.png", "a")
+	writeTestFile(t, mc, "screenshots/b.png", "b")
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), VerifyCopies: true}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	if result.Stats.FilesVerified != 3 {
+		t.Errorf("FilesVerified = %d, want 3", result.Stats.FilesVerified)
+	}
+
+	result, err = PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir()}, nil)
+	if err != nil || result.Stats.FilesVerified != 0 {
+		t.Errorf("FilesVerified without verify-copies = %d, %v", result.Stats.FilesVerified, err)
+	}
+
+	var verr error = &VerifyError{Path: "saves/World/level.dat"}
+	var target *VerifyError
+	if !errors.As(verr, &target) || !strings.Contains(verr.Error(), "level.dat does not match its source") {
+		t.Errorf("VerifyError = %v", verr)
+	}
+}
+
+// Size scans leave out what the copy would skip, and stop once the backup is cancelled
+func TestGetDirSize(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "saves/World/level.dat", "12345")
+	writeTestFile(t, mc, "saves/World/session.lock", "lock")
+	writeTestFile(t, mc, "saves/World/huge.bin", strings.Repeat("x", 100))
+	saves := filepath.Join(mc, "saves")
+
+	if got := getDirSize(saves, nil); got != 109 {
+		t.Errorf("getDirSize without options = %d, want 109", got)
+	}
+	opts := newCopyOptions(&tui.Config{MinecraftPath: mc, SkipJunk: true, MaxFileSize: 50}, nil)
+	if got := getDirSize(saves, opts); got != 5 {
+		t.Errorf("getDirSize skipping junk and huge files = %d, want 5", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts.ctx = ctx
+	if got := getDirSize(saves, opts); got != 0 {
+		t.Errorf("getDirSize after cancelling = %d, want 0", got)
+	}
+}
@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CrashReportsDir is where the game writes crash reports, relative to the instance
+const CrashReportsDir = "crash-reports"
+
+// CrashReports returns the crash report files in instance, oldest first
+func CrashReports(instance string) []string {
+	dir := filepath.Join(instance, CrashReportsDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	type report struct {
+		path string
+		mod  time.Time
+	}
+	var reports []report
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".txt") {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			reports = append(reports, report{filepath.Join(dir, e.Name()), info.ModTime()})
+		}
+	}
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].mod.Before(reports[j].mod) })
+	paths := make([]string, len(reports))
+	for i, r := range reports {
+		paths[i] = r.path
+	}
+	return paths
+}
+
+// CrashSnapshot preserves the state behind a crash: the report itself, config/, options.txt
+// and the most recently played world. It returns the snapshot folder and how many files it holds.
+func CrashSnapshot(instance, dest, report string) (string, int, error) {
+	if !exists(instance) {
+		return "", 0, fmt.Errorf("minecraft path does not exist: %s", instance)
+	}
+	if err := LocalOnly("a crash snapshot", dest); err != nil {
+		return "", 0, err
+	}
+	base := filepath.Join(dest, "crash_"+time.Now().Format(backupTimeLayout))
+	out := base
+	for i := 2; exists(out); i++ {
+		out = fmt.Sprintf("%s_%d", base, i)
+	}
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return "", 0, err
+	}
+
+	items := []string{"config", "options.txt"}
+	saves := filepath.Join(instance, "saves")
+	if worlds, err := listWorlds(saves); err == nil && len(worlds) > 0 {
+		items = append(items, filepath.Join("saves", worlds[0].Name))
+	}
+	count, err := copyItems(instance, out, items)
+	if err != nil {
+		return out, count, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(out, CrashReportsDir), 0755); err != nil {
+		return out, count, err
+	}
+	if err := copyFile(report, filepath.Join(out, CrashReportsDir, filepath.Base(report))); err != nil {
+		return out, count, err
+	}
+	return out, count + 1, nil
+}
+
+// CrashInfo is the header of a crash report: what crashed and which mods were loaded
+type CrashInfo struct {
+	File             string
+	Time             string
+	Description      string
+	Exception        string // First line of the stack trace
+	MinecraftVersion string
+	Mods             []string // As listed under "Fabric Mods" or "Mod List"
+	Suspected        []string // Mods the loader blamed, when it named any
+}
+
+// parseCrashReport reads the parts of a crash report worth showing in info.md
+func parseCrashReport(path string) (*CrashInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info := &CrashInfo{File: filepath.Base(path)}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	var list *[]string // The indented list being read, if any
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if list != nil {
+			if strings.HasPrefix(line, "\t\t") && trimmed != "" {
+				*list = append(*list, trimmed)
+				continue
+			}
+			list = nil
+		}
+
+		switch {
+		case strings.HasPrefix(line, "Time: "):
+			info.Time = strings.TrimPrefix(line, "Time: ")
+		case strings.HasPrefix(line, "Description: "):
+			info.Description = strings.TrimPrefix(line, "Description: ")
+			// The exception follows the description after a blank line
+			for _, next := range lines[i+1:] {
+				if next = strings.TrimSpace(next); next != "" {
+					info.Exception = next
+					break
+				}
+			}
+		case strings.HasPrefix(trimmed, "Minecraft Version: ") && info.MinecraftVersion == "":
+			info.MinecraftVersion = strings.TrimPrefix(trimmed, "Minecraft Version: ")
+		case strings.HasPrefix(trimmed, "Fabric Mods:"), strings.HasPrefix(trimmed, "Mod List:"):
+			list = &info.Mods
+		case strings.HasPrefix(trimmed, "Suspected Mod"):
+			// "Suspected Mods: None" or "Suspected Mod:" followed by one indented line per mod
+			if _, value, _ := strings.Cut(trimmed, ":"); strings.TrimSpace(value) != "" && strings.TrimSpace(value) != "None" {
+				info.Suspected = append(info.Suspected, strings.TrimSpace(value))
+			}
+			for _, next := range lines[i+1:] {
+				if !strings.HasPrefix(next, "\t\t") || strings.TrimSpace(next) == "" {
+					break
+				}
+				if next = strings.TrimSpace(next); !strings.HasPrefix(next, "at ") {
+					info.Suspected = append(info.Suspected, next)
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// Crash reports summarized in info.md, newest first
+const recentCrashLimit = 3
+
+// renderCrashSection summarizes the newest crash reports for info.md
+func renderCrashSection(instance string) string {
+	reports := CrashReports(instance)
+	if len(reports) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\n## 💥 Recent Crashes\n\n%d crash reports in `crash-reports/`", len(reports)))
+	if len(reports) > recentCrashLimit {
+		b.WriteString(fmt.Sprintf(", newest %d below", recentCrashLimit))
+	}
+	b.WriteString(".\n")
+
+	for i := len(reports) - 1; i >= 0 && i >= len(reports)-recentCrashLimit; i-- {
+		info, err := parseCrashReport(reports[i])
+		if err != nil || (info.Description == "" && info.Exception == "") {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("\n### %s\n\n", info.File))
+		if info.Time != "" {
+			b.WriteString(fmt.Sprintf("- **Time:** %s\n", info.Time))
+		}
+		if info.Description != "" {
+			b.WriteString(fmt.Sprintf("- **Description:** %s\n", info.Description))
+		}
+		if info.Exception != "" {
+			b.WriteString(fmt.Sprintf("- **Exception:** `%s`\n", info.Exception))
+		}
+		if info.MinecraftVersion != "" {
+			b.WriteString(fmt.Sprintf("- **Minecraft:** %s\n", info.MinecraftVersion))
+		}
+		if len(info.Suspected) > 0 {
+			b.WriteString(fmt.Sprintf("- **Suspected mods:** %s\n", strings.Join(info.Suspected, "; ")))
+		}
+		if len(info.Mods) > 0 {
+			b.WriteString(fmt.Sprintf("- **Mods loaded:** %d (full list in the report)\n", len(info.Mods)))
+		}
+	}
+	return b.String()
+}
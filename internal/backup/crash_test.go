@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A crash snapshot keeps the report, config, options and only the last played world
+func TestCrashSnapshot(t *testing.T) {
+	mc := t.TempDir()
+	now := time.Now()
+	writeTestFile(t, mc, "config/sodium.json", "{}")
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeLevelDat(t, mc, "saves/Crashed/level.dat", map[string]any{"Data": map[string]any{"LastPlayed": now.UnixMilli()}})
+	writeLevelDat(t, mc, "saves/Other/level.dat", map[string]any{"Data": map[string]any{"LastPlayed": now.AddDate(0, 0, -3).UnixMilli()}})
+	writeTestFile(t, mc, "crash-reports/old.txt", "old")
+	writeTestFile(t, mc, "crash-reports/crash-2026-10-16_12.00.00-client.txt", "---- Minecraft Crash Report ----")
+	writeTestFile(t, mc, "crash-reports/notes.md", "")
+	old := now.Add(-time.Hour)
+	os.Chtimes(filepath.Join(mc, "crash-reports", "old.txt"), old, old)
+
+	reports := CrashReports(mc)
+	if len(reports) != 2 || filepath.Base(reports[1]) != "crash-2026-10-16_12.00.00-client.txt" {
+		t.Fatalf("CrashReports = %v, want old.txt then the new report", reports)
+	}
+
+	out, n, err := CrashSnapshot(mc, t.TempDir(), reports[1])
+	if err != nil || n != 4 {
+		t.Fatalf("CrashSnapshot = %s, %d, %v; want 4 files", out, n, err)
+	}
+	for _, p := range []string{"config/sodium.json", "options.txt", "saves/Crashed/level.dat", "crash-reports/crash-2026-10-16_12.00.00-client.txt"} {
+		if !exists(filepath.Join(out, filepath.FromSlash(p))) {
+			t.Errorf("%s is not in the snapshot", p)
+		}
+	}
+	if exists(filepath.Join(out, "saves", "Other")) || exists(filepath.Join(out, "crash-reports", "old.txt")) {
+		t.Error("the snapshot holds another world or an older report")
+	}
+	if !strings.HasPrefix(filepath.Base(out), "crash_") {
+		t.Errorf("snapshot folder %s", out)
+	}
+}
+
+const sampleCrashReport = "---- Minecraft Crash Report ----\r\n" +
+	"// Uh... Did I do that?\r\n\r\n" +
+	"Time: 2026-10-16 12:00:00\r\n" +
+	"Description: Rendering entity in world\r\n\r\n" +
+	"java.lang.NullPointerException: Cannot invoke \"Entity.getPos()\"\r\n" +
+	"\tat net.minecraft.client.render.EntityRenderer.render(EntityRenderer.java:42)\r\n\r\n" +
+	"-- System Details --\r\n" +
+	"\tMinecraft Version: 1.21\r\n" +
+	"\tFabric Mods: \r\n" +
+	"\t\tfabric-api: Fabric API 0.100.0\r\n" +
+	"\t\tsodium: Sodium 0.5.8\r\n" +
+	"\tSuspected Mod: \r\n" +
+	"\t\tSodium (sodium), Version: 0.5.8\r\n" +
+	"\t\t\tat net.caffeinemc.sodium.Renderer.draw(Renderer.java:7)\r\n"
+
+func TestParseCrashReport(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "crash-reports/crash.txt", sampleCrashReport)
+	info, err := parseCrashReport(filepath.Join(mc, "crash-reports", "crash.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Time != "2026-10-16 12:00:00" || info.Description != "Rendering entity in world" || info.MinecraftVersion != "1.21" {
+		t.Errorf("parsed %+v", info)
+	}
+	if !strings.HasPrefix(info.Exception, "java.lang.NullPointerException") {
+		t.Errorf("Exception = %q, want the line after the description", info.Exception)
+	}
+	if len(info.Mods) != 2 || info.Mods[1] != "sodium: Sodium 0.5.8" {
+		t.Errorf("Mods = %q", info.Mods)
+	}
+	if len(info.Suspected) != 1 || info.Suspected[0] != "Sodium (sodium), Version: 0.5.8" {
+		t.Errorf("Suspected = %q, want Sodium without its stack frame", info.Suspected)
+	}
+}
+
+// With crash reports included, the backup copies them and info.md summarizes the newest
+func TestCrashReportsInBackup(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	for i := range recentCrashLimit + 1 {
+		name := fmt.Sprintf("crash-reports/crash-%d.txt", i)
+		writeTestFile(t, mc, name, strings.Replace(sampleCrashReport, "Rendering entity", fmt.Sprintf("Crash %d", i), 1))
+		at := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(filepath.Join(mc, filepath.FromSlash(name)), at, at)
+	}
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), IncludeCrashes: true}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	if result.Stats.CrashReportsCopied != recentCrashLimit+1 {
+		t.Errorf("copied %d crash reports, want %d", result.Stats.CrashReportsCopied, recentCrashLimit+1)
+	}
+	data, _ := os.ReadFile(filepath.Join(result.OutputPath, "info.md"))
+	info := string(data)
+	if !strings.Contains(info, "## 💥 Recent Crashes") || !strings.Contains(info, "4 crash reports in `crash-reports/`, newest 3 below") {
+		t.Fatalf("info.md has no crash summary:\n%s", info)
+	}
+	if strings.Contains(info, "Crash 0 in world") || strings.Index(info, "### crash-3.txt") > strings.Index(info, "### crash-1.txt") {
+		t.Error("info.md does not list the newest 3 reports, newest first")
+	}
+	if !strings.Contains(info, "- **Suspected mods:** Sodium (sodium), Version: 0.5.8") || !strings.Contains(info, "- **Mods loaded:** 2") {
+		t.Error("info.md leaves out the suspected or loaded mods")
+	}
+
+	if renderCrashSection(t.TempDir()) != "" {
+		t.Error("an instance without crash reports got a crash section")
+	}
+}
@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WorldDatapacks holds the datapacks found in a single world
+type WorldDatapacks struct {
+	World    string
+	Files    []string // Entries in saves/<world>/datapacks/
+	Enabled  []string // Enabled packs from level.dat
+	Disabled []string // Disabled packs from level.dat
+}
+
+// listWorldDatapacks enumerates datapacks for every world in the saves folder
+func listWorldDatapacks(savesDir string) ([]WorldDatapacks, error) {
+	entries, err := os.ReadDir(savesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var worlds []WorldDatapacks
+	for _, e := range entries {
+		if !isDirLink(savesDir, e) {
+			continue
+		}
+		worldDir := filepath.Join(savesDir, e.Name())
+		wd := WorldDatapacks{World: e.Name()}
+
+		if packs, err := os.ReadDir(filepath.Join(worldDir, "datapacks")); err == nil {
+			for _, p := range packs {
+				wd.Files = append(wd.Files, p.Name())
+			}
+		}
+
+		if level, err := readLevelDat(filepath.Join(worldDir, "level.dat")); err == nil {
+			if dp := nbtCompound(level, "Data", "DataPacks"); dp != nil {
+				wd.Enabled = nbtStrings(dp["Enabled"])
+				wd.Disabled = nbtStrings(dp["Disabled"])
+			}
+		}
+
+		if len(wd.Files) > 0 || len(wd.Enabled) > 0 {
+			worlds = append(worlds, wd)
+		}
+	}
+	return worlds, nil
+}
+
+// countDatapacks returns the total number of datapack files across worlds
+func countDatapacks(worlds []WorldDatapacks) int {
+	total := 0
+	for _, w := range worlds {
+		total += len(w.Files)
+	}
+	return total
+}
+
+// writeDatapacksList writes datapacks.txt grouped by world
+func writeDatapacksList(backupPath string, worlds []WorldDatapacks) error {
+	var b strings.Builder
+	for i, w := range worlds {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf("[%s]\n", w.World))
+		for _, f := range w.Files {
+			b.WriteString(f + "\n")
+		}
+		if len(w.Enabled) > 0 {
+			b.WriteString("enabled: " + strings.Join(w.Enabled, ", ") + "\n")
+		}
+	}
+	return os.WriteFile(filepath.Join(backupPath, "datapacks.txt"), []byte(b.String()), 0644)
+}
+
+// copyWorldConfigs copies level.dat and datapacks/ for each world, skipping region data
+func copyWorldConfigs(savesDir, dst string, opts *copyOptions) (int, error) {
+	entries, err := os.ReadDir(savesDir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, e := range entries {
+		if !isDirLink(savesDir, e) {
+			continue
+		}
+		worldSrc := filepath.Join(savesDir, e.Name())
+		worldDst := filepath.Join(dst, e.Name())
+
+		levelDat := filepath.Join(worldSrc, "level.dat")
+		if !exists(levelDat) {
+			continue
+		}
+		if err := os.MkdirAll(worldDst, 0755); err != nil {
+			return count, err
+		}
+		if err := opts.copyFile(levelDat, filepath.Join(worldDst, "level.dat")); err != nil {
+			return count, err
+		}
+		count++
+
+		datapacks := filepath.Join(worldSrc, "datapacks")
+		if exists(datapacks) {
+			n, err := copyDir(datapacks, filepath.Join(worldDst, "datapacks"), opts)
+			count += n
+			if err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
+
+// renderDatapacksSection renders the datapack section of info.md
+func renderDatapacksSection(worlds []WorldDatapacks) string {
+	if len(worlds) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n## 🧩 Datapacks\n\n")
+	for _, w := range worlds {
+		b.WriteString(fmt.Sprintf("- **%s:** %d datapack files\n", w.World, len(w.Files)))
+		for _, f := range w.Files {
+			b.WriteString(fmt.Sprintf("  - %s\n", f))
+		}
+		if len(w.Enabled) > 0 {
+			b.WriteString(fmt.Sprintf("  - *Enabled:* %s\n", strings.Join(w.Enabled, ", ")))
+		}
+		if len(w.Disabled) > 0 {
+			b.WriteString(fmt.Sprintf("  - *Disabled:* %s\n", strings.Join(w.Disabled, ", ")))
+		}
+	}
+	return b.String()
+}
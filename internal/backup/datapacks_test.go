@@ -0,0 +1,75 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Datapacks are listed per world from the datapacks folder and level.dat, and worlds with
+// neither are left out
+func TestListWorldDatapacks(t *testing.T) {
+	saves := t.TempDir()
+	writeTestFile(t, saves, "Survival/datapacks/terralith.zip", "x")
+	writeTestFile(t, saves, "Survival/datapacks/custom/pack.mcmeta", "{}")
+	writeLevelDat(t, saves, "Survival/level.dat", map[string]any{"Data": map[string]any{
+		"DataPacks": map[string]any{
+			"Enabled":  []string{"vanilla", "file/terralith.zip"},
+			"Disabled": []string{"file/custom"},
+		},
+	}})
+	writeLevelDat(t, saves, "Creative/level.dat", map[string]any{"Data": map[string]any{}})
+
+	worlds, err := listWorldDatapacks(saves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worlds) != 1 || worlds[0].World != "Survival" {
+		t.Fatalf("listWorldDatapacks = %+v, want only Survival", worlds)
+	}
+	w := worlds[0]
+	if strings.Join(w.Files, ",") != "custom,terralith.zip" || strings.Join(w.Enabled, ",") != "vanilla,file/terralith.zip" ||
+		strings.Join(w.Disabled, ",") != "file/custom" {
+		t.Errorf("Survival = %+v", w)
+	}
+	if countDatapacks(worlds) != 2 {
+		t.Errorf("countDatapacks = %d, want 2", countDatapacks(worlds))
+	}
+
+	dir := t.TempDir()
+	if err := writeDatapacksList(dir, worlds); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := os.ReadFile(filepath.Join(dir, "datapacks.txt"))
+	if want := "[Survival]\ncustom\nterralith.zip\nenabled: vanilla, file/terralith.zip\n"; string(list) != want {
+		t.Errorf("datapacks.txt = %q, want %q", list, want)
+	}
+}
+
+// The world config option keeps level.dat and datapacks but none of the region data
+func TestCopyWorldConfigs(t *testing.T) {
+	saves := t.TempDir()
+	writeTestFile(t, saves, "World/level.dat", "level")
+	writeTestFile(t, saves, "World/datapacks/pack.zip", "pack")
+	writeTestFile(t, saves, "World/region/r.0.0.mca", "region")
+	writeTestFile(t, saves, "NoLevel/region/r.0.0.mca", "region")
+
+	dst := t.TempDir()
+	n, err := copyWorldConfigs(saves, dst, newCopyOptions(&tui.Config{}, nil))
+	if err != nil || n != 2 {
+		t.Fatalf("copyWorldConfigs = %d, %v; want 2 files", n, err)
+	}
+	for _, p := range []string{"World/level.dat", "World/datapacks/pack.zip"} {
+		if !exists(filepath.Join(dst, p)) {
+			t.Errorf("%s was not copied", p)
+		}
+	}
+	for _, p := range []string{"World/region", "NoLevel"} {
+		if exists(filepath.Join(dst, p)) {
+			t.Errorf("%s was copied", p)
+		}
+	}
+}
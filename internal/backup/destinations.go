@@ -0,0 +1,256 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Destination is a backup folder with settings of its own. An encrypting destination,
+// such as a cloud-synced folder, gets its backups zipped and encrypted to a recipient;
+// backups everywhere else stay plaintext.
+type Destination struct {
+	Path      string `json:"path"`
+	Encrypt   string `json:"encrypt,omitempty"`   // "gpg" or "age"
+	Recipient string `json:"recipient,omitempty"` // GPG key ID or fingerprint, or age public key
+
+	Retention *Retention `json:"retention,omitempty"` // Overrides the top-level policy here
+}
+
+// Settings is config.json in the totem config folder
+type Settings struct {
+	Retention    *Retention    `json:"retention,omitempty"` // Pruning after each successful backup
+	Destinations []Destination `json:"destinations,omitempty"`
+}
+
+// Suffixes of the archives each encryption tool writes
+var encryptSuffixes = map[string]string{"gpg": ".gpg", "age": ".age"}
+
+func settingsPath() (string, error) {
+	dir, err := KeyDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// LoadSettings returns config.json, or empty settings when there is none
+func LoadSettings() (*Settings, error) {
+	path, err := settingsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Settings{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var s Settings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// destinationFor returns the configured destination holding dest, the deepest one when
+// they nest, or nil when none does
+func destinationFor(dest string) (*Destination, error) {
+	s, err := LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, nil
+	}
+	var found *Destination
+	for i, d := range s.Destinations {
+		if rest, ok := strings.CutPrefix(d.Path, "~"); ok {
+			home, _ := os.UserHomeDir()
+			d.Path = home + rest
+		}
+		root, err := filepath.Abs(d.Path)
+		if err != nil || d.Path == "" {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if found == nil || len(root) > len(found.Path) {
+			found = &s.Destinations[i]
+			found.Path = root
+		}
+	}
+	return found, nil
+}
+
+// encryptionFor returns the destination dest encrypts for, or nil when backups there stay
+// plaintext. A destination asking for a tool that is unknown or not installed is an error,
+// so the backup stops before anything is written unencrypted.
+func encryptionFor(dest string) (*Destination, error) {
+	d, err := destinationFor(dest)
+	if err != nil || d == nil || d.Encrypt == "" {
+		return nil, err
+	}
+	if _, ok := encryptSuffixes[d.Encrypt]; !ok {
+		return nil, fmt.Errorf("destination %s: unknown encryption %q (use gpg or age)", d.Path, d.Encrypt)
+	}
+	if d.Recipient == "" {
+		return nil, fmt.Errorf("destination %s: %s encryption needs a recipient", d.Path, d.Encrypt)
+	}
+	if _, err := exec.LookPath(d.Encrypt); err != nil {
+		return nil, fmt.Errorf("destination %s encrypts with %s, which is not installed", d.Path, d.Encrypt)
+	}
+	return d, nil
+}
+
+// encryptArchive encrypts path to the destination's recipient and removes the plaintext,
+// returning the encrypted archive's path. On failure the plaintext is kept.
+func encryptArchive(path string, d *Destination) (string, error) {
+	out := path + encryptSuffixes[d.Encrypt]
+	var cmd *exec.Cmd
+	switch d.Encrypt {
+	case "gpg":
+		// The recipient's key must be in the local keyring; gpg is not let fetch it over
+		// the network (WKD), which would go around offline mode
+		cmd = exec.Command("gpg", "--batch", "--yes", "--trust-model", "always", "--auto-key-locate", "clear,local",
+			"--recipient", d.Recipient, "--output", out, "--encrypt", path)
+	case "age":
+		cmd = exec.Command("age", "--encrypt", "--recipient", d.Recipient, "--output", out, path)
+	}
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(out)
+		return path, fmt.Errorf("%s: %v: %s (left unencrypted)", d.Encrypt, err, strings.TrimSpace(string(msg)))
+	}
+	if info, err := os.Stat(out); err != nil || info.Size() == 0 {
+		os.Remove(out)
+		return path, fmt.Errorf("%s wrote no output (left unencrypted)", d.Encrypt)
+	}
+	os.Remove(path)
+	return out, nil
+}
+
+// checkGPG makes sure a backup can be piped through gpg: gpg is installed, and the
+// destination does not encrypt its backups already
+func checkGPG(enc *Destination) error {
+	if enc != nil {
+		return fmt.Errorf("destination %s already encrypts its backups with %s; leave out the gpg recipient", enc.Path, enc.Encrypt)
+	}
+	if _, err := exec.LookPath("gpg"); err != nil {
+		return fmt.Errorf("encrypting with gpg needs gpg, which is not installed")
+	}
+	return nil
+}
+
+// encryptZipGPG zips srcDir straight into gpg, encrypted to recipient, so the zip is never
+// on disk unencrypted. gpg's compression is off, so the encrypted file is at least as large
+// as the zip that went in; a smaller one means gpg stopped short, and it is removed so the
+// caller keeps srcDir. The recipient's key must be in the local keyring; gpg is not let
+// fetch it over the network.
+func encryptZipGPG(srcDir, out string, plan *compressionPlan, password, recipient string, progress *Progress) error {
+	cmd := exec.Command("gpg", "--batch", "--yes", "--trust-model", "always", "--compress-algo", "none",
+		"--auto-key-locate", "clear,local", "--recipient", recipient, "--output", out, "--encrypt")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("gpg: %w", err)
+	}
+	zipped := &countingWriter{w: stdin}
+	zipErr := writeZip(zipped, srcDir, plan, password, progress)
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		os.Remove(out)
+		return fmt.Errorf("gpg: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if zipErr != nil {
+		os.Remove(out)
+		return zipErr
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		return fmt.Errorf("gpg wrote no output: %w", err)
+	}
+	if info.Size() < zipped.n {
+		os.Remove(out)
+		return fmt.Errorf("gpg wrote %s for a %s zip, so the encrypted archive is incomplete", formatBytes(info.Size()), formatBytes(zipped.n))
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// isEncrypted reports whether name is an archive encrypted for a destination
+func isEncrypted(name string) bool {
+	name = unsplitName(name)
+	for _, suffix := range encryptSuffixes {
+		for _, archive := range archiveSuffixes {
+			if strings.HasSuffix(name, archive+suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// backupKey names the key the backup at output is encrypted with: "gpg:" and the key's
+// fingerprint, "age:" and the recipient, or "zip-password". A rotated key leaves older
+// backups needing the old one, and the catalog says which. Empty when it is not encrypted.
+func backupKey(config *tui.Config, output string) string {
+	name := filepath.Base(output)
+	switch {
+	case config.GPGRecipient != "" && strings.Contains(name, encryptSuffixes["gpg"]):
+		return "gpg:" + gpgFingerprint(config.GPGRecipient)
+	case strings.Contains(name, encryptSuffixes["gpg"]) || strings.Contains(name, encryptSuffixes["age"]):
+		d, err := destinationFor(config.BackupDest)
+		if err != nil || d == nil || d.Encrypt == "" {
+			return ""
+		}
+		if d.Encrypt == "gpg" {
+			return "gpg:" + gpgFingerprint(d.Recipient)
+		}
+		return d.Encrypt + ":" + d.Recipient
+	case config.ProtectZip && config.ZipPassword != "" && strings.Contains(name, ".zip"):
+		return "zip-password"
+	}
+	return ""
+}
+
+// gpgFingerprint returns the fingerprint of recipient's key in the local keyring, or
+// recipient itself when gpg can't find it
+func gpgFingerprint(recipient string) string {
+	out, err := exec.Command("gpg", "--batch", "--with-colons", "--fingerprint", recipient).Output()
+	if err != nil {
+		return recipient
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		// The first fpr record is the primary key's
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" && len(fields) > 9 && fields[9] != "" {
+			return fields[9]
+		}
+	}
+	return recipient
+}
@@ -0,0 +1,186 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// fakeAge "encrypts" by prefixing the recipient, enough to tell the archive went through it
+const fakeAge = `#!/bin/sh
+while [ $# -gt 1 ]; do
+	case $1 in
+	--recipient) recipient=$2; shift ;;
+	--output) out=$2; shift ;;
+	esac
+	shift
+done
+{ echo "age:$recipient"; cat "$1"; } >"$out"
+`
+
+// writeSettings writes the totem config.json into TOTEM_CONFIG
+func writeSettings(t *testing.T, settings string) {
+	t.Helper()
+	writeTestFile(t, os.Getenv("TOTEM_CONFIG"), "config.json", settings)
+}
+
+func TestDestinationFor(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	nas := t.TempDir()
+	cloud := filepath.Join(nas, "cloud")
+	writeSettings(t, fmt.Sprintf(`{"destinations":[{"path":%q,"encrypt":"age","recipient":"age1xyz"},{"path":%q}]}`, cloud, nas))
+
+	for dest, want := range map[string]string{
+		filepath.Join(cloud, "Fabric"): cloud,
+		filepath.Join(nas, "Fabric"):   nas,
+		nas + "-other":                 "",
+	} {
+		d, err := destinationFor(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if d != nil {
+			got = d.Path
+		}
+		if got != want {
+			t.Errorf("destinationFor(%s) = %q, want %q", dest, got, want)
+		}
+	}
+
+	// Settings that can't encrypt stop the backup instead of writing plaintext
+	t.Setenv("PATH", t.TempDir())
+	for _, d := range []string{
+		`{"path":%q,"encrypt":"rot13","recipient":"x"}`,
+		`{"path":%q,"encrypt":"age"}`,
+		`{"path":%q,"encrypt":"age","recipient":"age1xyz"}`, // age is not installed
+	} {
+		writeSettings(t, fmt.Sprintf(`{"destinations":[`+d+`]}`, cloud))
+		if _, err := encryptionFor(cloud); err == nil {
+			t.Errorf("encryptionFor with %s: no error", d)
+		}
+	}
+}
+
+// Backups into an encrypting destination are zipped and encrypted; the rest stay plaintext
+func TestEncryptingDestination(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake age is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "age"), []byte(fakeAge), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	cloud := t.TempDir()
+	writeSettings(t, fmt.Sprintf(`{"destinations":[{"path":%q,"encrypt":"age","recipient":"age1xyz"}]}`, cloud))
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: cloud}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v, %v", err, result.Errors)
+	}
+	if !strings.HasSuffix(result.OutputPath, ".zip.age") {
+		t.Fatalf("backed up to %s, want an encrypted zip", result.OutputPath)
+	}
+	data, _ := os.ReadFile(result.OutputPath)
+	if !strings.HasPrefix(string(data), "age:age1xyz\nPK") {
+		t.Errorf("the archive was not encrypted to the recipient: %q", data[:min(len(data), 16)])
+	}
+	if exists(strings.TrimSuffix(result.OutputPath, ".age")) {
+		t.Error("the plaintext zip was left beside the encrypted one")
+	}
+	if c, _ := LoadCatalog(cloud); c.Entry(filepath.Base(result.OutputPath)) == nil || c.Entry(filepath.Base(result.OutputPath)).Key != "age:age1xyz" {
+		t.Error("the catalog does not record the key")
+	}
+
+	local, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir()}, nil)
+	if err != nil || !exists(filepath.Join(local.OutputPath, "options.txt")) {
+		t.Errorf("a backup elsewhere went to %s, %v; want a plain folder", local.OutputPath, err)
+	}
+}
+
+// fakeGPG "encrypts" stdin by prefixing the recipient, or writes only that with
+// FAKE_GPG_SHORT set, as a gpg that stopped short would; it answers fingerprint lookups
+const fakeGPG = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case $1 in
+	--recipient) recipient=$2; shift ;;
+	--output) out=$2; shift ;;
+	--fingerprint) echo "fpr:::::::::0123456789ABCDEF:"; exit 0 ;;
+	esac
+	shift
+done
+if [ -n "$FAKE_GPG_SHORT" ]; then
+	cat >/dev/null
+	echo "gpg:$recipient" >"$out"
+	exit 0
+fi
+{ echo "gpg:$recipient"; cat; } >"$out"
+`
+
+// A backup piped through gpg is only kept encrypted when gpg wrote all of it; otherwise the
+// plain folder stays
+func TestGPGBackup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake gpg is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "gpg"), []byte(fakeGPG), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+
+	dest := t.TempDir()
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, GPGRecipient: "alex@example.com"}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v, %v", err, result.Errors)
+	}
+	if !strings.HasSuffix(result.OutputPath, ".zip.gpg") {
+		t.Fatalf("backed up to %s, want a .zip.gpg", result.OutputPath)
+	}
+	data, _ := os.ReadFile(result.OutputPath)
+	if !strings.HasPrefix(string(data), "gpg:alex@example.com\nPK") {
+		t.Errorf("the zip was not piped through gpg: %q", data[:min(len(data), 24)])
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 2 {
+		t.Errorf("dest has %d entries, want the .zip.gpg and the catalog", len(entries))
+	}
+	if items, _ := ListBackups(dest); len(items) != 1 || items[0].Key != "gpg:0123456789ABCDEF" {
+		t.Errorf("catalogued as %+v, want the key's fingerprint", items)
+	}
+
+	t.Setenv("FAKE_GPG_SHORT", "1")
+	dest = t.TempDir()
+	result, err = PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, GPGRecipient: "alex@example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || !slices.ContainsFunc(result.Errors, func(e string) bool { return strings.Contains(e, "incomplete") }) {
+		t.Errorf("a short gpg output: success %v, errors %v", result.Success, result.Errors)
+	}
+	if !exists(filepath.Join(result.OutputPath, "options.txt")) {
+		t.Errorf("the plain backup was not kept: %s", result.OutputPath)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dest, "*.gpg")); len(leftovers) > 0 {
+		t.Errorf("left %v", leftovers)
+	}
+
+	// A destination that encrypts already can't be given a gpg recipient too
+	writeSettings(t, fmt.Sprintf(`{"destinations":[{"path":%q,"encrypt":"gpg","recipient":"alex@example.com"}]}`, dest))
+	if _, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, GPGRecipient: "alex@example.com"}, nil); err == nil {
+		t.Error("gpg recipient for an encrypting destination: no error")
+	}
+}
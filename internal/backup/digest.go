@@ -0,0 +1,379 @@
+package backup
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/netguard"
+	"github.com/vaalley/totem/internal/version"
+)
+
+// DigestDir is the folder in the destination that holds written digests
+const DigestDir = "digests"
+
+// DigestBackup is one backup taken during a digest's period
+type DigestBackup struct {
+	Name string
+	Time time.Time
+	Size int64
+	Err  string // First error from its info.md, or why it is incomplete
+}
+
+// DigestInstance is one destination folder's activity during a digest's period
+type DigestInstance struct {
+	Name    string // Folder name relative to the digest root ("." for the root itself)
+	Backups []DigestBackup
+	Size    int64 // Disk space the period's backups take
+	Latest  int64 // Size of the newest backup
+	Before  int64 // Size of the newest backup before the period, 0 if none
+}
+
+// Failures returns how many of the period's backups failed or are incomplete
+func (d *DigestInstance) Failures() int {
+	n := 0
+	for _, b := range d.Backups {
+		if b.Err != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Digest summarizes every backup taken in a destination over a period
+type Digest struct {
+	Root      string
+	From, To  time.Time
+	Instances []DigestInstance
+}
+
+// BuildDigest summarizes backups in dest and its instance subfolders (as written by
+// totem backup) taken between from and to
+func BuildDigest(dest string, from, to time.Time) (*Digest, error) {
+	if _, err := os.Stat(dest); err != nil {
+		return nil, fmt.Errorf("destination does not exist: %s", dest)
+	}
+	d := &Digest{Root: dest, From: from, To: to}
+	for _, dir := range backupFolders(dest) {
+		inst, ok := digestInstance(dir, from, to)
+		if !ok {
+			continue
+		}
+		inst.Name, _ = filepath.Rel(dest, dir)
+		d.Instances = append(d.Instances, inst)
+	}
+	return d, nil
+}
+
+// digestInstance collects the backups in dir; ok is false if it holds none at all
+func digestInstance(dir string, from, to time.Time) (DigestInstance, bool) {
+	var inst DigestInstance
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return inst, false
+	}
+	var before time.Time
+	found := false
+	for _, e := range entries {
+		name := e.Name()
+		if !isBackupEntry(name) {
+			continue
+		}
+		t, ok := parseBackupName(name)
+		if !ok {
+			continue
+		}
+		found = true
+		path := filepath.Join(dir, name)
+		var size int64
+		if e.IsDir() {
+			size = getDirSize(path, nil)
+		} else {
+			size = archiveSize(path)
+		}
+
+		switch {
+		case t.Before(from):
+			if !t.Before(before) {
+				before, inst.Before = t, size
+			}
+		case t.Before(to):
+			b := DigestBackup{Name: trimArchiveSuffix(name), Time: t, Size: size}
+			if exists(filepath.Join(dir, b.Name) + JournalSuffix) {
+				b.Err = "interrupted; run totem resume to finish it"
+			} else {
+				b.Err = backupFirstError(path)
+			}
+			inst.Backups = append(inst.Backups, b)
+			inst.Size += size
+		}
+	}
+	sort.Slice(inst.Backups, func(i, j int) bool { return inst.Backups[i].Time.Before(inst.Backups[j].Time) })
+	if n := len(inst.Backups); n > 0 {
+		inst.Latest = inst.Backups[n-1].Size
+	}
+	return inst, found
+}
+
+// backupFirstError returns the first error listed in a backup's info.md, or ""
+func backupFirstError(path string) string {
+	var data []byte
+	if isZipBackup(path) {
+		r, closeZip, err := openZipBackup(path)
+		if err != nil {
+			return "unreadable zip: " + err.Error()
+		}
+		defer closeZip()
+		for _, f := range r.File {
+			if filepath.Base(f.Name) != "info.md" {
+				continue
+			}
+			if rc, err := f.Open(); err == nil {
+				data, _ = io.ReadAll(rc)
+				rc.Close()
+			}
+			break
+		}
+	} else if isTarBackup(path) {
+		forEachMatchingFile(path, func(rel string) bool { return rel == "info.md" }, func(_ string, r io.Reader) error {
+			data, _ = io.ReadAll(r)
+			return nil
+		})
+	} else {
+		data, _ = os.ReadFile(filepath.Join(path, "info.md"))
+	}
+	if data == nil {
+		return "no info.md"
+	}
+	_, errors, ok := strings.Cut(string(data), "## ⚠️ Errors\n\n")
+	if !ok {
+		return ""
+	}
+	line, _, _ := strings.Cut(errors, "\n")
+	return strings.TrimPrefix(line, "- ")
+}
+
+// Totals returns the number of backups, failures and bytes across all instances
+func (d *Digest) Totals() (backups, failures int, size int64) {
+	for i := range d.Instances {
+		backups += len(d.Instances[i].Backups)
+		failures += d.Instances[i].Failures()
+		size += d.Instances[i].Size
+	}
+	return
+}
+
+func (d *Digest) period() string {
+	return fmt.Sprintf("%s – %s", d.From.Format("2006-01-02"), d.To.Format("2006-01-02"))
+}
+
+// growth formats the change in the newest backup's size over the period
+func (inst *DigestInstance) growth() string {
+	if len(inst.Backups) == 0 || inst.Before == 0 {
+		return "n/a"
+	}
+	delta := inst.Latest - inst.Before
+	if delta < 0 {
+		return "-" + formatBytes(-delta)
+	}
+	return "+" + formatBytes(delta)
+}
+
+// Markdown renders the digest as digest.md
+func (d *Digest) Markdown() string {
+	backups, failures, size := d.Totals()
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# 🗿 Totem Digest: %s\n\n", d.period()))
+	b.WriteString(fmt.Sprintf("**%d backups**, %d failed, %s written to `%s`\n\n", backups, failures, formatBytes(size), d.Root))
+
+	b.WriteString("| Instance | Backups | Failed | Written | Latest Size | Growth |\n|----------|---------|--------|---------|-------------|--------|\n")
+	for _, inst := range d.Instances {
+		b.WriteString(fmt.Sprintf("| %s | %d | %d | %s | %s | %s |\n", inst.Name, len(inst.Backups), inst.Failures(),
+			formatBytes(inst.Size), formatBytes(inst.Latest), inst.growth()))
+	}
+
+	for _, inst := range d.Instances {
+		if inst.Failures() == 0 {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("\n## ⚠️ %s\n\n", inst.Name))
+		for _, bk := range inst.Backups {
+			if bk.Err != "" {
+				b.WriteString(fmt.Sprintf("- `%s`: %s\n", bk.Name, bk.Err))
+			}
+		}
+	}
+	for _, inst := range d.Instances {
+		if len(inst.Backups) == 0 {
+			b.WriteString(fmt.Sprintf("\n- **%s** had no backups this period.\n", inst.Name))
+		}
+	}
+	b.WriteString(fmt.Sprintf("\n---\n\n*Generated on %s by Totem v%s*\n", time.Now().Format("2006-01-02 15:04"), version.Version))
+	return b.String()
+}
+
+// HTML renders the digest as a standalone page, also used as the email body
+func (d *Digest) HTML() string {
+	backups, failures, size := d.Totals()
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>Totem Digest</title>\n")
+	b.WriteString("<style>body{font-family:sans-serif;max-width:50em;margin:2em auto}td,th{padding:.3em .8em;text-align:left}" +
+		"tr:nth-child(even){background:#f3f3f3}.failed{color:#b00}</style></head><body>\n")
+	b.WriteString(fmt.Sprintf("<h1>🗿 Totem Digest: %s</h1>\n", html.EscapeString(d.period())))
+	b.WriteString(fmt.Sprintf("<p><b>%d backups</b>, %d failed, %s written to <code>%s</code></p>\n",
+		backups, failures, formatBytes(size), html.EscapeString(d.Root)))
+
+	b.WriteString("<table>\n<tr><th>Instance</th><th>Backups</th><th>Failed</th><th>Written</th><th>Latest Size</th><th>Growth</th></tr>\n")
+	for _, inst := range d.Instances {
+		class := ""
+		if inst.Failures() > 0 {
+			class = ` class="failed"`
+		}
+		b.WriteString(fmt.Sprintf("<tr%s><td>%s</td><td>%d</td><td>%d</td><td>%s</td><td>%s</td><td>%s</td></tr>\n", class,
+			html.EscapeString(inst.Name), len(inst.Backups), inst.Failures(), formatBytes(inst.Size), formatBytes(inst.Latest), inst.growth()))
+	}
+	b.WriteString("</table>\n")
+
+	for _, inst := range d.Instances {
+		if inst.Failures() == 0 {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("<h2>⚠️ %s</h2>\n<ul>\n", html.EscapeString(inst.Name)))
+		for _, bk := range inst.Backups {
+			if bk.Err != "" {
+				b.WriteString(fmt.Sprintf("<li><code>%s</code>: %s</li>\n", html.EscapeString(bk.Name), html.EscapeString(bk.Err)))
+			}
+		}
+		b.WriteString("</ul>\n")
+	}
+	b.WriteString(fmt.Sprintf("<p><small>Generated on %s by Totem v%s</small></p>\n</body></html>\n",
+		time.Now().Format("2006-01-02 15:04"), version.Version))
+	return b.String()
+}
+
+// WriteDigest writes digest_<date>.md and .html into dest/digests and returns the markdown path
+func WriteDigest(d *Digest) (string, error) {
+	dir := filepath.Join(d.Root, DigestDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(dir, "digest_"+d.To.Format("2006-01-02"))
+	if err := os.WriteFile(base+".html", []byte(d.HTML()), 0644); err != nil {
+		return "", err
+	}
+	return base + ".md", os.WriteFile(base+".md", []byte(d.Markdown()), 0644)
+}
+
+// MailDigest emails the digest as HTML through the SMTP server at addr (host:port) to the
+// comma-separated addresses in to. TOTEM_SMTP_USER and TOTEM_SMTP_PASSWORD sign in when set;
+// TOTEM_SMTP_FROM sets the sender, which is otherwise the first recipient.
+func MailDigest(d *Digest, addr, to string) error {
+	host, _, _ := strings.Cut(addr, ":")
+	from, rcpts, err := digestAddresses(to)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if user := os.Getenv("TOTEM_SMTP_USER"); user != "" {
+		auth = smtp.PlainAuth("", user, os.Getenv("TOTEM_SMTP_PASSWORD"), host)
+	}
+
+	addrs := make([]string, len(rcpts))
+	for i, r := range rcpts {
+		addrs[i] = r.Address
+	}
+	return sendMail(addr, host, auth, from.Address, addrs, digestMessage(d, from, rcpts, time.Now()))
+}
+
+// digestAddresses parses the recipients and picks the sender. A list can't be the sender,
+// so without TOTEM_SMTP_FROM it is the first recipient.
+func digestAddresses(to string) (from *mail.Address, rcpts []*mail.Address, err error) {
+	if rcpts, err = mail.ParseAddressList(to); err != nil {
+		return nil, nil, fmt.Errorf("invalid --mail %q: %w", to, err)
+	}
+	from = rcpts[0]
+	if env := os.Getenv("TOTEM_SMTP_FROM"); env != "" {
+		if from, err = mail.ParseAddress(env); err != nil {
+			return nil, nil, fmt.Errorf("invalid TOTEM_SMTP_FROM %q: %w", env, err)
+		}
+	}
+	return from, rcpts, nil
+}
+
+// digestMessage is the digest as an email, with the Date and Message-ID headers relays
+// expect of every message
+func digestMessage(d *Digest, from *mail.Address, to []*mail.Address, now time.Time) []byte {
+	recipients := make([]string, len(to))
+	for i, r := range to {
+		recipients[i] = r.String()
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	_, domain, _ := strings.Cut(from.Address, "@")
+
+	backups, failures, _ := d.Totals()
+	subject := fmt.Sprintf("Totem digest %s: %d backups, %d failed", d.period(), backups, failures)
+	msg := "From: " + from.String() + "\r\n" +
+		"To: " + strings.Join(recipients, ", ") + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n" +
+		"Date: " + now.Format(time.RFC1123Z) + "\r\n" +
+		"Message-ID: <" + hex.EncodeToString(id) + "@" + domain + ">\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n\r\n" +
+		d.HTML()
+	return []byte(msg)
+}
+
+// sendMail does what smtp.SendMail does, over a connection netguard allowed
+func sendMail(addr, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	conn, err := netguard.Dial("digest email", "tcp", addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
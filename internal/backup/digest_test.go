@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"bytes"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestAddresses(t *testing.T) {
+	t.Setenv("TOTEM_SMTP_FROM", "")
+	from, rcpts, err := digestAddresses("admin@example.com, Alex <alex@example.org>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.Address != "admin@example.com" || len(rcpts) != 2 || rcpts[1].Address != "alex@example.org" {
+		t.Errorf("from %v to %v; want the first recipient as the sender", from, rcpts)
+	}
+
+	t.Setenv("TOTEM_SMTP_FROM", "Totem <totem@example.net>")
+	if from, _, err := digestAddresses("admin@example.com,alex@example.org"); err != nil || from.Address != "totem@example.net" {
+		t.Errorf("with TOTEM_SMTP_FROM: from %v, %v", from, err)
+	}
+	for _, to := range []string{"", "not an address", "a@example.com;b@example.com"} {
+		if _, _, err := digestAddresses(to); err == nil {
+			t.Errorf("digestAddresses(%q): no error", to)
+		}
+	}
+}
+
+func TestDigestMessageHeaders(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC)
+	d := &Digest{From: now.AddDate(0, 0, -7), To: now}
+	from := &mail.Address{Address: "totem@example.net"}
+	to := []*mail.Address{{Address: "admin@example.com"}, {Name: "Alex", Address: "alex@example.org"}}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(digestMessage(d, from, to, now)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if date, err := msg.Header.Date(); err != nil || !date.Equal(now) {
+		t.Errorf("Date = %v, %v; want %v", date, err, now)
+	}
+	if id := msg.Header.Get("Message-ID"); len(id) < len("<@example.net>")+8 || id[len(id)-len("@example.net>"):] != "@example.net>" {
+		t.Errorf("Message-ID = %q", id)
+	}
+	if list, err := msg.Header.AddressList("To"); err != nil || len(list) != 2 {
+		t.Errorf("To = %v, %v", list, err)
+	}
+	if sender, err := mail.ParseAddress(msg.Header.Get("From")); err != nil || sender.Address != "totem@example.net" {
+		t.Errorf("From = %v, %v", sender, err)
+	}
+}
+
+// A digest counts each instance's backups in the period, their failures and how the newest grew
+func TestBuildDigest(t *testing.T) {
+	dest := t.TempDir()
+	writeTestFile(t, dest, "Fabric/backup_2026-03-01_00-00/options.txt", "1234567890")
+	writeTestFile(t, dest, "Fabric/backup_2026-03-04_00-00/options.txt", strings.Repeat("x", 30))
+	writeTestFile(t, dest, "Fabric/backup_2026-03-04_00-00/info.md", "")
+	writeTestFile(t, dest, "Fabric/backup_2026-03-05_00-00/info.md", "# Totem Backup\n\n## ⚠️ Errors\n\n- saves: disk full\n- options: disk full\n")
+	writeTestFile(t, dest, "Fabric/backup_2026-03-06_00-00/options.txt", "partial")
+	writeTestFile(t, dest, "Fabric/backup_2026-03-06_00-00.journal", "{}")
+	writeTestFile(t, dest, "Fabric/backup_2026-03-20_00-00/info.md", "after the period")
+	writeTestFile(t, dest, "Vanilla/backup_2026-02-01_00-00/info.md", "")
+	writeTestFile(t, dest, "Screenshots/a.png", "not a backup folder")
+
+	from := time.Date(2026, 3, 3, 0, 0, 0, 0, time.Local)
+	d, err := BuildDigest(dest, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Instances) != 2 || d.Instances[0].Name != "Fabric" || d.Instances[1].Name != "Vanilla" {
+		t.Fatalf("Instances = %+v, want Fabric and Vanilla", d.Instances)
+	}
+	fabric := d.Instances[0]
+	var errs []string
+	for _, b := range fabric.Backups {
+		errs = append(errs, b.Err)
+	}
+	want := []string{"", "saves: disk full", "interrupted; run totem resume to finish it"}
+	if !slices.Equal(errs, want) {
+		t.Errorf("backup errors = %q, want %q", errs, want)
+	}
+	if fabric.Before != 10 || fabric.Latest != int64(len("partial")) || fabric.growth() != "-"+formatBytes(3) {
+		t.Errorf("Before = %d, Latest = %d, growth %s", fabric.Before, fabric.Latest, fabric.growth())
+	}
+	if backups, failures, _ := d.Totals(); backups != 3 || failures != 2 {
+		t.Errorf("Totals = %d backups, %d failed; want 3 and 2", backups, failures)
+	}
+
+	path, err := WriteDigest(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, _ := os.ReadFile(path)
+	for _, s := range []string{"**3 backups**, 2 failed", "## ⚠️ Fabric", "- `backup_2026-03-05_00-00`: saves: disk full", "**Vanilla** had no backups this period"} {
+		if !strings.Contains(string(md), s) {
+			t.Errorf("digest.md lacks %q:\n%s", s, md)
+		}
+	}
+	if !exists(strings.TrimSuffix(path, ".md") + ".html") {
+		t.Error("no HTML digest beside the markdown one")
+	}
+	if _, err := BuildDigest(filepath.Join(dest, "missing"), from, from); err == nil {
+		t.Error("BuildDigest on a missing destination: no error")
+	}
+}
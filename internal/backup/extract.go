@@ -0,0 +1,200 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// isTarBackup reports whether a backup is a tar archive: a .tar.zst made with --zstd, or a
+// plain or gzipped tar made by other tools
+func isTarBackup(backupPath string) bool {
+	lower := strings.ToLower(unsplitName(backupPath))
+	return strings.HasSuffix(lower, ".tar") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") ||
+		strings.HasSuffix(lower, ZstdSuffix)
+}
+
+// Extract copies the files and folders at paths (slash-separated, relative to the backup root)
+// out of a backup folder, zip or tar into dest, keeping their layout. Only the matching entries
+// are read. Incremental backups are resolved through their chain, so files kept in a parent
+// are found too, with newer links winning. It returns how many files were written.
+func Extract(backupPath string, paths []string, dest string) (int, error) {
+	return extract(backupPath, paths, dest, true)
+}
+
+// ExtractNew is Extract that never overwrites a file already in dest: it stops at the first
+// one in the way. Files written earlier by the same extract are still replaced by newer
+// links of a chain.
+func ExtractNew(backupPath string, paths []string, dest string) (int, error) {
+	return extract(backupPath, paths, dest, false)
+}
+
+// joinInside joins the slash-separated rel onto dir, reporting false when the result would not
+// land inside dir. Join cleans away a leading "./", so dir "." or "/" is compared through Rel.
+func joinInside(dir, rel string) (string, bool) {
+	target := filepath.Join(dir, filepath.FromSlash(rel))
+	inside, err := filepath.Rel(filepath.Clean(dir), target)
+	if err != nil || inside == "." || inside == ".." || strings.HasPrefix(inside, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return target, true
+}
+
+func extract(backupPath string, paths []string, dest string, overwrite bool) (int, error) {
+	wanted := make([]string, len(paths))
+	for i, p := range paths {
+		wanted[i] = strings.Trim(path.Clean(filepath.ToSlash(p)), "/")
+	}
+	found := make([]bool, len(wanted))
+	matches := func(rel string) bool {
+		hit := false
+		for i, w := range wanted {
+			if w == "." || rel == w || strings.HasPrefix(rel, w+"/") {
+				found[i] = true
+				hit = true
+			}
+		}
+		return hit && rel != ManifestName && rel != SignatureName
+	}
+
+	// Other tools' tars have no manifest, and reading one just to find that out is slow
+	chain := []string{backupPath}
+	if !isTarBackup(backupPath) || strings.HasSuffix(strings.ToLower(backupPath), ZstdSuffix) {
+		if resolved, err := ResolveChain(backupPath); err == nil {
+			chain = resolved
+		}
+	}
+
+	written := map[string]bool{}
+	for _, link := range chain {
+		err := forEachMatchingFile(link, matches, func(rel string, r io.Reader) error {
+			target, ok := joinInside(dest, rel)
+			if !ok {
+				return fmt.Errorf("unsafe path in backup: %s", rel)
+			}
+			if !overwrite && !written[rel] && exists(target) {
+				return fmt.Errorf("%s already exists and would be overwritten", target)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, r); err != nil {
+				out.Close()
+				return err
+			}
+			written[rel] = true
+			return out.Close()
+		})
+		if err != nil {
+			return len(written), fmt.Errorf("%s: %w", backupName(link), err)
+		}
+	}
+
+	var missing []string
+	for i, ok := range found {
+		if !ok {
+			missing = append(missing, paths[i])
+		}
+	}
+	if len(missing) > 0 {
+		return len(written), fmt.Errorf("not in the backup: %s", strings.Join(missing, ", "))
+	}
+	return len(written), nil
+}
+
+// forEachMatchingFile calls fn for the files in a backup folder, zip or tar whose slash-separated
+// path passes match, opening only those
+func forEachMatchingFile(backupPath string, match func(rel string) bool, fn func(rel string, r io.Reader) error) error {
+	switch {
+	case isZipBackup(backupPath):
+		zr, closeZip, err := openZipBackup(backupPath)
+		if err != nil {
+			return err
+		}
+		defer closeZip()
+		for _, f := range zr.File {
+			rel := filepath.ToSlash(f.Name)
+			if f.FileInfo().IsDir() || !match(rel) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(rel, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case isTarBackup(backupPath):
+		f, err := os.Open(backupPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		var r io.Reader = f
+		switch lower := strings.ToLower(unsplitName(backupPath)); {
+		case strings.HasSuffix(lower, ZstdSuffix):
+			zr, err := openZstd(backupPath)
+			if err != nil {
+				return err
+			}
+			defer zr.Close()
+			r = zr
+		case !strings.HasSuffix(lower, ".tar"):
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			rel := strings.TrimPrefix(path.Clean(filepath.ToSlash(h.Name)), "./")
+			if h.Typeflag != tar.TypeReg || !match(rel) {
+				continue
+			}
+			if err := fn(rel, tr); err != nil {
+				return err
+			}
+		}
+	}
+
+	return filepath.WalkDir(backupPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(backupPath, p)
+		rel = filepath.ToSlash(rel)
+		if !match(rel) {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return fn(rel, f)
+	})
+}
@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJoinInside(t *testing.T) {
+	tests := []struct {
+		dir, rel string
+		ok       bool
+	}{
+		{".", "screenshots/a.png", true},
+		{"/", "screenshots/a.png", true},
+		{"restored", "screenshots/a.png", true},
+		{"restored/", "options.txt", true},
+		{".", "../escaped.txt", false},
+		{"/", "../escaped.txt", true}, // Join cleans /.. back to /
+		{"restored", "../escaped.txt", false},
+		{"restored", "a/../../escaped.txt", false},
+		{"restored", "", false},
+		{"restored", "..", false},
+	}
+	for _, tt := range tests {
+		if _, ok := joinInside(tt.dir, tt.rel); ok != tt.ok {
+			t.Errorf("joinInside(%q, %q) ok = %v, want %v", tt.dir, tt.rel, ok, tt.ok)
+		}
+	}
+}
+
+// "." is the default --to of totem extract
+func TestExtractIntoCurrentFolder(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00.zip")
+	writeTestZip(t, archive, map[string]string{
+		"screenshots/a.png": "png",
+		"options.txt":       "fov:0.0\n",
+	})
+
+	dest := t.TempDir()
+	t.Chdir(dest)
+	if n, err := Extract(archive, []string{"screenshots"}, "."); err != nil || n != 1 {
+		t.Fatalf("Extract = %d, %v; want 1 file", n, err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dest, "screenshots", "a.png")); err != nil || string(got) != "png" {
+		t.Errorf("screenshots/a.png = %q, %v", got, err)
+	}
+	if exists(filepath.Join(dest, "options.txt")) {
+		t.Error("options.txt was extracted without being asked for")
+	}
+}
+
+func TestExtractUnsafePath(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup_2026-01-01_00-00.zip")
+	writeTestZip(t, archive, map[string]string{"../escaped.txt": "gotcha"})
+
+	dest := filepath.Join(dir, "out", "extracted")
+	t.Chdir(dir)
+	for _, d := range []string{dest, "."} {
+		if _, err := Extract(archive, []string{"."}, d); err == nil {
+			t.Errorf("Extract of ../escaped.txt into %s: no error", d)
+		}
+	}
+	for _, p := range []string{filepath.Join(dir, "out", "escaped.txt"), filepath.Join(filepath.Dir(dir), "escaped.txt")} {
+		if exists(p) {
+			t.Errorf("%s was written outside the extract folder", p)
+		}
+	}
+}
+
+// Importing unpacks other tools' zips through the same check
+func TestExtractBackupIntoCurrentFolder(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "world.zip")
+	writeTestZip(t, archive, map[string]string{"World/level.dat": "12345"})
+
+	dest := t.TempDir()
+	t.Chdir(dest)
+	if err := extractBackup(archive, "."); err != nil {
+		t.Fatalf("extractBackup into .: %v", err)
+	}
+	if !exists(filepath.Join(dest, "World", "level.dat")) {
+		t.Error("World/level.dat was not unpacked")
+	}
+}
+
+// Other tools' gzipped tars are read without a manifest; only the asked-for entries are written
+func TestExtractTar(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "world-backup.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range [][2]string{
+		{"saves/World/level.dat", "level"},
+		{"saves/World/region/r.0.0.mca", "region"},
+		{"saves/Other/level.dat", "other"},
+		{"options.txt", "fov:0.0\n"},
+	} {
+		tw.WriteHeader(&tar.Header{Name: e[0], Mode: 0644, Size: int64(len(e[1]))})
+		tw.Write([]byte(e[1]))
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	dest := t.TempDir()
+	if n, err := Extract(archive, []string{"saves/World/", "options.txt"}, dest); err != nil || n != 3 {
+		t.Fatalf("Extract = %d, %v; want 3 files", n, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "saves", "World", "region", "r.0.0.mca")); string(got) != "region" {
+		t.Errorf("r.0.0.mca = %q", got)
+	}
+	if exists(filepath.Join(dest, "saves", "Other")) {
+		t.Error("saves/Other was extracted without being asked for")
+	}
+
+	if _, err := Extract(archive, []string{"options.txt", "servers.dat"}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "not in the backup: servers.dat") {
+		t.Errorf("Extract of a missing file: %v", err)
+	}
+	if _, err := ExtractNew(archive, []string{"options.txt"}, dest); err == nil || !strings.Contains(err.Error(), "would be overwritten") {
+		t.Errorf("ExtractNew over an existing file: %v", err)
+	}
+}
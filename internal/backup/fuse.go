@@ -0,0 +1,159 @@
+//go:build fuse && (linux || darwin)
+
+package backup
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"syscall"
+
+	gofs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// FUSEMount reports whether this build can mount backups as a folder
+const FUSEMount = true
+
+// MountFUSE mounts fsys read-only at mountpoint, which must be an empty folder. wait returns
+// once it is unmounted, by unmount or from outside (umount, fusermount -u).
+func MountFUSE(fsys fs.FS, mountpoint string) (wait func(), unmount func() error, err error) {
+	root := &fuseDir{fsys: fsys}
+	server, err := gofs.Mount(mountpoint, root, &gofs.Options{
+		// DirectMount lets root mount without fusermount, falling back to it otherwise
+		MountOptions: fuse.MountOptions{FsName: "totem", Name: "totem", Options: []string{"ro"}, DirectMount: true},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return server.Wait, server.Unmount, nil
+}
+
+// fuseDir is the root of a mounted backup. The whole tree is added when it is mounted, as
+// the backup never changes underneath.
+type fuseDir struct {
+	gofs.Inode
+	fsys fs.FS
+}
+
+var _ = (gofs.NodeOnAdder)((*fuseDir)(nil))
+
+func (d *fuseDir) OnAdd(ctx context.Context) {
+	fs.WalkDir(d.fsys, ".", func(name string, e fs.DirEntry, err error) error {
+		if err != nil || name == "." {
+			return nil
+		}
+		parent := &d.Inode
+		for _, part := range splitPath(path.Dir(name)) {
+			parent = parent.GetChild(part)
+		}
+		if parent == nil {
+			return nil
+		}
+		if e.IsDir() {
+			parent.AddChild(e.Name(), d.NewPersistentInode(ctx, &gofs.Inode{}, gofs.StableAttr{Mode: syscall.S_IFDIR}), false)
+			return nil
+		}
+		if !e.Type().IsRegular() {
+			return nil
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil
+		}
+		file := &fuseFile{fsys: d.fsys, name: name, info: info}
+		parent.AddChild(e.Name(), d.NewPersistentInode(ctx, file, gofs.StableAttr{}), false)
+		return nil
+	})
+}
+
+// splitPath returns the folders of a slash-separated path, none for "."
+func splitPath(p string) []string {
+	var parts []string
+	for p != "." {
+		parts = append([]string{path.Base(p)}, parts...)
+		p = path.Dir(p)
+	}
+	return parts
+}
+
+// fuseFile is a file of a mounted backup. Files in zips can only be read forward, so one
+// reader is kept and reopened when a read goes back.
+type fuseFile struct {
+	gofs.Inode
+	fsys fs.FS
+	name string
+	info fs.FileInfo
+
+	mu  sync.Mutex
+	r   fs.File
+	pos int64
+}
+
+var _ = (gofs.NodeGetattrer)((*fuseFile)(nil))
+var _ = (gofs.NodeOpener)((*fuseFile)(nil))
+var _ = (gofs.NodeReader)((*fuseFile)(nil))
+var _ = (gofs.NodeReleaser)((*fuseFile)(nil))
+
+func (f *fuseFile) Getattr(ctx context.Context, fh gofs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = 0444
+	out.Size = uint64(f.info.Size())
+	mtime := f.info.ModTime()
+	out.SetTimes(nil, &mtime, nil)
+	return 0
+}
+
+func (f *fuseFile) Open(ctx context.Context, flags uint32) (gofs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	return nil, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (f *fuseFile) Read(ctx context.Context, fh gofs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.r != nil && off < f.pos {
+		if _, ok := f.r.(io.ReaderAt); !ok {
+			f.r.Close()
+			f.r = nil
+		}
+	}
+	if f.r == nil {
+		r, err := f.fsys.Open(f.name)
+		if err != nil {
+			return nil, syscall.EIO
+		}
+		f.r, f.pos = r, 0
+	}
+	if ra, ok := f.r.(io.ReaderAt); ok {
+		n, err := ra.ReadAt(dest, off)
+		if err != nil && err != io.EOF {
+			return nil, syscall.EIO
+		}
+		return fuse.ReadResultData(dest[:n]), 0
+	}
+	if _, err := io.CopyN(io.Discard, f.r, off-f.pos); err != nil && err != io.EOF {
+		return nil, syscall.EIO
+	}
+	f.pos = off
+	n, err := io.ReadFull(f.r, dest)
+	f.pos += int64(n)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, syscall.EIO
+	}
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+// Release closes the reader; a file opened twice reopens it on its next read
+func (f *fuseFile) Release(ctx context.Context, fh gofs.FileHandle) syscall.Errno {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.r != nil {
+		f.r.Close()
+		f.r = nil
+	}
+	return 0
+}
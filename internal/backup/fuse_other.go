@@ -0,0 +1,18 @@
+//go:build !fuse || !(linux || darwin)
+
+package backup
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// FUSEMount reports whether this build can mount backups as a folder. Mounting needs the
+// fuse build tag and FUSE itself (fuse3 on Linux, macFUSE on macOS); other builds serve the
+// backup to the browser instead.
+const FUSEMount = false
+
+// MountFUSE is not available in this build
+func MountFUSE(fsys fs.FS, mountpoint string) (wait func(), unmount func() error, err error) {
+	return nil, nil, errors.New("this build can't mount backups as a folder; build it with -tags fuse on Linux or macOS")
+}
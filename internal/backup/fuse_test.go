@@ -0,0 +1,39 @@
+//go:build fuse && (linux || darwin)
+
+package backup
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Files in a zip read forward only; reads that go back reopen them, and the data still matches
+func TestFUSEReadZip(t *testing.T) {
+	contents := strings.Repeat("0123456789", 1000)
+	zipPath := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00.zip")
+	writeTestZip(t, zipPath, map[string]string{"saves/W/level.dat": contents})
+	fsys, closeFS, err := BackupFS(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFS()
+	info, err := fs.Stat(fsys, "saves/W/level.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fuseFile{fsys: fsys, name: "saves/W/level.dat", info: info}
+	for _, off := range []int64{5000, 9995, 10, 0, 5000} {
+		res, errno := f.Read(context.Background(), nil, make([]byte, 20), off)
+		if errno != 0 {
+			t.Fatalf("Read at %d: %v", off, errno)
+		}
+		got, _ := res.Bytes(nil)
+		if want := contents[off:min(off+20, int64(len(contents)))]; string(got) != want {
+			t.Errorf("Read at %d = %q, want %q", off, got, want)
+		}
+	}
+}
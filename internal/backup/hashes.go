@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// hashRecorder collects SHA-256 sums of files as they are copied, keyed by destination path,
+// so the manifest does not have to read every file a second time. Sums are kept as raw
+// bytes and dropped once the manifest has used them. The sources' modification times are
+// kept alongside for incremental backups.
+type hashRecorder struct {
+	mu     sync.Mutex
+	sums   map[string][sha256.Size]byte
+	mtimes map[string]time.Time
+}
+
+func newHashRecorder() *hashRecorder {
+	return &hashRecorder{sums: map[string][sha256.Size]byte{}, mtimes: map[string]time.Time{}}
+}
+
+func (h *hashRecorder) recordModTime(path string, t time.Time) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	h.mtimes[path] = t
+	h.mu.Unlock()
+}
+
+// takeModTime returns and forgets the source modification time recorded for path
+func (h *hashRecorder) takeModTime(path string) (time.Time, bool) {
+	if h == nil {
+		return time.Time{}, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.mtimes[path]
+	delete(h.mtimes, path)
+	return t, ok
+}
+
+func (h *hashRecorder) record(path string, sum []byte) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	h.sums[path] = [sha256.Size]byte(sum)
+	h.mu.Unlock()
+}
+
+// take returns and forgets the recorded sum for path
+func (h *hashRecorder) take(path string) (string, bool) {
+	if h == nil {
+		return "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sum, ok := h.sums[path]
+	if !ok {
+		return "", false
+	}
+	delete(h.sums, path)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// hashAll fills in sums for paths the recorder has not seen, hashing them on all cores
+func (h *hashRecorder) hashAll(paths []string) (map[string]string, error) {
+	out := make(map[string]string, len(paths))
+	var todo []string
+	for _, p := range paths {
+		if sum, ok := h.take(p); ok {
+			out[p] = sum
+		} else {
+			todo = append(todo, p)
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	jobs := make(chan string)
+	for range min(runtime.NumCPU(), max(len(todo), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				sum, err := hashFile(p)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				} else if err == nil {
+					out[p] = hex.EncodeToString(sum)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range todo {
+		jobs <- p
+	}
+	close(jobs)
+	wg.Wait()
+	return out, firstErr
+}
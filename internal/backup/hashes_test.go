@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Every file in the backup has its SHA-256 in the manifest, whether it was hashed while
+// being copied or written by Totem itself
+func TestManifestHashes(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "screenshots/shot.png", "png")
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir()}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	m, err := ReadManifest(result.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.SHA256) != len(m.Files) {
+		t.Errorf("%d hashes for %d files", len(m.SHA256), len(m.Files))
+	}
+	for _, rel := range m.Files {
+		data, err := os.ReadFile(filepath.Join(result.OutputPath, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(data)
+		if got := m.SHA256[rel]; got != hex.EncodeToString(sum[:]) {
+			t.Errorf("sha256 of %s = %s, want %x", rel, got, sum)
+		}
+	}
+}
+
+// Sums taken while copying are used once and then dropped; files the recorder never saw are
+// hashed from disk
+func TestHashRecorder(t *testing.T) {
+	dir := t.TempDir()
+	copied := filepath.Join(dir, "copied")
+	fresh := filepath.Join(dir, "fresh")
+	os.WriteFile(copied, []byte("on disk"), 0644)
+	os.WriteFile(fresh, []byte("fresh"), 0644)
+
+	h := newHashRecorder()
+	recorded := sha256.Sum256([]byte("as copied"))
+	h.record(copied, recorded[:])
+	sums, err := h.hashAll([]string{copied, fresh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte("fresh"))
+	if sums[copied] != hex.EncodeToString(recorded[:]) || sums[fresh] != hex.EncodeToString(want[:]) {
+		t.Errorf("hashAll = %v", sums)
+	}
+	if _, ok := h.take(copied); ok {
+		t.Error("the recorded sum was kept after use")
+	}
+}
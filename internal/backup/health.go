@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A previous backup older than this is worth a warning
+const staleBackupAge = 30 * 24 * time.Hour
+
+// healthCheck is one warning and how many points it costs the health score
+type healthCheck struct {
+	penalty int
+	warning string
+}
+
+// assessHealth scores a finished backup out of 100 and explains every deduction.
+// It looks for silent misconfigurations: a backup that "succeeded" but missed what matters.
+func assessHealth(backupPath string, config *tui.Config, result *Result, paths MinecraftPaths) (int, []string) {
+	var checks []healthCheck
+
+	if len(result.Errors) > 0 {
+		checks = append(checks, healthCheck{25, fmt.Sprintf("%d errors during the backup (see Errors below)", len(result.Errors))})
+	}
+	if !exists(paths.Options) {
+		checks = append(checks, healthCheck{15, "options.txt was not found, so settings and keybinds are not in this backup"})
+	}
+	// Incremental and cutoff backups leave unchanged worlds out, so an empty saves folder is expected
+	sinceCutoff := !config.SavesSince.IsZero() || !config.ScreenshotsSince.IsZero() || !config.XaeroSince.IsZero()
+	if config.IncludeSaves && !config.Incremental && !sinceCutoff &&
+		result.Stats.SavesCopied == 0 && result.Stats.WorldConfigsCopied == 0 {
+		checks = append(checks, healthCheck{30, "saves were selected but no world files were backed up; check the Minecraft path"})
+	}
+	if result.Stats.ModsListed > 0 && getMinecraftInfo(paths.Root).Loader == "Unknown" {
+		checks = append(checks, healthCheck{10, "mods were found but the mod loader could not be detected; restoring may need it picked by hand"})
+	}
+
+	if dups := findDuplicateMods(listModInfo(paths.Mods)); len(dups) > 0 {
+		ids := make([]string, len(dups))
+		for i, d := range dups {
+			ids[i] = d.ID
+		}
+		checks = append(checks, healthCheck{10, fmt.Sprintf("mods/ has more than one jar of %s, which usually crashes the game (see Duplicate Mods in info.md)", strings.Join(ids, ", "))})
+	}
+
+	// Mod lookups are extra information, so failing them is pointed out without costing points
+	if result.Stats.ModLookupError != "" {
+		checks = append(checks, healthCheck{0, "mods could not be looked up online (" + result.Stats.ModLookupError + "), so " + ModMetadataName + " names the rest from their jars"})
+	}
+
+	// The age of the last backup depends on today's date, which reproducible backups leave out
+	if !config.Deterministic {
+		if prev, ok := previousBackupTime(config.BackupDest, filepath.Base(backupPath)); ok && time.Since(prev) > staleBackupAge {
+			checks = append(checks, healthCheck{10, fmt.Sprintf("the previous backup is from %s, %d days before this one", prev.Format("2006-01-02"), int(time.Since(prev).Hours()/24))})
+		}
+	}
+
+	score := 100
+	var warnings []string
+	for _, c := range checks {
+		score -= c.penalty
+		warnings = append(warnings, c.warning)
+	}
+	return max(score, 0), warnings
+}
+
+// previousBackupTime returns the time of the newest backup in dest other than current
+// (whose zip and journal share its name)
+func previousBackupTime(dest, current string) (time.Time, bool) {
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var latest time.Time
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), current) || !isBackupEntry(e.Name()) {
+			continue
+		}
+		if t, ok := parseBackupName(e.Name()); ok && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// healthLabel describes a health score in a word
+func healthLabel(score int) string {
+	switch {
+	case score >= 90:
+		return "Good"
+	case score >= 60:
+		return "Fair"
+	}
+	return "Poor"
+}
+
+// renderHealthSection renders the health score and its warnings for info.md
+func renderHealthSection(result *Result) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\n## 🩺 Backup Health\n\n**Score:** %d/100 (%s)\n", result.Health, healthLabel(result.Health)))
+	if len(result.Warnings) == 0 {
+		b.WriteString("\nNo problems found.\n")
+		return b.String()
+	}
+	b.WriteString("\n")
+	for _, w := range result.Warnings {
+		b.WriteString("- ⚠️ " + w + "\n")
+	}
+	return b.String()
+}
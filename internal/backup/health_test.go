@@ -0,0 +1,42 @@
+package backup
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// An incremental run over unchanged saves copies no world files, which is not a problem
+func TestHealthIncrementalUnchangedSaves(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	dest := t.TempDir()
+
+	if _, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, IncludeSaves: true}, nil); err != nil {
+		t.Fatalf("full backup: %v", err)
+	}
+	parent, _, err := LatestBackup(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := PerformContext(context.Background(), &tui.Config{
+		MinecraftPath: mc, BackupDest: dest, IncludeSaves: true, Incremental: true, Parent: parent,
+	}, nil)
+	if err != nil {
+		t.Fatalf("incremental backup: %v", err)
+	}
+	if result.Stats.SavesCopied != 0 {
+		t.Fatalf("incremental backup copied %d unchanged saves", result.Stats.SavesCopied)
+	}
+	for _, w := range result.Warnings {
+		if strings.Contains(w, "no world files") {
+			t.Errorf("warning for unchanged saves: %s", w)
+		}
+	}
+	if result.Health != 100 {
+		t.Errorf("Health = %d, want 100 (warnings: %v)", result.Health, result.Warnings)
+	}
+}
@@ -0,0 +1,243 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+	"github.com/vaalley/totem/internal/version"
+)
+
+// Imported is the outcome of Import
+type Imported struct {
+	OutputPath string
+	Time       time.Time // When the original copy was made, as the backup's name records it
+	Files      int
+	Totem      bool     // The source was a Totem backup with its own info.md or manifest
+	Worlds     []string // Worlds of a world archive, filed under saves/
+}
+
+// Import adds an existing backup (a manual copy of an instance, an old Totem backup, a world
+// backup from AromaBackup or FTB Backups, or a zip of any of them) to dest as a regular
+// backup_<time> entry, so resume, restore, verify, --since last and digests all see it.
+// Files are copied as they are, except that world backups are filed under saves/<world>/ like
+// Totem's own; manifest.json is written when missing and info.md records where the copy came
+// from. A zero when takes the time from the source's name, or else from its newest file.
+func Import(ctx context.Context, source, dest string, when time.Time, zipOutput bool) (*Imported, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("nothing to import at %s", source)
+	}
+	if abs, err := filepath.Abs(source); err == nil {
+		source = abs
+	}
+	config := &tui.Config{MinecraftPath: source, BackupDest: dest, SkipJunk: true, ZipOutput: zipOutput}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination: %w", err)
+	}
+	if err := checkDestination(config); err != nil {
+		return nil, err
+	}
+
+	// Zips are unpacked to a staging folder first, then treated like a folder
+	root := source
+	if !info.IsDir() {
+		staging, err := os.MkdirTemp(dest, ".import-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(staging)
+		if err := extractBackup(source, staging); err != nil {
+			return nil, fmt.Errorf("unpack %s: %w", filepath.Base(source), err)
+		}
+		root = singleSubdir(staging)
+	}
+
+	if when.IsZero() {
+		t, ok := parseBackupName(filepath.Base(source))
+		if !ok {
+			t, ok = archiveNameTime(filepath.Base(source))
+		}
+		switch {
+		case ok:
+		case info.IsDir():
+			t = newestModTime(root)
+		default:
+			// Unpacked files are all new, but the zip remembers when each was written
+			t = newestZipTime(source)
+		}
+		when = t
+	}
+	imported := &Imported{Time: when}
+	imported.Totem = exists(filepath.Join(root, "info.md")) || exists(filepath.Join(root, ManifestName))
+
+	out := newBackupPath(dest, when)
+	opts := newCopyOptions(config, nil)
+	opts.ctx = ctx
+	if worlds := worldArchiveDirs(root); len(worlds) > 0 {
+		for _, w := range worlds {
+			fallback := filepath.Base(w)
+			if w == root {
+				fallback = importedWorldFallback(source)
+			}
+			base := worldName(w, fallback)
+			name := base
+			for i := 2; exists(filepath.Join(out, "saves", name)); i++ {
+				name = fmt.Sprintf("%s_%d", base, i)
+			}
+			imported.Worlds = append(imported.Worlds, name)
+			var count int
+			count, err = copyDir(w, filepath.Join(out, "saves", name), opts)
+			imported.Files += count
+			if err != nil {
+				break
+			}
+		}
+	} else {
+		imported.Files, err = copyDir(root, out, opts)
+	}
+	if err == nil && opts.cancelled() {
+		err = ErrCancelled
+	}
+	if err != nil {
+		os.RemoveAll(out)
+		return nil, err
+	}
+
+	if !exists(filepath.Join(out, "info.md")) {
+		if err := os.WriteFile(filepath.Join(out, "info.md"), []byte(renderImportMD(source, imported)), 0644); err != nil {
+			os.RemoveAll(out)
+			return nil, err
+		}
+	}
+	// A Totem manifest copied as is still matches; anything else needs one
+	if !exists(filepath.Join(out, ManifestName)) {
+		if err := writeManifest(out, config, opts.hashes); err != nil {
+			os.RemoveAll(out)
+			return nil, fmt.Errorf("manifest: %w", err)
+		}
+	}
+
+	imported.OutputPath = out
+	if zipOutput {
+		zipPath := out + ".zip"
+		if err := createZip(out, zipPath, nil, "", nil); err != nil {
+			os.Remove(zipPath)
+			return nil, fmt.Errorf("zip: %w", err)
+		}
+		os.RemoveAll(out)
+		imported.OutputPath = zipPath
+	}
+	return imported, nil
+}
+
+// importedWorldFallback names a world archive whose level.dat has no name: after the archive,
+// or after its folder when the archive is only named by date (AromaBackup keeps one folder per world)
+func importedWorldFallback(source string) string {
+	name := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	if _, dated := archiveNameTime(name); !dated {
+		return name
+	}
+	if parent := filepath.Base(filepath.Dir(source)); !strings.EqualFold(parent, "backups") {
+		return parent
+	}
+	return "world"
+}
+
+// extractBackup unpacks a zip into dir, refusing entries that would land outside it
+func extractBackup(zipPath, dir string) error {
+	return forEachFile(zipPath, func(rel string, r io.Reader) error {
+		target, ok := joinInside(dir, rel)
+		if !ok {
+			return fmt.Errorf("unsafe path in zip: %s", rel)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, r); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// singleSubdir descends into dir's only entry while it is a folder, since zips of a backup
+// usually wrap it in one
+func singleSubdir(dir string) string {
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+			return dir
+		}
+		dir = filepath.Join(dir, entries[0].Name())
+	}
+}
+
+// newestModTime returns the modification time of the newest file under root,
+// or now if it holds none
+func newestModTime(root string) time.Time {
+	var newest time.Time
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if newest.IsZero() {
+		return time.Now()
+	}
+	return newest
+}
+
+// newestZipTime returns the newest modification time recorded in a zip, or now if it holds none
+func newestZipTime(zipPath string) time.Time {
+	var newest time.Time
+	if r, err := zip.OpenReader(zipPath); err == nil {
+		for _, f := range r.File {
+			if !f.FileInfo().IsDir() && f.Modified.After(newest) {
+				newest = f.Modified
+			}
+		}
+		r.Close()
+	}
+	if newest.IsZero() {
+		return time.Now()
+	}
+	return newest.Local()
+}
+
+// renderImportMD is the info.md of an imported copy that did not have one
+func renderImportMD(source string, imported *Imported) string {
+	worlds := ""
+	if len(imported.Worlds) > 0 {
+		worlds = "| Worlds | " + strings.Join(imported.Worlds, ", ") + " (filed under `saves/`) |\n"
+	}
+	return fmt.Sprintf(`# 🗿 Totem Backup (Imported)
+
+> Imported on %s by Totem v%s
+
+| Property | Value |
+|----------|-------|
+| Imported From | `+"`%s`"+` |
+| Copy Made | %s |
+| Total Files | %d files |
+%s
+This backup was made outside Totem and its files were copied in unchanged. They are
+listed in `+"`manifest.json`"+`, so it can be verified and restored like any other.
+`, time.Now().Format("2006-01-02 15:04:05"), version.Version, source, imported.Time.Format("2006-01-02 15:04"), imported.Files, worlds)
+}
@@ -0,0 +1,83 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A manual copy of an instance becomes a backup named after its newest file, with a manifest
+func TestImportFolder(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "mc-copy")
+	writeTestFile(t, src, "options.txt", "fov:0.0\n")
+	writeTestFile(t, src, "saves/World/level.dat", "level")
+	copied := time.Date(2025, 6, 1, 14, 5, 0, 0, time.Local)
+	for _, p := range []string{"options.txt", "saves/World/level.dat"} {
+		os.Chtimes(filepath.Join(src, filepath.FromSlash(p)), copied, copied)
+	}
+
+	dest := t.TempDir()
+	imported, err := Import(context.Background(), src, dest, time.Time{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dest, "backup_2025-06-01_14-05"); imported.OutputPath != want {
+		t.Errorf("imported to %s, want %s", imported.OutputPath, want)
+	}
+	if imported.Files != 2 || imported.Totem {
+		t.Errorf("Imported = %+v, want 2 files from a manual copy", imported)
+	}
+	info, _ := os.ReadFile(filepath.Join(imported.OutputPath, "info.md"))
+	if !strings.Contains(string(info), "Totem Backup (Imported)") || !strings.Contains(string(info), src) {
+		t.Errorf("info.md = %s", info)
+	}
+	if !exists(filepath.Join(imported.OutputPath, ManifestName)) || !exists(filepath.Join(imported.OutputPath, "saves", "World", "level.dat")) {
+		t.Error("the import has no manifest or lost a file")
+	}
+
+	// A second copy with the same time sits beside the first instead of over it
+	again, err := Import(context.Background(), src, dest, time.Time{}, false)
+	if err != nil || again.OutputPath != imported.OutputPath+"_2" {
+		t.Errorf("second import: %+v, %v", again, err)
+	}
+	if _, err := Import(context.Background(), filepath.Join(src, "missing"), dest, time.Time{}, false); err == nil {
+		t.Error("imported a path that does not exist")
+	}
+}
+
+// A zipped old Totem backup keeps its own info.md and manifest, and its name's time
+func TestImportTotemZip(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "backup_2025-12-24_18-30.zip")
+	writeTestZip(t, src, map[string]string{
+		"backup_2025-12-24_18-30/info.md":     "# 🗿 Totem Backup\n",
+		"backup_2025-12-24_18-30/options.txt": "fov:0.0\n",
+	})
+
+	dest := t.TempDir()
+	imported, err := Import(context.Background(), src, dest, time.Time{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.OutputPath != filepath.Join(dest, "backup_2025-12-24_18-30.zip") || !imported.Totem {
+		t.Errorf("Imported = %+v", imported)
+	}
+	out := t.TempDir()
+	if _, err := Extract(imported.OutputPath, []string{"info.md"}, out); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "info.md")); string(data) != "# 🗿 Totem Backup\n" {
+		t.Errorf("info.md = %q, want the original", data)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dest, ".import-*")); len(leftovers) > 0 {
+		t.Errorf("left %v behind", leftovers)
+	}
+
+	unsafe := filepath.Join(t.TempDir(), "evil.zip")
+	writeTestZip(t, unsafe, map[string]string{"../escaped.txt": "x"})
+	if _, err := Import(context.Background(), unsafe, dest, time.Time{}, false); err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Errorf("importing a zip that escapes its folder: %v", err)
+	}
+}
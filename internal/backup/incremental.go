@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// fileStamp is what an incremental backup compares to tell that a file is unchanged
+type fileStamp struct {
+	Size    int64
+	ModTime time.Time
+	SHA256  string // Hex, reused when a snapshot links the file
+}
+
+// previousFiles returns the size and source modification time of every file the given
+// backups hold, applied oldest first, by slash-separated path in the backup. Files a backup
+// stored without a modification time (generated lists, info.md) are left out, so they are
+// always written.
+func previousFiles(links []string) (map[string]fileStamp, error) {
+	files := map[string]fileStamp{}
+	for _, link := range links {
+		m, err := ReadManifest(link)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range m.Files {
+			if t, ok := m.ModTimes[rel]; ok {
+				files[rel] = fileStamp{Size: m.Sizes[rel], ModTime: t, SHA256: m.SHA256[rel]}
+			} else {
+				delete(files, rel)
+			}
+		}
+	}
+	return files, nil
+}
+
+// incrementalBase returns the files an incremental backup or snapshot can skip or link,
+// and for snapshots the folder to link them from. Other backups get nil. Without a parent
+// (the first backup in a folder) the backup is a full one.
+//
+// An incremental compares against its parent's whole chain. A snapshot only links files
+// present in the parent folder itself, and copies everything when the parent is a zip.
+func incrementalBase(config *tui.Config) (map[string]fileStamp, string, error) {
+	if config.Parent == "" || (!config.Incremental && !config.LinkDest) {
+		return nil, "", nil
+	}
+	path, err := findBackup(config.BackupDest, config.Parent)
+	if err != nil {
+		return nil, "", fmt.Errorf("incremental backup: %w", err)
+	}
+	if config.LinkDest {
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			return nil, "", nil
+		}
+		files, err := previousFiles([]string{path})
+		if err != nil {
+			return nil, "", fmt.Errorf("snapshot: %w", err)
+		}
+		return files, path, nil
+	}
+	chain, err := ResolveChain(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("incremental backup: %w", err)
+	}
+	files, err := previousFiles(chain)
+	if err != nil {
+		return nil, "", fmt.Errorf("incremental backup: %w", err)
+	}
+	return files, "", nil
+}
+
+// unchanged reports whether the file copied to dst has the same size and modification time
+// as in the parent backup, so an incremental backup can leave it to the parent
+func (o *copyOptions) unchanged(dst string, d fs.DirEntry) bool {
+	if o == nil || o.previous == nil {
+		return false
+	}
+	rel, err := filepath.Rel(o.backupRoot, dst)
+	if err != nil {
+		return false
+	}
+	prev, ok := o.previous[filepath.ToSlash(rel)]
+	if !ok {
+		return false
+	}
+	info, err := d.Info()
+	return err == nil && info.Size() == prev.Size && info.ModTime().Equal(prev.ModTime)
+}
+
+// linkUnchanged hard-links dst to the same file in the parent snapshot, carrying its hash
+// and modification time over to the new manifest
+func (o *copyOptions) linkUnchanged(dst string) error {
+	rel, err := filepath.Rel(o.backupRoot, dst)
+	if err != nil {
+		return err
+	}
+	if err := os.Link(filepath.Join(o.linkFrom, rel), dst); err != nil {
+		return err
+	}
+	prev := o.previous[filepath.ToSlash(rel)]
+	if sum, err := hex.DecodeString(prev.SHA256); err == nil && len(sum) > 0 {
+		o.hashes.record(dst, sum)
+	}
+	o.hashes.recordModTime(dst, prev.ModTime)
+	return nil
+}
+
+// renderIncrementalSection notes which backup an incremental or snapshot builds on
+func renderIncrementalSection(config *tui.Config, stats Stats) string {
+	switch {
+	case config.Parent == "":
+		return ""
+	case config.Incremental:
+		return fmt.Sprintf("\n## 🧩 Incremental Backup\n\n%d files unchanged since `%s` were left to it; restore this backup with `totem restore` to rebuild the whole chain.\n",
+			stats.UnchangedSkipped, config.Parent)
+	case config.LinkDest:
+		return fmt.Sprintf("\n## 🔗 Snapshot\n\n%d files unchanged since `%s` are hard links to it, so they take no extra space. This folder is still a complete backup.\n",
+			stats.FilesLinked, config.Parent)
+	}
+	return ""
+}
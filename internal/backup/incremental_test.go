@@ -0,0 +1,132 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// touchFuture gives a source file a new modification time, so the next backup sees a change
+func touchFuture(t *testing.T, root, path string) {
+	t.Helper()
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(path)), future, future); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Each incremental stores only what changed since its parent's chain, and restores as a whole
+func TestIncrementalChain(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "saves/World/region/r.0.0.mca", "region")
+	dest := t.TempDir()
+	backupOf := func(parent string) *Result {
+		t.Helper()
+		config := &tui.Config{MinecraftPath: mc, BackupDest: dest, IncludeSaves: true}
+		if parent != "" {
+			config.Incremental, config.Parent = true, parent
+		}
+		result, err := PerformContext(context.Background(), config, nil)
+		if err != nil || !result.Success {
+			t.Fatalf("PerformContext: %v", err)
+		}
+		return result
+	}
+	full := backupOf("")
+
+	writeTestFile(t, mc, "saves/World/level.dat", "level 2")
+	touchFuture(t, mc, "saves/World/level.dat")
+	first := backupOf(filepath.Base(full.OutputPath))
+	writeTestFile(t, mc, "saves/World/playerdata/alex.dat", "alex")
+	touchFuture(t, mc, "saves/World/playerdata/alex.dat")
+	second := backupOf(filepath.Base(first.OutputPath))
+
+	// options.txt is copied on its own, so only the world's files are skipped
+	if first.Stats.UnchangedSkipped != 1 || second.Stats.UnchangedSkipped != 2 {
+		t.Errorf("skipped %d and %d unchanged files, want 1 and 2", first.Stats.UnchangedSkipped, second.Stats.UnchangedSkipped)
+	}
+	m, err := ReadManifest(second.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != TypeIncremental || m.Parent != filepath.Base(first.OutputPath) {
+		t.Errorf("manifest is %s on %q", m.Type, m.Parent)
+	}
+	if !slices.Contains(m.Files, "saves/World/playerdata/alex.dat") || slices.Contains(m.Files, "saves/World/level.dat") {
+		t.Errorf("the second link holds %q, want only what changed", m.Files)
+	}
+	if m.SHA256["saves/World/playerdata/alex.dat"] == "" || m.ModTimes["saves/World/playerdata/alex.dat"].IsZero() {
+		t.Error("the manifest has no hash or modification time for the new file")
+	}
+	info, _ := os.ReadFile(filepath.Join(second.OutputPath, "info.md"))
+	if !strings.Contains(string(info), "## 🧩 Incremental Backup") {
+		t.Error("info.md does not say it is an incremental")
+	}
+
+	restored := t.TempDir()
+	if _, err := Restore(second.OutputPath, restored); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"options.txt":                     "fov:0.0\n",
+		"saves/World/level.dat":           "level 2",
+		"saves/World/region/r.0.0.mca":    "region",
+		"saves/World/playerdata/alex.dat": "alex",
+	} {
+		if got, _ := os.ReadFile(filepath.Join(restored, filepath.FromSlash(path))); string(got) != want {
+			t.Errorf("restored %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// A snapshot is a complete backup whose unchanged files are hard links into its parent
+func TestLinkDestSnapshot(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "saves/World/region/r.0.0.mca", "region")
+	dest := t.TempDir()
+	full, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, IncludeSaves: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, mc, "saves/World/level.dat", "level 2")
+	touchFuture(t, mc, "saves/World/level.dat")
+	snap, err := PerformContext(context.Background(), &tui.Config{
+		MinecraftPath: mc, BackupDest: dest, IncludeSaves: true, LinkDest: true, Parent: filepath.Base(full.OutputPath),
+	}, nil)
+	if err != nil || !snap.Success {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if snap.Stats.FilesLinked != 1 {
+		t.Errorf("linked %d files, want 1", snap.Stats.FilesLinked)
+	}
+	same := func(rel string) bool {
+		a, _ := os.Stat(filepath.Join(full.OutputPath, filepath.FromSlash(rel)))
+		b, _ := os.Stat(filepath.Join(snap.OutputPath, filepath.FromSlash(rel)))
+		return a != nil && b != nil && os.SameFile(a, b)
+	}
+	if !same("saves/World/region/r.0.0.mca") || same("saves/World/level.dat") {
+		t.Error("want only the unchanged region file linked to the parent")
+	}
+
+	m, err := ReadManifest(snap.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != TypeFull || !slices.Contains(m.Files, "saves/World/region/r.0.0.mca") || m.SHA256["saves/World/region/r.0.0.mca"] == "" {
+		t.Errorf("snapshot manifest is %s with %q; want a full backup listing the linked file with its hash", m.Type, m.Files)
+	}
+	if report, err := VerifyFiles(snap.OutputPath); err != nil || len(report.Missing)+len(report.Corrupted) > 0 {
+		t.Errorf("VerifyFiles = %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,203 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// JournalSuffix is appended to a backup folder's path for its progress journal.
+// The journal lives next to the folder so it never ends up in the manifest or zip.
+const JournalSuffix = ".journal"
+
+// ErrCancelled is returned when a backup is stopped part way; its journal is kept for resume
+var ErrCancelled = errors.New("backup cancelled; run totem resume to finish it")
+
+// journal records which steps of a backup have finished so a crashed run can resume
+type journal struct {
+	Config     tui.Config `json:"config"`
+	BackupPath string     `json:"backup_path"`
+	Started    time.Time  `json:"started"`
+	Completed  []string   `json:"completed"`
+	Result     Result     `json:"result"`           // Stats and errors of the completed steps
+	Remote     string     `json:"remote,omitempty"` // sftp destination a staged backup is uploaded to
+}
+
+func (j *journal) path() string {
+	return j.BackupPath + JournalSuffix
+}
+
+// completed reports whether a step already finished in an earlier run
+func (j *journal) completed(step string) bool {
+	return j != nil && slices.Contains(j.Completed, step)
+}
+
+// complete marks a step finished and persists the journal
+func (j *journal) complete(step string, result *Result) error {
+	if j == nil {
+		return nil
+	}
+	j.Completed = append(j.Completed, step)
+	j.Result = *result
+	return j.save()
+}
+
+// save writes the journal atomically so a crash mid-write can't corrupt it
+func (j *journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := j.path() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path())
+}
+
+// remove deletes the journal once the backup is done
+func (j *journal) remove() {
+	if j != nil {
+		os.Remove(j.path())
+	}
+}
+
+func readJournal(path string) (*journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("corrupt journal %s: %w", path, err)
+	}
+	return &j, nil
+}
+
+// FindJournals lists unfinished backups in dest
+func FindJournals(dest string) ([]string, error) {
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		return nil, err
+	}
+	var journals []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), JournalSuffix) {
+			journals = append(journals, filepath.Join(dest, e.Name()))
+		}
+	}
+	return journals, nil
+}
+
+// Resume continues an interrupted backup from its journal. A backup staged for an sftp
+// destination is uploaded once it finishes.
+func Resume(journalPath string, progress *Progress) (*Result, error) {
+	j, err := readJournal(journalPath)
+	if err != nil {
+		return nil, err
+	}
+	if j.Remote == "" {
+		return resume(j, progress)
+	}
+	remote, ok := ParseRemoteDest(j.Remote)
+	if !ok {
+		return nil, fmt.Errorf("corrupt journal %s: bad sftp destination %q", journalPath, j.Remote)
+	}
+	if err := checkRemote(remote); err != nil {
+		return nil, err
+	}
+	config := j.Config
+	config.ZipPassword = os.Getenv(ZipPasswordEnv)
+	return uploadStaged(filepath.Dir(j.BackupPath), &config, remote, func() (*Result, error) { return resume(j, progress) })
+}
+
+func resume(j *journal, progress *Progress) (*Result, error) {
+	// The crash happened after archiving, which removed the folder; the archive may still
+	// need encrypting, splitting or parity
+	if !exists(j.BackupPath) {
+		out := archivedOutput(j)
+		if out == "" {
+			return nil, fmt.Errorf("backup folder %s no longer exists", j.BackupPath)
+		}
+		enc, err := encryptionFor(j.Config.BackupDest)
+		if err != nil {
+			return nil, err
+		}
+		result := j.Result
+		result.OutputPath = out
+		finishArchive(&j.Config, enc, &result, j, func(step string, start time.Time) {
+			result.Stats.timeStep(step, start)
+			j.complete(step, &result)
+		})
+		j.remove()
+		result.Success = len(result.Errors) == 0
+		return &result, nil
+	}
+
+	config := j.Config
+	// The password is never written to the journal
+	if config.ProtectZip {
+		if config.ZipPassword = os.Getenv(ZipPasswordEnv); config.ZipPassword == "" {
+			return nil, fmt.Errorf("this backup makes a password-protected zip; set %s to resume it", ZipPasswordEnv)
+		}
+	}
+	return perform(context.Background(), &config, progress, j)
+}
+
+// archivedOutput finds the archive a backup was turned into before a crash, or "" if there
+// is none. The journal records it once Zip finishes; an older journal is matched by name.
+func archivedOutput(j *journal) string {
+	if out := j.Result.OutputPath; j.completed("Zip") && out != "" && out != j.BackupPath && exists(out) {
+		return out
+	}
+	for _, suffix := range archiveSuffixes {
+		for _, out := range []string{j.BackupPath + suffix, j.BackupPath + suffix + firstPart} {
+			if exists(out) {
+				return out
+			}
+		}
+	}
+	return ""
+}
+
+// sameFile reports whether dst is already a complete copy of src (same size and hash)
+func sameFile(src, dst string) bool {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false
+	}
+	dstInfo, err := os.Stat(dst)
+	if err != nil || dstInfo.Size() != srcInfo.Size() {
+		return false
+	}
+	srcSum, err := hashFile(src)
+	if err != nil {
+		return false
+	}
+	dstSum, err := hashFile(dst)
+	return err == nil && bytes.Equal(srcSum, dstSum)
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
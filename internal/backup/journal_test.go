@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A crash after Zip must not leave the archive marked done while steps after it are missing
+func TestResumeFinishesArchiveSteps(t *testing.T) {
+	dest := t.TempDir()
+	backupPath := filepath.Join(dest, "backup_2026-01-01_00-00")
+	zipPath := backupPath + ".zip"
+	// Random data so the zip stays larger than a split part
+	noise := make([]byte, 16<<10)
+	rand.Read(noise)
+	writeTestZip(t, zipPath, map[string]string{"level.dat": string(noise)})
+
+	j := &journal{
+		Config:     tui.Config{BackupDest: dest, ZipOutput: true, SplitSize: 4096, Parity: true},
+		BackupPath: backupPath,
+		Completed:  []string{"Screenshots", "Zip"},
+		Result:     Result{OutputPath: zipPath},
+	}
+	if err := j.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Resume(j.path(), nil)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if !result.Success || result.OutputPath != zipPath+firstPart {
+		t.Fatalf("Resume = %s, %v; want the first split part", result.OutputPath, result.Errors)
+	}
+	if exists(zipPath) {
+		t.Error("the unsplit archive was kept")
+	}
+	for _, part := range SplitParts(result.OutputPath) {
+		if !exists(part + ParitySuffix) {
+			t.Errorf("%s has no parity file", filepath.Base(part))
+		}
+	}
+	if _, err := os.Stat(j.path()); !os.IsNotExist(err) {
+		t.Error("the journal was kept after the backup finished")
+	}
+}
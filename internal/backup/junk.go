@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Files that are never worth backing up (lock files, OS metadata)
+var junkFiles = map[string]bool{
+	"session.lock": true,
+	".ds_store":    true,
+	"thumbs.db":    true,
+	"desktop.ini":  true,
+}
+
+// Folders that only hold temporary or regenerable data
+var junkDirs = map[string]bool{
+	"##mcedit.temp##": true,
+}
+
+// Folders Xaero's minimap and world map keep their data in, current and older versions
+var xaeroDirs = map[string]bool{
+	"xaero":          true,
+	"xaeroworldmap":  true,
+	"xaerowaypoints": true,
+}
+
+// Suffixes of transient SQLite files written by Distant Horizons
+var junkSuffixes = []string{"-wal", "-shm"}
+
+// isJunk reports whether an entry should be skipped by the default exclude set. rel is
+// its path in the instance (or launcher folder), which scopes rules to one mod's data.
+func isJunk(rel string, d fs.DirEntry) bool {
+	name := strings.ToLower(d.Name())
+	if d.IsDir() {
+		// Xaero keeps regenerable caches in folders like "cache" or "cache_1.20"; other
+		// mods' and worlds' cache folders may hold data that can't be rebuilt
+		isCache := name == "cache" || strings.HasPrefix(name, "cache_")
+		return junkDirs[name] || isCache && underXaero(rel)
+	}
+	if junkFiles[name] {
+		return true
+	}
+	for _, suffix := range junkSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// underXaero reports whether rel is inside one of Xaero's data folders
+func underXaero(rel string) bool {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for _, part := range parts[:len(parts)-1] {
+		if xaeroDirs[strings.ToLower(part)] {
+			return true
+		}
+	}
+	return false
+}
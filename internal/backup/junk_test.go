@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+func TestCacheFoldersOnlyJunkUnderXaero(t *testing.T) {
+	mc := t.TempDir()
+	junk := []string{
+		"xaero/world-map/Server/cache_1.20/a.bin",
+		"XaeroWorldMap/World/cache/b.bin",
+		"XaeroWaypoints/cache/c.bin",
+	}
+	kept := []string{
+		"saves/World/cache/d.bin",
+		"config/somemod/cache/e.bin",
+		"mods/cache_data/f.bin",
+	}
+	for _, p := range append(junk, kept...) {
+		writeTestFile(t, mc, p, "x")
+	}
+
+	isCacheJunk := func(p string) bool {
+		dir := filepath.Dir(filepath.Join(mc, filepath.FromSlash(p)))
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, _ := filepath.Rel(mc, dir)
+		return isJunk(rel, fs.FileInfoToDirEntry(info))
+	}
+	for _, p := range junk {
+		if !isCacheJunk(p) {
+			t.Errorf("%s: cache folder not treated as junk", filepath.Dir(p))
+		}
+	}
+	for _, p := range kept {
+		if isCacheJunk(p) {
+			t.Errorf("%s: treated as junk outside Xaero's folders", filepath.Dir(p))
+		}
+	}
+
+	opts := newCopyOptions(&tui.Config{MinecraftPath: mc, SkipJunk: true}, nil)
+	dst := t.TempDir()
+	if _, err := copyDir(filepath.Join(mc, "saves"), filepath.Join(dst, "saves"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "saves", "World", "cache", "d.bin")); err != nil {
+		t.Errorf("a world's cache folder was not copied: %v", err)
+	}
+	if _, err := copyDir(filepath.Join(mc, "xaero"), filepath.Join(dst, "xaero"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "xaero", "world-map", "Server", "cache_1.20")); err == nil {
+		t.Error("Xaero's cache folder was copied")
+	}
+}
@@ -0,0 +1,196 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Launcher folders left out of a launcher archive because the launcher downloads them again.
+// They are only skipped outside the instances folder, where an instance's own copies live.
+var launcherDownloads = map[string]string{
+	"assets":    "game assets, downloaded again by the launcher",
+	"libraries": "libraries, downloaded again by the launcher",
+	"meta":      "launcher metadata, downloaded again by the launcher",
+	"runtime":   "bundled Java, downloaded again by the launcher",
+	"java":      "bundled Java, downloaded again by the launcher",
+}
+
+// Launcher caches, always left out
+var launcherCaches = map[string]string{
+	"cache":     "launcher cache",
+	"caches":    "launcher cache",
+	"webcache":  "launcher web cache",
+	"webcache2": "launcher web cache",
+}
+
+// Launcher files that are never archived
+var launcherSecrets = map[string]string{
+	"accounts.json": "sign-in tokens; sign in again after restoring",
+}
+
+// Folders launchers keep their instances in
+var instanceFolders = map[string]bool{"instances": true, "profiles": true}
+
+// SkippedItem is something a launcher archive intentionally left out
+type SkippedItem struct {
+	Path   string // Relative to the launcher folder
+	Size   int64
+	Reason string
+}
+
+// LauncherArchive is the outcome of ArchiveLauncher
+type LauncherArchive struct {
+	OutputPath  string
+	Files       int
+	JunkSkipped int
+	Skipped     []SkippedItem // Largest first
+	SkippedSize int64
+}
+
+// launcherSkipReason explains why rel (relative to the launcher folder) is left out, or returns "".
+// With keepDownloads, only caches and secrets are left out.
+func launcherSkipReason(rel string, d fs.DirEntry, keepDownloads bool) string {
+	parts := strings.Split(strings.ToLower(filepath.ToSlash(rel)), "/")
+	if instanceFolders[parts[0]] {
+		return ""
+	}
+	name := strings.ToLower(d.Name())
+	if !d.IsDir() {
+		if reason := launcherSecrets[name]; reason != "" {
+			return reason
+		}
+		// versions/ also holds version JSONs, which custom and modded profiles need
+		if !keepDownloads && parts[0] == "versions" && strings.HasSuffix(name, ".jar") {
+			return "game jar, downloaded again by the launcher"
+		}
+		return ""
+	}
+	if reason := launcherCaches[name]; reason != "" {
+		return reason
+	}
+	if keepDownloads {
+		return ""
+	}
+	return launcherDownloads[name]
+}
+
+// ArchiveLauncher copies a launcher's whole data folder (instances, icons, settings) into a new
+// backup folder in dest, leaving out what the launcher can download again unless keepDownloads
+// is set, and writes skipped.md listing everything that was left out and why
+func ArchiveLauncher(ctx context.Context, dataDir, dest string, zipOutput, keepDownloads bool) (*LauncherArchive, error) {
+	config := &tui.Config{MinecraftPath: dataDir, BackupDest: dest, SkipJunk: true, ZipOutput: zipOutput}
+	if _, err := os.Stat(dataDir); err != nil {
+		return nil, fmt.Errorf("launcher folder does not exist: %s", dataDir)
+	}
+	if err := LocalOnly("a launcher archive", dest); err != nil {
+		return nil, err
+	}
+	if err := checkDestination(config); err != nil {
+		return nil, err
+	}
+
+	out := newBackupPath(dest, time.Now())
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup folder: %w", err)
+	}
+	opts := newCopyOptions(config, nil)
+	opts.ctx = ctx
+	archive := &LauncherArchive{}
+
+	err := walkDirFollow(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if opts.cancelled() {
+			return ErrCancelled
+		}
+		if path == dataDir {
+			return nil
+		}
+		rel, _ := filepath.Rel(dataDir, path)
+
+		if reason := launcherSkipReason(rel, d, keepDownloads); reason != "" {
+			var size int64
+			if d.IsDir() {
+				size = getDirSize(path, nil)
+			} else if info, err := d.Info(); err == nil {
+				size = info.Size()
+			}
+			archive.Skipped = append(archive.Skipped, SkippedItem{Path: filepath.ToSlash(rel), Size: size, Reason: reason})
+			archive.SkippedSize += size
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isJunk(rel, d) {
+			opts.JunkSkipped++
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(out, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if err := opts.copyFile(path, target); err != nil {
+			return err
+		}
+		archive.Files++
+		return nil
+	})
+	if err != nil {
+		// Launcher archives have no journal, so a partial one is only clutter
+		os.RemoveAll(out)
+		return nil, err
+	}
+	archive.JunkSkipped = opts.JunkSkipped
+
+	sort.SliceStable(archive.Skipped, func(i, j int) bool { return archive.Skipped[i].Size > archive.Skipped[j].Size })
+	if err := os.WriteFile(filepath.Join(out, "skipped.md"), []byte(renderSkippedLauncherMD(dataDir, archive)), 0644); err != nil {
+		return nil, err
+	}
+	if err := writeManifest(out, config, opts.hashes); err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+
+	archive.OutputPath = out
+	if zipOutput {
+		zipPath := out + ".zip"
+		if err := createZip(out, zipPath, nil, "", nil); err != nil {
+			return nil, fmt.Errorf("zip: %w", err)
+		}
+		os.RemoveAll(out)
+		archive.OutputPath = zipPath
+	}
+	return archive, nil
+}
+
+// renderSkippedLauncherMD lists what a launcher archive left out, so nothing is missed by surprise
+func renderSkippedLauncherMD(dataDir string, archive *LauncherArchive) string {
+	var b strings.Builder
+	b.WriteString("# 🗿 Totem Launcher Archive\n\n")
+	b.WriteString(fmt.Sprintf("Archived `%s`: %d files copied, %d junk files skipped.\n\n", dataDir, archive.Files, archive.JunkSkipped))
+	if len(archive.Skipped) == 0 {
+		b.WriteString("Nothing else was left out.\n")
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("## Intentionally Skipped (%s)\n\n", formatBytes(archive.SkippedSize)))
+	b.WriteString("| Path | Size | Why |\n|------|------|-----|\n")
+	for _, s := range archive.Skipped {
+		b.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", s.Path, formatBytes(s.Size), s.Reason))
+	}
+	b.WriteString("\nRestore the folder and start the launcher once; it downloads the skipped files again.\n")
+	b.WriteString("Run `totem archive-launcher --keep-downloads` to include them.\n")
+	return b.String()
+}
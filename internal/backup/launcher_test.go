@@ -0,0 +1,83 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTestLauncher lays out a Prism-like launcher folder
+func writeTestLauncher(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for path, contents := range map[string]string{
+		"prismlauncher.cfg":                       "Language=en",
+		"accounts.json":                           `{"token":"secret"}`,
+		"assets/objects/ab/abcd":                  "asset",
+		"libraries/lwjgl.jar":                     "lib",
+		"cache/icon.png":                          "cache",
+		"versions/1.21/1.21.jar":                  "game jar",
+		"versions/1.21/1.21.json":                 "{}",
+		"instances/Fabric/.minecraft/options.txt": "fov:0.0\n",
+		"instances/Fabric/.minecraft/assets/a":    "an instance's own assets",
+	} {
+		writeTestFile(t, dir, path, contents)
+	}
+	return dir
+}
+
+// A launcher archive keeps instances and settings, leaves out sign-in tokens, caches and
+// what the launcher downloads again, and lists each of those in skipped.md
+func TestArchiveLauncher(t *testing.T) {
+	dir := writeTestLauncher(t)
+	archive, err := ArchiveLauncher(context.Background(), dir, t.TempDir(), false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kept := range []string{"prismlauncher.cfg", "versions/1.21/1.21.json", "instances/Fabric/.minecraft/options.txt", "instances/Fabric/.minecraft/assets/a"} {
+		if !exists(filepath.Join(archive.OutputPath, filepath.FromSlash(kept))) {
+			t.Errorf("%s was left out", kept)
+		}
+	}
+	skipped := map[string]bool{}
+	for _, s := range archive.Skipped {
+		skipped[s.Path] = true
+	}
+	for _, left := range []string{"accounts.json", "assets", "libraries", "cache", "versions/1.21/1.21.jar"} {
+		if exists(filepath.Join(archive.OutputPath, filepath.FromSlash(left))) || !skipped[left] {
+			t.Errorf("%s: archived, or not listed as skipped", left)
+		}
+	}
+	md, _ := os.ReadFile(filepath.Join(archive.OutputPath, "skipped.md"))
+	if !strings.Contains(string(md), "| `accounts.json` |") {
+		t.Errorf("skipped.md does not list accounts.json:\n%s", md)
+	}
+}
+
+// --keep-downloads archives assets, libraries and game jars too, but never caches or tokens
+func TestArchiveLauncherKeepDownloads(t *testing.T) {
+	dir := writeTestLauncher(t)
+	archive, err := ArchiveLauncher(context.Background(), dir, t.TempDir(), true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(archive.OutputPath, ".zip") {
+		t.Fatalf("OutputPath = %s, want a zip", archive.OutputPath)
+	}
+	var skipped []string
+	for _, s := range archive.Skipped {
+		skipped = append(skipped, s.Path)
+	}
+	slices.Sort(skipped)
+	if strings.Join(skipped, ",") != "accounts.json,cache" {
+		t.Errorf("skipped %v, want only accounts.json and cache", skipped)
+	}
+
+	out := t.TempDir()
+	if _, err := Extract(archive.OutputPath, []string{"versions/1.21/1.21.jar", "libraries"}, out); err != nil {
+		t.Fatalf("the downloads are not in the archive: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// walkDirFollow works like filepath.WalkDir but follows symlinks and Windows junctions,
+// which launchers like Prism use to keep saves on another drive. Each real directory is
+// visited once, so link loops end and a folder linked twice is not counted twice.
+func walkDirFollow(root string, fn fs.WalkDirFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkFollow(root, fs.FileInfoToDirEntry(info), fn, sameTarget{})
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkFollow(path string, d fs.DirEntry, fn fs.WalkDirFunc, visited sameTarget) error {
+	if d.IsDir() && visited.seen(path) {
+		return nil
+	}
+
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			return nil
+		}
+		return err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if err := fn(path, d, err); err != nil {
+			if err == filepath.SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		child := filepath.Join(path, e.Name())
+		// Junctions show up as irregular files; resolve both kinds of link to their target
+		if e.Type()&(fs.ModeSymlink|fs.ModeIrregular) != 0 {
+			info, err := os.Stat(child)
+			if err != nil {
+				if err := fn(child, e, err); err != nil {
+					if err == filepath.SkipDir {
+						break
+					}
+					return err
+				}
+				continue
+			}
+			e = fs.FileInfoToDirEntry(info)
+		}
+		if err := walkFollow(child, e, fn, visited); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// isDirLink reports whether an entry of dir is a directory, following symlinks and junctions
+func isDirLink(dir string, e fs.DirEntry) bool {
+	if e.IsDir() {
+		return true
+	}
+	if e.Type()&(fs.ModeSymlink|fs.ModeIrregular) == 0 {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, e.Name()))
+	return err == nil && info.IsDir()
+}
+
+// sameTarget tracks directories by file identity so two links to one folder are copied
+// once. Resolved paths won't do: filepath.EvalSymlinks no longer follows Windows junctions
+// (Go 1.23), so a junction loop would look like a new folder at every level.
+type sameTarget map[dirKey][]os.FileInfo
+
+// dirKey narrows down which recorded directories os.SameFile has to compare against
+type dirKey struct {
+	modTime int64
+	size    int64
+}
+
+// seen reports whether path resolves to a directory already recorded, recording it if not
+func (s sameTarget) seen(path string) bool {
+	// Stat follows symlinks and junctions, so a link has its target's identity
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	key := dirKey{info.ModTime().UnixNano(), info.Size()}
+	for _, other := range s[key] {
+		if os.SameFile(info, other) {
+			return true
+		}
+	}
+	s[key] = append(s[key], info)
+	return false
+}
@@ -0,0 +1,80 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// symlinkOrSkip links name to target, skipping the test where links can't be made
+func symlinkOrSkip(t *testing.T, target, name string) {
+	t.Helper()
+	if err := os.Symlink(target, name); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+}
+
+// countFiles counts the regular files under root, without following links
+func countFiles(t *testing.T, root string) int {
+	t.Helper()
+	n := 0
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSelfLoopSymlink(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, src, "World/level.dat", "12345")
+	symlinkOrSkip(t, src, filepath.Join(src, "World", "loop"))
+
+	opts := newCopyOptions(&tui.Config{}, nil)
+	if size := getDirSize(src, opts); size != 5 {
+		t.Errorf("getDirSize = %d, want 5", size)
+	}
+	dst := filepath.Join(t.TempDir(), "copy")
+	count, err := copyDir(src, dst, opts)
+	if err != nil {
+		t.Fatalf("copyDir: %v", err)
+	}
+	if count != 1 || countFiles(t, dst) != 1 {
+		t.Errorf("copyDir copied %d files (%d on disk), want 1", count, countFiles(t, dst))
+	}
+}
+
+func TestDirectoryLinkedTwice(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, src, "real/region/r.0.0.mca", "1234567890")
+	symlinkOrSkip(t, filepath.Join(src, "real"), filepath.Join(src, "first"))
+	symlinkOrSkip(t, filepath.Join(src, "real"), filepath.Join(src, "second"))
+
+	opts := newCopyOptions(&tui.Config{}, nil)
+	if size := getDirSize(src, opts); size != 10 {
+		t.Errorf("getDirSize = %d, want 10", size)
+	}
+	dst := filepath.Join(t.TempDir(), "copy")
+	count, err := copyDir(src, dst, opts)
+	if err != nil {
+		t.Fatalf("copyDir: %v", err)
+	}
+	if count != 1 || countFiles(t, dst) != 1 {
+		t.Errorf("copyDir copied %d files (%d on disk), want 1", count, countFiles(t, dst))
+	}
+
+	targets := sameTarget{}
+	if targets.seen(filepath.Join(src, "first")) || !targets.seen(filepath.Join(src, "second")) || !targets.seen(filepath.Join(src, "real")) {
+		t.Error("sameTarget does not see both links and the folder as one directory")
+	}
+}
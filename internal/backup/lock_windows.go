@@ -0,0 +1,12 @@
+package backup
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isLockError reports whether err means another process (often antivirus) holds the file
+func isLockError(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+}
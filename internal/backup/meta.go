@@ -0,0 +1,34 @@
+package backup
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MatchesMeta reports whether a backup's labels pass every filter: key=value matches the
+// value (ignoring case), a bare key matches any backup that has the label
+func MatchesMeta(meta map[string]string, filters []string) bool {
+	for _, f := range filters {
+		key, want, hasValue := strings.Cut(f, "=")
+		got, ok := meta[key]
+		if !ok || (hasValue && !strings.EqualFold(got, want)) {
+			return false
+		}
+	}
+	return true
+}
+
+// renderMetaSection lists the labels a backup was given with --meta
+func renderMetaSection(meta map[string]string) string {
+	if len(meta) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n## 🏷️ Labels\n\n| Key | Value |\n|-----|-------|\n")
+	for _, k := range slices.Sorted(maps.Keys(meta)) {
+		fmt.Fprintf(&b, "| %s | %s |\n", k, meta[k])
+	}
+	return b.String()
+}
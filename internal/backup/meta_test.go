@@ -0,0 +1,57 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+func TestMatchesMeta(t *testing.T) {
+	meta := map[string]string{"season": "3", "server": "Hypixel"}
+	for _, c := range []struct {
+		filters []string
+		want    bool
+	}{
+		{nil, true},
+		{[]string{"season=3"}, true},
+		{[]string{"server=hypixel", "season"}, true},
+		{[]string{"season=4"}, false},
+		{[]string{"seed"}, false},
+		{[]string{"season=3", "seed"}, false},
+	} {
+		if got := MatchesMeta(meta, c.filters); got != c.want {
+			t.Errorf("MatchesMeta(%v) = %v, want %v", c.filters, got, c.want)
+		}
+	}
+	if MatchesMeta(nil, []string{"season"}) {
+		t.Error("an unlabelled backup matched a filter")
+	}
+}
+
+// Labels reach the catalog, where totem list filters on them, and info.md
+func TestMetaRecorded(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	dest := t.TempDir()
+	meta := map[string]string{"season": "3", "seed": "-4172144997902289642"}
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, Meta: meta}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+
+	items, _ := ListBackups(dest)
+	if len(items) != 1 || !MatchesMeta(items[0].Meta, []string{"season=3", "seed"}) {
+		t.Errorf("ListBackups = %+v, want the labels", items)
+	}
+	info, _ := os.ReadFile(filepath.Join(result.OutputPath, "info.md"))
+	if !strings.Contains(string(info), "## 🏷️ Labels") || !strings.Contains(string(info), "| seed | -4172144997902289642 |") {
+		t.Errorf("info.md has no labels:\n%s", info)
+	}
+	if renderMetaSection(nil) != "" {
+		t.Error("a backup without labels got a labels section")
+	}
+}
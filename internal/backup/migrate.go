@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// archiveTimePattern finds the date and time other backup tools put in archive names,
+// e.g. AromaBackup's "2023-05-01--12-30-45" or FTB Backups' "2023-05-01_12-30-45"
+var archiveTimePattern = regexp.MustCompile(`(\d{4})-(\d{1,2})-(\d{1,2})[_\-T ]+(\d{1,2})[-.:h](\d{2})(?:[-.:m](\d{2}))?`)
+
+// archiveNameTime parses the timestamp in a community backup's file name
+func archiveNameTime(name string) (time.Time, bool) {
+	m := archiveTimePattern.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, false
+	}
+	n := make([]int, 6)
+	for i, s := range m[1:] {
+		n[i], _ = strconv.Atoi(s)
+	}
+	if n[1] < 1 || n[1] > 12 || n[2] < 1 || n[2] > 31 || n[3] > 23 || n[4] > 59 {
+		return time.Time{}, false
+	}
+	return time.Date(n[0], time.Month(n[1]), n[2], n[3], n[4], n[5], 0, time.Local), true
+}
+
+// worldArchiveDirs returns the worlds in an unpacked world backup (AromaBackup, FTB Backups
+// or a plain world zip): root itself when it holds level.dat, otherwise its subfolders that do.
+// Instance copies and Totem backups return nil, since they keep worlds under saves/.
+func worldArchiveDirs(root string) []string {
+	if looksLikeMinecraft(root) || exists(filepath.Join(root, "info.md")) || exists(filepath.Join(root, ManifestName)) {
+		return nil
+	}
+	if exists(filepath.Join(root, "level.dat")) {
+		return []string{root}
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var worlds []string
+	for _, e := range entries {
+		if e.IsDir() && exists(filepath.Join(root, e.Name(), "level.dat")) {
+			worlds = append(worlds, filepath.Join(root, e.Name()))
+		}
+	}
+	return worlds
+}
+
+// worldName returns a world's name from its level.dat, or fallback when unreadable
+func worldName(dir, fallback string) string {
+	if data, err := readLevelDat(filepath.Join(dir, "level.dat")); err == nil {
+		if name, ok := nbtCompound(data, "Data")["LevelName"].(string); ok && strings.TrimSpace(name) != "" {
+			return safeFileName(name)
+		}
+	}
+	return fallback
+}
+
+// safeFileName replaces characters that are not allowed in folder names
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+}
+
+// ImportSources expands a folder of backup archives (such as AromaBackup's or FTB Backups'
+// backups folder, one level of world subfolders deep) into the archives it holds, oldest name
+// first. Anything else is returned as is.
+func ImportSources(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() || looksLikeMinecraft(path) || len(worldArchiveDirs(path)) > 0 ||
+		exists(filepath.Join(path, "info.md")) || exists(filepath.Join(path, ManifestName)) {
+		return []string{path}
+	}
+	var archives []string
+	for _, pattern := range []string{"*.zip", "*/*.zip"} {
+		matches, _ := filepath.Glob(filepath.Join(path, pattern))
+		archives = append(archives, matches...)
+	}
+	if len(archives) == 0 {
+		return []string{path}
+	}
+	sort.Strings(archives)
+	return archives
+}
@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestArchiveNameTime(t *testing.T) {
+	for name, want := range map[string]time.Time{
+		"2023-05-01--12-30-45.zip":     time.Date(2023, 5, 1, 12, 30, 45, 0, time.Local),
+		"Skyblock_2023-05-01_12-30-45": time.Date(2023, 5, 1, 12, 30, 45, 0, time.Local),
+		"world 2023-5-1 9.05.zip":      time.Date(2023, 5, 1, 9, 5, 0, 0, time.Local),
+	} {
+		if got, ok := archiveNameTime(name); !ok || !got.Equal(want) {
+			t.Errorf("archiveNameTime(%q) = %v, %v; want %v", name, got, ok, want)
+		}
+	}
+	for _, name := range []string{"world.zip", "2023-13-01_12-30.zip", "2023-05-01_25-00.zip"} {
+		if got, ok := archiveNameTime(name); ok {
+			t.Errorf("archiveNameTime(%q) = %v; want no time", name, got)
+		}
+	}
+}
+
+// levelDatBytes returns a gzipped level.dat for a world called name ("" for none)
+func levelDatBytes(t *testing.T, name string) string {
+	t.Helper()
+	dir := t.TempDir()
+	data := map[string]any{"Version": map[string]any{"Name": "1.20.1"}}
+	if name != "" {
+		data["LevelName"] = name
+	}
+	writeLevelDat(t, dir, "level.dat", map[string]any{"Data": data})
+	b, err := os.ReadFile(filepath.Join(dir, "level.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// AromaBackup and FTB Backups folders import as one backup per archive, worlds under saves/
+func TestImportWorldArchives(t *testing.T) {
+	backups := filepath.Join(t.TempDir(), "backups")
+	if err := os.MkdirAll(filepath.Join(backups, "Hardcore"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// AromaBackup: one folder per world, archives named by date holding the world's files
+	aroma := filepath.Join(backups, "Hardcore", "2023-05-01--12-30-45.zip")
+	writeTestZip(t, aroma, map[string]string{"level.dat": levelDatBytes(t, ""), "region/r.0.0.mca": "region"})
+	// FTB Backups: every world in one archive, each in its own folder
+	ftb := filepath.Join(backups, "2023-05-02_08-00-00.zip")
+	writeTestZip(t, ftb, map[string]string{
+		"New World/level.dat":     levelDatBytes(t, "Skyblock"),
+		"New World (1)/level.dat": levelDatBytes(t, "Skyblock"),
+		"notes.txt":               "not a world",
+	})
+
+	sources := ImportSources(backups)
+	if !slices.Equal(sources, []string{ftb, aroma}) {
+		t.Fatalf("ImportSources = %q, want both archives", sources)
+	}
+	empty := t.TempDir()
+	if got := ImportSources(empty); len(got) != 1 || got[0] != empty {
+		t.Errorf("a folder without archives expanded to %q", got)
+	}
+
+	dest := t.TempDir()
+	imported, err := Import(context.Background(), aroma, dest, time.Time{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Time.Format(backupTimeLayout) != "2023-05-01_12-30" || !slices.Equal(imported.Worlds, []string{"Hardcore"}) {
+		t.Errorf("AromaBackup import = %+v, want the Hardcore world at the archive's time", imported)
+	}
+	if !exists(filepath.Join(imported.OutputPath, "saves", "Hardcore", "region", "r.0.0.mca")) {
+		t.Error("the world was not filed under saves/Hardcore")
+	}
+
+	imported, err = Import(context.Background(), ftb, dest, time.Time{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(imported.Worlds, []string{"Skyblock", "Skyblock_2"}) {
+		t.Errorf("FTB import worlds = %q, want both worlds under their level names", imported.Worlds)
+	}
+	if exists(filepath.Join(imported.OutputPath, "notes.txt")) {
+		t.Error("a file beside the worlds was imported")
+	}
+}
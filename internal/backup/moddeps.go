@@ -0,0 +1,247 @@
+package backup
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// platformIDs are dependencies the loader or game provides rather than a mod in mods/
+var platformIDs = map[string]bool{
+	"minecraft":    true,
+	"java":         true,
+	"fabricloader": true,
+	"quilt_loader": true,
+	"forge":        true,
+	"neoforge":     true,
+	"javafml":      true,
+	"mixinextras":  true, // Bundled with recent loaders
+}
+
+// missingDependency is a mod whose required dependency is not in mods/
+type missingDependency struct {
+	Mod   ModInfo
+	Needs string
+}
+
+// modDependencies is what the declared dependencies of a mods folder say about it
+type modDependencies struct {
+	Declared        int // Required dependencies on other mods
+	Missing         []missingDependency
+	UnusedLibraries []ModInfo // Libraries no installed mod requires
+}
+
+// analyzeModDependencies matches every mod's required dependencies against the ids the
+// installed mods have and provide
+func analyzeModDependencies(mods []ModInfo) modDependencies {
+	installed := map[string]bool{}
+	for _, m := range mods {
+		if m.ID != "" {
+			installed[m.ID] = true
+		}
+		for _, id := range m.Provides {
+			installed[id] = true
+		}
+	}
+
+	var deps modDependencies
+	needed := map[string]bool{}
+	for _, m := range mods {
+		for _, id := range m.Depends {
+			if platformIDs[id] || id == m.ID {
+				continue
+			}
+			deps.Declared++
+			needed[id] = true
+			if !installed[id] {
+				deps.Missing = append(deps.Missing, missingDependency{m, id})
+			}
+		}
+	}
+
+	for _, m := range mods {
+		if !isLibraryMod(m) || needed[m.ID] {
+			continue
+		}
+		used := false
+		for _, id := range m.Provides {
+			used = used || needed[id]
+		}
+		if !used {
+			deps.UnusedLibraries = append(deps.UnusedLibraries, m)
+		}
+	}
+	sort.Slice(deps.Missing, func(i, j int) bool { return deps.Missing[i].Mod.File < deps.Missing[j].Mod.File })
+	sort.Slice(deps.UnusedLibraries, func(i, j int) bool { return deps.UnusedLibraries[i].File < deps.UnusedLibraries[j].File })
+	return deps
+}
+
+// isLibraryMod reports whether a mod only exists for other mods to use. Mod Menu's library
+// badge says so for Fabric mods; for the rest it is guessed from the id and name.
+func isLibraryMod(m ModInfo) bool {
+	if m.Library {
+		return true
+	}
+	if m.ID == "" {
+		return false
+	}
+	id, name := strings.ToLower(m.ID), strings.ToLower(m.Name)
+	return strings.Contains(id, "lib") || strings.Contains(name, "library") ||
+		strings.HasSuffix(id, "api") || strings.HasSuffix(name, " api")
+}
+
+// modLabel names a mod by its display name and jar
+func modLabel(m ModInfo) string {
+	if m.Name == "" {
+		return m.File
+	}
+	return fmt.Sprintf("%s (%s)", m.Name, m.File)
+}
+
+// renderModDependenciesSection lists missing dependencies and unused libraries for info.md,
+// which is what matters when pruning a modlist; it is left out when no jar declares any
+func renderModDependenciesSection(mods []ModInfo) string {
+	deps := analyzeModDependencies(mods)
+	if deps.Declared == 0 && len(deps.UnusedLibraries) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n## 🔗 Mod Dependencies\n\n")
+	b.WriteString(fmt.Sprintf("- **Declared:** %d required dependencies between mods\n", deps.Declared))
+	if len(deps.Missing) == 0 && len(deps.UnusedLibraries) == 0 {
+		b.WriteString("- Every dependency is installed and every library is used\n")
+		return b.String()
+	}
+	if len(deps.Missing) > 0 {
+		b.WriteString(fmt.Sprintf("- **Missing:** %d dependencies are not in `mods/`\n", len(deps.Missing)))
+		for _, d := range deps.Missing {
+			b.WriteString(fmt.Sprintf("  - %s needs `%s`\n", modLabel(d.Mod), d.Needs))
+		}
+	}
+	if len(deps.UnusedLibraries) > 0 {
+		b.WriteString(fmt.Sprintf("- **Unused libraries:** %d that no installed mod requires, candidates to drop when pruning\n", len(deps.UnusedLibraries)))
+		for _, m := range deps.UnusedLibraries {
+			b.WriteString(fmt.Sprintf("  - %s\n", modLabel(m)))
+		}
+	}
+	return b.String()
+}
+
+// duplicateMod is a mod id more than one jar in mods/ claims, which crashes most loaders
+type duplicateMod struct {
+	ID   string
+	Jars []ModInfo
+	Keep string // Jar with the newest version; empty when the versions can't tell
+}
+
+// findDuplicateMods groups the jars that share a mod id, suggesting the newest of each to keep
+func findDuplicateMods(mods []ModInfo) []duplicateMod {
+	byID := map[string][]ModInfo{}
+	for _, m := range mods {
+		if m.ID != "" {
+			byID[m.ID] = append(byID[m.ID], m)
+		}
+	}
+	var dups []duplicateMod
+	for id, jars := range byID {
+		if len(jars) < 2 {
+			continue
+		}
+		sort.Slice(jars, func(i, j int) bool { return jars[i].File < jars[j].File })
+		d := duplicateMod{ID: id, Jars: jars}
+		// Only a version newer than every other one makes a clear pick
+		newest, clear := jars[0], jars[0].Version != ""
+		for _, m := range jars[1:] {
+			if m.Version == "" {
+				clear = false
+				break
+			}
+			switch compareModVersions(m.Version, newest.Version) {
+			case 1:
+				newest, clear = m, true
+			case 0:
+				clear = false
+			}
+		}
+		if clear {
+			d.Keep = newest.File
+		}
+		dups = append(dups, d)
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i].ID < dups[j].ID })
+	return dups
+}
+
+// compareModVersions orders two mod versions, returning -1, 0 or 1. Numbers compare as
+// numbers, build metadata after a + is ignored, and a pre-release tag (1.0-beta) sorts
+// before the release it leads to.
+func compareModVersions(a, b string) int {
+	split := func(v string) []string {
+		v, _, _ = strings.Cut(strings.TrimPrefix(strings.ToLower(v), "v"), "+")
+		return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' || r == '_' || r == ' ' })
+	}
+	as, bs := split(a), split(b)
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		if i >= len(as) || i >= len(bs) {
+			// The longer one is newer unless what it adds is a pre-release tag
+			longer, sign := bs, -1
+			if i >= len(bs) {
+				longer, sign = as, 1
+			}
+			if _, err := strconv.Atoi(longer[i]); err != nil {
+				return -sign
+			}
+			return sign
+		}
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return cmp.Compare(an, bn)
+			}
+		case aErr == nil:
+			return 1
+		case bErr == nil:
+			return -1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// renderDuplicateModsSection lists the mod ids with more than one jar for info.md
+func renderDuplicateModsSection(dups []duplicateMod) string {
+	if len(dups) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n## ⚠️ Duplicate Mods\n\n")
+	b.WriteString("More than one jar has the same mod id. Most loaders refuse to start, or load whichever they find first.\n\n")
+	for _, d := range dups {
+		var jars []string
+		for _, m := range d.Jars {
+			jar := "`" + m.File + "`"
+			if m.Version != "" {
+				jar += " (" + m.Version + ")"
+			}
+			jars = append(jars, jar)
+		}
+		name := d.ID
+		if d.Jars[0].Name != "" {
+			name = fmt.Sprintf("%s (`%s`)", d.Jars[0].Name, d.ID)
+		}
+		b.WriteString(fmt.Sprintf("- **%s:** %s\n", name, strings.Join(jars, ", ")))
+		if d.Keep != "" {
+			b.WriteString(fmt.Sprintf("  - Keep `%s`, the newest version, and remove the others\n", d.Keep))
+		} else {
+			b.WriteString("  - The versions don't say which is newer; keep the one downloaded last\n")
+		}
+	}
+	return b.String()
+}
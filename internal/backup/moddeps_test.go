@@ -0,0 +1,210 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Each loader's metadata gives the id, name, version and required dependencies, and nested
+// jars count as provided
+func TestReadModInfo(t *testing.T) {
+	mods := t.TempDir()
+	writeTestZip(t, filepath.Join(mods, "create.jar"), map[string]string{
+		"fabric.mod.json": `{"id": "create", "name": "Create", "version": "0.5.1",
+			"depends": {"minecraft": "1.20.1", "flywheel": "*", "fabricloader": "*"},
+			"jars": [{"file": "META-INF/jars/registrate.jar"}]}`,
+		"META-INF/jars/registrate.jar": testJar(t, `{"id": "registrate", "provides": ["registrate-api"]}`),
+	})
+	writeTestZip(t, filepath.Join(mods, "qsl.jar"), map[string]string{
+		"quilt.mod.json": `{"quilt_loader": {"id": "qsl", "version": "6.0", "metadata": {"name": "QSL"},
+			"depends": ["minecraft", {"id": "sodium", "optional": true}, {"id": "fabric-api"}],
+			"provides": [{"id": "quilted_fabric_api"}]}}`,
+	})
+	writeTestZip(t, filepath.Join(mods, "jei.jar"), map[string]string{
+		"META-INF/mods.toml": `modLoader="javafml"
+[[mods]]
+modId="jei"
+version="${file.jarVersion}"
+displayName="Just Enough Items" # shown in the mod list
+[[dependencies.jei]]
+    modId="forge"
+    mandatory=true
+[[dependencies.jei]]
+    modId="optionalthing"
+    mandatory=false
+[[dependencies.jei]]
+    modId='architectury'
+    mandatory=true
+[[mods]]
+modId="jei_second"
+`,
+		"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\nImplementation-Version: 15.2.0.27\n",
+	})
+	writeTestZip(t, filepath.Join(mods, "ftb.jar"), map[string]string{
+		"META-INF/neoforge.mods.toml": "[[mods]]\nmodId = \"ftblibrary\"\nversion = \"2101.1.0\"\n[[dependencies.ftblibrary]]\nmodId = \"neoforge\"\ntype = \"required\"\n[[dependencies.ftblibrary]]\nmodId = \"jei\"\ntype = \"optional\"\n",
+	})
+	writeTestFile(t, mods, "broken.jar", "not a zip")
+	writeTestFile(t, mods, "notes.txt", "not a mod")
+
+	infos := listModInfo(mods)
+	byFile := map[string]ModInfo{}
+	for _, m := range infos {
+		byFile[m.File] = m
+	}
+	if len(infos) != 5 {
+		t.Fatalf("listModInfo read %d jars, want 5", len(infos))
+	}
+	for file, want := range map[string]ModInfo{
+		"create.jar": {ID: "create", Name: "Create", Version: "0.5.1", Loader: "fabric", Depends: []string{"fabricloader", "flywheel", "minecraft"}, Provides: []string{"registrate", "registrate-api"}},
+		"qsl.jar":    {ID: "qsl", Name: "QSL", Version: "6.0", Loader: "quilt", Depends: []string{"minecraft", "fabric-api"}, Provides: []string{"quilted_fabric_api"}},
+		"jei.jar":    {ID: "jei", Name: "Just Enough Items", Version: "15.2.0.27", Loader: "forge", Depends: []string{"forge", "architectury"}},
+		"ftb.jar":    {ID: "ftblibrary", Version: "2101.1.0", Loader: "neoforge", Depends: []string{"neoforge"}},
+		"broken.jar": {},
+	} {
+		got := byFile[file]
+		if got.ID != want.ID || got.Name != want.Name || got.Version != want.Version || got.Loader != want.Loader ||
+			!slices.Equal(got.Depends, want.Depends) || !slices.Equal(got.Provides, want.Provides) || got.Size == 0 {
+			t.Errorf("%s = %+v, want %+v", file, got, want)
+		}
+	}
+}
+
+// The report names dependencies nothing installed provides, and libraries nothing requires
+func TestModDependencies(t *testing.T) {
+	mods := []ModInfo{
+		{File: "create.jar", ID: "create", Name: "Create", Depends: []string{"minecraft", "fabric-api", "flywheel", "registrate"}, Provides: []string{"registrate"}},
+		{File: "fabric-api.jar", ID: "fabric-api", Name: "Fabric API"},
+		{File: "cloth-config.jar", ID: "cloth-config", Name: "Cloth Config", Library: true},
+		{File: "sodium.jar", ID: "sodium", Depends: []string{"fabricloader", "sodium"}},
+	}
+	deps := analyzeModDependencies(mods)
+	if deps.Declared != 3 || len(deps.Missing) != 1 || deps.Missing[0].Needs != "flywheel" {
+		t.Errorf("declared %d, missing %+v; want 3 and flywheel", deps.Declared, deps.Missing)
+	}
+	if len(deps.UnusedLibraries) != 1 || deps.UnusedLibraries[0].ID != "cloth-config" {
+		t.Errorf("unused libraries = %+v, want cloth-config", deps.UnusedLibraries)
+	}
+	section := renderModDependenciesSection(mods)
+	for _, s := range []string{"**Declared:** 3", "  - Create (create.jar) needs `flywheel`", "  - Cloth Config (cloth-config.jar)"} {
+		if !strings.Contains(section, s) {
+			t.Errorf("section lacks %q:\n%s", s, section)
+		}
+	}
+
+	if s := renderModDependenciesSection(mods[3:]); s != "" {
+		t.Errorf("section without any dependencies = %q", s)
+	}
+	if s := renderModDependenciesSection(mods[:2]); !strings.Contains(s, "- **Missing:**") {
+		t.Errorf("section = %q", s)
+	}
+	mods[0].Depends = []string{"fabric-api"}
+	if s := renderModDependenciesSection(mods[:2]); !strings.Contains(s, "Every dependency is installed") {
+		t.Errorf("section for a complete modlist = %q", s)
+	}
+}
+
+// A backup's info.md carries the dependency summary of its mods
+func TestModDependenciesInBackup(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	if err := os.MkdirAll(filepath.Join(mc, "mods"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestZip(t, filepath.Join(mc, "mods", "create.jar"), map[string]string{
+		"fabric.mod.json": `{"id": "create", "name": "Create", "depends": {"flywheel": "*"}}`,
+	})
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir()}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	info, _ := os.ReadFile(filepath.Join(result.OutputPath, "info.md"))
+	if !strings.Contains(string(info), "## 🔗 Mod Dependencies") || !strings.Contains(string(info), "needs `flywheel`") {
+		t.Errorf("info.md lacks the dependency summary:\n%s", info)
+	}
+}
+
+func TestCompareModVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"0.5.8", "0.5.10", -1},
+		{"v1.2", "1.2.0", -1},
+		{"1.2+build.7", "1.2+build.9", 0},
+		{"1.0-beta", "1.0", -1},
+		{"1.0.1", "1.0-rc2", 1},
+		{"mc1.20.1-0.5.8", "mc1.20.1-0.5.7", 1},
+		{"2.0", "2.0", 0},
+	} {
+		if got := compareModVersions(c.a, c.b); got != c.want {
+			t.Errorf("compareModVersions(%s, %s) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := compareModVersions(c.b, c.a); got != -c.want {
+			t.Errorf("compareModVersions(%s, %s) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+// Jars sharing a mod id are grouped, with the newest suggested only when it clearly is
+func TestFindDuplicateMods(t *testing.T) {
+	dups := findDuplicateMods([]ModInfo{
+		{File: "sodium-0.5.8.jar", ID: "sodium", Name: "Sodium", Version: "0.5.8"},
+		{File: "sodium-0.5.11.jar", ID: "sodium", Name: "Sodium", Version: "0.5.11"},
+		{File: "lithium.jar", ID: "lithium", Version: "0.11.2"},
+		{File: "lithium (1).jar", ID: "lithium", Version: "0.11.2"},
+		{File: "iris.jar", ID: "iris", Version: "1.7.0"},
+		{File: "plain.jar"},
+		{File: "other-plain.jar"},
+	})
+	if len(dups) != 2 || dups[0].ID != "lithium" || dups[1].ID != "sodium" {
+		t.Fatalf("findDuplicateMods = %+v, want lithium and sodium", dups)
+	}
+	if dups[0].Keep != "" || dups[1].Keep != "sodium-0.5.11.jar" {
+		t.Errorf("keep %q and %q, want nothing for equal versions and sodium-0.5.11.jar", dups[0].Keep, dups[1].Keep)
+	}
+	section := renderDuplicateModsSection(dups)
+	for _, s := range []string{
+		"- **lithium:** `lithium (1).jar` (0.11.2), `lithium.jar` (0.11.2)",
+		"don't say which is newer",
+		"- **Sodium (`sodium`):** `sodium-0.5.11.jar` (0.5.11), `sodium-0.5.8.jar` (0.5.8)",
+		"Keep `sodium-0.5.11.jar`",
+	} {
+		if !strings.Contains(section, s) {
+			t.Errorf("section lacks %q:\n%s", s, section)
+		}
+	}
+	if renderDuplicateModsSection(nil) != "" {
+		t.Error("section without duplicates is not empty")
+	}
+}
+
+// Duplicate jars in a backed up instance are flagged in info.md and lower the health score
+func TestDuplicateModsInBackup(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	if err := os.MkdirAll(filepath.Join(mc, "mods"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"0.5.8", "0.5.11"} {
+		writeTestZip(t, filepath.Join(mc, "mods", "sodium-"+v+".jar"), map[string]string{
+			"fabric.mod.json": `{"id": "sodium", "name": "Sodium", "version": "` + v + `"}`,
+		})
+	}
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir()}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	info, _ := os.ReadFile(filepath.Join(result.OutputPath, "info.md"))
+	if !strings.Contains(string(info), "## ⚠️ Duplicate Mods") || !strings.Contains(string(info), "Keep `sodium-0.5.11.jar`") {
+		t.Errorf("info.md lacks the duplicate mods:\n%s", info)
+	}
+	if !slices.ContainsFunc(result.Warnings, func(w string) bool { return strings.Contains(w, "more than one jar of sodium") }) {
+		t.Errorf("warnings = %v, want one for sodium", result.Warnings)
+	}
+}
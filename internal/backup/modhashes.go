@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ModHashesName lists the SHA-256 of each file in mods/ at backup time, in sha256sum format,
+// so mods downloaded again can be checked against the exact files the instance ran
+const ModHashesName = "mods.sha256"
+
+// RejectedSuffix is added to mods whose hash does not match the backup, so loaders skip them
+const RejectedSuffix = ".rejected"
+
+// writeModHashes records the SHA-256 of the listed files in modsDir into backupPath
+func writeModHashes(modsDir, backupPath string, mods []string) error {
+	paths := make([]string, len(mods))
+	for i, name := range mods {
+		paths[i] = filepath.Join(modsDir, name)
+	}
+	sums, err := newHashRecorder().hashAll(paths)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for i, name := range mods {
+		fmt.Fprintf(&b, "%s  %s\n", sums[paths[i]], name)
+	}
+	return os.WriteFile(filepath.Join(backupPath, ModHashesName), []byte(b.String()), 0644)
+}
+
+// ReadModHashes returns the mod hashes recorded in a backup by file name; backups made before
+// Totem recorded them have none
+func ReadModHashes(backupPath string) (map[string]string, error) {
+	data, err := ReadBackupFile(backupPath, ModHashesName)
+	if err != nil {
+		return nil, fmt.Errorf("%s has no mod hashes (made before Totem recorded them)", filepath.Base(backupPath))
+	}
+	sums := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if sum, name, ok := strings.Cut(line, "  "); ok {
+			sums[name] = sum
+		}
+	}
+	return sums, nil
+}
+
+// ModCheck is the state of one mod after CheckMods
+type ModCheck struct {
+	File   string
+	Status string // "ok", "rejected" (hash mismatch, renamed), "missing" or "extra" (not in the backup)
+}
+
+// CheckMods compares the files in instance's mods/ with the hashes recorded in backupPath.
+// A file named like a backed up mod but with other contents is renamed with RejectedSuffix,
+// since it is not the version the instance ran and may have been swapped upstream.
+func CheckMods(backupPath, instance string) ([]ModCheck, error) {
+	sums, err := ReadModHashes(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	modsDir := filepath.Join(instance, "mods")
+	present, err := listFiles(modsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var checks []ModCheck
+	seen := map[string]bool{}
+	for _, name := range present {
+		if strings.HasSuffix(name, RejectedSuffix) {
+			continue
+		}
+		seen[name] = true
+		want, ok := sums[name]
+		if !ok {
+			checks = append(checks, ModCheck{File: name, Status: "extra"})
+			continue
+		}
+		path := filepath.Join(modsDir, name)
+		got, err := hashFile(path)
+		if err != nil {
+			return checks, err
+		}
+		if hex.EncodeToString(got) == want {
+			checks = append(checks, ModCheck{File: name, Status: "ok"})
+			continue
+		}
+		if err := os.Rename(path, path+RejectedSuffix); err != nil {
+			return checks, err
+		}
+		checks = append(checks, ModCheck{File: name, Status: "rejected"})
+	}
+	for name := range sums {
+		if !seen[name] {
+			checks = append(checks, ModCheck{File: name, Status: "missing"})
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].File < checks[j].File })
+	return checks, nil
+}
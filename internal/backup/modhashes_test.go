@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Mods downloaded again are checked against the files the backup was made from, and any
+// swapped one is renamed so the loader skips it
+func TestCheckMods(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "mods/sodium-0.5.8.jar", "sodium")
+	writeTestFile(t, mc, "mods/iris-1.7.0.jar", "iris")
+	writeTestFile(t, mc, "mods/lithium-0.11.jar", "lithium")
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir()}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	sums, err := ReadModHashes(result.OutputPath)
+	if err != nil || len(sums) != 3 {
+		t.Fatalf("ReadModHashes = %v, %v", sums, err)
+	}
+
+	instance := t.TempDir()
+	writeTestFile(t, instance, "mods/sodium-0.5.8.jar", "sodium")
+	writeTestFile(t, instance, "mods/iris-1.7.0.jar", "iris, swapped upstream")
+	writeTestFile(t, instance, "mods/modmenu-9.0.jar", "modmenu")
+	checks, err := CheckMods(result.OutputPath, instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ModCheck{
+		{File: "iris-1.7.0.jar", Status: "rejected"},
+		{File: "lithium-0.11.jar", Status: "missing"},
+		{File: "modmenu-9.0.jar", Status: "extra"},
+		{File: "sodium-0.5.8.jar", Status: "ok"},
+	}
+	if !slices.Equal(checks, want) {
+		t.Errorf("CheckMods = %+v, want %+v", checks, want)
+	}
+	if exists(filepath.Join(instance, "mods", "iris-1.7.0.jar")) || !exists(filepath.Join(instance, "mods", "iris-1.7.0.jar"+RejectedSuffix)) {
+		t.Error("the swapped mod was not renamed")
+	}
+
+	// A second check skips rejected files and reports the mod as still missing
+	checks, _ = CheckMods(result.OutputPath, instance)
+	if checks[0] != (ModCheck{File: "iris-1.7.0.jar", Status: "missing"}) {
+		t.Errorf("after rejecting: %+v", checks[0])
+	}
+
+	old := t.TempDir()
+	if err := os.WriteFile(filepath.Join(old, "mods.txt"), []byte("sodium-0.5.8.jar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckMods(old, instance); err == nil {
+		t.Error("CheckMods of a backup without hashes: no error")
+	}
+}
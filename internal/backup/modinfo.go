@@ -0,0 +1,301 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ModInfo is what a mod jar says about itself
+type ModInfo struct {
+	File    string `json:"file"`
+	Size    int64  `json:"size"`
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Loader  string `json:"loader,omitempty"` // fabric, quilt, forge or neoforge; empty if unknown
+	// Depends are the mod ids it requires to load, and Provides the other ids it answers to,
+	// including those of the mods nested inside it
+	Depends  []string `json:"depends,omitempty"`
+	Provides []string `json:"provides,omitempty"`
+	Library  bool     `json:"library,omitempty"` // Marked as a library for Mod Menu
+}
+
+// readModInfo opens a mod jar and reads its Fabric, Quilt, Forge or NeoForge metadata.
+// A jar without readable metadata still gets its file name and size.
+func readModInfo(path string) ModInfo {
+	var info ModInfo
+	if r, err := zip.OpenReader(path); err == nil {
+		info = readModInfoZip(&r.Reader)
+		r.Close()
+	}
+	info.File = filepath.Base(path)
+	if stat, err := os.Stat(path); err == nil {
+		info.Size = stat.Size()
+	}
+	return info
+}
+
+// readModInfoZip reads the metadata of an opened jar; File and Size are left empty
+func readModInfoZip(r *zip.Reader) ModInfo {
+	var info ModInfo
+	files := map[string]*zip.File{}
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	if f := files["fabric.mod.json"]; f != nil {
+		var meta struct {
+			ID       string         `json:"id"`
+			Name     string         `json:"name"`
+			Version  string         `json:"version"`
+			Depends  map[string]any `json:"depends"`
+			Provides []string       `json:"provides"`
+			Jars     []struct {
+				File string `json:"file"`
+			} `json:"jars"`
+			Custom struct {
+				ModMenu struct {
+					Badges []string `json:"badges"`
+				} `json:"modmenu"`
+			} `json:"custom"`
+		}
+		if readZipJSON(f, &meta) == nil {
+			info.ID, info.Name, info.Version, info.Loader = meta.ID, meta.Name, meta.Version, "fabric"
+			for id := range meta.Depends {
+				info.Depends = append(info.Depends, id)
+			}
+			sort.Strings(info.Depends)
+			info.Provides = meta.Provides
+			for _, jar := range meta.Jars {
+				info.Provides = append(info.Provides, nestedModIDs(files[jar.File])...)
+			}
+			info.Library = slices.Contains(meta.Custom.ModMenu.Badges, "library")
+		}
+		return info
+	}
+	if f := files["quilt.mod.json"]; f != nil {
+		var meta struct {
+			Loader struct {
+				ID       string            `json:"id"`
+				Version  string            `json:"version"`
+				Depends  []json.RawMessage `json:"depends"`
+				Provides []json.RawMessage `json:"provides"`
+				Jars     []string          `json:"jars"`
+				Metadata struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+			} `json:"quilt_loader"`
+		}
+		if readZipJSON(f, &meta) == nil {
+			info.ID, info.Name, info.Version, info.Loader = meta.Loader.ID, meta.Loader.Metadata.Name, meta.Loader.Version, "quilt"
+			// Entries are an id or an object with one; optional and any-of dependencies are skipped
+			for _, raw := range meta.Loader.Depends {
+				if id, optional := quiltEntry(raw); id != "" && !optional {
+					info.Depends = append(info.Depends, id)
+				}
+			}
+			for _, raw := range meta.Loader.Provides {
+				if id, _ := quiltEntry(raw); id != "" {
+					info.Provides = append(info.Provides, id)
+				}
+			}
+			for _, jar := range meta.Loader.Jars {
+				info.Provides = append(info.Provides, nestedModIDs(files[jar])...)
+			}
+		}
+		return info
+	}
+	for _, toml := range []struct{ name, loader string }{
+		{"META-INF/neoforge.mods.toml", "neoforge"},
+		{"META-INF/mods.toml", "forge"},
+	} {
+		if f := files[toml.name]; f != nil {
+			info.Loader = toml.loader
+			info.ID, info.Name, info.Version, info.Depends = readModsToml(f)
+			// Jar-in-jar mods are unpacked from META-INF/jarjar
+			for name, nested := range files {
+				if strings.HasPrefix(name, "META-INF/jarjar/") && strings.HasSuffix(name, ".jar") {
+					info.Provides = append(info.Provides, nestedModIDs(nested)...)
+				}
+			}
+			sort.Strings(info.Provides)
+			// Forge fills the version from the jar manifest at load time
+			if strings.Contains(info.Version, "${") {
+				info.Version = ""
+				if mf := files["META-INF/MANIFEST.MF"]; mf != nil {
+					info.Version = readManifestVersion(mf)
+				}
+			}
+			return info
+		}
+	}
+	return info
+}
+
+func readZipJSON(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(io.LimitReader(rc, 1<<20)).Decode(v)
+}
+
+// quiltEntry reads a quilt.mod.json depends or provides entry, which is an id or an object
+// with one
+func quiltEntry(raw json.RawMessage) (id string, optional bool) {
+	if json.Unmarshal(raw, &id) == nil {
+		return id, false
+	}
+	var entry struct {
+		ID       string `json:"id"`
+		Optional bool   `json:"optional"`
+	}
+	if json.Unmarshal(raw, &entry) != nil {
+		return "", false
+	}
+	return entry.ID, entry.Optional
+}
+
+// nestedModIDs returns the ids of a mod nested inside a jar, along with those it provides.
+// Jars too big to unpack in memory are skipped.
+func nestedModIDs(f *zip.File) []string {
+	if f == nil || f.UncompressedSize64 > searchJarLimit {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil
+	}
+	nested := readModInfoZip(zr)
+	if nested.ID == "" {
+		return nested.Provides
+	}
+	return append([]string{nested.ID}, nested.Provides...)
+}
+
+// readModsToml reads the first mod's id, display name, version and required dependencies
+// from a mods.toml. Only the few keys needed are picked out; a full TOML parser is not worth
+// the dependency.
+func readModsToml(f *zip.File) (id, name, version string, depends []string) {
+	rc, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	// A dependency is required when Forge marks it mandatory or NeoForge gives it type "required"
+	var (
+		section  string // "mods" for the first [[mods]] table, "dep" for one of its dependencies
+		seenMods bool
+		depID    string
+		required bool
+	)
+	flush := func() {
+		if section == "dep" && depID != "" && required {
+			depends = append(depends, depID)
+		}
+		depID, required = "", false
+	}
+	scanner := bufio.NewScanner(io.LimitReader(rc, 1<<20))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			flush()
+			switch {
+			case line == "[[mods]]" && !seenMods:
+				section, seenMods = "mods", true
+			case id != "" && line == "[[dependencies."+id+"]]":
+				section = "dep"
+			default:
+				section = ""
+			}
+			continue
+		}
+		if section == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = tomlValue(value)
+		switch key = strings.TrimSpace(key); {
+		case section == "mods" && key == "modId":
+			id = value
+		case section == "mods" && key == "displayName":
+			name = value
+		case section == "mods" && key == "version":
+			version = value
+		case section == "dep" && key == "modId":
+			depID = value
+		case section == "dep" && key == "mandatory":
+			required = value == "true"
+		case section == "dep" && key == "type":
+			required = strings.EqualFold(value, "required")
+		}
+	}
+	flush()
+	return
+}
+
+// tomlValue reads a simple TOML value, dropping quotes and a trailing comment
+func tomlValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if before, _, ok := strings.Cut(value, "#"); ok {
+		value = before
+	}
+	return strings.Trim(strings.TrimSpace(value), `"'`)
+}
+
+func readManifestVersion(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	scanner := bufio.NewScanner(io.LimitReader(rc, 1<<20))
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "Implementation-Version:"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// listModInfo reads the metadata of every jar in modsDir
+func listModInfo(modsDir string) []ModInfo {
+	files, err := listFiles(modsDir)
+	if err != nil {
+		return nil
+	}
+	var mods []ModInfo
+	for _, name := range files {
+		if strings.HasSuffix(strings.ToLower(name), ".jar") {
+			mods = append(mods, readModInfo(filepath.Join(modsDir, name)))
+		}
+	}
+	return mods
+}
@@ -0,0 +1,263 @@
+package backup
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/vaalley/totem/internal/modapi"
+	"github.com/vaalley/totem/internal/netguard"
+)
+
+// ModMetadataName holds what Modrinth and CurseForge know about each mod, for --mod-metadata
+const ModMetadataName = "mods.json"
+
+// modLookupCacheName is the cache of API answers in the config folder, keyed by jar hash
+const modLookupCacheName = "mod-lookups.json"
+
+// ModMetadata is one mod in mods.json. Mods no API identified are marked Unidentified and
+// named from the jar's own metadata where it has any.
+type ModMetadata struct {
+	File         string `json:"file"`
+	SHA1         string `json:"sha1"`
+	ModID        string `json:"mod_id,omitempty"` // From the jar
+	Source       string `json:"source,omitempty"` // "modrinth", "curseforge", or "jar" for the jar's own metadata
+	Project      string `json:"project_id,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Version      string `json:"version,omitempty"`
+	URL          string `json:"url,omitempty"`
+	Unidentified bool   `json:"unidentified,omitempty"`
+}
+
+// modLookupClient returns an API client whose cache lives in the config folder
+func modLookupClient() *modapi.Client {
+	cachePath := ""
+	if dir, err := KeyDir(); err == nil {
+		cachePath = filepath.Join(dir, modLookupCacheName)
+	}
+	return modapi.New(cachePath)
+}
+
+// lookupBatch is how many hashed jars are sent to the APIs at once, and lookupWorkers how
+// many batches may be in flight; the client's rate limiter spaces out the requests
+const (
+	lookupBatch   = 50
+	lookupWorkers = 2
+)
+
+// writeModMetadata looks up the listed jars in modsDir and writes mods.json into backupPath.
+// Jars are hashed on all cores and sent to the APIs in batches as soon as they are hashed,
+// so lookups overlap the hashing. It returns how many were identified. A failed lookup
+// comes back as lookupErr and still leaves a complete mods.json, with the jars' own metadata
+// for mods it could not identify; err is for failures to read the mods or write the file.
+func writeModMetadata(ctx context.Context, modsDir, backupPath string, mods []string, progress *Progress) (identified int, lookupErr, err error) {
+	var entries []ModMetadata
+	for _, name := range mods {
+		if strings.HasSuffix(strings.ToLower(name), ".jar") {
+			entries = append(entries, ModMetadata{File: name})
+		}
+	}
+	progress.setModsTotal(len(entries))
+
+	var (
+		mu       sync.Mutex
+		readErr  error
+		lookErrs []error
+	)
+
+	// Hash on all cores, handing each jar on as soon as it is done
+	jobs := make(chan int)
+	hashed := make(chan modapi.Hashes)
+	var hashers sync.WaitGroup
+	for range min(runtime.NumCPU(), max(len(entries), 1)) {
+		hashers.Add(1)
+		go func() {
+			defer hashers.Done()
+			for i := range jobs {
+				path := filepath.Join(modsDir, entries[i].File)
+				h, err := modapi.HashFile(path)
+				if err != nil {
+					mu.Lock()
+					readErr = cmp.Or(readErr, err)
+					mu.Unlock()
+					progress.modsLookedUp(1)
+					continue
+				}
+				jar := readModInfo(path)
+				entries[i].SHA1, entries[i].ModID = h.SHA1, jar.ID
+				entries[i].Title, entries[i].Version = jar.Name, jar.Version
+				hashed <- h
+			}
+		}()
+	}
+	go func() {
+		for i := range entries {
+			jobs <- i
+		}
+		close(jobs)
+		hashers.Wait()
+		close(hashed)
+	}()
+
+	// Look up full batches while hashing goes on, and whatever is left at the end
+	client := modLookupClient()
+	found := map[string]*modapi.Project{}
+	var lookups sync.WaitGroup
+	slots := make(chan struct{}, lookupWorkers)
+	lookup := func(batch []modapi.Hashes) {
+		slots <- struct{}{}
+		lookups.Add(1)
+		go func() {
+			defer func() { <-slots; lookups.Done() }()
+			projects, err := client.Lookup(ctx, batch)
+			mu.Lock()
+			if err != nil {
+				lookErrs = append(lookErrs, err)
+			}
+			maps.Copy(found, projects)
+			mu.Unlock()
+			progress.modsLookedUp(len(batch))
+		}()
+	}
+	var batch []modapi.Hashes
+	for h := range hashed {
+		if batch = append(batch, h); len(batch) == lookupBatch {
+			lookup(batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		lookup(batch)
+	}
+	lookups.Wait()
+	client.Save()
+
+	for i, e := range entries {
+		p := found[e.SHA1]
+		switch {
+		case p != nil:
+			entries[i].Source, entries[i].Project, entries[i].URL = p.Source, p.ProjectID, p.URL
+			entries[i].Title = cmp.Or(p.Title, e.Title)
+			entries[i].Version = cmp.Or(p.Version, e.Version)
+			identified++
+		case e.Title != "" || e.ModID != "":
+			entries[i].Source, entries[i].Unidentified = "jar", true
+		default:
+			entries[i].Unidentified = true
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(backupPath, ModMetadataName), data, 0644)
+	}
+	// Identical failures from each batch are reported once
+	if len(lookErrs) > 0 {
+		lookupErr = lookErrs[0]
+	}
+	return identified, lookupErr, cmp.Or(err, readErr)
+}
+
+// modEnrichment is mods.json being written in the background while the backup copies
+// everything else, so lookups do not add their time to the backup's
+type modEnrichment struct {
+	done       chan struct{}
+	identified int
+	lookupErr  error
+	err        error
+}
+
+// startModMetadata lists the mods in modsDir and starts writing mods.json for them
+func startModMetadata(ctx context.Context, modsDir, backupPath string, progress *Progress) *modEnrichment {
+	e := &modEnrichment{done: make(chan struct{})}
+	go func() {
+		defer close(e.done)
+		mods, err := listFiles(modsDir)
+		if err != nil {
+			e.err = err
+			return
+		}
+		e.identified, e.lookupErr, e.err = writeModMetadata(ctx, modsDir, backupPath, mods, progress)
+	}()
+	return e
+}
+
+// ReadModMetadata returns the mods.json of a backup by file name; backups made without
+// --mod-metadata have none
+func ReadModMetadata(backupPath string) (map[string]ModMetadata, error) {
+	data, err := ReadBackupFile(backupPath, ModMetadataName)
+	if err != nil {
+		return nil, err
+	}
+	var entries []ModMetadata
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	byFile := make(map[string]ModMetadata, len(entries))
+	for _, e := range entries {
+		byFile[e.File] = e
+	}
+	return byFile, nil
+}
+
+// wait blocks until mods.json is written and records the outcome. Lookups are a bonus, so
+// a network problem never fails the backup: it is noted for the health report, and the
+// mods it left unidentified are named from their jars.
+func (e *modEnrichment) wait(result *Result) {
+	<-e.done
+	result.Stats.ModsIdentified = e.identified
+	switch {
+	case errors.Is(e.lookupErr, netguard.ErrOffline):
+		result.Stats.ModLookupError = "offline mode is on"
+	case e.lookupErr != nil:
+		result.Stats.ModLookupError = e.lookupErr.Error()
+	}
+	if e.err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("mod metadata: %v", e.err))
+	}
+}
+
+// unidentifiedListLimit caps how many unidentified mods info.md names
+const unidentifiedListLimit = 10
+
+// renderModMetadataLines summarizes mods.json for info.md's mod statistics
+func renderModMetadataLines(backupPath string, stats Stats) string {
+	out := fmt.Sprintf("- **Identified:** %d of %d on Modrinth or CurseForge (links in `%s`)\n",
+		stats.ModsIdentified, stats.ModsListed, ModMetadataName)
+	if stats.ModLookupError != "" {
+		out += fmt.Sprintf("- **Lookups failed:** %s; mods not already cached are named from their jars\n", stats.ModLookupError)
+	}
+	data, err := os.ReadFile(filepath.Join(backupPath, ModMetadataName))
+	if err != nil {
+		return out
+	}
+	var entries []ModMetadata
+	if json.Unmarshal(data, &entries) != nil {
+		return out
+	}
+	var unidentified []string
+	for _, e := range entries {
+		if e.Unidentified {
+			unidentified = append(unidentified, e.File)
+		}
+	}
+	if len(unidentified) == 0 {
+		return out
+	}
+	shown := unidentified[:min(len(unidentified), unidentifiedListLimit)]
+	out += fmt.Sprintf("- **Unidentified:** %d mods (marked `\"unidentified\": true`)\n", len(unidentified))
+	for _, name := range shown {
+		out += fmt.Sprintf("  - %s\n", name)
+	}
+	if more := len(unidentified) - len(shown); more > 0 {
+		out += fmt.Sprintf("  - ...and %d more\n", more)
+	}
+	return out
+}
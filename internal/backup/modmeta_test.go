@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/modapi"
+	"github.com/vaalley/totem/internal/netguard"
+)
+
+// cacheModLookups writes the API answers for the jars in modsDir into the lookup cache, so
+// tests never ask the real APIs; jars without a project are cached as known misses
+func cacheModLookups(t *testing.T, modsDir string, projects map[string]*modapi.Project) {
+	t.Helper()
+	type entry struct {
+		Project *modapi.Project `json:"project,omitempty"`
+		Checked time.Time       `json:"checked"`
+	}
+	entries := map[string]entry{}
+	for name, p := range projects {
+		h, err := modapi.HashFile(filepath.Join(modsDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[h.SHA1] = entry{Project: p, Checked: time.Now()}
+	}
+	dir, err := KeyDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(entries)
+	writeTestFile(t, dir, modLookupCacheName, string(data))
+}
+
+// Hundreds of jars are hashed and looked up in batches, with every one counted in the
+// progress and written to mods.json in the order the mods were listed
+func TestModMetadataBatches(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	t.Setenv("CURSEFORGE_API_KEY", "")
+	mc := t.TempDir()
+	modsDir := filepath.Join(mc, "mods")
+	const jars = 3*lookupBatch - 7
+	projects := map[string]*modapi.Project{}
+	var mods []string
+	for i := range jars {
+		name := fmt.Sprintf("mod-%03d.jar", i)
+		mods = append(mods, name)
+		writeTestFile(t, modsDir, name, fmt.Sprintf("jar %d", i))
+		projects[name] = nil
+		if i%2 == 0 {
+			projects[name] = &modapi.Project{Source: "modrinth", ProjectID: fmt.Sprint(i), Title: fmt.Sprintf("Mod %d", i), URL: fmt.Sprintf("https://modrinth.com/mod/mod-%d", i)}
+		}
+	}
+	writeTestFile(t, modsDir, "notes.txt", "not a mod")
+	mods = append(mods, "notes.txt")
+	cacheModLookups(t, modsDir, projects)
+
+	backupPath := t.TempDir()
+	progress := &Progress{}
+	before := netguard.Attempts()
+	identified, lookupErr, err := writeModMetadata(context.Background(), modsDir, backupPath, mods, progress)
+	if err != nil || lookupErr != nil {
+		t.Fatalf("writeModMetadata: %v, %v", lookupErr, err)
+	}
+	if identified != (jars+1)/2 {
+		t.Errorf("identified %d, want %d", identified, (jars+1)/2)
+	}
+	if done, total := progress.Mods(); done != jars || total != jars {
+		t.Errorf("progress %d of %d, want %d of %d", done, total, jars, jars)
+	}
+	if netguard.Attempts() != before {
+		t.Error("cached lookups asked for the network")
+	}
+
+	data, err := os.ReadFile(filepath.Join(backupPath, ModMetadataName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []ModMetadata
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != jars {
+		t.Fatalf("mods.json has %d entries, want %d", len(entries), jars)
+	}
+	for i, e := range entries {
+		if e.File != mods[i] || e.SHA1 == "" || (i%2 == 0) == e.Unidentified {
+			t.Errorf("entry %d = %+v", i, e)
+		}
+	}
+	if e := entries[4]; e.Source != "modrinth" || e.Title != "Mod 4" || e.URL != "https://modrinth.com/mod/mod-4" {
+		t.Errorf("identified entry = %+v", e)
+	}
+}
+
+// When the APIs can't be reached, cached answers are still used, the other jars are named
+// from their own metadata and marked unidentified, and the backup does not fail
+func TestModMetadataLookupFails(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	t.Setenv("CURSEFORGE_API_KEY", "")
+	modsDir := filepath.Join(t.TempDir(), "mods")
+	writeTestFile(t, modsDir, "sodium-0.5.8.jar", "sodium")
+	writeTestZip(t, filepath.Join(modsDir, "lithium-0.11.jar"), map[string]string{
+		"fabric.mod.json": `{"id": "lithium", "name": "Lithium", "version": "0.11.2"}`,
+	})
+	writeTestFile(t, modsDir, "mystery.jar", "no metadata")
+	cacheModLookups(t, modsDir, map[string]*modapi.Project{
+		"sodium-0.5.8.jar": {Source: "modrinth", ProjectID: "AANobbMI", Title: "Sodium", URL: "https://modrinth.com/mod/sodium"},
+	})
+
+	// A cancelled lookup fails as an unreachable API would, without reaching one
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	backupPath := t.TempDir()
+	e := startModMetadata(ctx, modsDir, backupPath, nil)
+	result := &Result{Stats: Stats{ModsListed: 3}}
+	e.wait(result)
+	if result.Stats.ModLookupError == "" || len(result.Errors) > 0 || result.Stats.ModsIdentified != 1 {
+		t.Fatalf("after a failed lookup: stats %+v, errors %v", result.Stats, result.Errors)
+	}
+
+	entries, err := ReadModMetadata(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := entries["sodium-0.5.8.jar"]; e.Source != "modrinth" || e.Unidentified {
+		t.Errorf("cached sodium = %+v", e)
+	}
+	if e := entries["lithium-0.11.jar"]; e.Source != "jar" || !e.Unidentified || e.ModID != "lithium" || e.Title != "Lithium" || e.Version != "0.11.2" {
+		t.Errorf("lithium = %+v, want named from its fabric.mod.json", e)
+	}
+	if e := entries["mystery.jar"]; e.Source != "" || !e.Unidentified {
+		t.Errorf("mystery = %+v", e)
+	}
+
+	lines := renderModMetadataLines(backupPath, result.Stats)
+	for _, s := range []string{"1 of 3", "Lookups failed", "Unidentified:** 2 mods", "  - mystery.jar"} {
+		if !strings.Contains(lines, s) {
+			t.Errorf("info.md lines lack %q:\n%s", s, lines)
+		}
+	}
+
+	// Offline mode is reported as such rather than as an error message
+	e = &modEnrichment{done: make(chan struct{}), lookupErr: fmt.Errorf("mod lookups %w", netguard.ErrOffline)}
+	close(e.done)
+	e.wait(result)
+	if result.Stats.ModLookupError != "offline mode is on" {
+		t.Errorf("ModLookupError = %q", result.Stats.ModLookupError)
+	}
+}
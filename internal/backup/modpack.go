@@ -0,0 +1,303 @@
+package backup
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Override folders in an .mrpack that end up in a client instance
+var packOverrideDirs = []string{"overrides/", "client-overrides/"}
+
+// PackChange is a pack file whose installed copy differs from the pack
+type PackChange struct {
+	Path      string // Where the pack puts it, relative to the instance
+	Installed string // The installed file it was matched to, if it has another name
+}
+
+// PackDiff is how an instance deviates from the modpack it was installed from
+type PackDiff struct {
+	Name      string
+	Version   string
+	Minecraft string
+	Loader    string
+
+	Unchanged int
+	Added     []string     // Installed files in the pack's folders that the pack does not ship
+	Removed   []string     // Pack files missing from the instance
+	Changed   []PackChange // Pack files replaced by another version
+	Disabled  []string     // Pack files renamed to .disabled
+
+	OverridesChanged []string // Files from the pack's overrides that were edited
+	OverridesRemoved []string // Files from the pack's overrides that were deleted
+}
+
+// Deviations returns how many differences were found
+func (d *PackDiff) Deviations() int {
+	return len(d.Added) + len(d.Removed) + len(d.Changed) + len(d.Disabled) +
+		len(d.OverridesChanged) + len(d.OverridesRemoved)
+}
+
+// mrpackIndex is the part of modrinth.index.json needed to compare an instance
+type mrpackIndex struct {
+	Name         string            `json:"name"`
+	VersionID    string            `json:"versionId"`
+	Dependencies map[string]string `json:"dependencies"`
+	Files        []struct {
+		Path   string            `json:"path"`
+		Hashes map[string]string `json:"hashes"`
+		Env    map[string]string `json:"env"`
+	} `json:"files"`
+}
+
+// ComparePack compares instance with a Modrinth modpack (.mrpack): which of the pack's mods and
+// override files were added, removed, replaced or edited since it was installed
+func ComparePack(packPath, instance string) (*PackDiff, error) {
+	r, err := zip.OpenReader(packPath)
+	if err != nil {
+		return nil, fmt.Errorf("open modpack: %w", err)
+	}
+	defer r.Close()
+
+	var index mrpackIndex
+	found := false
+	for _, f := range r.File {
+		if f.Name == "modrinth.index.json" {
+			if err := readZipJSON(f, &index); err != nil {
+				return nil, fmt.Errorf("modrinth.index.json: %w", err)
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not a Modrinth modpack (no modrinth.index.json)", filepath.Base(packPath))
+	}
+
+	d := &PackDiff{Name: index.Name, Version: index.VersionID, Minecraft: index.Dependencies["minecraft"]}
+	for _, loader := range []string{"fabric-loader", "quilt-loader", "neoforge", "forge"} {
+		if v := index.Dependencies[loader]; v != "" {
+			d.Loader = loader + " " + v
+			break
+		}
+	}
+
+	// Pack files by folder, so files the pack does not know can be matched to a pack file by name
+	packFiles := map[string]bool{}
+	packDirs := map[string]bool{}
+	var missing []string
+	for _, f := range index.Files {
+		if f.Env["client"] == "unsupported" {
+			continue
+		}
+		rel := path.Clean(f.Path)
+		packFiles[rel] = true
+		packDirs[path.Dir(rel)] = true
+
+		target := filepath.Join(instance, filepath.FromSlash(rel))
+		sum, err := fileSHA1(target)
+		switch {
+		case err == nil && strings.EqualFold(sum, f.Hashes["sha1"]):
+			d.Unchanged++
+		case err == nil:
+			d.Changed = append(d.Changed, PackChange{Path: rel})
+		case exists(target + ".disabled"):
+			d.Disabled = append(d.Disabled, rel)
+		default:
+			missing = append(missing, rel)
+		}
+	}
+
+	// Installed files in the pack's folders that the pack does not ship
+	extra := map[string][]string{} // By modBaseName, for matching against missing pack files
+	for dir := range packDirs {
+		names, err := listFiles(filepath.Join(instance, filepath.FromSlash(dir)))
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			rel := path.Join(dir, name)
+			if packFiles[rel] || packFiles[strings.TrimSuffix(rel, ".disabled")] {
+				continue
+			}
+			key := path.Join(dir, modBaseName(name))
+			extra[key] = append(extra[key], rel)
+		}
+	}
+
+	// A missing pack file with an installed namesake (same mod, other version) was updated
+	for _, rel := range missing {
+		key := path.Join(path.Dir(rel), modBaseName(path.Base(rel)))
+		if candidates := extra[key]; len(candidates) > 0 {
+			d.Changed = append(d.Changed, PackChange{Path: rel, Installed: candidates[0]})
+			extra[key] = candidates[1:]
+			continue
+		}
+		d.Removed = append(d.Removed, rel)
+	}
+	for _, rels := range extra {
+		d.Added = append(d.Added, rels...)
+	}
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		for _, prefix := range packOverrideDirs {
+			rel, ok := strings.CutPrefix(f.Name, prefix)
+			if !ok || rel == "" {
+				continue
+			}
+			installed, err := fileSHA1(filepath.Join(instance, filepath.FromSlash(rel)))
+			if err != nil {
+				d.OverridesRemoved = append(d.OverridesRemoved, rel)
+				continue
+			}
+			if packed, err := zipEntrySHA1(f); err == nil && packed != installed {
+				d.OverridesChanged = append(d.OverridesChanged, rel)
+			}
+		}
+	}
+
+	for _, list := range [][]string{d.Added, d.Removed, d.Disabled, d.OverridesChanged, d.OverridesRemoved} {
+		sort.Strings(list)
+	}
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Path < d.Changed[j].Path })
+	return d, nil
+}
+
+// modBaseName strips the version from a mod file name, so "sodium-fabric-0.5.8+mc1.20.1.jar"
+// and "sodium-fabric-0.5.11+mc1.20.1.jar" compare equal
+func modBaseName(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, ".disabled"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	for i := 1; i < len(name); i++ {
+		if strings.ContainsRune("-_+ ", rune(name[i-1])) && name[i] >= '0' && name[i] <= '9' {
+			return strings.TrimRight(name[:i], "-_+ ")
+		}
+		// "v1.2" style versions
+		if i+1 < len(name) && strings.ContainsRune("-_+ ", rune(name[i-1])) && name[i] == 'v' && name[i+1] >= '0' && name[i+1] <= '9' {
+			return strings.TrimRight(name[:i], "-_+ ")
+		}
+	}
+	return name
+}
+
+func fileSHA1(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a folder", path)
+	}
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func zipEntrySHA1(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// comparePackStep compares the instance with config.Modpack and writes modpack.md into the backup
+func comparePackStep(config *tui.Config, paths MinecraftPaths, backupPath string, result *Result) error {
+	d, err := ComparePack(config.Modpack, paths.Root)
+	if err != nil {
+		return err
+	}
+	result.Pack = d
+	return os.WriteFile(filepath.Join(backupPath, "modpack.md"), []byte(renderPackDiffMD(config.Modpack, d)), 0644)
+}
+
+// renderPackDiffMD writes modpack.md: every deviation from the pack, for documenting customizations
+func renderPackDiffMD(packPath string, d *PackDiff) string {
+	var b strings.Builder
+	b.WriteString("# 🧩 Modpack Customizations\n\n")
+	b.WriteString(fmt.Sprintf("Compared with `%s`", filepath.Base(packPath)))
+	if d.Name != "" {
+		b.WriteString(fmt.Sprintf(" (%s %s", d.Name, d.Version))
+		if d.Minecraft != "" {
+			b.WriteString(", Minecraft " + d.Minecraft)
+		}
+		if d.Loader != "" {
+			b.WriteString(", " + d.Loader)
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(fmt.Sprintf(".\n\n%d pack files unchanged, %d deviations.\n", d.Unchanged, d.Deviations()))
+	if d.Deviations() == 0 {
+		b.WriteString("\nThe instance matches the pack.\n")
+		return b.String()
+	}
+
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString(fmt.Sprintf("\n## %s (%d)\n\n", title, len(items)))
+		for _, item := range items {
+			b.WriteString(fmt.Sprintf("- `%s`\n", item))
+		}
+	}
+	writeList("➕ Added", d.Added)
+	writeList("➖ Removed", d.Removed)
+	if len(d.Changed) > 0 {
+		b.WriteString(fmt.Sprintf("\n## 🔄 Changed Version (%d)\n\n", len(d.Changed)))
+		for _, c := range d.Changed {
+			if c.Installed != "" {
+				b.WriteString(fmt.Sprintf("- `%s` → `%s`\n", c.Path, c.Installed))
+			} else {
+				b.WriteString(fmt.Sprintf("- `%s` (replaced with a different file)\n", c.Path))
+			}
+		}
+	}
+	writeList("⏸️ Disabled", d.Disabled)
+	writeList("✏️ Edited Override Files", d.OverridesChanged)
+	writeList("🗑️ Deleted Override Files", d.OverridesRemoved)
+	b.WriteString("\nFiles mods create on first launch (most of `config/`) are only listed when the pack ships them.\n")
+	return b.String()
+}
+
+// renderPackSection summarizes modpack.md for info.md
+func renderPackSection(d *PackDiff) string {
+	if d == nil {
+		return ""
+	}
+	return fmt.Sprintf(`
+## 🧩 Modpack
+
+- **Pack:** %s %s
+- **Unchanged:** %d files
+- **Added / removed / changed / disabled:** %d / %d / %d / %d
+- **Edited or deleted override files:** %d
+
+See `+"`modpack.md`"+` for the full list.
+`, d.Name, d.Version, d.Unchanged, len(d.Added), len(d.Removed), len(d.Changed), len(d.Disabled),
+		len(d.OverridesChanged)+len(d.OverridesRemoved))
+}
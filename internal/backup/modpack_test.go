@@ -0,0 +1,144 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// writeTestMrpack writes an .mrpack shipping files (path to contents) through its index,
+// plus override files by their path in the pack
+func writeTestMrpack(t *testing.T, path string, files map[string]string, overrides map[string]string) {
+	t.Helper()
+	type packFile struct {
+		Path   string            `json:"path"`
+		Hashes map[string]string `json:"hashes"`
+		Env    map[string]string `json:"env,omitempty"`
+	}
+	index := struct {
+		Name         string            `json:"name"`
+		VersionID    string            `json:"versionId"`
+		Dependencies map[string]string `json:"dependencies"`
+		Files        []packFile        `json:"files"`
+	}{Name: "Cozy Pack", VersionID: "1.2.0", Dependencies: map[string]string{"minecraft": "1.20.1", "fabric-loader": "0.15.11"}}
+	for p, contents := range files {
+		sum := sha1.Sum([]byte(contents))
+		f := packFile{Path: p, Hashes: map[string]string{"sha1": hex.EncodeToString(sum[:])}}
+		if strings.Contains(p, "server") {
+			f.Env = map[string]string{"client": "unsupported", "server": "required"}
+		}
+		index.Files = append(index.Files, f)
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{"modrinth.index.json": string(data)}
+	for name, contents := range overrides {
+		entries[name] = contents
+	}
+	writeTestZip(t, path, entries)
+}
+
+func TestComparePack(t *testing.T) {
+	pack := filepath.Join(t.TempDir(), "cozy.mrpack")
+	writeTestMrpack(t, pack, map[string]string{
+		"mods/lithium-fabric-0.11.2.jar":  "lithium",
+		"mods/sodium-fabric-0.5.8.jar":    "sodium 0.5.8",
+		"mods/iris-1.7.0.jar":             "iris",
+		"mods/journeymap-5.9.jar":         "journeymap",
+		"mods/modmenu-7.2.jar":            "modmenu",
+		"mods/server-utilities-1.0.jar":   "server only",
+		"resourcepacks/Faithful-1.20.zip": "faithful",
+	}, map[string]string{
+		"overrides/config/sodium.json":      "{}",
+		"overrides/options.txt":             "fov:0.0\n",
+		"client-overrides/config/iris.json": "{}",
+		"overrides/config/":                 "",
+	})
+
+	mc := t.TempDir()
+	writeTestFile(t, mc, "mods/lithium-fabric-0.11.2.jar", "lithium")
+	writeTestFile(t, mc, "mods/sodium-fabric-0.5.11.jar", "sodium 0.5.11")
+	writeTestFile(t, mc, "mods/iris-1.7.0.jar.disabled", "iris")
+	writeTestFile(t, mc, "mods/modmenu-7.2.jar", "patched modmenu")
+	writeTestFile(t, mc, "mods/emi-1.1.jar", "emi")
+	writeTestFile(t, mc, "resourcepacks/Faithful-1.20.zip", "faithful")
+	writeTestFile(t, mc, "config/sodium.json", `{"fps": 60}`)
+	writeTestFile(t, mc, "config/iris.json", "{}")
+
+	d, err := ComparePack(pack, mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PackDiff{
+		Name: "Cozy Pack", Version: "1.2.0", Minecraft: "1.20.1", Loader: "fabric-loader 0.15.11",
+		Unchanged: 2,
+		Added:     []string{"mods/emi-1.1.jar"},
+		Removed:   []string{"mods/journeymap-5.9.jar"},
+		Changed: []PackChange{
+			{Path: "mods/modmenu-7.2.jar"},
+			{Path: "mods/sodium-fabric-0.5.8.jar", Installed: "mods/sodium-fabric-0.5.11.jar"},
+		},
+		Disabled:         []string{"mods/iris-1.7.0.jar"},
+		OverridesChanged: []string{"config/sodium.json"},
+		OverridesRemoved: []string{"options.txt"},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("ComparePack =\n%+v\nwant\n%+v", d, want)
+	}
+	if d.Deviations() != 7 {
+		t.Errorf("Deviations = %d, want 7", d.Deviations())
+	}
+
+	notPack := filepath.Join(t.TempDir(), "world.zip")
+	writeTestZip(t, notPack, map[string]string{"level.dat": "level"})
+	if _, err := ComparePack(notPack, mc); err == nil || !strings.Contains(err.Error(), "not a Modrinth modpack") {
+		t.Errorf("ComparePack on a plain zip: %v", err)
+	}
+}
+
+func TestModBaseName(t *testing.T) {
+	for name, want := range map[string]string{
+		"sodium-fabric-0.5.8+mc1.20.1.jar":  "sodium-fabric",
+		"sodium-fabric-0.5.11+mc1.20.1.jar": "sodium-fabric",
+		"Iris_1.7.0.jar.disabled":           "iris",
+		"journeymap-v5.9.jar":               "journeymap",
+		"modmenu.jar":                       "modmenu",
+		"3dskinlayers-fabric-1.6.jar":       "3dskinlayers-fabric",
+	} {
+		if got := modBaseName(name); got != want {
+			t.Errorf("modBaseName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// With a modpack set, the backup gets modpack.md and a summary in info.md
+func TestModpackInBackup(t *testing.T) {
+	pack := filepath.Join(t.TempDir(), "cozy.mrpack")
+	writeTestMrpack(t, pack, map[string]string{"mods/lithium-fabric-0.11.2.jar": "lithium"}, nil)
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "mods/lithium-fabric-0.11.2.jar", "lithium")
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), Modpack: pack}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	report, _ := os.ReadFile(filepath.Join(result.OutputPath, "modpack.md"))
+	if !strings.Contains(string(report), "The instance matches the pack.") {
+		t.Errorf("modpack.md = %s", report)
+	}
+	info, _ := os.ReadFile(filepath.Join(result.OutputPath, "info.md"))
+	if !strings.Contains(string(info), "## 🧩 Modpack") || !strings.Contains(string(info), "- **Unchanged:** 1 files") {
+		t.Errorf("info.md has no modpack summary")
+	}
+}
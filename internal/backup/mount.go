@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// BackupFS returns a read-only view of a backup folder or zip. Incremental backups show the
+// state their chain adds up to, like totem restore would write it. Call close when done.
+func BackupFS(backupPath string) (fsys fs.FS, close func() error, err error) {
+	if isTarBackup(backupPath) {
+		return nil, nil, fmt.Errorf("tar archives can't be browsed in place; use totem extract")
+	}
+	if _, err := os.Stat(backupPath); err != nil {
+		return nil, nil, err
+	}
+	chain := []string{backupPath}
+	// Backups without a manifest (made elsewhere) are shown on their own
+	if _, err := ReadManifest(backupPath); err == nil {
+		if chain, err = ResolveChain(backupPath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var links chainFS
+	var closers []func() error
+	closeAll := func() error {
+		var errs []error
+		for _, c := range closers {
+			errs = append(errs, c())
+		}
+		return errors.Join(errs...)
+	}
+	// Newest first, so later backups win
+	for i := len(chain) - 1; i >= 0; i-- {
+		info, err := os.Stat(chain[i])
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		if info.IsDir() {
+			links = append(links, os.DirFS(chain[i]))
+			continue
+		}
+		zr, closeZip, err := openZipBackup(chain[i])
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("%s: %w", backupName(chain[i]), err)
+		}
+		closers = append(closers, closeZip)
+		links = append(links, zr)
+	}
+	if len(links) == 1 {
+		return links[0], closeAll, nil
+	}
+	return links, closeAll, nil
+}
+
+// chainFS overlays the backups of a chain, newest first: a file comes from the newest backup
+// that has it, and folders list the entries of every backup
+type chainFS []fs.FS
+
+func (c chainFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	var dirs []fs.FS
+	for _, link := range c {
+		info, err := fs.Stat(link, name)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			if len(dirs) == 0 {
+				return link.Open(name)
+			}
+			continue
+		}
+		dirs = append(dirs, link)
+	}
+	switch len(dirs) {
+	case 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case 1:
+		return dirs[0].Open(name)
+	}
+
+	f, err := dirs[0].Open(name)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]fs.DirEntry{}
+	for _, link := range dirs {
+		entries, err := fs.ReadDir(link, name)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if _, ok := byName[e.Name()]; !ok {
+				byName[e.Name()] = e
+			}
+		}
+	}
+	merged := &mergedDir{File: f}
+	for _, e := range byName {
+		merged.entries = append(merged.entries, e)
+	}
+	sort.Slice(merged.entries, func(i, j int) bool { return merged.entries[i].Name() < merged.entries[j].Name() })
+	return merged, nil
+}
+
+// mergedDir is a folder present in several backups of a chain
+type mergedDir struct {
+	fs.File
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *mergedDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
+
+// OpenURL opens a link in the default browser; the commands that open folders handle URLs too
+func OpenURL(url string) {
+	openFolder(url)
+}
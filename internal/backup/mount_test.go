@@ -0,0 +1,65 @@
+package backup
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// An incremental backup is browsed as the state its chain adds up to, across folders and zips
+func TestBackupFSChain(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "saves/World/region/r.0.0.mca", "region")
+	dest := t.TempDir()
+	if _, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest, IncludeSaves: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	parent, _, _ := LatestBackup(dest)
+	future := time.Now().Add(time.Hour)
+	writeTestFile(t, mc, "saves/World/level.dat", "level 2")
+	writeTestFile(t, mc, "saves/World/playerdata/alex.dat", "alex")
+	for _, p := range []string{"saves/World/level.dat", "saves/World/playerdata/alex.dat"} {
+		os.Chtimes(filepath.Join(mc, filepath.FromSlash(p)), future, future)
+	}
+	link, err := PerformContext(context.Background(), &tui.Config{
+		MinecraftPath: mc, BackupDest: dest, IncludeSaves: true, Incremental: true, Parent: parent, ZipOutput: true,
+	}, nil)
+	if err != nil || link.Stats.SavesCopied != 2 {
+		t.Fatalf("incremental backup copied %d saves, %v; want 2", link.Stats.SavesCopied, err)
+	}
+
+	fsys, closeFS, err := BackupFS(link.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFS()
+	if err := fstest.TestFS(fsys, "options.txt", "saves/World/level.dat", "saves/World/region/r.0.0.mca", "saves/World/playerdata/alex.dat"); err != nil {
+		t.Error(err)
+	}
+	if data, _ := fs.ReadFile(fsys, "saves/World/level.dat"); string(data) != "level 2" {
+		t.Errorf("level.dat = %q, want the newest link's", data)
+	}
+	entries, _ := fs.ReadDir(fsys, "saves/World")
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 3 || names[0] != "level.dat" || names[1] != "playerdata" || names[2] != "region" {
+		t.Errorf("saves/World lists %q, want both links' entries", names)
+	}
+
+	tarPath := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00"+ZstdSuffix)
+	writeTestFile(t, filepath.Dir(tarPath), filepath.Base(tarPath), "")
+	if _, _, err := BackupFS(tarPath); err == nil {
+		t.Error("browsed a tar in place")
+	}
+}
@@ -0,0 +1,234 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// NBT tag types
+const (
+	tagEnd byte = iota
+	tagByte
+	tagShort
+	tagInt
+	tagLong
+	tagFloat
+	tagDouble
+	tagByteArray
+	tagString
+	tagList
+	tagCompound
+	tagIntArray
+	tagLongArray
+)
+
+// Limits on what a corrupt or hostile file can make the reader do. Lengths are only a
+// sanity cap; arrays and lists grow as their data actually arrives, so a truncated file
+// claiming a huge length fails at its end instead of allocating the claim up front.
+// Minecraft itself refuses NBT nested deeper than 512.
+const (
+	maxNBTLength = 1 << 24
+	maxNBTDepth  = 512
+	nbtChunk     = 4096 // Array elements read at a time
+)
+
+// readLevelDat reads a gzipped NBT file (such as level.dat) and returns its root compound
+func readLevelDat(path string) (map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	return readNBT(gz)
+}
+
+// readNBT decodes a named root compound tag
+func readNBT(r io.Reader) (map[string]any, error) {
+	var typ byte
+	if err := binary.Read(r, binary.BigEndian, &typ); err != nil {
+		return nil, err
+	}
+	if typ != tagCompound {
+		return nil, fmt.Errorf("nbt: root tag is not a compound")
+	}
+	if _, err := readNBTString(r); err != nil {
+		return nil, err
+	}
+	v, err := readNBTPayload(r, typ, 0)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]any), nil
+}
+
+func readNBTString(r io.Reader) (string, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func readNBTLength(r io.Reader) (int, error) {
+	var n int32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return 0, err
+	}
+	if n < 0 || n > maxNBTLength {
+		return 0, fmt.Errorf("nbt: bad length %d", n)
+	}
+	return int(n), nil
+}
+
+// readNBTArray reads n big-endian elements, a chunk at a time
+func readNBTArray[T int32 | int64](r io.Reader, n int) ([]T, error) {
+	var arr []T
+	chunk := make([]T, min(n, nbtChunk))
+	for len(arr) < n {
+		part := chunk[:min(n-len(arr), nbtChunk)]
+		if err := binary.Read(r, binary.BigEndian, part); err != nil {
+			return nil, err
+		}
+		arr = append(arr, part...)
+	}
+	return arr, nil
+}
+
+// readNBTPayload decodes one tag's payload; depth counts the lists and compounds it is in
+func readNBTPayload(r io.Reader, typ byte, depth int) (any, error) {
+	if depth > maxNBTDepth {
+		return nil, fmt.Errorf("nbt: nested deeper than %d", maxNBTDepth)
+	}
+	switch typ {
+	case tagByte:
+		var v int8
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case tagShort:
+		var v int16
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case tagInt:
+		var v int32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case tagLong:
+		var v int64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case tagFloat:
+		var v float32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case tagDouble:
+		var v float64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case tagByteArray:
+		n, err := readNBTLength(r)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case tagString:
+		return readNBTString(r)
+	case tagList:
+		var elemType byte
+		if err := binary.Read(r, binary.BigEndian, &elemType); err != nil {
+			return nil, err
+		}
+		n, err := readNBTLength(r)
+		if err != nil {
+			return nil, err
+		}
+		var list []any
+		for i := 0; i < n; i++ {
+			v, err := readNBTPayload(r, elemType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case tagCompound:
+		compound := map[string]any{}
+		for {
+			var childType byte
+			if err := binary.Read(r, binary.BigEndian, &childType); err != nil {
+				return nil, err
+			}
+			if childType == tagEnd {
+				return compound, nil
+			}
+			name, err := readNBTString(r)
+			if err != nil {
+				return nil, err
+			}
+			v, err := readNBTPayload(r, childType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			compound[name] = v
+		}
+	case tagIntArray:
+		n, err := readNBTLength(r)
+		if err != nil {
+			return nil, err
+		}
+		return readNBTArray[int32](r, n)
+	case tagLongArray:
+		n, err := readNBTLength(r)
+		if err != nil {
+			return nil, err
+		}
+		return readNBTArray[int64](r, n)
+	}
+	return nil, fmt.Errorf("nbt: unknown tag type %d", typ)
+}
+
+// nbtCompound walks nested compounds by key, returning nil if any step is missing
+func nbtCompound(root map[string]any, keys ...string) map[string]any {
+	cur := root
+	for _, k := range keys {
+		next, ok := cur[k].(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = next
+	}
+	return cur
+}
+
+// nbtStrings returns the string elements of a list tag
+func nbtStrings(v any) []string {
+	list, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, e := range list {
+		if s, ok := e.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
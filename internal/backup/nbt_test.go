@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// nbtRoot starts an NBT file: an unnamed root compound holding one tag called name
+func nbtRoot(typ byte, name string) *bytes.Buffer {
+	var b bytes.Buffer
+	b.WriteByte(tagCompound)
+	binary.Write(&b, binary.BigEndian, uint16(0))
+	b.WriteByte(typ)
+	binary.Write(&b, binary.BigEndian, uint16(len(name)))
+	b.WriteString(name)
+	return &b
+}
+
+// writeLevelDat writes a gzipped level.dat under root holding the compound c, whose values
+// may be strings, int64s, string slices (lists) and nested compounds
+func writeLevelDat(t *testing.T, root, path string, c map[string]any) {
+	t.Helper()
+	var b bytes.Buffer
+	b.WriteByte(tagCompound)
+	writeNBTString(&b, "")
+	writeNBTCompound(&b, c)
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	gz.Write(b.Bytes())
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeNBTString(b *bytes.Buffer, s string) {
+	binary.Write(b, binary.BigEndian, uint16(len(s)))
+	b.WriteString(s)
+}
+
+func writeNBTCompound(b *bytes.Buffer, c map[string]any) {
+	for name, v := range c {
+		switch v := v.(type) {
+		case string:
+			b.WriteByte(tagString)
+			writeNBTString(b, name)
+			writeNBTString(b, v)
+		case int64:
+			b.WriteByte(tagLong)
+			writeNBTString(b, name)
+			binary.Write(b, binary.BigEndian, v)
+		case []string:
+			b.WriteByte(tagList)
+			writeNBTString(b, name)
+			b.WriteByte(tagString)
+			binary.Write(b, binary.BigEndian, int32(len(v)))
+			for _, s := range v {
+				writeNBTString(b, s)
+			}
+		case map[string]any:
+			b.WriteByte(tagCompound)
+			writeNBTString(b, name)
+			writeNBTCompound(b, v)
+		}
+	}
+	b.WriteByte(tagEnd)
+}
+
+func TestReadNBT(t *testing.T) {
+	b := nbtRoot(tagList, "Enabled")
+	b.WriteByte(tagString)
+	binary.Write(b, binary.BigEndian, int32(2))
+	for _, s := range []string{"vanilla", "file/pack.zip"} {
+		binary.Write(b, binary.BigEndian, uint16(len(s)))
+		b.WriteString(s)
+	}
+	b.WriteByte(tagLongArray)
+	binary.Write(b, binary.BigEndian, uint16(1))
+	b.WriteString("L")
+	binary.Write(b, binary.BigEndian, int32(nbtChunk+1))
+	binary.Write(b, binary.BigEndian, make([]int64, nbtChunk+1))
+	b.WriteByte(tagEnd)
+
+	root, err := readNBT(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nbtStrings(root["Enabled"]); strings.Join(got, ",") != "vanilla,file/pack.zip" {
+		t.Errorf("Enabled = %v", got)
+	}
+	if got := root["L"].([]int64); len(got) != nbtChunk+1 {
+		t.Errorf("long array has %d elements, want %d", len(got), nbtChunk+1)
+	}
+}
+
+// A corrupt length fails at the end of the data rather than allocating what it claims
+func TestReadNBTBadLengths(t *testing.T) {
+	for _, typ := range []byte{tagByteArray, tagIntArray, tagLongArray} {
+		for _, n := range []int32{maxNBTLength, 1<<31 - 1, -1} {
+			b := nbtRoot(typ, "x")
+			binary.Write(b, binary.BigEndian, n)
+			b.Write(make([]byte, 64))
+			if _, err := readNBT(b); err == nil {
+				t.Errorf("tag %d with length %d: no error", typ, n)
+			}
+		}
+	}
+
+	b := nbtRoot(tagList, "x")
+	b.WriteByte(tagInt)
+	binary.Write(b, binary.BigEndian, int32(maxNBTLength))
+	if _, err := readNBT(b); err == nil {
+		t.Error("truncated list: no error")
+	}
+}
+
+func TestReadNBTDepth(t *testing.T) {
+	b := nbtRoot(tagList, "x")
+	for i := 0; i < maxNBTDepth+10; i++ {
+		b.WriteByte(tagList)
+		binary.Write(b, binary.BigEndian, int32(1))
+	}
+	if _, err := readNBT(b); err == nil || !strings.Contains(err.Error(), "nested") {
+		t.Errorf("deep nesting: got %v, want a nesting error", err)
+	}
+}
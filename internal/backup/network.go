@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Backoff between attempts when a network share drops out mid-copy
+var networkRetryDelays = []time.Duration{
+	1 * time.Second,
+	3 * time.Second,
+	10 * time.Second,
+}
+
+// NetworkParallelism caps concurrent copies to a network destination
+const NetworkParallelism = 2
+
+// partialSuffix marks a file still being written to a network destination
+const partialSuffix = ".part"
+
+// NetworkMode reports whether a backup should use the network-share copy path,
+// either because it was asked for or because the destination is a UNC path
+func NetworkMode(config *tui.Config) bool {
+	return config.NetworkDest || strings.HasPrefix(config.BackupDest, `\\`) || strings.HasPrefix(config.BackupDest, "//")
+}
+
+// retryNetwork runs copy, retrying with backoff on I/O errors and stale handles
+func retryNetwork(copy func() error) error {
+	err := copy()
+	for _, delay := range networkRetryDelays {
+		if err == nil || !isNetworkError(err) {
+			return err
+		}
+		time.Sleep(delay)
+		err = copy()
+	}
+	return err
+}
+
+// copyFileDurable writes to a .part file, syncs it and renames it into place,
+// so an interrupted transfer never leaves a truncated file that looks complete
+func copyFileDurable(src, dst string, wrap func(io.Reader) io.Reader) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	partial := dst + partialSuffix
+	dest, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = source
+	if wrap != nil {
+		r = wrap(r)
+	}
+	if _, err := io.Copy(dest, r); err != nil {
+		dest.Close()
+		os.Remove(partial)
+		return err
+	}
+	if err := dest.Sync(); err != nil {
+		dest.Close()
+		os.Remove(partial)
+		return err
+	}
+	if err := dest.Close(); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, dst)
+}
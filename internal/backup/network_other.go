@@ -0,0 +1,16 @@
+//go:build !windows
+
+package backup
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isNetworkError reports whether err is a transient failure typical of NFS or SMB mounts
+func isNetworkError(err error) bool {
+	return errors.Is(err, syscall.EIO) ||
+		errors.Is(err, syscall.ESTALE) ||
+		errors.Is(err, syscall.ETIMEDOUT) ||
+		errors.Is(err, syscall.EAGAIN)
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+
+package backup
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// I/O errors and stale handles are retried until the share comes back; other errors are not
+func TestRetryNetwork(t *testing.T) {
+	saved := networkRetryDelays
+	networkRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	defer func() { networkRetryDelays = saved }()
+
+	calls := 0
+	err := retryNetwork(func() error {
+		if calls++; calls == 1 {
+			return syscall.ESTALE
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("retryNetwork = %v after %d calls, want success on the 2nd", err, calls)
+	}
+
+	calls = 0
+	err = retryNetwork(func() error {
+		calls++
+		return syscall.EIO
+	})
+	if !errors.Is(err, syscall.EIO) || calls != 3 {
+		t.Errorf("retryNetwork of a share that stays down = %v after %d calls, want EIO after 3", err, calls)
+	}
+
+	calls = 0
+	retryNetwork(func() error {
+		calls++
+		return syscall.ENOSPC
+	})
+	if calls != 1 {
+		t.Errorf("a full disk was tried %d times, want once", calls)
+	}
+}
@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+func TestNetworkMode(t *testing.T) {
+	for dest, want := range map[string]bool{
+		`\\nas\backups`:      true,
+		"//nas/backups":      true,
+		"/mnt/nas/backups":   false,
+		`D:\Minecraft Saves`: false,
+	} {
+		if got := NetworkMode(&tui.Config{BackupDest: dest}); got != want {
+			t.Errorf("NetworkMode(%s) = %v, want %v", dest, got, want)
+		}
+	}
+	if !NetworkMode(&tui.Config{BackupDest: "/mnt/nas", NetworkDest: true}) {
+		t.Error("NetworkDest does not force network mode")
+	}
+}
+
+// A durable copy lands whole under its own name; one that fails midway leaves neither the
+// file nor its .part behind
+func TestCopyFileDurable(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "level.dat")
+	os.WriteFile(src, []byte("level"), 0644)
+	dst := filepath.Join(t.TempDir(), "level.dat")
+	if err := copyFileDurable(src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "level" || exists(dst+partialSuffix) {
+		t.Errorf("copy = %q, .part left %v", data, exists(dst+partialSuffix))
+	}
+
+	failed := filepath.Join(t.TempDir(), "level.dat")
+	dropped := errors.New("connection dropped")
+	err := copyFileDurable(src, failed, func(io.Reader) io.Reader { return &failingReader{err: dropped} })
+	if !errors.Is(err, dropped) || exists(failed) || exists(failed+partialSuffix) {
+		t.Errorf("failed copy = %v, file left %v, .part left %v", err, exists(failed), exists(failed+partialSuffix))
+	}
+}
+
+type failingReader struct{ err error }
+
+func (r *failingReader) Read([]byte) (int, error) { return 0, r.err }
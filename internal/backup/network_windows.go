@@ -0,0 +1,16 @@
+package backup
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isNetworkError reports whether err is a transient failure talking to an SMB share
+func isNetworkError(err error) bool {
+	return errors.Is(err, windows.ERROR_NETNAME_DELETED) ||
+		errors.Is(err, windows.ERROR_UNEXP_NET_ERR) ||
+		errors.Is(err, windows.ERROR_SEM_TIMEOUT) ||
+		errors.Is(err, windows.ERROR_BAD_NETPATH) ||
+		errors.Is(err, windows.ERROR_NETWORK_BUSY)
+}
@@ -0,0 +1,247 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChecklistName is the file a new-PC restore leaves in the instance, listing what to download
+const ChecklistName = "totem-checklist.md"
+
+// downloadLists are the lists a backup keeps instead of re-downloadable files
+var downloadLists = map[string]bool{
+	"mods.txt":          true,
+	"shaders.txt":       true,
+	"resourcepacks.txt": true,
+	"datapacks.txt":     true,
+	ModHashesName:       true,
+	ModMetadataName:     true,
+}
+
+// NewPCPlan is what a backup brings to a fresh machine: the files to restore into an
+// instance, and what has to be downloaded again because backups only list it
+type NewPCPlan struct {
+	Minecraft     string   // Version from info.md, "Unknown" if not recorded
+	Loader        string   // Mod loader from info.md, "Unknown" if none was detected
+	Items         []string // Top-level instance items restored, e.g. "options.txt", "saves"
+	Files         int      // Files restored
+	Mods          []string // Mod files listed in mods.txt and not in the backup itself
+	Shaders       []string
+	ResourcePacks []string
+	ModHashes     bool              // The backup recorded mod hashes, so downloads can be checked
+	ModPages      map[string]string // Project pages of listed mods, from mods.json
+}
+
+// instancePath maps a file in a backup to where it goes in an instance, or "" for Totem's
+// own files (reports, lists, manifest) and crash reports, which a new PC has no use for
+func instancePath(rel string) string {
+	top, rest, nested := strings.Cut(rel, "/")
+	switch {
+	case !nested && (rel == ManifestName || rel == SignatureName || strings.HasSuffix(rel, ".md") ||
+		strings.HasSuffix(rel, ParitySuffix) || downloadLists[rel]):
+		return ""
+	case top == CrashReportsDir:
+		return ""
+	case top == "shader_configs" && nested:
+		return path.Join("shaderpacks", rest)
+	}
+	return rel
+}
+
+// PlanNewPC reads what backupPath would restore onto a new PC
+func PlanNewPC(backupPath string) (*NewPCPlan, error) {
+	if isEncrypted(backupPath) {
+		return nil, fmt.Errorf("%s is encrypted; decrypt it with gpg or age first", filepath.Base(backupPath))
+	}
+	fsys, closeFS, err := BackupFS(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFS()
+
+	plan := &NewPCPlan{Minecraft: "Unknown", Loader: "Unknown"}
+	if info, err := fs.ReadFile(fsys, "info.md"); err == nil {
+		plan.Minecraft = infoTableValue(string(info), "Minecraft Version", plan.Minecraft)
+		plan.Loader = infoTableValue(string(info), "Mod Loader", plan.Loader)
+	}
+
+	items := map[string]bool{}
+	jars := map[string]bool{}
+	err = fs.WalkDir(fsys, ".", func(rel string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		target := instancePath(rel)
+		if target == "" {
+			return nil
+		}
+		top, _, _ := strings.Cut(target, "/")
+		items[top] = true
+		plan.Files++
+		if strings.HasPrefix(target, "mods/") {
+			jars[path.Base(target)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for item := range items {
+		plan.Items = append(plan.Items, item)
+	}
+	sort.Strings(plan.Items)
+
+	readList := func(name string) []string {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil
+		}
+		var names []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !jars[line] {
+				names = append(names, line)
+			}
+		}
+		return names
+	}
+	plan.Mods = readList("mods.txt")
+	plan.Shaders = readList("shaders.txt")
+	plan.ResourcePacks = readList("resourcepacks.txt")
+	_, err = fs.Stat(fsys, ModHashesName)
+	plan.ModHashes = err == nil
+	if data, err := fs.ReadFile(fsys, ModMetadataName); err == nil {
+		var entries []ModMetadata
+		if json.Unmarshal(data, &entries) == nil {
+			plan.ModPages = map[string]string{}
+			for _, e := range entries {
+				if e.URL != "" {
+					plan.ModPages[e.File] = e.URL
+				}
+			}
+		}
+	}
+	return plan, nil
+}
+
+// infoTableValue returns a property from the tables in info.md, or fallback
+func infoTableValue(info, property, fallback string) string {
+	for _, line := range strings.Split(info, "\n") {
+		cells := strings.Split(line, "|")
+		if len(cells) >= 3 && strings.TrimSpace(cells[1]) == property {
+			if v := strings.Trim(strings.TrimSpace(cells[2]), "`"); v != "" {
+				return v
+			}
+		}
+	}
+	return fallback
+}
+
+// RestoreNewPC writes the settings, configs, saves and other files of backupPath (and its
+// chain) into instance, in the instance's own layout. Without overwrite, files the instance
+// already has are kept. It returns the number of files written.
+func RestoreNewPC(backupPath, instance string, overwrite bool) (int, error) {
+	fsys, closeFS, err := BackupFS(backupPath)
+	if err != nil {
+		return 0, err
+	}
+	defer closeFS()
+
+	count := 0
+	err = fs.WalkDir(fsys, ".", func(rel string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		target := instancePath(rel)
+		if target == "" {
+			return nil
+		}
+		dst := filepath.Join(instance, filepath.FromSlash(target))
+		if !overwrite && exists(dst) {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		in, err := fsys.Open(rel)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return fmt.Errorf("%s: %w", target, err)
+		}
+		count++
+		return out.Close()
+	})
+	return count, err
+}
+
+// WriteChecklist saves the download checklist for plan as ChecklistName in instance and
+// returns its path
+func WriteChecklist(instance, backupPath string, plan *NewPCPlan) (string, error) {
+	var b strings.Builder
+	b.WriteString("# 🗿 New PC Checklist\n\n")
+	fmt.Fprintf(&b, "Restored from `%s`.\n\n", filepath.Base(backupPath))
+	b.WriteString("## 1. Game\n\n")
+	if plan.Minecraft != "Unknown" {
+		fmt.Fprintf(&b, "- [ ] Minecraft %s\n", plan.Minecraft)
+	} else {
+		b.WriteString("- [ ] Minecraft (the backup does not record the version)\n")
+	}
+	if plan.Loader != "Unknown" {
+		fmt.Fprintf(&b, "- [ ] %s loader for that version\n", plan.Loader)
+	}
+
+	sections := []struct {
+		title, kind string
+		names       []string
+	}{
+		{"Mods", "mods", plan.Mods},
+		{"Shaders", "shaders", plan.Shaders},
+		{"Resource Packs", "resourcepacks", plan.ResourcePacks},
+	}
+	n := 2
+	for _, s := range sections {
+		if len(s.names) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", n, s.title)
+		for _, name := range s.names {
+			if page, ok := plan.ModPages[name]; ok && s.kind == "mods" {
+				fmt.Fprintf(&b, "- [ ] %s ([page](%s))\n", name, page)
+				continue
+			}
+			fmt.Fprintf(&b, "- [ ] %s ([search](%s))\n", name, DownloadSearchURL(s.kind, name))
+		}
+		n++
+	}
+	if plan.ModHashes && len(plan.Mods) > 0 {
+		fmt.Fprintf(&b, "\nOnce the mods are in `mods/`, run `totem check-mods %s --instance %s` to\n", backupPath, instance)
+		b.WriteString("check them against the files the backup was made from.\n")
+	}
+
+	dst := filepath.Join(instance, ChecklistName)
+	if err := os.WriteFile(dst, []byte(b.String()), 0644); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// DownloadSearchURL returns the Modrinth search for a listed file, kind being "mods",
+// "shaders" or "resourcepacks"
+func DownloadSearchURL(kind, name string) string {
+	return "https://modrinth.com/" + kind + "?q=" + url.QueryEscape(modBaseName(name))
+}
@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeNewPCBackup writes a backup with settings, a world, shader settings and download lists
+func writeNewPCBackup(t *testing.T) string {
+	t.Helper()
+	backupPath := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00")
+	for path, contents := range map[string]string{
+		"info.md":                "| Minecraft Version | 1.20.1 |\n| Mod Loader | `Fabric` |\n",
+		"options.txt":            "fov:0.0\n",
+		"saves/World/level.dat":  "level",
+		"shader_configs/BSL.txt": "shadowMapResolution=2048",
+		"crash-reports/c.txt":    "crash",
+		"mods.txt":               "sodium-fabric-0.5.8.jar\niris-1.7.0.jar\n",
+		"mods/iris-1.7.0.jar":    "iris, kept because --include mods",
+		"shaders.txt":            "BSL_v8.2.zip\n",
+		ModHashesName:            "",
+		ModMetadataName:          `[{"file": "sodium-fabric-0.5.8.jar", "sha1": "x", "url": "https://modrinth.com/mod/sodium"}]`,
+	} {
+		writeTestFile(t, backupPath, path, contents)
+	}
+	return backupPath
+}
+
+// A new PC gets the settings, worlds and shader settings, plus a checklist of what to download
+func TestPlanNewPC(t *testing.T) {
+	backupPath := writeNewPCBackup(t)
+	plan, err := PlanNewPC(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Minecraft != "1.20.1" || plan.Loader != "Fabric" || !plan.ModHashes {
+		t.Errorf("plan = %+v", plan)
+	}
+	if want := []string{"mods", "options.txt", "saves", "shaderpacks"}; !slices.Equal(plan.Items, want) || plan.Files != 4 {
+		t.Errorf("restores %q in %d files, want %q in 4", plan.Items, plan.Files, want)
+	}
+	// iris is in the backup itself, so only sodium needs downloading
+	if !slices.Equal(plan.Mods, []string{"sodium-fabric-0.5.8.jar"}) || !slices.Equal(plan.Shaders, []string{"BSL_v8.2.zip"}) {
+		t.Errorf("downloads: mods %q, shaders %q", plan.Mods, plan.Shaders)
+	}
+
+	instance := t.TempDir()
+	writeTestFile(t, instance, "options.txt", "fov:1.0\n")
+	if n, err := RestoreNewPC(backupPath, instance, false); err != nil || n != 3 {
+		t.Errorf("RestoreNewPC = %d, %v; want 3 files, keeping options.txt", n, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(instance, "options.txt")); string(data) != "fov:1.0\n" {
+		t.Error("options.txt was overwritten")
+	}
+	for _, p := range []string{"shaderpacks/BSL.txt", "saves/World/level.dat", "mods/iris-1.7.0.jar"} {
+		if !exists(filepath.Join(instance, filepath.FromSlash(p))) {
+			t.Errorf("%s was not restored", p)
+		}
+	}
+	for _, p := range []string{"info.md", "mods.txt", "crash-reports", ModMetadataName} {
+		if exists(filepath.Join(instance, p)) {
+			t.Errorf("%s was restored into the instance", p)
+		}
+	}
+
+	checklist, err := WriteChecklist(instance, backupPath, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(checklist)
+	for _, s := range []string{
+		"- [ ] Minecraft 1.20.1",
+		"- [ ] Fabric loader for that version",
+		"- [ ] sodium-fabric-0.5.8.jar ([page](https://modrinth.com/mod/sodium))",
+		"- [ ] BSL_v8.2.zip ([search](https://modrinth.com/shaders?q=bsl))",
+		"totem check-mods",
+	} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("the checklist lacks %q:\n%s", s, data)
+		}
+	}
+}
+
+// An encrypted backup can't be planned until it's decrypted
+func TestPlanNewPCEncrypted(t *testing.T) {
+	backupPath := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00.zip.age")
+	writeTestFile(t, filepath.Dir(backupPath), filepath.Base(backupPath), "age")
+	if _, err := PlanNewPC(backupPath); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("PlanNewPC = %v, want an encrypted error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/netguard"
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// writeTestFile creates path under root with contents, making its folders
+func writeTestFile(t *testing.T, root, path, contents string) {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestOfflineBackupAndRestore checks that backing up and restoring never ask for the network.
+// Attempts counts every ask, let through or not, so offline mode is not switched on: it
+// would stay on for the rest of the package's tests.
+func TestOfflineBackupAndRestore(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "mods/example.jar", "not really a jar")
+	writeTestFile(t, mc, "saves/World/region/r.0.0.mca", "region data")
+	writeTestFile(t, mc, "screenshots/2024-01-01_00.00.00.png", "png")
+
+	for _, zipOutput := range []bool{false, true} {
+		before := netguard.Attempts()
+		config := &tui.Config{
+			MinecraftPath: mc,
+			BackupDest:    t.TempDir(),
+			IncludeSaves:  true,
+			SkipJunk:      true,
+			ZipOutput:     zipOutput,
+		}
+		result, err := PerformQuiet(config)
+		if err != nil {
+			t.Fatalf("backup (zip %v): %v", zipOutput, err)
+		}
+		if !result.Success {
+			t.Fatalf("backup (zip %v) failed: %v", zipOutput, result.Errors)
+		}
+
+		restored := t.TempDir()
+		if _, err := Restore(result.OutputPath, restored); err != nil {
+			t.Fatalf("restore (zip %v): %v", zipOutput, err)
+		}
+		if data, err := os.ReadFile(filepath.Join(restored, "saves", "World", "region", "r.0.0.mca")); err != nil || string(data) != "region data" {
+			t.Errorf("restore (zip %v): world not restored: %q, %v", zipOutput, data, err)
+		}
+
+		if n := netguard.Attempts() - before; n != 0 {
+			t.Errorf("backup and restore (zip %v) asked for the network %d times", zipOutput, n)
+		}
+	}
+}
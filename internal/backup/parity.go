@@ -0,0 +1,313 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// ParitySuffix is appended to an archive's name for its parity file
+const ParitySuffix = ".parity"
+
+const (
+	parityMagic     = "TOTEMPAR2" // Reed-Solomon recovery blocks
+	xorParityMagic  = "TOTEMPAR1" // One XOR block per group, written by earlier versions
+	parityBlockSize = ParityBlockSize
+	parityGroupSize = 16 // data blocks per group; each recovery block adds ~6%
+)
+
+// ParityBlockSize is the size of the blocks parity checks and rebuilds
+const ParityBlockSize = 64 << 10
+
+// MaxParityBlocks is the most recovery blocks a group can have: as many as it has data blocks
+const MaxParityBlocks = parityGroupSize
+
+// parityHeader describes the protected file; recovery blocks follow it on disk, group by group
+type parityHeader struct {
+	BlockSize    int      `json:"block_size"`
+	GroupSize    int      `json:"group_size"`
+	Recovery     int      `json:"recovery,omitempty"` // Recovery blocks per group; 1 in XOR files
+	FileSize     int64    `json:"file_size"`
+	Hashes       []string `json:"hashes"`                  // SHA-256 of every data block
+	ParityHashes []string `json:"parity_hashes,omitempty"` // SHA-256 of every recovery block; none in XOR files
+
+	xor bool // An earlier version's file, with XOR parity
+}
+
+// ParityReport summarizes a parity check
+type ParityReport struct {
+	Blocks   int
+	Damaged  int
+	Repaired int
+	Recovery int // Damaged blocks each group of ParityGroupBlocks can rebuild
+}
+
+// ParityGroupBlocks is how many data blocks share a group's recovery blocks
+const ParityGroupBlocks = parityGroupSize
+
+// writeParity creates path+".parity" holding block hashes and recovery Reed-Solomon blocks
+// per group of data blocks. Any recovery damaged blocks in a group, data or recovery ones,
+// can be rebuilt from the rest. Groups are written as they are read, so memory stays at one
+// group whatever the archive's size.
+func writeParity(path string, recovery int) (err error) {
+	enc, err := reedsolomon.New(parityGroupSize, recovery)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := parityHeader{BlockSize: parityBlockSize, GroupSize: parityGroupSize, Recovery: recovery, FileSize: info.Size()}
+	blocks := int((info.Size() + parityBlockSize - 1) / parityBlockSize)
+	groups := groupCount(blocks, parityGroupSize)
+
+	// Every hash is 64 hex digits, so the header's length is known before the file is read
+	placeholder := strings.Repeat("0", sha256.Size*2)
+	header.Hashes = slices.Repeat([]string{placeholder}, blocks)
+	header.ParityHashes = slices.Repeat([]string{placeholder}, groups*recovery)
+	meta, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(path + ParitySuffix)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	w := bufio.NewWriter(out)
+	w.WriteString(parityMagic)
+	binary.Write(w, binary.BigEndian, uint32(len(meta)))
+	w.Write(meta)
+
+	shards := make([][]byte, parityGroupSize+recovery)
+	for i := range shards {
+		shards[i] = make([]byte, parityBlockSize)
+	}
+	for g := 0; g < groups; g++ {
+		// The last group is padded with zero blocks, which are not stored
+		for k := range parityGroupSize {
+			i := g*parityGroupSize + k
+			if i >= blocks {
+				clear(shards[k])
+				continue
+			}
+			n, err := io.ReadFull(f, shards[k])
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return err
+			}
+			clear(shards[k][n:])
+			header.Hashes[i] = blockHash(shards[k][:n])
+		}
+		if err := enc.Encode(shards); err != nil {
+			return err
+		}
+		for r, block := range shards[parityGroupSize:] {
+			header.ParityHashes[g*recovery+r] = blockHash(block)
+			if _, err := w.Write(block); err != nil {
+				return err
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// Fill in the real hashes, which take exactly the room left for them
+	meta, err = json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	_, err = out.WriteAt(meta, int64(len(parityMagic)+4))
+	return err
+}
+
+func groupCount(blocks, groupSize int) int {
+	return (blocks + groupSize - 1) / groupSize
+}
+
+func blockHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func xorInto(dst, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
+
+// readParity reads a parity file's header and returns it with the offset its recovery
+// blocks start at. The blocks themselves are read a group at a time by CheckParity.
+func readParity(f *os.File) (*parityHeader, int64, error) {
+	prefix := make([]byte, len(parityMagic)+4)
+	_, err := io.ReadFull(f, prefix)
+	magic := string(prefix[:len(parityMagic)])
+	if err != nil || (magic != parityMagic && magic != xorParityMagic) {
+		return nil, 0, fmt.Errorf("%s is not a totem parity file", f.Name())
+	}
+	metaLen := int64(binary.BigEndian.Uint32(prefix[len(parityMagic):]))
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := int64(len(prefix)) + metaLen
+	if offset > info.Size() {
+		return nil, 0, fmt.Errorf("%s is truncated", f.Name())
+	}
+
+	var header parityHeader
+	if err := json.NewDecoder(io.LimitReader(f, metaLen)).Decode(&header); err != nil {
+		return nil, 0, err
+	}
+	if magic == xorParityMagic {
+		header.xor, header.Recovery = true, 1
+	}
+	if header.BlockSize <= 0 || header.GroupSize <= 0 || header.Recovery <= 0 {
+		return nil, 0, fmt.Errorf("%s has a damaged header", f.Name())
+	}
+	recoveryBlocks := groupCount(len(header.Hashes), header.GroupSize) * header.Recovery
+	if !header.xor && len(header.ParityHashes) != recoveryBlocks {
+		return nil, 0, fmt.Errorf("%s has a damaged header", f.Name())
+	}
+	if info.Size()-offset != int64(recoveryBlocks)*int64(header.BlockSize) {
+		return nil, 0, fmt.Errorf("%s has the wrong amount of parity data", f.Name())
+	}
+	return &header, offset, nil
+}
+
+// CheckParity verifies an archive against its parity file, rebuilding damaged blocks when
+// repair is set. A group with more damaged blocks than it has recovery blocks can't be
+// rebuilt; its blocks count as damaged but not repaired.
+func CheckParity(path string, repair bool) (*ParityReport, error) {
+	pf, err := os.Open(path + ParitySuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+	header, parityStart, err := readParity(pf)
+	if err != nil {
+		return nil, err
+	}
+	var enc reedsolomon.Encoder
+	if !header.xor {
+		if enc, err = reedsolomon.New(header.GroupSize, header.Recovery); err != nil {
+			return nil, fmt.Errorf("%s: %w", pf.Name(), err)
+		}
+	}
+
+	flag := os.O_RDONLY
+	if repair {
+		flag = os.O_RDWR
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if repair {
+		if err := f.Truncate(header.FileSize); err != nil {
+			return nil, err
+		}
+	}
+
+	blockLen := func(i int) int {
+		return int(min(header.FileSize-int64(i)*int64(header.BlockSize), int64(header.BlockSize)))
+	}
+	// readBlock returns block i zero-padded to the block size, or nil when it is damaged
+	readBlock := func(i int) ([]byte, error) {
+		buf := make([]byte, header.BlockSize)
+		n, err := f.ReadAt(buf, int64(i)*int64(header.BlockSize))
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		clear(buf[min(n, blockLen(i)):])
+		if blockHash(buf[:blockLen(i)]) != header.Hashes[i] {
+			return nil, nil
+		}
+		return buf, nil
+	}
+
+	report := &ParityReport{Blocks: len(header.Hashes), Recovery: header.Recovery}
+	for g := 0; g < groupCount(len(header.Hashes), header.GroupSize); g++ {
+		first := g * header.GroupSize
+		shards := make([][]byte, header.GroupSize+header.Recovery)
+		var damaged []int
+		for k := range header.GroupSize {
+			i := first + k
+			if i >= len(header.Hashes) {
+				shards[k] = make([]byte, header.BlockSize)
+				continue
+			}
+			if shards[k], err = readBlock(i); err != nil {
+				return report, err
+			} else if shards[k] == nil {
+				damaged = append(damaged, k)
+			}
+		}
+		report.Damaged += len(damaged)
+		if !repair || len(damaged) == 0 || len(damaged) > header.Recovery {
+			continue
+		}
+
+		for r := range header.Recovery {
+			block := make([]byte, header.BlockSize)
+			j := g*header.Recovery + r
+			if _, err := pf.ReadAt(block, parityStart+int64(j)*int64(header.BlockSize)); err != nil {
+				return report, err
+			}
+			// XOR files have no hashes for their parity; the rebuilt block's hash checks it
+			if header.xor || blockHash(block) == header.ParityHashes[j] {
+				shards[header.GroupSize+r] = block
+			}
+		}
+		if header.xor {
+			rebuilt := shards[header.GroupSize]
+			if rebuilt == nil {
+				continue
+			}
+			for k, block := range shards[:header.GroupSize] {
+				if k != damaged[0] {
+					xorInto(rebuilt, block)
+				}
+			}
+			shards[damaged[0]] = rebuilt
+		} else if err := enc.ReconstructData(shards); err != nil {
+			continue // Too many recovery blocks are damaged as well
+		}
+
+		for _, k := range damaged {
+			i := first + k
+			block := shards[k][:blockLen(i)]
+			if blockHash(block) != header.Hashes[i] {
+				continue
+			}
+			if _, err := f.WriteAt(block, int64(i)*int64(header.BlockSize)); err != nil {
+				return report, err
+			}
+			report.Repaired++
+		}
+	}
+	return report, nil
+}
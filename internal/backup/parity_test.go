@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A damaged block is found and rebuilt from a streamed parity file
+func TestParityRepair(t *testing.T) {
+	// Two full groups and a short last block
+	data := make([]byte, 2*parityGroupSize*parityBlockSize+1000)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00.zip")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeParity(path, 1); err != nil {
+		t.Fatalf("writeParity: %v", err)
+	}
+
+	if report, err := CheckParity(path, false); err != nil || report.Blocks != 2*parityGroupSize+1 || report.Damaged != 0 {
+		t.Fatalf("CheckParity of an intact archive = %+v, %v", report, err)
+	}
+
+	damaged := bytes.Clone(data)
+	damaged[parityGroupSize*parityBlockSize+5] ^= 0xff
+	damaged[len(damaged)-1] ^= 0xff
+	if err := os.WriteFile(path, damaged, 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, err := CheckParity(path, true)
+	if err != nil || report.Damaged != 2 || report.Repaired != 2 {
+		t.Fatalf("CheckParity repair = %+v, %v; want 2 damaged and repaired", report, err)
+	}
+	if repaired, _ := os.ReadFile(path); !bytes.Equal(repaired, data) {
+		t.Error("the repaired archive differs from the original")
+	}
+}
+
+// With more recovery blocks, a group survives as many damaged blocks, even with one of its
+// recovery blocks damaged too; past that, the report says what was left
+func TestParityReedSolomon(t *testing.T) {
+	data := make([]byte, parityGroupSize*parityBlockSize+3000)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00.zip")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeParity(path, 4); err != nil {
+		t.Fatalf("writeParity: %v", err)
+	}
+
+	damaged := bytes.Clone(data)
+	for _, block := range []int{0, 5, 15} {
+		damaged[block*parityBlockSize+7] ^= 0xff
+	}
+	if err := os.WriteFile(path, damaged, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pf, err := os.OpenFile(path+ParitySuffix, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := pf.Stat()
+	pf.WriteAt([]byte{0xff, 0xff}, info.Size()-10) // The last group's last recovery block
+	pf.Close()
+
+	report, err := CheckParity(path, true)
+	if err != nil || report.Damaged != 3 || report.Repaired != 3 || report.Recovery != 4 {
+		t.Fatalf("CheckParity repair = %+v, %v; want 3 damaged and repaired", report, err)
+	}
+	if repaired, _ := os.ReadFile(path); !bytes.Equal(repaired, data) {
+		t.Error("the repaired archive differs from the original")
+	}
+
+	damaged = bytes.Clone(data)
+	for block := range 5 {
+		damaged[block*parityBlockSize] ^= 0xff
+	}
+	os.WriteFile(path, damaged, 0644)
+	if report, err := CheckParity(path, true); err != nil || report.Damaged != 5 || report.Repaired != 0 {
+		t.Errorf("CheckParity of 5 damaged blocks with 4 recovery blocks = %+v, %v", report, err)
+	}
+}
+
+// Parity files from before Reed-Solomon, with one XOR block per group, still repair
+func TestParityXORFile(t *testing.T) {
+	data := make([]byte, parityBlockSize*3+10)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00.zip")
+	header := parityHeader{BlockSize: parityBlockSize, GroupSize: parityGroupSize, FileSize: int64(len(data))}
+	parity := make([]byte, parityBlockSize)
+	for i := 0; i*parityBlockSize < len(data); i++ {
+		block := data[i*parityBlockSize : min((i+1)*parityBlockSize, len(data))]
+		header.Hashes = append(header.Hashes, blockHash(block))
+		xorInto(parity, block)
+	}
+	meta, _ := json.Marshal(header)
+	var par bytes.Buffer
+	par.WriteString(xorParityMagic)
+	binary.Write(&par, binary.BigEndian, uint32(len(meta)))
+	par.Write(meta)
+	par.Write(parity)
+	os.WriteFile(path+ParitySuffix, par.Bytes(), 0644)
+
+	damaged := bytes.Clone(data)
+	damaged[parityBlockSize*3+2] ^= 0xff
+	os.WriteFile(path, damaged, 0644)
+	report, err := CheckParity(path, true)
+	if err != nil || report.Damaged != 1 || report.Repaired != 1 {
+		t.Fatalf("CheckParity repair = %+v, %v; want 1 damaged and repaired", report, err)
+	}
+	if repaired, _ := os.ReadFile(path); !bytes.Equal(repaired, data) {
+		t.Error("the repaired archive differs from the original")
+	}
+}
@@ -0,0 +1,59 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// PortableMarker is the file that, next to the executable, turns on portable mode
+const PortableMarker = "totem.portable"
+
+// portableForced turns on portable mode without a marker; see SetPortable
+var portableForced bool
+
+// SetPortable turns on portable mode for the rest of the run, for --portable
+func SetPortable() {
+	portableForced = true
+}
+
+// portableRoot returns the folder holding the executable, with symlinks resolved
+func portableRoot() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return filepath.Dir(exe), nil
+}
+
+// Portable reports whether Totem runs in portable mode: with --portable, or with a
+// PortableMarker next to the executable
+func Portable() bool {
+	if portableForced {
+		return true
+	}
+	root, err := portableRoot()
+	return err == nil && exists(filepath.Join(root, PortableMarker))
+}
+
+// PortableDir returns the folder portable mode keeps Totem's state in: totem-data next to
+// the executable, so a copy on a USB stick carries its keys and settings with it
+func PortableDir() (string, error) {
+	root, err := portableRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "totem-data"), nil
+}
+
+// PortableBackups returns the default backup destination in portable mode: TotemBackups
+// next to the executable, so backups and their catalog stay on the stick too
+func PortableBackups() (string, error) {
+	root, err := portableRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "TotemBackups"), nil
+}
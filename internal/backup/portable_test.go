@@ -0,0 +1,42 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A totem.portable marker next to the binary, or --portable, keeps state and backups beside it
+func TestPortable(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	root, err := portableRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Portable() {
+		t.Fatal("portable without a marker or --portable")
+	}
+
+	marker := filepath.Join(root, PortableMarker)
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Skipf("the test binary's folder is read-only: %v", err)
+	}
+	defer os.Remove(marker)
+	if !Portable() {
+		t.Error("the marker next to the binary did not turn on portable mode")
+	}
+	// Portable mode wins over $TOTEM_CONFIG, so a stick carries its own keys
+	if dir, err := KeyDir(); err != nil || dir != filepath.Join(root, "totem-data") {
+		t.Errorf("KeyDir = %s, %v; want totem-data next to the binary", dir, err)
+	}
+	if dir, err := PortableBackups(); err != nil || dir != filepath.Join(root, "TotemBackups") {
+		t.Errorf("PortableBackups = %s, %v", dir, err)
+	}
+	os.Remove(marker)
+
+	t.Cleanup(func() { portableForced = false })
+	SetPortable()
+	if !Portable() {
+		t.Error("--portable did not turn on portable mode")
+	}
+}
@@ -0,0 +1,117 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// maxPreflightProblems caps how many unreadable paths are reported
+const maxPreflightProblems = 50
+
+// Preflight checks that everything the backup will read can be opened, before copying starts.
+// Sources are only ever opened read-only; nothing is written under MinecraftPath.
+func Preflight(config *tui.Config) []string {
+	paths := buildPaths(config.MinecraftPath)
+	roots := append(copyRoots(config, paths), paths.Mods, paths.Shaderpacks, paths.Resourcepacks)
+	if !config.IncludeSaves {
+		roots = append(roots, paths.Saves)
+	}
+
+	var problems []string
+	report := func(path string, err error) {
+		if len(problems) < maxPreflightProblems {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, unwrapPathError(err)))
+		}
+	}
+
+	for _, root := range roots {
+		if !exists(root) {
+			continue
+		}
+		walkDirFollow(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				report(path, err)
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// Opening an online-only file starts a download; the copy will fetch it later
+			if info, err := d.Info(); err == nil && isCloudOnly(info) {
+				return nil
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				report(path, err)
+				return nil
+			}
+			// Reading one byte catches locks that only fail on read
+			_, err = f.Read(make([]byte, 1))
+			f.Close()
+			if err != nil && err != io.EOF {
+				report(path, err)
+			}
+			return nil
+		})
+	}
+	return problems
+}
+
+// checkDestination refuses backup destinations inside the Minecraft folder,
+// which would make totem write into (and recursively copy) its own source.
+// Both paths are resolved through symlinks and junctions first, so a link
+// pointing back into the instance is caught too.
+func checkDestination(config *tui.Config) error {
+	src, err1 := resolvePath(config.MinecraftPath)
+	dst, err2 := resolvePath(config.BackupDest)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	// Windows and macOS folders are case-insensitive by default
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		src, dst = strings.ToLower(src), strings.ToLower(dst)
+	}
+	rel, err := filepath.Rel(src, dst)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("backup destination %s is inside the minecraft folder %s; the backup would copy itself, pick a folder outside it",
+			config.BackupDest, config.MinecraftPath)
+	}
+	return nil
+}
+
+// resolvePath returns path made absolute with links resolved. The destination may not
+// exist yet, so the deepest existing parent is resolved and the rest appended.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// unwrapPathError drops the path from *fs.PathError since it's printed separately
+func unwrapPathError(err error) error {
+	if pe, ok := err.(*fs.PathError); ok {
+		return pe.Err
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Destinations inside the instance are refused, also when they reach it through a link or
+// do not exist yet; a sibling folder with a matching prefix is fine
+func TestCheckDestination(t *testing.T) {
+	root := t.TempDir()
+	mc := filepath.Join(root, "mc")
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(mc, link); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+
+	for dest, refused := range map[string]bool{
+		mc:                                  true,
+		filepath.Join(mc, "backups"):        true,
+		filepath.Join(link, "new", "later"): true,
+		filepath.Join(root, "mc-backups"):   false,
+		root:                                false,
+	} {
+		err := checkDestination(&tui.Config{MinecraftPath: mc, BackupDest: dest})
+		if (err != nil) != refused {
+			t.Errorf("checkDestination(%s) = %v, want refused %v", dest, err, refused)
+		}
+	}
+}
+
+// Preflight names files the backup would fail to read, here a link to a deleted file
+func TestPreflight(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "mods/sodium.jar", "jar")
+	if err := os.Symlink(filepath.Join(t.TempDir(), "gone.jar"), filepath.Join(mc, "mods", "linked.jar")); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+
+	problems := Preflight(&tui.Config{MinecraftPath: mc})
+	if len(problems) != 1 || !strings.Contains(problems[0], "linked.jar") {
+		t.Errorf("Preflight = %v, want linked.jar", problems)
+	}
+}
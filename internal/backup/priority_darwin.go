@@ -0,0 +1,8 @@
+package backup
+
+import "syscall"
+
+// lowerPriority drops CPU niceness; macOS throttles I/O for low-priority processes
+func lowerPriority() error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, 19)
+}
@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// lowerPriority drops CPU niceness and switches to the idle I/O class (like `ionice -c3`).
+// On Linux both only apply to the thread they name, so every thread of the process is
+// lowered. Threads the runtime starts later are cloned from a lowered one and inherit both.
+func lowerPriority() error {
+	// Repeat until a pass finds no thread it has not seen, in case one started meanwhile
+	done := map[int]bool{}
+	for {
+		tids, err := threadIDs()
+		if err != nil {
+			return err
+		}
+		changed := false
+		for _, tid := range tids {
+			if done[tid] {
+				continue
+			}
+			if err := lowerThread(tid); err != nil && err != syscall.ESRCH {
+				return err
+			}
+			done[tid], changed = true, true
+		}
+		if !changed {
+			return nil
+		}
+	}
+}
+
+// lowerThread lowers the CPU and I/O priority of one thread; ESRCH means it has exited
+func lowerThread(tid int) error {
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, 19); err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// threadIDs lists the process's threads from /proc/self/task
+func threadIDs() ([]int, error) {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, err
+	}
+	tids := make([]int, 0, len(entries))
+	for _, e := range entries {
+		if tid, err := strconv.Atoi(e.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids, nil
+}
@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// threadNice reads a thread's niceness, field 19 of its stat file
+func threadNice(t *testing.T, stat string) int {
+	t.Helper()
+	data, err := os.ReadFile(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The command name in field 2 may hold spaces, so count from the ')' that closes it
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	nice, err := strconv.Atoi(fields[16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nice
+}
+
+// priorityChildEnv marks the test binary re-run to lower its own priority
+const priorityChildEnv = "TOTEM_TEST_PRIORITY_CHILD"
+
+func TestLowerPriorityEveryThread(t *testing.T) {
+	// An unprivileged process can't raise its priority back, so the assertions run in a
+	// child process and the rest of the package's tests keep their speed
+	if os.Getenv(priorityChildEnv) == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestLowerPriorityEveryThread$", "-test.count=1", "-test.v")
+		cmd.Env = append(os.Environ(), priorityChildEnv+"=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil || !strings.Contains(string(out), "--- PASS: TestLowerPriorityEveryThread") {
+			t.Fatalf("child test: %v\n%s", err, out)
+		}
+		return
+	}
+
+	// Park goroutines on threads of their own, so there are threads besides the caller's
+	release := make(chan struct{})
+	var started, stopped sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		started.Add(1)
+		stopped.Add(1)
+		go func() {
+			defer stopped.Done()
+			runtime.LockOSThread()
+			started.Done()
+			<-release
+		}()
+	}
+	started.Wait()
+	defer func() { close(release); stopped.Wait() }()
+
+	if err := lowerPriority(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Threads started afterwards must inherit the lowered priority too
+	started.Add(1)
+	stopped.Add(1)
+	go func() {
+		defer stopped.Done()
+		runtime.LockOSThread()
+		started.Done()
+		<-release
+	}()
+	started.Wait()
+
+	stats, err := filepath.Glob("/proc/self/task/*/stat")
+	if err != nil || len(stats) < 5 {
+		t.Fatalf("found %d threads (%v), want at least 5", len(stats), err)
+	}
+	for _, stat := range stats {
+		if nice := threadNice(t, stat); nice != 19 {
+			t.Errorf("%s: niceness %d, want 19", stat, nice)
+		}
+		tid, _ := strconv.Atoi(filepath.Base(filepath.Dir(stat)))
+		prio, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(tid), 0)
+		if errno == 0 && prio>>ioprioClassShift != ioprioClassIdle {
+			t.Errorf("%s: I/O class %d, want idle", stat, prio>>ioprioClassShift)
+		}
+	}
+}
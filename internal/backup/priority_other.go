@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package backup
+
+// lowerPriority is a no-op on platforms without a supported priority API
+func lowerPriority() error {
+	return nil
+}
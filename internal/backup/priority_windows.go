@@ -0,0 +1,12 @@
+package backup
+
+import "golang.org/x/sys/windows"
+
+// lowerPriority enters background processing mode, which lowers CPU, I/O and memory priority
+func lowerPriority() error {
+	h, err := windows.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	return windows.SetPriorityClass(h, windows.PROCESS_MODE_BACKGROUND_BEGIN)
+}
@@ -0,0 +1,135 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Progress tracks bytes processed against a precomputed total.
+// It is weighted by bytes rather than file count so one huge region file
+// advances the bar smoothly instead of stalling it. Safe for concurrent use.
+type Progress struct {
+	total atomic.Int64
+	done  atomic.Int64
+	// Mod lookups for --mod-metadata run alongside the copy, so they are counted apart
+	modsTotal atomic.Int64
+	modsDone  atomic.Int64
+}
+
+// Mods returns how many mods have been looked up for mods.json and how many will be;
+// both are 0 without --mod-metadata
+func (p *Progress) Mods() (done, total int) {
+	if p == nil {
+		return 0, 0
+	}
+	return int(p.modsDone.Load()), int(p.modsTotal.Load())
+}
+
+func (p *Progress) setModsTotal(n int) {
+	if p != nil {
+		p.modsTotal.Store(int64(n))
+	}
+}
+
+// modsLookedUp counts n mods whose lookup finished, found or not
+func (p *Progress) modsLookedUp(n int) {
+	if p != nil {
+		p.modsDone.Add(int64(n))
+	}
+}
+
+// Fraction returns completion between 0 and 1
+func (p *Progress) Fraction() float64 {
+	if p == nil {
+		return 0
+	}
+	total := p.total.Load()
+	if total <= 0 {
+		return 0
+	}
+	return min(float64(p.done.Load())/float64(total), 1)
+}
+
+// Bytes returns bytes processed and the expected total
+func (p *Progress) Bytes() (done, total int64) {
+	if p == nil {
+		return 0, 0
+	}
+	return p.done.Load(), p.total.Load()
+}
+
+func (p *Progress) setTotal(n int64) {
+	if p != nil {
+		p.total.Store(n)
+	}
+}
+
+// finish marks the work complete, even if the estimate was off
+func (p *Progress) finish() {
+	if p != nil {
+		p.done.Store(p.total.Load())
+	}
+}
+
+// skip counts a file that did not need copying
+func (p *Progress) skip(path string) {
+	if p == nil {
+		return
+	}
+	if info, err := os.Stat(path); err == nil {
+		p.done.Add(info.Size())
+	}
+}
+
+// track wraps r so every byte read counts toward progress
+func (p *Progress) track(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+type progressReader struct {
+	r io.Reader
+	p *Progress
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.done.Add(int64(n))
+	return n, err
+}
+
+// copyRoots lists the source folders and files a backup will copy
+func copyRoots(config *tui.Config, paths MinecraftPaths) []string {
+	roots := []string{paths.Screenshots, paths.Options}
+	if config.IncludeSaves {
+		roots = append(roots, paths.Saves)
+	}
+	if config.IncludeXaero {
+		roots = append(roots, paths.Xaero)
+	}
+	if config.IncludeDH {
+		roots = append(roots, paths.DistantHorizons)
+	}
+	for _, p := range config.Include {
+		roots = append(roots, filepath.Join(paths.Root, filepath.FromSlash(p)))
+	}
+	return roots
+}
+
+// estimateBackupBytes sums the bytes a backup will copy, counted twice when zipping
+func estimateBackupBytes(config *tui.Config, paths MinecraftPaths, opts *copyOptions) int64 {
+	var total int64
+	for _, root := range copyRoots(config, paths) {
+		total += getDirSize(root, opts)
+	}
+	if config.ZipOutput && !config.StreamArchive {
+		total *= 2
+	}
+	return total
+}
@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Progress counts bytes read, so a big file moves it as far as many small ones together
+func TestProgressTrack(t *testing.T) {
+	p := &Progress{}
+	p.setTotal(1000)
+	io.Copy(io.Discard, p.track(strings.NewReader(strings.Repeat("x", 750))))
+	if got := p.Fraction(); got != 0.75 {
+		t.Errorf("Fraction = %v, want 0.75", got)
+	}
+	io.Copy(io.Discard, p.track(strings.NewReader(strings.Repeat("x", 750))))
+	if got := p.Fraction(); got != 1 {
+		t.Errorf("Fraction past the estimate = %v, want 1", got)
+	}
+	var none *Progress
+	if none.Fraction() != 0 || none.track(strings.NewReader("x")) == nil {
+		t.Error("a nil Progress is not a no-op")
+	}
+}
+
+// The total is the bytes the backup copies, twice over when they are zipped afterwards,
+// and a finished backup reports all of it done
+func TestBackupProgress(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "screenshots/shot.png", strings.Repeat("x", 1000))
+	writeTestFile(t, mc, "saves/World/level.dat", "left out")
+
+	config := &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir()}
+	paths := buildPaths(mc)
+	if got := estimateBackupBytes(config, paths, nil); got != 1008 {
+		t.Errorf("estimateBackupBytes = %d, want 1008", got)
+	}
+	zipped := *config
+	zipped.ZipOutput = true
+	if got := estimateBackupBytes(&zipped, paths, nil); got != 2016 {
+		t.Errorf("estimateBackupBytes with a zip = %d, want 2016", got)
+	}
+
+	p := &Progress{}
+	if _, err := PerformContext(context.Background(), config, p); err != nil {
+		t.Fatal(err)
+	}
+	if done, total := p.Bytes(); done != total || total < 1008 {
+		t.Errorf("progress after the backup = %d of %d", done, total)
+	}
+}
@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Top-level files kept by Velocity and BungeeCord/Waterfall proxies
+var proxyFiles = []string{
+	"velocity.toml",
+	"forwarding.secret",
+	"config.yml",
+	"waterfall.yml",
+	"modules.yml",
+}
+
+// Matches the inline secret used by older velocity.toml files
+var forwardingSecretLine = regexp.MustCompile(`(?m)^(\s*forwarding-secret\s*=\s*).*$`)
+
+// proxyKind returns "Velocity" or "BungeeCord" if root is a proxy server, or ""
+func proxyKind(root string) string {
+	if exists(filepath.Join(root, "velocity.toml")) {
+		return "Velocity"
+	}
+	if exists(filepath.Join(root, "config.yml")) &&
+		(exists(filepath.Join(root, "modules.yml")) || exists(filepath.Join(root, "waterfall.yml"))) {
+		return "BungeeCord"
+	}
+	return ""
+}
+
+// copyProxyConfig copies proxy and plugin configs into dst/proxy, leaving out plugin jars
+func copyProxyConfig(root, dst string, redact bool) (int, error) {
+	proxyDst := filepath.Join(dst, "proxy")
+	if err := os.MkdirAll(proxyDst, 0755); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, name := range proxyFiles {
+		src := filepath.Join(root, name)
+		if !exists(src) {
+			continue
+		}
+		if err := copyProxyFile(src, filepath.Join(proxyDst, name), redact); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	plugins := filepath.Join(root, "plugins")
+	if !exists(plugins) {
+		return count, nil
+	}
+	err := filepath.WalkDir(plugins, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Jars can be re-downloaded; only their data folders hold config
+		if d.IsDir() || strings.EqualFold(filepath.Ext(path), ".jar") || isJunk(path, d) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(proxyDst, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := copyFile(path, target); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// copyProxyFile copies a proxy file, blanking forwarding secrets when redact is set
+func copyProxyFile(src, dst string, redact bool) error {
+	if !redact {
+		return copyFile(src, dst)
+	}
+	switch filepath.Base(src) {
+	case "forwarding.secret":
+		return os.WriteFile(dst, []byte("REDACTED\n"), 0600)
+	case "velocity.toml":
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		data = forwardingSecretLine.ReplaceAll(data, []byte(`${1}"REDACTED"`))
+		return os.WriteFile(dst, data, 0644)
+	}
+	return copyFile(src, dst)
+}
+
+// renderProxySection notes the proxy type and whether secrets were kept
+func renderProxySection(root string, redacted bool) string {
+	kind := proxyKind(root)
+	if kind == "" {
+		return ""
+	}
+	secrets := "included — keep this backup private"
+	if redacted {
+		secrets = "redacted — regenerate or restore them by hand"
+	}
+	return fmt.Sprintf("\n## 🔀 Proxy\n\n- **Type:** %s\n- **Forwarding secrets:** %s\n- **Plugins:** configs only, re-download the jars\n", kind, secrets)
+}
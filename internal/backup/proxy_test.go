@@ -0,0 +1,55 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A Velocity proxy's configs and plugin data are copied without the jars, and redaction
+// blanks the forwarding secret in both places it can live
+func TestCopyProxyConfig(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "velocity.toml", "bind = \"0.0.0.0:25577\"\n  forwarding-secret = \"s3cret\"\n")
+	writeTestFile(t, root, "forwarding.secret", "s3cret")
+	writeTestFile(t, root, "plugins/LuckPerms.jar", "jar")
+	writeTestFile(t, root, "plugins/luckperms/config.yml", "storage: h2\n")
+	if got := proxyKind(root); got != "Velocity" {
+		t.Fatalf("proxyKind = %q, want Velocity", got)
+	}
+
+	for _, redact := range []bool{false, true} {
+		dst := t.TempDir()
+		n, err := copyProxyConfig(root, dst, redact)
+		if err != nil || n != 3 {
+			t.Fatalf("copyProxyConfig(redact %v) = %d, %v; want 3 files", redact, n, err)
+		}
+		if exists(filepath.Join(dst, "proxy", "plugins", "LuckPerms.jar")) || !exists(filepath.Join(dst, "proxy", "plugins", "luckperms", "config.yml")) {
+			t.Error("plugin jars copied or plugin configs left out")
+		}
+		toml, _ := os.ReadFile(filepath.Join(dst, "proxy", "velocity.toml"))
+		secret, _ := os.ReadFile(filepath.Join(dst, "proxy", "forwarding.secret"))
+		leaked := strings.Contains(string(toml), "s3cret") || strings.Contains(string(secret), "s3cret")
+		if leaked == redact {
+			t.Errorf("redact %v: velocity.toml %q, forwarding.secret %q", redact, toml, secret)
+		}
+		if redact && !strings.Contains(string(toml), `bind = "0.0.0.0:25577"`) {
+			t.Errorf("redaction changed other lines: %q", toml)
+		}
+	}
+}
+
+func TestProxyKind(t *testing.T) {
+	bungee := t.TempDir()
+	writeTestFile(t, bungee, "config.yml", "listeners: []\n")
+	writeTestFile(t, bungee, "modules.yml", "modules: []\n")
+	plugin := t.TempDir()
+	writeTestFile(t, plugin, "config.yml", "a: b\n")
+	if got := proxyKind(bungee); got != "BungeeCord" {
+		t.Errorf("proxyKind(bungee) = %q", got)
+	}
+	if got := proxyKind(plugin); got != "" {
+		t.Errorf("proxyKind of a folder with only config.yml = %q", got)
+	}
+}
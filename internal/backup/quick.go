@@ -0,0 +1,172 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// QuickProfile is the saved source and destination used by `totem quick`
+type QuickProfile struct {
+	MinecraftPath string `json:"minecraft_path"`
+	BackupDest    string `json:"backup_dest"`
+}
+
+func quickProfilePath() (string, error) {
+	dir, err := KeyDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "quick.json"), nil
+}
+
+// SaveQuickProfile remembers where the last interactive backup read from and wrote to
+func SaveQuickProfile(config *tui.Config) error {
+	path, err := quickProfilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(QuickProfile{
+		MinecraftPath: config.MinecraftPath,
+		BackupDest:    config.BackupDest,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadQuickProfile returns the saved quick profile
+func LoadQuickProfile() (*QuickProfile, error) {
+	path, err := quickProfilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p QuickProfile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// PerformQuick backs up only saves, options.txt and config/, copying worlds in parallel.
+// An sftp destination is a staged upload, as for a full backup.
+func PerformQuick(config *tui.Config) (*Result, error) {
+	if remote, ok := ParseRemoteDest(config.BackupDest); ok {
+		return performRemote(config, remote, PerformQuick)
+	}
+	startTime := time.Now()
+	result := &Result{Success: true, Errors: []string{}}
+	paths := buildPaths(config.MinecraftPath)
+
+	if _, err := os.Stat(paths.Root); os.IsNotExist(err) {
+		return nil, fmt.Errorf("minecraft path does not exist: %s", paths.Root)
+	}
+	if err := checkSource(paths.Root); err != nil {
+		return nil, err
+	}
+	if err := checkDestination(config); err != nil {
+		return nil, err
+	}
+
+	backupPath := newBackupPath(config.BackupDest, time.Now())
+	if err := os.MkdirAll(backupPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup folder: %w", err)
+	}
+
+	// Each job gets its own copyOptions so counters are not shared between goroutines; the
+	// space monitor, rate limit and hashes are shared so they cover every copy together
+	type job struct {
+		name, src, dst string
+		count          *int
+	}
+	var jobs []job
+	if worlds, err := os.ReadDir(paths.Saves); err == nil {
+		targets := sameTarget{}
+		for _, w := range worlds {
+			if isDirLink(paths.Saves, w) && !targets.seen(filepath.Join(paths.Saves, w.Name())) {
+				jobs = append(jobs, job{"saves", filepath.Join(paths.Saves, w.Name()), filepath.Join(backupPath, "saves", w.Name()), &result.Stats.SavesCopied})
+			}
+		}
+	}
+	configDir := filepath.Join(paths.Root, "config")
+	if exists(configDir) {
+		jobs = append(jobs, job{"config", configDir, filepath.Join(backupPath, "config"), &result.Stats.ConfigsCopied})
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	// One world per CPU keeps a hard drive from seeking between hundreds of copies, and
+	// network shares cope badly with many writers at once
+	limit := runtime.NumCPU()
+	if NetworkMode(config) {
+		limit = NetworkParallelism
+	}
+	slots := make(chan struct{}, max(min(limit, len(jobs)), 1))
+
+	hashes := newHashRecorder()
+	space := newSpaceMonitor(config.BackupDest)
+	limiter := newRateLimiter(config.RateLimit)
+	stepStart := time.Now()
+	for _, jb := range jobs {
+		wg.Add(1)
+		go func(jb job) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			opts := newCopyOptions(config, nil)
+			opts.hashes, opts.space, opts.limiter = hashes, space, limiter
+			count, err := copyDir(jb.src, jb.dst, opts)
+
+			mu.Lock()
+			defer mu.Unlock()
+			*jb.count += count
+			result.Stats.JunkSkipped += opts.JunkSkipped
+			result.Stats.FilesVerified += opts.Verified
+			result.Skipped = append(result.Skipped, opts.LargeSkipped...)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", jb.name, err))
+			}
+		}(jb)
+	}
+	wg.Wait()
+	result.TotalFiles += result.Stats.SavesCopied + result.Stats.ConfigsCopied
+	result.Stats.timeStep("Saves and configs", stepStart)
+
+	if exists(paths.Options) {
+		opts := newCopyOptions(config, nil)
+		opts.hashes, opts.space, opts.limiter = hashes, space, limiter
+		if err := opts.copyFile(paths.Options, filepath.Join(backupPath, "options.txt")); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("options: %v", err))
+		} else {
+			result.TotalFiles++
+			result.Stats.FilesVerified += opts.Verified
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+	result.Health, result.Warnings = assessHealth(backupPath, config, result, paths)
+	generateInfoMD(backupPath, config, result, paths, newCopyOptions(config, nil))
+	if err := writeManifest(backupPath, config, hashes); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("manifest: %v", err))
+	}
+
+	result.OutputPath = backupPath
+	result.Success = len(result.Errors) == 0
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+func TestPerformQuickRemote(t *testing.T) {
+	root := useFakeSFTP(t)
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+
+	result, err := PerformQuick(&tui.Config{MinecraftPath: mc, BackupDest: "me@host:/srv/quick", IncludeSaves: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, ok := strings.CutPrefix(result.OutputPath, "me@host:/srv/quick/")
+	if !result.Success || !ok {
+		t.Fatalf("PerformQuick = %s, %v", result.OutputPath, result.Errors)
+	}
+	if !exists(filepath.Join(root, "srv", "quick", name, "saves", "World", "level.dat")) {
+		t.Error("the world was not uploaded")
+	}
+	if entries, _ := os.ReadDir(os.TempDir()); len(entries) != 0 {
+		t.Errorf("%d entries left in the staging folder", len(entries))
+	}
+}
+
+// What writes straight into a destination must not make a local folder named user@host:...
+func TestLocalOnlyRefusesRemote(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	wd := t.TempDir()
+	t.Chdir(wd)
+
+	for _, dest := range []string{"me@host:/srv/backups", "sftp://me@host:2222/srv/backups"} {
+		if _, err := Checkpoint(mc, dest, "before-update"); err == nil {
+			t.Errorf("Checkpoint to %s: no error", dest)
+		}
+		if _, _, err := CrashSnapshot(mc, dest, ""); err == nil {
+			t.Errorf("CrashSnapshot to %s: no error", dest)
+		}
+		if _, err := SupportBundle(mc, dest, false); err == nil {
+			t.Errorf("SupportBundle to %s: no error", dest)
+		}
+	}
+	if entries, _ := os.ReadDir(wd); len(entries) != 0 {
+		t.Errorf("refused destinations still created %d local entries", len(entries))
+	}
+}
+
+// Worlds copied in parallel share the rate limit instead of each getting all of it
+func TestPerformQuickRateLimit(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	mc := t.TempDir()
+	for _, world := range []string{"A", "B", "C", "D"} {
+		writeTestFile(t, mc, "saves/"+world+"/region/r.0.0.mca", strings.Repeat("x", 1<<20))
+	}
+
+	start := time.Now()
+	result, err := PerformQuick(&tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), IncludeSaves: true, RateLimit: 8 << 20})
+	if err != nil || !result.Success {
+		t.Fatalf("PerformQuick: %v, %v", err, result)
+	}
+	// 4 MB at 8 MB/s, less the 2 MB burst, takes a quarter second
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("4 MB took %v at 8 MB/s; the worlds did not share the limit", elapsed)
+	}
+}
@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactor blanks usernames, server addresses and absolute paths in text meant for sharing
+type redactor struct {
+	paths   []string // Replaced by their placeholder, longest first
+	names   map[string]string
+	users   []string
+	servers []string
+}
+
+// newRedactor collects what to hide for instance: its folder and the home folder, the OS and
+// in-game usernames, and every server address in servers.dat and options.txt
+func newRedactor(instance string) *redactor {
+	r := &redactor{names: map[string]string{}}
+	if abs, err := filepath.Abs(instance); err == nil {
+		r.addPath(abs, "<minecraft>")
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" && home != "/" {
+		r.addPath(home, "~")
+		r.addUser(filepath.Base(home))
+	}
+	for _, env := range []string{"USER", "USERNAME"} {
+		r.addUser(os.Getenv(env))
+	}
+	r.addUser(logUsername(filepath.Join(instance, "logs", "latest.log")))
+	for _, s := range knownServers(instance) {
+		r.addServer(s)
+	}
+	sort.Slice(r.paths, func(i, j int) bool { return len(r.paths[i]) > len(r.paths[j]) })
+	sort.Slice(r.servers, func(i, j int) bool { return len(r.servers[i]) > len(r.servers[j]) })
+	return r
+}
+
+func (r *redactor) addPath(path, placeholder string) {
+	for _, p := range []string{path, filepath.ToSlash(path)} {
+		if _, ok := r.names[p]; !ok {
+			r.names[p] = placeholder
+			r.paths = append(r.paths, p)
+		}
+	}
+}
+
+func (r *redactor) addUser(name string) {
+	// Very short names would blank ordinary words too
+	if len(name) < 3 || name == "root" {
+		return
+	}
+	for _, u := range r.users {
+		if strings.EqualFold(u, name) {
+			return
+		}
+	}
+	r.users = append(r.users, name)
+}
+
+func (r *redactor) addServer(addr string) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return
+	}
+	r.servers = append(r.servers, addr)
+	// The host alone also shows up in logs ("Connecting to host, port")
+	if host, _, ok := strings.Cut(addr, ":"); ok && host != "" {
+		r.servers = append(r.servers, host)
+	}
+}
+
+// Redact returns text with paths, usernames and server addresses replaced by placeholders.
+// A nil redactor leaves text unchanged.
+func (r *redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	for _, p := range r.paths {
+		text = strings.ReplaceAll(text, p, r.names[p])
+	}
+	for _, s := range r.servers {
+		text = replaceFold(text, s, "<server>")
+	}
+	for _, u := range r.users {
+		text = replaceWordFold(text, u, "<user>")
+	}
+	return text
+}
+
+// replaceFold replaces every case-insensitive occurrence of old
+func replaceFold(text, old, repl string) string {
+	return regexp.MustCompile(`(?i)`+regexp.QuoteMeta(old)).ReplaceAllString(text, repl)
+}
+
+// replaceWordFold replaces case-insensitive occurrences of word that are not part of a longer word
+func replaceWordFold(text, word, repl string) string {
+	re := regexp.MustCompile(`(?i)(^|[^\pL\pN_])` + regexp.QuoteMeta(word) + `($|[^\pL\pN_])`)
+	// Adjacent matches share a boundary character, so run until nothing changes
+	for {
+		next := re.ReplaceAllString(text, "${1}"+repl+"${2}")
+		if next == text {
+			return text
+		}
+		text = next
+	}
+}
+
+// logUsername returns the in-game name from a client log's "Setting user:" line, or ""
+func logUsername(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if _, name, ok := strings.Cut(scanner.Text(), "Setting user: "); ok {
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+// knownServers returns the addresses in the multiplayer list and options.txt's lastServer
+func knownServers(instance string) []string {
+	var servers []string
+	if f, err := os.Open(filepath.Join(instance, "servers.dat")); err == nil {
+		// servers.dat is uncompressed NBT, unlike level.dat
+		if root, err := readNBT(f); err == nil {
+			if list, ok := root["servers"].([]any); ok {
+				for _, e := range list {
+					if entry, ok := e.(map[string]any); ok {
+						if ip, ok := entry["ip"].(string); ok {
+							servers = append(servers, ip)
+						}
+					}
+				}
+			}
+		}
+		f.Close()
+	}
+	if data, err := os.ReadFile(filepath.Join(instance, "options.txt")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(line), "lastServer:"); ok {
+				servers = append(servers, v)
+			}
+		}
+	}
+	return servers
+}
@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeServersDat writes an uncompressed servers.dat listing ips, as the multiplayer screen does
+func writeServersDat(t *testing.T, root string, ips ...string) {
+	t.Helper()
+	b := nbtRoot(tagList, "servers")
+	b.WriteByte(tagCompound)
+	binary.Write(b, binary.BigEndian, int32(len(ips)))
+	for _, ip := range ips {
+		var entry bytes.Buffer
+		writeNBTCompound(&entry, map[string]any{"ip": ip, "name": "Survival"})
+		b.Write(entry.Bytes())
+	}
+	b.WriteByte(tagEnd)
+	if err := os.WriteFile(filepath.Join(root, "servers.dat"), b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "alexkrafts")
+	t.Setenv("HOME", home)
+	t.Setenv("USER", "alexkrafts")
+	t.Setenv("USERNAME", "")
+	mc := t.TempDir()
+	writeTestFile(t, mc, "logs/latest.log", "[Render thread/INFO]: Setting user: Steve_Miner\n")
+	writeTestFile(t, mc, "options.txt", "lastServer:mc.friends.org\n")
+	writeServersDat(t, mc, "play.example.net:25565")
+
+	r := newRedactor(mc)
+	text := "Loading " + filepath.Join(mc, "mods", "sodium.jar") + " from " + filepath.Join(home, ".config") + "\n" +
+		"Steve_Miner joined PLAY.EXAMPLE.NET:25565 (play.example.net, port 25565) after mc.friends.org\n" +
+		"alexkrafts and ALEXKRAFTS are blanked, alexkraftsmod is not"
+	want := "Loading " + filepath.Join("<minecraft>", "mods", "sodium.jar") + " from " + filepath.Join("~", ".config") + "\n" +
+		"<user> joined <server> (<server>, port 25565) after <server>\n" +
+		"<user> and <user> are blanked, alexkraftsmod is not"
+	if got := r.Redact(text); got != want {
+		t.Errorf("Redact =\n%s\nwant\n%s", got, want)
+	}
+
+	var none *redactor
+	if got := none.Redact(text); got != text {
+		t.Error("a nil redactor changed the text")
+	}
+	short := &redactor{names: map[string]string{}}
+	short.addUser("al")
+	short.addUser("root")
+	if len(short.users) != 0 {
+		t.Errorf("users = %q, want short names and root left alone", short.users)
+	}
+}
+
+// --redact applies to the logs and summary in a support bundle
+func TestSupportBundleRedacted(t *testing.T) {
+	t.Setenv("HOME", filepath.Join(t.TempDir(), "alexkrafts"))
+	mc := t.TempDir()
+	writeTestFile(t, mc, "logs/latest.log", "[Render thread/INFO]: Setting user: Steve_Miner\nLoaded "+filepath.Join(mc, "options.txt")+"\n")
+
+	path, err := SupportBundle(mc, t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := readZipEntries(t, path)["logs/latest.log"]
+	if strings.Contains(log, "Steve_Miner") || strings.Contains(log, mc) {
+		t.Errorf("latest.log was not redacted:\n%s", log)
+	}
+}
@@ -0,0 +1,608 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/netguard"
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// RemoteDest is a destination on another host, written to over SFTP
+type RemoteDest struct {
+	User string
+	Host string
+	Port int    // 0 for ssh's default
+	Path string // Relative paths start in the user's home folder
+}
+
+// ParseRemoteDest reads a remote destination as scp writes one, user@host:/path, or as
+// sftp://user@host:port/path. Anything else is a local folder.
+func ParseRemoteDest(dest string) (*RemoteDest, bool) {
+	if strings.HasPrefix(dest, "sftp://") {
+		u, err := url.Parse(dest)
+		if err != nil || u.Hostname() == "" {
+			return nil, false
+		}
+		port, _ := strconv.Atoi(u.Port())
+		return &RemoteDest{User: u.User.Username(), Host: u.Hostname(), Port: port, Path: u.Path}, true
+	}
+	// The user is required, so C:\Backups and relative folders with a colon stay local
+	userHost, p, ok := strings.Cut(dest, ":")
+	user, host, hasUser := strings.Cut(userHost, "@")
+	if !ok || !hasUser || user == "" || host == "" || strings.ContainsAny(userHost, `/\`) {
+		return nil, false
+	}
+	return &RemoteDest{User: user, Host: host, Path: p}, true
+}
+
+// target is the [user@]host sftp connects to
+func (r *RemoteDest) target() string {
+	if r.User == "" {
+		return r.Host
+	}
+	return r.User + "@" + r.Host
+}
+
+// join returns the remote path of name inside the destination
+func (r *RemoteDest) join(name string) string {
+	if r.Path == "" {
+		return name
+	}
+	return path.Join(r.Path, name)
+}
+
+// String is the destination in the form it was given, sftp:// only when a port needs it
+func (r *RemoteDest) String() string {
+	if r.Port != 0 {
+		return fmt.Sprintf("sftp://%s:%d%s", r.target(), r.Port, r.Path)
+	}
+	return r.target() + ":" + r.Path
+}
+
+// LocalOnly refuses dest when it is an sftp destination, for what writes straight into it.
+// Check dest before joining anything to it: filepath.Join turns sftp:// into sftp:/.
+func LocalOnly(what, dest string) error {
+	if remote, ok := ParseRemoteDest(dest); ok {
+		return fmt.Errorf("%s needs a local destination, not %s", what, remote)
+	}
+	return nil
+}
+
+// stagePrefix starts the name of each staging folder in the temp folder
+const stagePrefix = "totem-sftp-"
+
+// performRemote is a staged upload: it runs a backup into a local staging folder with run,
+// then uploads what it wrote to remote with OpenSSH's sftp, which reuses the user's keys and
+// known_hosts. A zip or tar.zst is not made in the staging folder when the server has a
+// shell: the copied folder is archived straight into ssh instead (see streamArchive). The
+// staging folder's disk must hold the whole backup; checkStagingSpace refuses the run
+// otherwise. The staging folder is removed once the upload succeeds, and kept for a retry
+// when it fails.
+func performRemote(config *tui.Config, remote *RemoteDest, run func(*tui.Config) (*Result, error)) (*Result, error) {
+	if err := checkRemote(remote); err != nil {
+		return nil, err
+	}
+	// Incremental backups read their parent from the destination, which is not local
+	if config.Incremental || config.LinkDest {
+		return nil, fmt.Errorf("incremental backups need a local destination, not %s", remote)
+	}
+
+	local := *config
+	local.BackupDest = os.TempDir()
+	local.OpenWhenDone = false
+	local.StreamArchive = streamsArchive(&local) && remoteShell(remote)
+	if err := checkStagingSpace(&local); err != nil {
+		return nil, err
+	}
+	stage, err := os.MkdirTemp("", stagePrefix)
+	if err != nil {
+		return nil, err
+	}
+	local.BackupDest = stage
+	result, err := uploadStaged(stage, &local, remote, func() (*Result, error) { return run(&local) })
+	if err == nil && RetentionFor(config.BackupDest).Enabled() {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Retention only applies to local destinations; old backups on %s are kept", remote))
+	}
+	return result, err
+}
+
+// checkRemote refuses an sftp destination when the network is off or sftp is missing
+func checkRemote(remote *RemoteDest) error {
+	if err := netguard.Allow("sftp destination " + remote.Host); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("sftp"); err != nil {
+		return fmt.Errorf("an sftp destination needs the sftp command (OpenSSH)")
+	}
+	return nil
+}
+
+// uploadStaged runs a backup into stage, or resumes the one there, and uploads it, streaming
+// its archive when config says to. A backup stopped part way (cancelled, or the staging disk
+// filled up) keeps its stage and journal, marked with remote so `totem resume` finishes the
+// upload too; any other failure removes it.
+func uploadStaged(stage string, config *tui.Config, remote *RemoteDest, run func() (*Result, error)) (*Result, error) {
+	result, err := run()
+	if err != nil {
+		journals, _ := FindJournals(stage)
+		for _, path := range journals {
+			if j, readErr := readJournal(path); readErr == nil {
+				j.Remote = remote.String()
+				j.save()
+			}
+		}
+		if len(journals) == 0 {
+			os.RemoveAll(stage)
+		}
+		return result, err
+	}
+
+	staged, catalogErr := LoadCatalog(stage)
+	rel, err := filepath.Rel(stage, result.OutputPath)
+	var want map[string]int64
+	var dirs []string
+	switch {
+	case err != nil:
+	case config.StreamArchive:
+		// The folder stays in the stage; only its archive goes to the server
+		suffix := archiveSuffix(config)
+		var size int64
+		if size, err = streamArchive(config, result.OutputPath, remote.join(filepath.ToSlash(rel)+suffix), remote); err == nil {
+			if e := staged.Entry(filepath.Base(result.OutputPath)); e != nil {
+				e.Name, e.Size = e.Name+suffix, size
+			}
+			rel += suffix
+			want = map[string]int64{path.Clean(remote.join(filepath.ToSlash(rel))): size}
+			dirs = []string{path.Dir(remote.join(filepath.ToSlash(rel)))}
+		}
+	default:
+		if err = uploadSFTP(stage, remote); err == nil {
+			want, dirs, err = stagedFiles(stage, remote)
+		}
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("sftp upload failed: %v; the staged backup is kept in %s, "+
+			"upload it yourself or delete it", err, stage))
+		result.Success = false
+		return result, nil
+	}
+	check, err := verifyUpload(remote, want, dirs)
+	if err != nil {
+		check = &UploadCheck{Time: time.Now(), Problem: err.Error()}
+	}
+	// The remote catalog is an index; failing to update it leaves the upload itself good
+	if catalogErr == nil {
+		catalogErr = mergeRemoteCatalog(staged, remote, check)
+	}
+	if err := catalogErr; err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Could not update the catalog on %s: %v", remote, err))
+	}
+	if check.Problem != "" {
+		result.Errors = append(result.Errors, fmt.Sprintf("the upload to %s does not match the backup: %s; the staged backup is kept in %s, "+
+			"upload it yourself or delete it", remote, check.Problem, stage))
+		result.Success = false
+		return result, nil
+	}
+	os.RemoveAll(stage)
+	result.OutputPath = (&RemoteDest{remote.User, remote.Host, remote.Port, remote.join(filepath.ToSlash(rel))}).String()
+	return result, nil
+}
+
+// StagedJournals lists interrupted backups staged for an sftp destination, which wait in
+// the temp folder rather than in a backup destination
+func StagedJournals() []string {
+	stages, _ := filepath.Glob(filepath.Join(os.TempDir(), stagePrefix+"*"))
+	var journals []string
+	for _, stage := range stages {
+		found, _ := FindJournals(stage)
+		journals = append(journals, found...)
+	}
+	return journals
+}
+
+// checkStagingSpace refuses a staged upload whose backup would not fit in the staging
+// folder: the copied files, plus the archive made from them when there is one
+func checkStagingSpace(config *tui.Config) error {
+	free, _, ok := diskFree(config.BackupDest)
+	if !ok {
+		return nil
+	}
+	est, err := EstimateSize(config)
+	if err != nil {
+		return err
+	}
+	need := est.Raw
+	if (config.ZipOutput || config.Zstd) && !config.StreamArchive {
+		need += est.Compressed
+	}
+	if uint64(need)+spaceReserve > free {
+		return fmt.Errorf("an sftp upload is staged in %s first, which has %s free; this backup needs about %s, "+
+			"and %s is kept free (set TMPDIR to stage it somewhere else)",
+			config.BackupDest, formatBytes(int64(free)), formatBytes(need), formatBytes(spaceReserve))
+	}
+	return nil
+}
+
+// uploadSFTP copies everything a backup wrote into stage to remote, creating the remote
+// folder and the backup's timestamped folder as needed. The staging catalog is left out;
+// mergeRemoteCatalog adds its entries to the remote one afterwards. Nothing is uploaded when a name is already taken on the
+// remote, since put -r would merge two backups into one folder.
+func uploadSFTP(stage string, remote *RemoteDest) error {
+	entries, err := os.ReadDir(stage)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name() != CatalogName {
+			names = append(names, e.Name())
+		}
+	}
+	taken, err := remoteNames(remote)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if taken[name] {
+			return fmt.Errorf("%s already exists on %s", name, remote)
+		}
+	}
+
+	var batch strings.Builder
+	// A leading - lets mkdir fail on folders that already exist
+	if dir := strings.TrimSuffix(remote.Path, "/"); dir != "" {
+		var parents []string
+		for p := dir; p != "/" && p != "."; p = path.Dir(p) {
+			parents = append(parents, p)
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			fmt.Fprintf(&batch, "-mkdir %s\n", sftpQuote(parents[i]))
+		}
+	}
+	for _, name := range names {
+		fmt.Fprintf(&batch, "put -r %s %s\n", sftpQuote(filepath.Join(stage, name)), sftpQuote(remote.join(name)))
+	}
+	_, err = runSFTP(remote, batch.String())
+	return err
+}
+
+// UploadCheck is how a backup's upload to a remote destination was verified. sftp cannot
+// hash files on the server, so each file's size there is compared with the staged copy the
+// manifest was written from.
+type UploadCheck struct {
+	Time    time.Time `json:"time"`
+	Files   int       `json:"files"` // Files whose size matched
+	Bytes   int64     `json:"bytes"`
+	Problem string    `json:"problem,omitempty"` // Why the upload does not match; empty when it does
+}
+
+// streamsArchive reports whether config's archive can go to the server as it is made: an
+// encrypted, split or parity-protected archive is read again once written, so it is staged
+func streamsArchive(config *tui.Config) bool {
+	return (config.ZipOutput || config.Zstd || config.ProtectZip) && config.GPGRecipient == "" &&
+		config.SplitSize == 0 && !config.Parity
+}
+
+// archiveSuffix is the extension of the archive config makes
+func archiveSuffix(config *tui.Config) string {
+	if config.Zstd {
+		return ZstdSuffix
+	}
+	return ".zip"
+}
+
+// remoteShell reports whether remote runs commands over ssh, which streaming an archive
+// needs; sftp-only accounts, and servers whose shell is not a POSIX one, are staged
+func remoteShell(remote *RemoteDest) bool {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return false
+	}
+	return exec.Command("ssh", sshArgs(remote, "test -d / && command -v cat")...).Run() == nil
+}
+
+// sshArgs are ssh's arguments to run command on remote without prompting for anything
+func sshArgs(remote *RemoteDest, command string) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if remote.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(remote.Port))
+	}
+	return append(args, remote.target(), command)
+}
+
+// shellQuote quotes s as one word for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// streamArchive zips or tars folder straight into ssh, where cat writes it to dest under a
+// temporary name, so the archive never takes room in the staging folder. It is renamed to
+// dest once complete, and removed when anything fails. It returns the archive's size.
+func streamArchive(config *tui.Config, folder, dest string, remote *RemoteDest) (int64, error) {
+	taken, err := remoteNames(remote)
+	if err != nil {
+		return 0, err
+	}
+	if taken[path.Base(dest)] {
+		return 0, fmt.Errorf("%s already exists on %s", path.Base(dest), remote)
+	}
+
+	tmp := dest + ".part"
+	cmd := exec.Command("ssh", sshArgs(remote, fmt.Sprintf("mkdir -p %s && cat > %s", shellQuote(path.Dir(dest)), shellQuote(tmp)))...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("ssh: %w", err)
+	}
+	out := &countingWriter{w: stdin}
+	if config.Zstd {
+		err = writeTarZst(out, folder, config.ZstdLevel, config.ZstdLong, config.Deterministic, nil)
+	} else {
+		err = writeZip(out, folder, planCompression(folder), config.ZipPassword, nil)
+	}
+	stdin.Close()
+	if waitErr := cmd.Wait(); waitErr != nil && err == nil {
+		err = fmt.Errorf("ssh: %v: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	if err == nil {
+		_, err = runSFTP(remote, fmt.Sprintf("rename %s %s\n", sftpQuote(tmp), sftpQuote(dest)))
+	}
+	if err != nil {
+		runSFTP(remote, fmt.Sprintf("-rm %s\n", sftpQuote(tmp)))
+		return 0, err
+	}
+	return out.n, nil
+}
+
+// stagedFiles returns the size of every file uploadSFTP sends from stage, by its path on
+// remote, and the remote folders that hold them
+func stagedFiles(stage string, remote *RemoteDest) (map[string]int64, []string, error) {
+	want := map[string]int64{}
+	dirs := []string{remote.Path}
+	err := filepath.WalkDir(stage, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(stage, p)
+		switch {
+		case rel == "." || rel == CatalogName:
+			return nil
+		case d.IsDir():
+			dirs = append(dirs, remote.join(filepath.ToSlash(rel)))
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		want[path.Clean(remote.join(filepath.ToSlash(rel)))] = info.Size()
+		return nil
+	})
+	return want, dirs, err
+}
+
+// verifyUpload lists dirs on remote and compares the files in want, by path, with the sizes
+// they should have. A mismatch is reported in the check's Problem; an error means the
+// listing itself failed.
+func verifyUpload(remote *RemoteDest, want map[string]int64, dirs []string) (*UploadCheck, error) {
+	var batch strings.Builder
+	for _, dir := range dirs {
+		if dir == "" {
+			dir = "."
+		}
+		// -a because sftp's ls hides dot files; a leading - so a missing folder lists nothing
+		// rather than failing the batch, and its files are reported missing below
+		fmt.Fprintf(&batch, "-ls -lna %s\n", sftpQuote(dir))
+	}
+	out, err := runSFTP(remote, batch.String())
+	if err != nil {
+		return nil, err
+	}
+	got := remoteSizes(out)
+	check := &UploadCheck{Time: time.Now()}
+	var bad []string
+	for _, name := range slices.Sorted(maps.Keys(want)) {
+		size, ok := got[name]
+		switch {
+		case !ok:
+			bad = append(bad, name+" is missing")
+		case size != want[name]:
+			bad = append(bad, fmt.Sprintf("%s is %d bytes there and %d here", name, size, want[name]))
+		default:
+			check.Files++
+			check.Bytes += size
+		}
+	}
+	if len(bad) > 0 {
+		check.Problem = fmt.Sprintf("%d of %d files differ, first %s", len(bad), len(want), bad[0])
+	}
+	return check, nil
+}
+
+// remoteSizes reads the sizes of regular files from sftp's ls -l output, by the path it
+// printed: ls shows each entry as the folder it was given joined with the entry's name
+func remoteSizes(out string) map[string]int64 {
+	sizes := map[string]int64{}
+	for _, line := range strings.Split(out, "\n") {
+		// Mode, links, owner, group, size and a three-field date come before the name
+		fields := strings.Fields(line)
+		if len(fields) < 9 || !strings.HasPrefix(fields[0], "-") {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		rest := strings.TrimLeft(line, " ")
+		for range 8 {
+			rest = strings.TrimLeft(rest[strings.IndexByte(rest, ' '):], " ")
+		}
+		sizes[path.Clean(rest)] = size
+	}
+	return sizes
+}
+
+// mergeRemoteCatalog adds the entries of the staged catalog to the catalog kept next to the
+// backups on remote, with check as their upload's verification, so machines backing up to
+// the same host share one catalog. Entries whose backup is no longer on the remote are
+// dropped, as a local catalog drops them.
+func mergeRemoteCatalog(staged *Catalog, remote *RemoteDest, check *UploadCheck) error {
+	if len(staged.Backups) == 0 {
+		return nil
+	}
+	for i := range staged.Backups {
+		staged.Backups[i].Upload = check
+	}
+
+	work, err := os.MkdirTemp("", stagePrefix+"catalog-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+	unlock, err := lockRemoteCatalog(remote, work)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	local := filepath.Join(work, CatalogName)
+	// A leading - lets get fail when the remote has no catalog yet
+	if _, err := runSFTP(remote, fmt.Sprintf("-get %s %s\n", sftpQuote(remote.join(CatalogName)), sftpQuote(local))); err != nil {
+		return err
+	}
+	c, err := LoadCatalog(work)
+	if err != nil {
+		return err
+	}
+	taken, err := remoteNames(remote)
+	if err != nil {
+		return err
+	}
+	c.Backups = slices.DeleteFunc(c.Backups, func(e CatalogEntry) bool { return !taken[e.Name] })
+	c.merge(staged)
+
+	data, err := c.encode()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(local, data, 0644); err != nil {
+		return err
+	}
+	// Uploaded under a name of its own, then renamed over the old catalog in one step
+	tmp := remote.join(fmt.Sprintf("%s.%d.tmp", CatalogName, time.Now().UnixNano()))
+	_, err = runSFTP(remote, fmt.Sprintf("put %s %s\nrename %s %s\n",
+		sftpQuote(local), sftpQuote(tmp), sftpQuote(tmp), sftpQuote(remote.join(CatalogName))))
+	return err
+}
+
+// lockRemoteCatalog takes the lock on remote's catalog as lockCatalog does on a local one,
+// using work for the lease files it sends and fetches. A lock whose lease can't be read, as
+// when its holder stopped right after taking it, is waited for and then reported.
+func lockRemoteCatalog(remote *RemoteDest, work string) (func(), error) {
+	lock := remote.join(CatalogLockName)
+	lease := path.Join(lock, catalogLeaseName)
+	deadline := time.Now().Add(catalogLockWait)
+	for {
+		// Without a leading -, mkdir fails the batch when the folder already exists
+		_, err := runSFTP(remote, "mkdir "+sftpQuote(lock)+"\n")
+		if err == nil {
+			break
+		}
+		held := filepath.Join(work, "held-"+catalogLeaseName)
+		os.Remove(held)
+		if _, getErr := runSFTP(remote, fmt.Sprintf("-get %s %s\n", sftpQuote(lease), sftpQuote(held))); getErr != nil {
+			return nil, err
+		}
+		holder := &catalogLease{Host: "an unknown writer"}
+		data, readErr := os.ReadFile(held)
+		if readErr != nil {
+			if taken, _ := remoteNames(remote); !taken[CatalogLockName] {
+				return nil, err // Not held: mkdir failed for another reason
+			}
+		} else if json.Unmarshal(data, holder) == nil && time.Now().After(holder.Expires) {
+			runSFTP(remote, fmt.Sprintf("-rm %s\n-rmdir %s\n", sftpQuote(lease), sftpQuote(lock)))
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("the catalog is locked by %s; if no backup is running there, delete %s", holder.Host, CatalogLockName)
+		}
+		time.Sleep(catalogLockRetry)
+	}
+
+	unlock := func() { runSFTP(remote, fmt.Sprintf("-rm %s\n-rmdir %s\n", sftpQuote(lease), sftpQuote(lock))) }
+	mine := filepath.Join(work, catalogLeaseName)
+	data, _ := json.Marshal(newCatalogLease())
+	if err := os.WriteFile(mine, data, 0644); err != nil {
+		unlock()
+		return nil, err
+	}
+	if _, err := runSFTP(remote, fmt.Sprintf("put %s %s\n", sftpQuote(mine), sftpQuote(lease))); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// remoteNames lists the names in the remote destination folder, none when it doesn't exist yet
+func remoteNames(remote *RemoteDest) (map[string]bool, error) {
+	// A leading - keeps a missing folder from failing the batch
+	batch := "-ls -1\n"
+	if remote.Path != "" {
+		batch = "-ls -1 " + sftpQuote(remote.Path) + "\n"
+	}
+	out, err := runSFTP(remote, batch)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "sftp>") {
+			names[path.Base(line)] = true
+		}
+	}
+	return names, nil
+}
+
+// sftpQuote quotes s as one argument of an sftp batch command
+func sftpQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// runSFTP runs batch against remote and returns sftp's output
+func runSFTP(remote *RemoteDest, batch string) (string, error) {
+	args := []string{"-b", "-"}
+	if remote.Port != 0 {
+		args = append(args, "-P", strconv.Itoa(remote.Port))
+	}
+	cmd := exec.Command("sftp", append(args, remote.target())...)
+	cmd.Stdin = strings.NewReader(batch)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		// sftp echoes each batch command; ssh's and sftp's complaints are the rest
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			if line != "" && !strings.HasPrefix(line, "sftp>") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			return "", err
+		}
+		return "", errors.New(strings.Join(lines, "; "))
+	}
+	return string(out), nil
+}
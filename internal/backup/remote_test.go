@@ -0,0 +1,306 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// fakeSFTP is an sftp stand-in that runs batch files against the folder in FAKE_SFTP_ROOT
+const fakeSFTP = `#!/bin/sh
+while IFS= read -r line; do
+	eval "set -- $line"
+	cmd=$1; shift
+	case $cmd in
+	-mkdir) mkdir -p "$FAKE_SFTP_ROOT/$1" ;;
+	mkdir) mkdir "$FAKE_SFTP_ROOT/$1" || exit 1 ;;
+	put) [ "$1" = -r ] && shift; cp -R "$1" "$FAKE_SFTP_ROOT/$2" || exit 1 ;;
+	-get) cp "$FAKE_SFTP_ROOT/$1" "$2" 2>/dev/null || true ;;
+	get) cp "$FAKE_SFTP_ROOT/$1" "$2" || exit 1 ;;
+	rename) mv "$FAKE_SFTP_ROOT/$1" "$FAKE_SFTP_ROOT/$2" || exit 1 ;;
+	-rm|rm) rm -f "$FAKE_SFTP_ROOT/$1" ;;
+	-rmdir) rmdir "$FAKE_SFTP_ROOT/$1" 2>/dev/null || true ;;
+	rmdir) rmdir "$FAKE_SFTP_ROOT/$1" || exit 1 ;;
+	-ls|ls)
+		case $1 in
+		-1) shift; ls -1 "$FAKE_SFTP_ROOT/$1" 2>/dev/null | sed "s|^|$1/|" ;;
+		-lna) shift; ls -A "$FAKE_SFTP_ROOT/$1" 2>/dev/null | while IFS= read -r n; do
+			f="$FAKE_SFTP_ROOT/$1/$n"
+			if [ -d "$f" ]; then echo "drwxr-xr-x 2 0 0 4096 Jan 1 00:00 $1/$n"
+			else echo "-rw-r--r-- 1 0 0 $(wc -c <"$f") Jan 1 00:00 $1/$n"; fi
+		done ;;
+		esac ;;
+	esac
+done
+`
+
+// fakeSSH runs the command it is given with the remote's absolute paths moved under
+// FAKE_SFTP_ROOT; with FAKE_SSH_FAIL set it behaves like an sftp-only account
+const fakeSSH = `#!/bin/sh
+[ -n "$FAKE_SSH_FAIL" ] && { echo "This service allows sftp connections only." >&2; exit 1; }
+while [ $# -gt 1 ]; do shift; done
+eval "$(printf '%s' "$1" | sed "s|'/|'$FAKE_SFTP_ROOT/|g")"
+`
+
+// useFakeSFTP puts fakeSFTP and fakeSSH first on PATH, stages in a test folder and returns the folder
+// standing in for the remote host
+func useFakeSFTP(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sftp is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "sftp"), []byte(fakeSFTP), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "ssh"), []byte(fakeSSH), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	root := t.TempDir()
+	t.Setenv("FAKE_SFTP_ROOT", root)
+	return root
+}
+
+// A cancelled staged backup keeps its stage, and resuming it finishes the upload
+func TestStagedBackupResumes(t *testing.T) {
+	root := useFakeSFTP(t)
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := PerformContext(ctx, &tui.Config{MinecraftPath: mc, BackupDest: "me@host:/srv/backups"}, nil)
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("cancelled backup: got %v, want ErrCancelled", err)
+	}
+	journals := StagedJournals()
+	if len(journals) != 1 {
+		t.Fatalf("found %d staged journals, want 1", len(journals))
+	}
+
+	result, err := Resume(journals[0], nil)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if !result.Success || !strings.HasPrefix(result.OutputPath, "me@host:/srv/backups/backup_") {
+		t.Fatalf("Resume = %s, %v", result.OutputPath, result.Errors)
+	}
+	name := strings.TrimPrefix(result.OutputPath, "me@host:/srv/backups/")
+	if data, err := os.ReadFile(filepath.Join(root, "srv", "backups", name, "options.txt")); err != nil || string(data) != "fov:0.0\n" {
+		t.Errorf("uploaded options.txt = %q, %v", data, err)
+	}
+	if len(StagedJournals()) != 0 || exists(filepath.Dir(journals[0])) {
+		t.Error("the stage was kept after the upload")
+	}
+}
+
+func TestUploadSFTP(t *testing.T) {
+	root := useFakeSFTP(t)
+	stage := t.TempDir()
+	writeTestFile(t, stage, "backup_2026-01-01_00-00/options.txt", "fov:0.0\n")
+	writeTestFile(t, stage, CatalogName, "{}")
+	remote := &RemoteDest{User: "me", Host: "host", Path: `/srv/my "mc" back\ups`}
+
+	if err := uploadSFTP(stage, remote); err != nil {
+		t.Fatalf("uploadSFTP: %v", err)
+	}
+	dir := filepath.Join(root, "srv", `my "mc" back\ups`)
+	if data, err := os.ReadFile(filepath.Join(dir, "backup_2026-01-01_00-00", "options.txt")); err != nil || string(data) != "fov:0.0\n" {
+		t.Errorf("uploaded options.txt = %q, %v", data, err)
+	}
+	if exists(filepath.Join(dir, CatalogName)) {
+		t.Error("the staging catalog was uploaded")
+	}
+
+	// A second backup with the same name must not be merged into the first
+	if err := uploadSFTP(stage, remote); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("second upload: got %v, want an already exists error", err)
+	}
+}
+
+// verifyUpload passes a complete upload, including dot files and names with spaces, and
+// names the file that came out short
+func TestVerifyUpload(t *testing.T) {
+	root := useFakeSFTP(t)
+	stage := t.TempDir()
+	writeTestFile(t, stage, "backup_2026-01-01_00-00/options.txt", "fov:0.0\n")
+	writeTestFile(t, stage, "backup_2026-01-01_00-00/saves/My World/level.dat", "level")
+	writeTestFile(t, stage, "backup_2026-01-01_00-00/.hidden", "x")
+	writeTestFile(t, stage, CatalogName, "{}")
+	remote := &RemoteDest{User: "me", Host: "host", Path: "/srv/backups"}
+	if err := uploadSFTP(stage, remote); err != nil {
+		t.Fatalf("uploadSFTP: %v", err)
+	}
+
+	want, dirs, err := stagedFiles(stage, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check, err := verifyUpload(remote, want, dirs)
+	if err != nil {
+		t.Fatalf("verifyUpload: %v", err)
+	}
+	if check.Problem != "" || check.Files != 3 || check.Bytes != int64(len("fov:0.0\nlevelx")) {
+		t.Errorf("verifyUpload = %+v, want 3 matching files", check)
+	}
+
+	writeTestFile(t, root, "srv/backups/backup_2026-01-01_00-00/saves/My World/level.dat", "lev")
+	check, err = verifyUpload(remote, want, dirs)
+	if err != nil {
+		t.Fatalf("verifyUpload: %v", err)
+	}
+	if !strings.Contains(check.Problem, "My World/level.dat is 3 bytes there and 5 here") {
+		t.Errorf("truncated upload: Problem = %q", check.Problem)
+	}
+}
+
+// remoteSizes reads OpenSSH's ls -l format, skipping folders and sftp's command echoes
+func TestRemoteSizes(t *testing.T) {
+	out := `sftp> ls -lna "/srv/b"
+drwxr-xr-x    3 1000     1000         4096 Oct 16 10:00 /srv/b/.
+-rw-r--r--    1 1000     1000     12345678 Oct 16 10:00 /srv/b/backup_2026-01-01_00-00.zip
+-rw-r--r--    1 1000     1000            5 Jan  2  2025 /srv/b/two  spaces.txt
+`
+	got := remoteSizes(out)
+	want := map[string]int64{"/srv/b/backup_2026-01-01_00-00.zip": 12345678, "/srv/b/two  spaces.txt": 5}
+	if len(got) != len(want) {
+		t.Fatalf("remoteSizes = %v, want %v", got, want)
+	}
+	for name, size := range want {
+		if got[name] != size {
+			t.Errorf("remoteSizes[%q] = %d, want %d", name, got[name], size)
+		}
+	}
+}
+
+// Archives are streamed over ssh rather than staged, and staged as before when the account
+// has no shell; either way the remote ends up with the whole archive and its catalog entry
+func TestStreamedArchiveUpload(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		zstd     bool
+		sftpOnly bool
+	}{{"zip", false, false}, {"zstd", true, false}, {"sftp only", false, true}} {
+		t.Run(c.name, func(t *testing.T) {
+			if c.zstd {
+				if _, err := exec.LookPath("zstd"); err != nil {
+					t.Skip("zstd is not installed")
+				}
+			}
+			root := useFakeSFTP(t)
+			if c.sftpOnly {
+				t.Setenv("FAKE_SSH_FAIL", "1")
+			}
+			mc := t.TempDir()
+			writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+			config := &tui.Config{MinecraftPath: mc, BackupDest: "me@host:/srv/backups", ZipOutput: !c.zstd, Zstd: c.zstd}
+			if streams := streamsArchive(config) && remoteShell(&RemoteDest{User: "me", Host: "host", Path: "/srv/backups"}); streams == c.sftpOnly {
+				t.Fatalf("streaming = %v", streams)
+			}
+
+			result, err := PerformContext(context.Background(), config, nil)
+			if err != nil || !result.Success || len(result.Warnings) > 0 {
+				t.Fatalf("PerformContext: %v, errors %v, warnings %v", err, result.Errors, result.Warnings)
+			}
+			name := strings.TrimPrefix(result.OutputPath, "me@host:/srv/backups/")
+			if !strings.HasSuffix(name, archiveSuffix(config)) {
+				t.Fatalf("OutputPath = %s, want an archive", result.OutputPath)
+			}
+			archive := filepath.Join(root, "srv", "backups", name)
+			info, err := os.Stat(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parts, _ := filepath.Glob(filepath.Join(root, "srv", "backups", "*.part")); len(parts) > 0 {
+				t.Errorf("left %v on the remote", parts)
+			}
+			out := t.TempDir()
+			if _, err := Extract(archive, []string{"options.txt"}, out); err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if data, err := os.ReadFile(filepath.Join(out, "options.txt")); err != nil || string(data) != "fov:0.0\n" {
+				t.Errorf("options.txt in the uploaded archive = %q, %v", data, err)
+			}
+
+			catalog, err := LoadCatalog(filepath.Join(root, "srv", "backups"))
+			if err != nil || len(catalog.Backups) != 1 {
+				t.Fatalf("remote catalog = %+v, %v", catalog, err)
+			}
+			entry := catalog.Backups[0]
+			if entry.Name != name || entry.Size != info.Size() || entry.Upload == nil || entry.Upload.Problem != "" {
+				t.Errorf("catalog entry %s (%d bytes, check %+v), want %s (%d bytes) checked", entry.Name, entry.Size, entry.Upload, name, info.Size())
+			}
+		})
+	}
+}
+
+// An upload adds its backup to the catalog on the remote, keeping what other machines recorded
+func TestRemoteCatalogMerge(t *testing.T) {
+	root := useFakeSFTP(t)
+	writeTestFile(t, root, "srv/backups/backup_2025-01-01_00-00/options.txt", "fov:0.0\n")
+	writeTestFile(t, root, "srv/backups/"+CatalogName, `{"version":1,"backups":[
+		{"name":"backup_2025-01-01_00-00","type":"full","source":"laptop"},
+		{"name":"backup_2024-01-01_00-00","type":"full"}]}`)
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: "me@host:/srv/backups"}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("warnings: %v", result.Warnings)
+	}
+
+	c, err := LoadCatalog(filepath.Join(root, "srv", "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range c.Backups {
+		names = append(names, e.Name)
+	}
+	uploaded := strings.TrimPrefix(result.OutputPath, "me@host:/srv/backups/")
+	if len(names) != 2 || names[0] != "backup_2025-01-01_00-00" || names[1] != uploaded {
+		t.Errorf("remote catalog lists %v, want the laptop's backup and %s", names, uploaded)
+	}
+	if check := c.Backups[1].Upload; check == nil || check.Problem != "" || check.Files == 0 {
+		t.Errorf("the uploaded backup's check is %+v, want matching files", check)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(root, "srv", "backups", "*.tmp")); len(tmps) > 0 {
+		t.Errorf("left %v on the remote", tmps)
+	}
+}
+
+// A remote catalog lock left by a crashed machine is taken over once its lease runs out,
+// and the uploader's own lock is gone once the catalog is written
+func TestRemoteCatalogExpiredLease(t *testing.T) {
+	root := useFakeSFTP(t)
+	lease, _ := json.Marshal(catalogLease{Host: "laptop", PID: 1, Expires: time.Now().Add(-time.Minute)})
+	writeTestFile(t, root, "srv/backups/"+CatalogLockName+"/"+catalogLeaseName, string(lease))
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: "me@host:/srv/backups"}, nil)
+	if err != nil || !result.Success || len(result.Warnings) > 0 {
+		t.Fatalf("PerformContext: %v, warnings %v", err, result.Warnings)
+	}
+	if c, err := LoadCatalog(filepath.Join(root, "srv", "backups")); err != nil || len(c.Backups) != 1 {
+		t.Errorf("remote catalog = %+v, %v; want the new backup", c, err)
+	}
+	if exists(filepath.Join(root, "srv", "backups", CatalogLockName)) {
+		t.Error("the catalog lock was left on the remote")
+	}
+}
@@ -0,0 +1,211 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Retention decides which backups in a destination survive pruning: the newest KeepLast,
+// plus the newest backup of each of the last KeepDaily days, KeepWeekly weeks and
+// KeepMonthly months that have one. All zero keeps everything.
+type Retention struct {
+	KeepLast    int `json:"keep_last,omitempty"`
+	KeepDaily   int `json:"keep_daily,omitempty"`
+	KeepWeekly  int `json:"keep_weekly,omitempty"`
+	KeepMonthly int `json:"keep_monthly,omitempty"`
+}
+
+// Enabled reports whether the policy prunes anything
+func (r Retention) Enabled() bool {
+	return r.KeepLast > 0 || r.KeepDaily > 0 || r.KeepWeekly > 0 || r.KeepMonthly > 0
+}
+
+func (r Retention) String() string {
+	var parts []string
+	for _, p := range []struct {
+		n    int
+		name string
+	}{{r.KeepLast, "last"}, {r.KeepDaily, "daily"}, {r.KeepWeekly, "weekly"}, {r.KeepMonthly, "monthly"}} {
+		switch {
+		case p.n == 0:
+		case p.name == "last":
+			parts = append(parts, fmt.Sprintf("last %d", p.n))
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s", p.n, p.name))
+		}
+	}
+	if len(parts) == 0 {
+		return "keep everything"
+	}
+	return "keep " + strings.Join(parts, ", ")
+}
+
+// pruningDisabled is set in builds that must never delete backups
+var pruningDisabled bool
+
+// DisablePruning turns retention off for the rest of the run, for kiosk builds
+func DisablePruning() {
+	pruningDisabled = true
+}
+
+// RetentionFor returns the retention policy for dest: its destination's own from the
+// destinations section of config.json, else the top-level one
+func RetentionFor(dest string) Retention {
+	if pruningDisabled {
+		return Retention{}
+	}
+	if d, err := destinationFor(dest); err == nil && d != nil && d.Retention != nil {
+		return *d.Retention
+	}
+	if s, err := LoadSettings(); err == nil && s.Retention != nil {
+		return *s.Retention
+	}
+	return Retention{}
+}
+
+// prunable is a backup in a destination that retention may delete
+type prunable struct {
+	file string // Folder or archive name in the destination
+	name string // Name without extensions, as manifests refer to it
+	time time.Time
+}
+
+// Prune deletes the backups directly in dest that policy does not keep and returns their
+// names. The parents of kept incremental backups are kept too, parity files go with their
+// archive, and interrupted backups waiting for `totem resume` are left alone. With dryRun
+// nothing is deleted.
+func Prune(dest string, policy Retention, dryRun bool) ([]string, error) {
+	if !policy.Enabled() || pruningDisabled {
+		return nil, nil
+	}
+	// Held throughout, so a backup recorded by another machine meanwhile is not lost
+	if !dryRun {
+		unlock, err := lockCatalog(dest)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		return nil, err
+	}
+	var backups []prunable
+	byName := map[string]int{}
+	for _, e := range entries {
+		t, ok := parseBackupName(e.Name())
+		if !ok || !isBackupEntry(e.Name()) || exists(filepath.Join(dest, e.Name())+JournalSuffix) {
+			continue
+		}
+		name := unsplitName(e.Name())
+		for _, suffix := range encryptSuffixes {
+			name = strings.TrimSuffix(name, suffix)
+		}
+		name = trimArchiveSuffix(name)
+		backups = append(backups, prunable{file: e.Name(), name: name, time: t})
+	}
+	// Names embed the time, and same-minute suffixes sort after the first backup
+	sort.Slice(backups, func(i, j int) bool { return backups[i].file > backups[j].file })
+	for i, b := range backups {
+		byName[b.name] = i
+	}
+
+	keep := make([]bool, len(backups))
+	for i := range min(policy.KeepLast, len(backups)) {
+		keep[i] = true
+	}
+	periods := []struct {
+		n   int
+		key func(time.Time) string
+	}{
+		{policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, p := range periods {
+		seen := map[string]bool{}
+		for i, b := range backups {
+			if len(seen) == p.n {
+				break
+			}
+			if k := p.key(b.time); !seen[k] {
+				seen[k] = true
+				keep[i] = true
+			}
+		}
+	}
+
+	// A kept incremental needs its whole chain; unreadable (encrypted) backups count as full
+	catalog, _ := LoadCatalog(dest)
+	parentOf := func(file string) string {
+		if c := catalog.Entry(file); c != nil {
+			return c.Parent
+		}
+		if m, err := ReadManifest(filepath.Join(dest, file)); err == nil && m.Type == TypeIncremental {
+			return m.Parent
+		}
+		return ""
+	}
+	for i := range backups {
+		for j := i; keep[j]; {
+			name := parentOf(backups[j].file)
+			if name == "" {
+				break
+			}
+			parent, ok := byName[name]
+			if !ok || keep[parent] {
+				break
+			}
+			keep[parent] = true
+			j = parent
+		}
+	}
+
+	var pruned []string
+	for i, b := range backups {
+		if keep[i] {
+			continue
+		}
+		path := filepath.Join(dest, b.file)
+		if !dryRun {
+			if err := removeBackup(path); err != nil {
+				return pruned, err
+			}
+		}
+		pruned = append(pruned, b.file)
+	}
+	if len(pruned) > 0 && !dryRun && exists(filepath.Join(dest, CatalogName)) {
+		catalog.save(dest)
+	}
+	return pruned, nil
+}
+
+// PruneAll prunes dest and each instance folder in it, by policy when given and otherwise
+// by each folder's configured one. It returns the pruned backups by folder.
+func PruneAll(dest string, policy *Retention, dryRun bool) (map[string][]string, error) {
+	if _, err := os.Stat(dest); err != nil {
+		return nil, err
+	}
+	pruned := map[string][]string{}
+	for _, dir := range backupFolders(dest) {
+		p := RetentionFor(dir)
+		if policy != nil {
+			p = *policy
+		}
+		names, err := Prune(dir, p, dryRun)
+		if len(names) > 0 {
+			pruned[dir] = names
+		}
+		if err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
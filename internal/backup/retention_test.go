@@ -0,0 +1,172 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeTestBackup creates a backup folder in dir with a manifest of type typ and parent
+func writeTestBackup(t *testing.T, dir, name, typ, parent string) {
+	t.Helper()
+	writeTestFile(t, dir, name+"/"+ManifestName, `{"version":1,"type":"`+typ+`","parent":"`+parent+`","files":[]}`)
+}
+
+func TestPruneKeepRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Retention
+		backups []string
+		pruned  []string
+	}{
+		{
+			name:    "nothing set keeps everything",
+			backups: []string{"backup_2026-03-10_12-00", "backup_2026-03-01_12-00"},
+		},
+		{
+			name:    "last",
+			policy:  Retention{KeepLast: 2},
+			backups: []string{"backup_2026-03-10_12-00", "backup_2026-03-10_08-00", "backup_2026-03-09_12-00", "backup_2026-03-01_12-00"},
+			pruned:  []string{"backup_2026-03-01_12-00", "backup_2026-03-09_12-00"},
+		},
+		{
+			name:    "same-minute backups sort after the first",
+			policy:  Retention{KeepLast: 1},
+			backups: []string{"backup_2026-03-10_12-00", "backup_2026-03-10_12-00_2"},
+			pruned:  []string{"backup_2026-03-10_12-00"},
+		},
+		{
+			name:    "daily keeps the newest of each day",
+			policy:  Retention{KeepDaily: 2},
+			backups: []string{"backup_2026-03-10_12-00", "backup_2026-03-10_08-00", "backup_2026-03-09_20-00", "backup_2026-03-09_10-00", "backup_2026-03-08_09-00"},
+			pruned:  []string{"backup_2026-03-08_09-00", "backup_2026-03-09_10-00", "backup_2026-03-10_08-00"},
+		},
+		{
+			name:    "weekly goes by ISO week",
+			policy:  Retention{KeepWeekly: 2},
+			backups: []string{"backup_2026-03-10_12-00", "backup_2026-03-09_12-00", "backup_2026-03-05_12-00", "backup_2026-03-02_12-00", "backup_2026-02-25_12-00"},
+			pruned:  []string{"backup_2026-02-25_12-00", "backup_2026-03-02_12-00", "backup_2026-03-09_12-00"},
+		},
+		{
+			name:    "monthly",
+			policy:  Retention{KeepMonthly: 2},
+			backups: []string{"backup_2026-03-10_12-00", "backup_2026-03-01_12-00", "backup_2026-02-20_12-00", "backup_2026-02-01_12-00", "backup_2026-01-15_12-00"},
+			pruned:  []string{"backup_2026-01-15_12-00", "backup_2026-02-01_12-00", "backup_2026-03-01_12-00"},
+		},
+		{
+			name:    "rules add up",
+			policy:  Retention{KeepLast: 1, KeepMonthly: 2},
+			backups: []string{"backup_2026-03-10_12-00", "backup_2026-03-01_12-00", "backup_2026-02-20_12-00", "backup_2026-01-15_12-00"},
+			pruned:  []string{"backup_2026-01-15_12-00", "backup_2026-03-01_12-00"},
+		},
+		{
+			name:    "other files are not backups",
+			policy:  Retention{KeepLast: 1},
+			backups: []string{"backup_2026-03-10_12-00", "checkpoints", "crash_2026-01-01_00-00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			for _, name := range tt.backups {
+				writeTestBackup(t, dest, name, TypeFull, "")
+			}
+			pruned, err := Prune(dest, tt.policy, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(pruned)
+			if !slices.Equal(pruned, tt.pruned) {
+				t.Errorf("pruned %v, want %v", pruned, tt.pruned)
+			}
+			for _, name := range tt.backups {
+				if gone := !exists(filepath.Join(dest, name)); gone != slices.Contains(tt.pruned, name) {
+					t.Errorf("%s deleted = %v", name, gone)
+				}
+			}
+		})
+	}
+}
+
+// A kept incremental keeps its whole chain, however old
+func TestPruneKeepsChainParents(t *testing.T) {
+	dest := t.TempDir()
+	writeTestBackup(t, dest, "backup_2026-01-01_12-00", TypeFull, "")
+	writeTestBackup(t, dest, "backup_2026-02-01_12-00", TypeFull, "")
+	writeTestBackup(t, dest, "backup_2026-02-02_12-00", TypeIncremental, "backup_2026-01-01_12-00")
+	writeTestBackup(t, dest, "backup_2026-03-01_12-00", TypeIncremental, "backup_2026-02-02_12-00")
+
+	pruned, err := Prune(dest, Retention{KeepLast: 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pruned, []string{"backup_2026-02-01_12-00"}) {
+		t.Errorf("pruned %v, want only the unrelated full backup", pruned)
+	}
+}
+
+// An interrupted backup waiting for totem resume is never pruned
+func TestPruneSkipsJournaled(t *testing.T) {
+	dest := t.TempDir()
+	writeTestBackup(t, dest, "backup_2026-03-10_12-00", TypeFull, "")
+	writeTestBackup(t, dest, "backup_2026-01-01_12-00", TypeFull, "")
+	writeTestFile(t, dest, "backup_2026-01-01_12-00"+JournalSuffix, "{}")
+
+	pruned, err := Prune(dest, Retention{KeepLast: 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 0 || !exists(filepath.Join(dest, "backup_2026-01-01_12-00")) {
+		t.Errorf("pruned %v, want the journaled backup kept", pruned)
+	}
+}
+
+// A split archive goes with all its parts and their parity files
+func TestPruneSplitArchive(t *testing.T) {
+	dest := t.TempDir()
+	writeTestBackup(t, dest, "backup_2026-03-10_12-00", TypeFull, "")
+	old := "backup_2026-01-01_12-00.zip"
+	for _, part := range []string{".001", ".002", ".003"} {
+		writeTestFile(t, dest, old+part, "part")
+		writeTestFile(t, dest, old+part+ParitySuffix, "parity")
+	}
+
+	pruned, err := Prune(dest, Retention{KeepLast: 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pruned, []string{old + ".001"}) {
+		t.Errorf("pruned %v, want the split archive once", pruned)
+	}
+	entries, _ := os.ReadDir(dest)
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("left %v, want only the newest backup", names)
+	}
+}
+
+func TestPruneDryRun(t *testing.T) {
+	dest := t.TempDir()
+	names := []string{"backup_2026-03-10_12-00", "backup_2026-03-09_12-00", "backup_2026-03-08_12-00"}
+	for _, name := range names {
+		writeTestBackup(t, dest, name, TypeFull, "")
+	}
+	writeTestFile(t, dest, "backup_2026-03-01_12-00.zip", "zip")
+	writeTestFile(t, dest, "backup_2026-03-01_12-00.zip"+ParitySuffix, "parity")
+
+	pruned, err := Prune(dest, Retention{KeepLast: 1}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 3 {
+		t.Errorf("dry run lists %v, want 3 backups", pruned)
+	}
+	entries, _ := os.ReadDir(dest)
+	if len(entries) != 5 {
+		t.Errorf("dry run left %d entries, want all 5", len(entries))
+	}
+}
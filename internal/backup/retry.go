@@ -0,0 +1,45 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backoff between attempts when a file is locked, e.g. while Defender scans a fresh .jar
+var lockRetryDelays = []time.Duration{
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+}
+
+// LockedFileError is a copy failure caused by another program holding the file
+type LockedFileError struct {
+	Path string
+	Err  error
+}
+
+func (e *LockedFileError) Error() string {
+	return fmt.Sprintf("%s is locked by another program (often antivirus or Windows Defender scanning it); "+
+		"add your Minecraft folder to its exclusions or close the game and retry", e.Path)
+}
+
+func (e *LockedFileError) Unwrap() error {
+	return e.Err
+}
+
+// retryLocked runs copy, retrying with backoff while the file is locked
+func retryLocked(path string, copy func() error) error {
+	err := copy()
+	for _, delay := range lockRetryDelays {
+		if err == nil || !isLockError(err) {
+			return err
+		}
+		time.Sleep(delay)
+		err = copy()
+	}
+	if err != nil && isLockError(err) {
+		return &LockedFileError{Path: path, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,22 @@
+package backup
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+// Errors other than a lock fail at once rather than after the backoff
+func TestRetryLockedOtherErrors(t *testing.T) {
+	calls := 0
+	err := retryLocked("mods/sodium.jar", func() error {
+		calls++
+		return fs.ErrNotExist
+	})
+	if !errors.Is(err, fs.ErrNotExist) || calls != 1 {
+		t.Errorf("retryLocked = %v after %d calls, want ErrNotExist after 1", err, calls)
+	}
+	if err := retryLocked("options.txt", func() error { return nil }); err != nil {
+		t.Errorf("retryLocked of a working copy = %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// A file locked for a moment is copied once the lock goes; one that stays locked fails with
+// an error that points at antivirus and still wraps the sharing violation
+func TestRetryLocked(t *testing.T) {
+	saved := lockRetryDelays
+	lockRetryDelays = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+	defer func() { lockRetryDelays = saved }()
+
+	calls := 0
+	err := retryLocked("mods/sodium.jar", func() error {
+		if calls++; calls < 3 {
+			return windows.ERROR_SHARING_VIOLATION
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("retryLocked = %v after %d calls, want success on the 3rd", err, calls)
+	}
+
+	err = retryLocked("mods/sodium.jar", func() error { return windows.ERROR_LOCK_VIOLATION })
+	var locked *LockedFileError
+	if !errors.As(err, &locked) || !errors.Is(err, windows.ERROR_LOCK_VIOLATION) || !strings.Contains(err.Error(), "antivirus") {
+		t.Errorf("retryLocked of a file that stays locked = %v", err)
+	}
+}
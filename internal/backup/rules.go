@@ -0,0 +1,210 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// folderNotes says what a backup does with the instance folders it knows, without rules
+var folderNotes = map[string]string{
+	"screenshots":                  "Copied",
+	"saves":                        "Copied with \"Include saves\"",
+	"mods":                         "Listed in mods.txt",
+	"resourcepacks":                "Listed in resourcepacks.txt",
+	"shaderpacks":                  "Listed, shader configs copied",
+	"xaero":                        "Copied with \"Include Xaero maps\"",
+	"distant_horizons_server_data": "Copied with \"Include Distant Horizons\"",
+	CrashReportsDir:                "Copied with \"Include crash reports\"",
+}
+
+func rulesPath() (string, error) {
+	dir, err := KeyDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rules.json"), nil
+}
+
+// rulesKey identifies an instance in rules.json
+func rulesKey(instance string) string {
+	if abs, err := filepath.Abs(instance); err == nil {
+		return abs
+	}
+	return filepath.Clean(instance)
+}
+
+func loadAllRules() (map[string]tui.Rules, error) {
+	path, err := rulesPath()
+	if err != nil {
+		return nil, err
+	}
+	all := map[string]tui.Rules{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return all, nil
+}
+
+// LoadRules returns the include/exclude rules saved for an instance (none if never saved)
+func LoadRules(instance string) (tui.Rules, error) {
+	all, err := loadAllRules()
+	if err != nil {
+		return tui.Rules{}, err
+	}
+	return all[rulesKey(instance)], nil
+}
+
+// SaveRules stores an instance's include/exclude rules, which every later backup of it applies
+func SaveRules(instance string, rules tui.Rules) error {
+	all, err := loadAllRules()
+	if err != nil {
+		return err
+	}
+	if len(rules.Include) == 0 && len(rules.Exclude) == 0 {
+		delete(all, rulesKey(instance))
+	} else {
+		all[rulesKey(instance)] = rules
+	}
+	path, err := rulesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// withRules returns a copy of config with the instance's saved rules added to its own.
+// Rule paths are slash-separated and relative to the instance; ones leaving it are dropped.
+func withRules(config *tui.Config) *tui.Config {
+	merged := *config
+	saved, _ := LoadRules(config.MinecraftPath)
+	clean := func(lists ...[]string) []string {
+		var out []string
+		for _, list := range lists {
+			for _, p := range list {
+				p = strings.Trim(path.Clean(filepath.ToSlash(p)), "/")
+				if p == "." || p == ".." || strings.HasPrefix(p, "../") || slices.Contains(out, p) {
+					continue
+				}
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	merged.Include = clean(config.Include, saved.Include)
+	merged.Exclude = clean(config.Exclude, saved.Exclude)
+	return &merged
+}
+
+// excludePaths returns the absolute paths of config's exclude rules
+func excludePaths(config *tui.Config) []string {
+	var paths []string
+	for _, p := range config.Exclude {
+		paths = append(paths, filepath.Join(config.MinecraftPath, filepath.FromSlash(p)))
+	}
+	return paths
+}
+
+// ruledOut reports whether path is, or is inside, a folder excluded by rule
+func (o *copyOptions) ruledOut(path string) bool {
+	return o != nil && underAny(o.exclude, path)
+}
+
+// underAny reports whether path is one of roots or inside one
+func underAny(roots []string, path string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyIncluded copies the folders and files added by include rules into the backup as they are
+func copyIncluded(root, backupPath string, include []string, opts *copyOptions) (int, error) {
+	count := 0
+	for _, rel := range include {
+		src := filepath.Join(root, filepath.FromSlash(rel))
+		dst := filepath.Join(backupPath, filepath.FromSlash(rel))
+		info, err := os.Stat(src)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return count, err
+			}
+			if err := opts.copyFile(src, dst); err != nil {
+				return count, fmt.Errorf("%s: %w", rel, err)
+			}
+			count++
+			continue
+		}
+		n, err := copyDir(src, dst, opts)
+		count += n
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", rel, err)
+		}
+	}
+	return count, nil
+}
+
+// InstanceFolders lists an instance's top-level folders with their sizes for the rules screen
+func InstanceFolders(instance string) ([]tui.InstanceFolder, error) {
+	entries, err := os.ReadDir(instance)
+	if err != nil {
+		return nil, err
+	}
+	var folders []tui.InstanceFolder
+	for _, e := range entries {
+		if !isDirLink(instance, e) {
+			continue
+		}
+		note, ok := folderNotes[e.Name()]
+		if !ok {
+			note = "Not backed up"
+		}
+		folders = append(folders, tui.InstanceFolder{
+			Name: e.Name(),
+			Size: getDirSize(filepath.Join(instance, e.Name()), nil),
+			Note: note,
+		})
+	}
+	return folders, nil
+}
+
+// renderRulesSection lists the include/exclude rules a backup applied
+func renderRulesSection(config *tui.Config) string {
+	if len(config.Include) == 0 && len(config.Exclude) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n## 📐 Copy Rules\n\n")
+	for _, p := range config.Include {
+		fmt.Fprintf(&b, "- **Included:** `%s`\n", p)
+	}
+	for _, p := range config.Exclude {
+		fmt.Fprintf(&b, "- **Excluded:** `%s`\n", p)
+	}
+	return b.String()
+}
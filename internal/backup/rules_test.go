@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Saved rules apply to every later backup of the instance, on top of its own flags
+func TestSavedRules(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "config/sodium.json", "{}")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "saves/Huge/level.dat", "level")
+	writeTestFile(t, mc, "saves/Huge/region/r.0.0.mca", "region")
+
+	rules := tui.Rules{Include: []string{"config", "../outside"}, Exclude: []string{"saves/Huge"}}
+	if err := SaveRules(mc, rules); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LoadRules(mc); err != nil || len(got.Include) != 2 || got.Exclude[0] != "saves/Huge" {
+		t.Fatalf("LoadRules = %+v, %v", got, err)
+	}
+
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), IncludeSaves: true}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v", err)
+	}
+	if !exists(filepath.Join(result.OutputPath, "config", "sodium.json")) || !exists(filepath.Join(result.OutputPath, "saves", "World", "level.dat")) {
+		t.Error("the included folder or another world is missing")
+	}
+	if exists(filepath.Join(result.OutputPath, "saves", "Huge")) {
+		t.Error("the excluded world was backed up")
+	}
+	info, _ := os.ReadFile(filepath.Join(result.OutputPath, "info.md"))
+	if !strings.Contains(string(info), "- **Included:** `config`") || strings.Contains(string(info), "outside") {
+		t.Errorf("info.md lists the rules wrong:\n%s", info)
+	}
+
+	// Clearing every rule removes the instance from rules.json
+	if err := SaveRules(mc, tui.Rules{}); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := loadAllRules()
+	if len(all) != 0 {
+		t.Errorf("rules.json still holds %v", all)
+	}
+}
+
+func TestInstanceFolders(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "logs/latest.log", "log")
+	folders, err := InstanceFolders(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 2 || folders[0].Name != "logs" || folders[0].Note != "Not backed up" ||
+		folders[1].Size != 5 || folders[1].Note != folderNotes["saves"] {
+		t.Errorf("InstanceFolders = %+v", folders)
+	}
+}
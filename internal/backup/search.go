@@ -0,0 +1,207 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupFolders returns dest and its instance subfolders (as written by totem backup),
+// the folders that may hold backup_<time> entries
+func backupFolders(dest string) []string {
+	dirs := []string{dest}
+	if entries, err := os.ReadDir(dest); err == nil {
+		for _, e := range entries {
+			if e.IsDir() && e.Name() != DigestDir && e.Name() != CatalogLockName && !strings.HasPrefix(e.Name(), "backup_") {
+				dirs = append(dirs, filepath.Join(dest, e.Name()))
+			}
+		}
+	}
+	return dirs
+}
+
+// SearchMatch is one backup that contains something matching a search
+type SearchMatch struct {
+	Backup   string // Path of the backup folder or zip
+	Instance string // Folder it is in, relative to the searched destination
+	Time     time.Time
+	Paths    []string // Matching files and folders (folders end in "/"), and mod entries
+}
+
+// A single .jar is read for metadata only up to this size when it sits inside a zip
+const searchJarLimit = 64 << 20
+
+// SearchBackups finds backups in dest and its instance subfolders whose file or folder names
+// match pattern: a case-insensitive substring, or a glob when it contains * ? or [.
+// With mods, the mod lists (mods.txt) and the metadata of backed up jars are searched too.
+// It returns the matches oldest first and how many backups were searched.
+func SearchBackups(dest, pattern string, mods bool) ([]SearchMatch, int, error) {
+	if _, err := os.Stat(dest); err != nil {
+		return nil, 0, fmt.Errorf("destination does not exist: %s", dest)
+	}
+	match := searchMatcher(pattern)
+
+	var matches []SearchMatch
+	searched := 0
+	for _, dir := range backupFolders(dest) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			t, ok := parseBackupName(e.Name())
+			if !ok || !isBackupEntry(e.Name()) {
+				continue
+			}
+			searched++
+			backupPath := filepath.Join(dir, e.Name())
+			paths, err := searchBackup(backupPath, match, mods)
+			if err != nil || len(paths) == 0 {
+				continue
+			}
+			instance, _ := filepath.Rel(dest, dir)
+			matches = append(matches, SearchMatch{Backup: backupPath, Instance: instance, Time: t, Paths: paths})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Time.Before(matches[j].Time) })
+	return matches, searched, nil
+}
+
+// searchMatcher turns a search pattern into a name test
+func searchMatcher(pattern string) func(name string) bool {
+	lower := strings.ToLower(pattern)
+	if strings.ContainsAny(pattern, "*?[") {
+		return func(name string) bool {
+			ok, _ := path.Match(lower, strings.ToLower(name))
+			return ok
+		}
+	}
+	return func(name string) bool { return strings.Contains(strings.ToLower(name), lower) }
+}
+
+// searchBackup returns the matching paths in one backup. A folder whose name matches is
+// reported once instead of every file in it.
+func searchBackup(backupPath string, match func(string) bool, mods bool) ([]string, error) {
+	seen := map[string]bool{}
+	var paths []string
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	visit := func(rel string, open func() (io.ReadCloser, int64, error)) {
+		parts := strings.Split(rel, "/")
+		for i, part := range parts {
+			if !match(part) {
+				continue
+			}
+			if i < len(parts)-1 {
+				add(strings.Join(parts[:i+1], "/") + "/")
+			} else {
+				add(rel)
+			}
+			break
+		}
+		if !mods {
+			return
+		}
+		switch {
+		case rel == "mods.txt":
+			if rc, _, err := open(); err == nil {
+				scanner := bufio.NewScanner(rc)
+				for scanner.Scan() {
+					if line := strings.TrimSpace(scanner.Text()); line != "" && match(line) {
+						add("mods.txt: " + line)
+					}
+				}
+				rc.Close()
+			}
+		case strings.HasSuffix(strings.ToLower(rel), ".jar"):
+			if info, ok := searchJarInfo(open); ok && (match(info.ID) || match(info.Name)) {
+				add(fmt.Sprintf("%s: %s %s", rel, info.Name, info.Version))
+			}
+		}
+	}
+
+	if isZipBackup(backupPath) {
+		r, closeZip, err := openZipBackup(backupPath)
+		if err != nil {
+			return nil, err
+		}
+		defer closeZip()
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			visit(filepath.ToSlash(f.Name), func() (io.ReadCloser, int64, error) {
+				rc, err := f.Open()
+				return rc, int64(f.UncompressedSize64), err
+			})
+		}
+		return paths, nil
+	}
+
+	if isTarBackup(backupPath) {
+		// Tars only stream, so each file is offered once, with its size unknown
+		err := forEachMatchingFile(backupPath, func(string) bool { return true }, func(rel string, r io.Reader) error {
+			visit(rel, func() (io.ReadCloser, int64, error) {
+				return io.NopCloser(r), 0, nil
+			})
+			return nil
+		})
+		return paths, err
+	}
+
+	err := filepath.WalkDir(backupPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(backupPath, p)
+		visit(filepath.ToSlash(rel), func() (io.ReadCloser, int64, error) {
+			f, err := os.Open(p)
+			if err != nil {
+				return nil, 0, err
+			}
+			info, err := f.Stat()
+			if err != nil {
+				f.Close()
+				return nil, 0, err
+			}
+			return f, info.Size(), nil
+		})
+		return nil
+	})
+	return paths, err
+}
+
+// searchJarInfo reads a jar's metadata through open, which works for jars inside zips too
+func searchJarInfo(open func() (io.ReadCloser, int64, error)) (ModInfo, bool) {
+	rc, size, err := open()
+	if err != nil {
+		return ModInfo{}, false
+	}
+	defer rc.Close()
+	if size > searchJarLimit {
+		return ModInfo{}, false
+	}
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return ModInfo{}, false
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ModInfo{}, false
+	}
+	info := readModInfoZip(zr)
+	return info, info.ID != "" || info.Name != ""
+}
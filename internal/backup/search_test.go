@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testJar returns a Fabric mod jar's bytes, for backups that hold mods
+func testJar(t *testing.T, metadata string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mod.jar")
+	writeTestZip(t, path, map[string]string{"fabric.mod.json": metadata})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSearchBackups(t *testing.T) {
+	dest := t.TempDir()
+	writeTestFile(t, dest, "backup_2026-01-01_00-00/saves/Creative World/level.dat", "level")
+	writeTestFile(t, dest, "backup_2026-01-01_00-00/saves/Creative World/region/r.0.0.mca", "region")
+	writeTestFile(t, dest, "backup_2026-01-01_00-00/screenshots/creative-base.png", "png")
+	if err := os.MkdirAll(filepath.Join(dest, "Fabric"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestZip(t, filepath.Join(dest, "Fabric", "backup_2025-12-01_00-00.zip"), map[string]string{
+		"screenshots/2025-11-30_20.00.00.png": "png",
+		"mods.txt":                            "Sodium 0.5.8\nLithium 0.11.2\n",
+		"mods/fast-render.jar":                testJar(t, `{"id": "sodium", "name": "Sodium", "version": "0.5.8"}`),
+	})
+	writeTestFile(t, dest, "Fabric/notes.txt", "creative ideas")
+
+	matches, searched, err := SearchBackups(dest, "CREATIVE", false)
+	if err != nil || searched != 2 {
+		t.Fatalf("SearchBackups = %d searched, %v", searched, err)
+	}
+	if len(matches) != 1 || matches[0].Instance != "." {
+		t.Fatalf("matches = %+v, want only the folder backup", matches)
+	}
+	if want := []string{"saves/Creative World/", "screenshots/creative-base.png"}; !slices.Equal(matches[0].Paths, want) {
+		t.Errorf("Paths = %q, want the world folder once and the screenshot", matches[0].Paths)
+	}
+
+	matches, _, _ = SearchBackups(dest, "*.png", false)
+	if len(matches) != 2 || matches[0].Instance != "Fabric" || matches[1].Instance != "." {
+		t.Errorf("glob search found %+v, want both backups, oldest first", matches)
+	}
+
+	if matches, _, _ := SearchBackups(dest, "sodium", false); len(matches) != 0 {
+		t.Errorf("found mods without --mods: %+v", matches)
+	}
+	matches, _, _ = SearchBackups(dest, "sodium", true)
+	// Paths follow the zip's entry order, which writeTestZip does not fix
+	want := []string{"mods.txt: Sodium 0.5.8", "mods/fast-render.jar: Sodium 0.5.8"}
+	if len(matches) != 1 || !slices.Equal(slices.Sorted(slices.Values(matches[0].Paths)), want) {
+		t.Errorf("--mods search found %+v, want %q", matches, want)
+	}
+
+	if _, _, err := SearchBackups(filepath.Join(dest, "missing"), "x", false); err == nil {
+		t.Error("searched a destination that does not exist")
+	}
+}
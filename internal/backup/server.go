@@ -0,0 +1,246 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Admin files a dedicated server keeps next to server.properties
+var serverAdminFiles = []string{
+	"server.properties",
+	"whitelist.json",
+	"ops.json",
+	"banned-players.json",
+	"banned-ips.json",
+}
+
+// serverPlayer is an entry from whitelist.json, ops.json or a ban list
+type serverPlayer struct {
+	UUID    string `json:"uuid"`
+	Name    string `json:"name"`
+	IP      string `json:"ip"`
+	Level   int    `json:"level"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+// isServer reports whether root looks like a dedicated server rather than a client
+func isServer(root string) bool {
+	return exists(filepath.Join(root, "server.properties"))
+}
+
+// copyServerFiles copies the admin files into dst/server and writes admin.md
+func copyServerFiles(root, dst string) (int, error) {
+	serverDst := filepath.Join(dst, "server")
+	if err := os.MkdirAll(serverDst, 0755); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, name := range serverAdminFiles {
+		src := filepath.Join(root, name)
+		if !exists(src) {
+			continue
+		}
+		if err := copyFile(src, filepath.Join(serverDst, name)); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err := os.WriteFile(filepath.Join(dst, "admin.md"), []byte(renderAdminMD(root)), 0644); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// readServerList reads one of the server's JSON player lists, returning nil if it is missing
+func readServerList(path string) ([]serverPlayer, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []serverPlayer
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// renderAdminMD summarizes the whitelist, operators and bans as markdown tables
+func renderAdminMD(root string) string {
+	var b strings.Builder
+	b.WriteString("# 🛡️ Server Administration\n\n")
+	b.WriteString("> Raw files are in the `server/` folder of this backup.\n")
+
+	sections := []struct {
+		file, title, header string
+		row                 func(p serverPlayer) string
+	}{
+		{"whitelist.json", "✅ Whitelist", "| Player | UUID |\n|--------|------|\n", func(p serverPlayer) string {
+			return fmt.Sprintf("| %s | `%s` |\n", p.Name, p.UUID)
+		}},
+		{"ops.json", "👑 Operators", "| Player | Level | UUID |\n|--------|-------|------|\n", func(p serverPlayer) string {
+			return fmt.Sprintf("| %s | %d | `%s` |\n", p.Name, p.Level, p.UUID)
+		}},
+		{"banned-players.json", "🚫 Banned Players", "| Player | Reason | By | Since | Expires |\n|--------|--------|----|-------|---------|\n", func(p serverPlayer) string {
+			return fmt.Sprintf("| %s | %s | %s | %s | %s |\n", p.Name, p.Reason, p.Source, p.Created, p.Expires)
+		}},
+		{"banned-ips.json", "🚫 Banned IPs", "| IP | Reason | By | Since | Expires |\n|----|--------|----|-------|---------|\n", func(p serverPlayer) string {
+			return fmt.Sprintf("| %s | %s | %s | %s | %s |\n", p.IP, p.Reason, p.Source, p.Created, p.Expires)
+		}},
+	}
+
+	for _, s := range sections {
+		b.WriteString(fmt.Sprintf("\n## %s\n\n", s.title))
+		list, err := readServerList(filepath.Join(root, s.file))
+		switch {
+		case err != nil:
+			b.WriteString(fmt.Sprintf("*Could not read %s: %v*\n", s.file, err))
+		case len(list) == 0:
+			b.WriteString("*None*\n")
+		default:
+			b.WriteString(s.header)
+			for _, p := range list {
+				b.WriteString(s.row(p))
+			}
+		}
+	}
+	return b.String()
+}
+
+// Vanilla server.properties defaults (1.21), used to find the values an admin changed
+var serverDefaults = map[string]string{
+	"accepts-transfers":                 "false",
+	"allow-flight":                      "false",
+	"allow-nether":                      "true",
+	"broadcast-console-to-ops":          "true",
+	"broadcast-rcon-to-ops":             "true",
+	"bug-report-link":                   "",
+	"difficulty":                        "easy",
+	"enable-command-block":              "false",
+	"enable-jmx-monitoring":             "false",
+	"enable-query":                      "false",
+	"enable-rcon":                       "false",
+	"enable-status":                     "true",
+	"enforce-secure-profile":            "true",
+	"enforce-whitelist":                 "false",
+	"entity-broadcast-range-percentage": "100",
+	"force-gamemode":                    "false",
+	"function-permission-level":         "2",
+	"gamemode":                          "survival",
+	"generate-structures":               "true",
+	"generator-settings":                "{}",
+	"hardcore":                          "false",
+	"hide-online-players":               "false",
+	"initial-disabled-packs":            "",
+	"initial-enabled-packs":             "vanilla",
+	"level-name":                        "world",
+	"level-seed":                        "",
+	"level-type":                        "minecraft\\:normal",
+	"log-ips":                           "true",
+	"max-chained-neighbor-updates":      "1000000",
+	"max-players":                       "20",
+	"max-tick-time":                     "60000",
+	"max-world-size":                    "29999984",
+	"motd":                              "A Minecraft Server",
+	"network-compression-threshold":     "256",
+	"online-mode":                       "true",
+	"op-permission-level":               "4",
+	"player-idle-timeout":               "0",
+	"prevent-proxy-connections":         "false",
+	"pvp":                               "true",
+	"query.port":                        "25565",
+	"rate-limit":                        "0",
+	"rcon.password":                     "",
+	"rcon.port":                         "25575",
+	"region-file-compression":           "deflate",
+	"require-resource-pack":             "false",
+	"resource-pack":                     "",
+	"resource-pack-id":                  "",
+	"resource-pack-prompt":              "",
+	"resource-pack-sha1":                "",
+	"server-ip":                         "",
+	"server-port":                       "25565",
+	"simulation-distance":               "10",
+	"spawn-animals":                     "true",
+	"spawn-monsters":                    "true",
+	"spawn-npcs":                        "true",
+	"spawn-protection":                  "16",
+	"sync-chunk-writes":                 "true",
+	"text-filtering-config":             "",
+	"use-native-transport":              "true",
+	"view-distance":                     "10",
+	"white-list":                        "false",
+}
+
+// Properties whose values should never end up in a readable report
+var secretProperties = map[string]bool{
+	"rcon.password": true,
+}
+
+// readProperties parses a Java .properties file into ordered keys and values
+func readProperties(path string) ([]string, map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	values := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if _, seen := values[key]; !seen {
+			keys = append(keys, key)
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	return keys, values, nil
+}
+
+// renderServerPropertiesSection lists server.properties values that differ from vanilla defaults
+func renderServerPropertiesSection(root string) string {
+	keys, values, err := readProperties(filepath.Join(root, "server.properties"))
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n## 🖥️ Server Properties\n\nOnly values that differ from vanilla defaults are shown.\n\n")
+	changed := 0
+	for _, key := range keys {
+		value := values[key]
+		def, known := serverDefaults[key]
+		if known && value == def {
+			continue
+		}
+		if changed == 0 {
+			b.WriteString("| Property | Value | Default |\n|----------|-------|---------|\n")
+		}
+		value = "`" + value + "`"
+		if secretProperties[key] && values[key] != "" {
+			value = "*(redacted)*"
+		}
+		if !known {
+			def = "*(not vanilla)*"
+		}
+		b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", key, value, def))
+		changed++
+	}
+	if changed == 0 {
+		b.WriteString("*All values are at their defaults.*\n")
+	}
+	return b.String()
+}
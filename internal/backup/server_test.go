@@ -0,0 +1,68 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The admin files are copied under server/ and admin.md tables who is whitelisted, opped
+// and banned, saying so when a list is empty or missing
+func TestCopyServerFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "server.properties", "motd=Hi\n")
+	writeTestFile(t, root, "whitelist.json", `[{"uuid":"1234","name":"Alex"}]`)
+	writeTestFile(t, root, "ops.json", `[{"uuid":"5678","name":"Steve","level":4}]`)
+	writeTestFile(t, root, "banned-players.json", `[{"uuid":"9","name":"Griefer","source":"Steve","reason":"TNT","created":"2026-01-01","expires":"forever"}]`)
+	writeTestFile(t, root, "banned-ips.json", `[]`)
+	if !isServer(root) || isServer(t.TempDir()) {
+		t.Fatal("isServer does not go by server.properties")
+	}
+
+	dst := t.TempDir()
+	n, err := copyServerFiles(root, dst)
+	if err != nil || n != 5 {
+		t.Fatalf("copyServerFiles = %d, %v; want 5 files", n, err)
+	}
+	if !exists(filepath.Join(dst, "server", "ops.json")) {
+		t.Error("ops.json was not copied into server/")
+	}
+	admin, _ := os.ReadFile(filepath.Join(dst, "admin.md"))
+	for _, row := range []string{
+		"| Alex | `1234` |",
+		"| Steve | 4 | `5678` |",
+		"| Griefer | TNT | Steve | 2026-01-01 | forever |",
+		"## 🚫 Banned IPs\n\n*None*",
+	} {
+		if !strings.Contains(string(admin), row) {
+			t.Errorf("admin.md lacks %q:\n%s", row, admin)
+		}
+	}
+}
+
+// Only changed and unknown properties are listed, and the RCON password never is
+func TestServerPropertiesSection(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "server.properties", `#Minecraft server properties
+motd=A Minecraft Server
+max-players=50
+rcon.password=hunter2
+custom-plugin-key=on
+pvp=true
+`)
+	s := renderServerPropertiesSection(root)
+	for _, want := range []string{"| max-players | `50` | 20 |", "| rcon.password | *(redacted)* |", "| custom-plugin-key | `on` | *(not vanilla)* |"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("section lacks %q:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "hunter2") || strings.Contains(s, "motd") || strings.Contains(s, "pvp") {
+		t.Errorf("section shows a secret or a default value:\n%s", s)
+	}
+
+	writeTestFile(t, root, "server.properties", "pvp=true\n")
+	if s := renderServerPropertiesSection(root); !strings.Contains(s, "All values are at their defaults") {
+		t.Errorf("all-default section = %q", s)
+	}
+}
@@ -0,0 +1,242 @@
+package backup
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SignatureName is the detached signature stored next to manifest.json
+const SignatureName = ManifestName + ".sig"
+
+// ErrUnsigned is returned by VerifySignature when a backup has no signature
+var ErrUnsigned = errors.New("backup is not signed")
+
+// Signature is the contents of manifest.json.sig
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"` // base64, identifies the signer
+	Signature string `json:"signature"`  // base64 signature over manifest.json
+}
+
+// configDir overrides where Totem keeps its keys and settings; see SetConfigDir
+var configDir string
+
+// SetConfigDir makes KeyDir return dir, for --config
+func SetConfigDir(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	configDir = abs
+	return nil
+}
+
+// KeyDir returns the folder holding Totem's signing keys, settings and saved rules: the
+// --config folder, totem-data next to the binary in portable mode, or $TOTEM_CONFIG if
+// set, else totem in the user's own config folder (so users of a shared PC keep separate
+// state)
+func KeyDir() (string, error) {
+	if configDir != "" {
+		return configDir, nil
+	}
+	if Portable() {
+		return PortableDir()
+	}
+	if dir := os.Getenv("TOTEM_CONFIG"); dir != "" {
+		return filepath.Abs(dir)
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "totem"), nil
+}
+
+// LoadOrCreateKey loads the local ed25519 signing key, generating one on first use
+func LoadOrCreateKey() (ed25519.PrivateKey, error) {
+	dir, err := KeyDir()
+	if err != nil {
+		return nil, err
+	}
+	keyPath := filepath.Join(dir, "signing.key")
+
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid signing key in %s", keyPath)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	seed := base64.StdEncoding.EncodeToString(priv.Seed())
+	if err := os.WriteFile(keyPath, []byte(seed+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	pubText := base64.StdEncoding.EncodeToString(pub)
+	if err := os.WriteFile(filepath.Join(dir, "signing.pub"), []byte(pubText+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
+
+// LoadPublicKey reads a base64 public key file (defaults to the local signing.pub)
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	if path == "" {
+		dir, err := KeyDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "signing.pub")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key in %s", path)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// signManifest writes manifest.json.sig for the manifest in backupPath
+func signManifest(backupPath string) error {
+	key, err := LoadOrCreateKey()
+	if err != nil {
+		return err
+	}
+	manifest, err := os.ReadFile(filepath.Join(backupPath, ManifestName))
+	if err != nil {
+		return err
+	}
+
+	sig := Signature{
+		Algorithm: "ed25519",
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifest)),
+	}
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(backupPath, SignatureName), data, 0644)
+}
+
+// IsSigned reports whether a backup folder or zip has a manifest.json.sig, without
+// checking it, for when there is no trusted key to check it against
+func IsSigned(backupPath string) (bool, error) {
+	signed := false
+	err := forEachFile(backupPath, func(rel string, r io.Reader) error {
+		signed = signed || rel == SignatureName
+		return nil
+	})
+	return signed, err
+}
+
+// VerifySignature checks manifest.json.sig in a backup folder or zip against a trusted key
+func VerifySignature(backupPath string, trusted ed25519.PublicKey) error {
+	var manifest []byte
+	var sig *Signature
+	err := forEachFile(backupPath, func(rel string, r io.Reader) error {
+		var err error
+		switch rel {
+		case ManifestName:
+			manifest, err = io.ReadAll(r)
+		case SignatureName:
+			sig = &Signature{}
+			err = json.NewDecoder(r).Decode(sig)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return fmt.Errorf("no %s found", ManifestName)
+	}
+	if sig == nil {
+		return ErrUnsigned
+	}
+
+	if len(trusted) != ed25519.PublicKeySize {
+		return fmt.Errorf("trusted key is %d bytes, not an ed25519 public key", len(trusted))
+	}
+	signer, err := base64.StdEncoding.DecodeString(sig.PublicKey)
+	if err != nil || !trusted.Equal(ed25519.PublicKey(signer)) {
+		return fmt.Errorf("signed by an untrusted key")
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(trusted, manifest, raw) {
+		return fmt.Errorf("signature does not match manifest (tampered?)")
+	}
+	return nil
+}
+
+// VerifyReport is the result of re-reading a backup against its manifest
+type VerifyReport struct {
+	Checked   int      // Files whose SHA-256 was recomputed and matched
+	Missing   []string // Listed in the manifest but not in the backup
+	Corrupted []string // Present, but unreadable or no longer matching the manifest's SHA-256
+}
+
+// VerifyFiles re-hashes every file (or zip entry) of a backup and compares it with the
+// SHA-256 in the manifest, so damage shows up before a restore is needed. Manifests from
+// before hashes were recorded only get their files' presence checked.
+func VerifyFiles(backupPath string) (*VerifyReport, error) {
+	m, err := ReadManifest(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	report := &VerifyReport{}
+	present := map[string]bool{}
+	err = forEachFile(backupPath, func(rel string, r io.Reader) error {
+		present[rel] = true
+		want, ok := m.SHA256[rel]
+		if !ok {
+			return nil
+		}
+		h := sha256.New()
+		_, err := io.Copy(h, r)
+		// Without the password nothing can be checked, which is not damage
+		if errors.Is(err, ErrZipPassword) {
+			return err
+		}
+		if err != nil || hex.EncodeToString(h.Sum(nil)) != want {
+			report.Corrupted = append(report.Corrupted, rel)
+			return nil
+		}
+		report.Checked++
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, f := range m.Files {
+		if !present[f] {
+			report.Missing = append(report.Missing, f)
+		}
+	}
+	return report, nil
+}
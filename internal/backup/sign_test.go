@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A signature claiming an empty key must not make a missing trusted key look like a match
+func TestVerifySignatureWithoutKey(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ManifestName, `{"files":[]}`)
+	writeTestFile(t, dir, SignatureName, `{"algorithm":"ed25519","public_key":"","signature":""}`)
+
+	if err := VerifySignature(dir, nil); err == nil {
+		t.Error("VerifySignature with no trusted key: no error")
+	}
+	if signed, err := IsSigned(dir); err != nil || !signed {
+		t.Errorf("IsSigned = %v, %v; want true", signed, err)
+	}
+
+	unsigned := t.TempDir()
+	writeTestFile(t, unsigned, ManifestName, `{"files":[]}`)
+	if err := VerifySignature(unsigned, nil); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("unsigned backup: got %v, want ErrUnsigned", err)
+	}
+}
+
+// State lives in --config, else $TOTEM_CONFIG, else the user's own config folder, never
+// next to the binary
+func TestKeyDir(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TOTEM_CONFIG", "")
+	if dir, err := KeyDir(); err != nil || (runtime.GOOS == "linux" && dir != filepath.Join(xdg, "totem")) {
+		t.Errorf("KeyDir = %s, %v; want totem in the user's config folder", dir, err)
+	}
+
+	t.Chdir(t.TempDir())
+	t.Setenv("TOTEM_CONFIG", "profiles/alex")
+	want, _ := filepath.Abs("profiles/alex")
+	if dir, err := KeyDir(); err != nil || dir != want {
+		t.Errorf("with TOTEM_CONFIG: KeyDir = %s, %v; want %s", dir, err, want)
+	}
+
+	t.Cleanup(func() { configDir = "" })
+	if err := SetConfigDir("profiles/sam"); err != nil {
+		t.Fatal(err)
+	}
+	want, _ = filepath.Abs("profiles/sam")
+	if dir, err := KeyDir(); err != nil || dir != want {
+		t.Errorf("with --config: KeyDir = %s, %v; want %s", dir, err, want)
+	}
+}
+
+// verify finds files that changed or went missing since the backup, in folders and zips
+func TestVerifyFiles(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "screenshots/a.png", "a")
+	writeTestFile(t, mc, "screenshots/b.png", "b")
+	for _, zipped := range []bool{false, true} {
+		result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), ZipOutput: zipped}, nil)
+		if err != nil || !result.Success {
+			t.Fatalf("PerformContext: %v", err)
+		}
+		report, err := VerifyFiles(result.OutputPath)
+		if err != nil || report.Checked < 3 || len(report.Missing)+len(report.Corrupted) > 0 {
+			t.Fatalf("zip %v: VerifyFiles of a fresh backup = %+v, %v", zipped, report, err)
+		}
+		checked := report.Checked
+
+		if zipped {
+			entries := readZipEntries(t, result.OutputPath)
+			entries["screenshots/a.png"] = "bitrot"
+			delete(entries, "screenshots/b.png")
+			writeTestZip(t, result.OutputPath, entries)
+		} else {
+			writeTestFile(t, result.OutputPath, "screenshots/a.png", "bitrot")
+			if err := os.Remove(filepath.Join(result.OutputPath, "screenshots", "b.png")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		report, err = VerifyFiles(result.OutputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(report.Corrupted, []string{"screenshots/a.png"}) || !slices.Equal(report.Missing, []string{"screenshots/b.png"}) || report.Checked != checked-2 {
+			t.Errorf("zip %v: VerifyFiles of a damaged backup = %+v", zipped, report)
+		}
+	}
+
+	if _, err := VerifyFiles(t.TempDir()); err == nil {
+		t.Error("VerifyFiles of a folder without a manifest: no error")
+	}
+}
@@ -0,0 +1,84 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// backupTimeLayout is the timestamp format used in backup folder names
+const backupTimeLayout = "2006-01-02_15-04"
+
+// LatestBackupTime returns the timestamp of the newest backup in dest
+func LatestBackupTime(dest string) (time.Time, error) {
+	_, t, err := LatestBackup(dest)
+	return t, err
+}
+
+// LatestBackup returns the name (without an archive extension) and timestamp of the newest backup in dest
+func LatestBackup(dest string) (string, time.Time, error) {
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var name string
+	var latest time.Time
+	for _, e := range entries {
+		// Same-minute backups get suffixes that sort after the first one. Encrypted
+		// archives cannot be read back, so they are no base for a top-up.
+		t, ok := parseBackupName(e.Name())
+		if ok && isBackupEntry(e.Name()) && !isEncrypted(e.Name()) && !t.Before(latest) {
+			name, latest = trimArchiveSuffix(e.Name()), t
+		}
+	}
+	if latest.IsZero() {
+		return "", latest, fmt.Errorf("no previous backup found in %s", dest)
+	}
+	return name, latest, nil
+}
+
+// isBackupEntry reports whether name is a backup rather than a file kept next to one
+// (parity data, a journal, the later parts of a split archive)
+func isBackupEntry(name string) bool {
+	return !strings.HasSuffix(name, ParitySuffix) && !strings.HasSuffix(name, JournalSuffix) && !isLaterPart(name)
+}
+
+// parseBackupName extracts the timestamp from a "backup_<time>" folder or zip name
+func parseBackupName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, "backup_") {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimPrefix(name, "backup_")
+	if len(stamp) < len(backupTimeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(backupTimeLayout, stamp[:len(backupTimeLayout)], time.Local)
+	return t, err == nil
+}
+
+// renderSinceSection notes which categories were limited to recent changes
+func renderSinceSection(config *tui.Config) string {
+	cutoffs := []struct {
+		name string
+		t    time.Time
+	}{
+		{"Screenshots", config.ScreenshotsSince},
+		{"Saves", config.SavesSince},
+		{"Xaero Maps", config.XaeroSince},
+	}
+
+	var b strings.Builder
+	for _, c := range cutoffs {
+		if !c.t.IsZero() {
+			b.WriteString(fmt.Sprintf("- **%s:** changed since %s\n", c.name, c.t.Format("2006-01-02 15:04")))
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "\n## 🔁 Differential Backup\n\nOnly files modified after these dates were copied:\n\n" + b.String()
+}
@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// The newest backup wins, same-minute suffixes included, while parity files, journals,
+// later split parts and encrypted archives are passed over
+func TestLatestBackup(t *testing.T) {
+	dest := t.TempDir()
+	for _, name := range []string{
+		"backup_2026-01-01_00-00",
+		"backup_2026-02-01_00-00.zip",
+		"backup_2026-02-01_00-00_2.zip",
+		"backup_2026-02-01_00-00.zip" + ParitySuffix,
+		"backup_2026-03-01_00-00.zip.gpg",
+		"backup_2026-03-01_00-00" + JournalSuffix,
+		"backup_2026-04-01_00-00.zip.002",
+		"notes.txt",
+	} {
+		writeTestFile(t, dest, name, "x")
+	}
+
+	name, stamp, err := LatestBackup(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)
+	if name != "backup_2026-02-01_00-00_2" || !stamp.Equal(want) {
+		t.Errorf("LatestBackup = %s, %v; want backup_2026-02-01_00-00_2 at %v", name, stamp, want)
+	}
+
+	if _, _, err := LatestBackup(t.TempDir()); err == nil {
+		t.Error("LatestBackup of an empty folder: no error")
+	}
+}
+
+// Only files modified after the cutoff are copied, and the folders above them still are
+func TestCopyDirSince(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, src, "old.png", "old")
+	writeTestFile(t, src, "2026/new.png", "new")
+	cutoff := time.Now().Add(-time.Hour)
+	old := cutoff.Add(-time.Hour)
+	os.Chtimes(filepath.Join(src, "old.png"), old, old)
+
+	dst := t.TempDir()
+	n, err := copyDirSince(src, dst, newCopyOptions(&tui.Config{}, nil), cutoff)
+	if err != nil || n != 1 {
+		t.Fatalf("copyDirSince = %d, %v; want 1 file", n, err)
+	}
+	if exists(filepath.Join(dst, "old.png")) || !exists(filepath.Join(dst, "2026", "new.png")) {
+		t.Error("copied the wrong files")
+	}
+	if s := renderSinceSection(&tui.Config{ScreenshotsSince: cutoff}); s == "" {
+		t.Error("info.md has no section for the screenshots cutoff")
+	}
+}
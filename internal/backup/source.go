@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Files and folders that mark a Minecraft game folder
+var minecraftMarkers = []string{
+	"options.txt", "saves", "mods", "screenshots", "resourcepacks", "shaderpacks",
+	"config", "xaero", "distant_horizons_server_data",
+}
+
+// How far below a wrong path to look for the right one, and how many folders to visit doing it
+const (
+	suggestDepth    = 3
+	suggestMaxDirs  = 2000
+	suggestMaxPaths = 5
+)
+
+// looksLikeMinecraft reports whether root holds anything totem knows how to back up
+func looksLikeMinecraft(root string) bool {
+	for _, marker := range minecraftMarkers {
+		if exists(filepath.Join(root, marker)) {
+			return true
+		}
+	}
+	return isServer(root) || proxyKind(root) != ""
+}
+
+// SuggestMinecraftPaths looks a few folders below root for folders that look like a
+// Minecraft folder, e.g. when root is a launcher folder rather than its .minecraft
+func SuggestMinecraftPaths(root string) []string {
+	var found []string
+	queue := []string{root}
+	visited := 0
+	for depth := 0; depth < suggestDepth && len(queue) > 0; depth++ {
+		var next []string
+		for _, dir := range queue {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if visited >= suggestMaxDirs {
+					break
+				}
+				if !isDirLink(dir, e) {
+					continue
+				}
+				visited++
+				path := filepath.Join(dir, e.Name())
+				if looksLikeMinecraft(path) {
+					found = append(found, path)
+				} else {
+					next = append(next, path)
+				}
+			}
+		}
+		queue = next
+	}
+
+	// Folders named like a game folder are the likeliest answer
+	sort.SliceStable(found, func(i, j int) bool {
+		return gameFolderName(found[i]) && !gameFolderName(found[j])
+	})
+	if len(found) > suggestMaxPaths {
+		found = found[:suggestMaxPaths]
+	}
+	return found
+}
+
+// CorrectMinecraftPath returns the folders the user most likely meant when path is a launcher
+// folder or instance root rather than the game folder itself; nil when path already looks right
+func CorrectMinecraftPath(path string) []string {
+	if !exists(path) || looksLikeMinecraft(path) {
+		return nil
+	}
+	return SuggestMinecraftPaths(path)
+}
+
+func gameFolderName(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return name == ".minecraft" || name == "minecraft"
+}
+
+// NothingFoundError is returned when the source has none of the folders a backup copies,
+// which almost always means the wrong folder was picked
+type NothingFoundError struct {
+	Path        string
+	Suggestions []string // Likely correct folders below Path
+}
+
+func (e *NothingFoundError) Error() string {
+	msg := fmt.Sprintf("%s has no saves, mods, options.txt or other Minecraft folders", e.Path)
+	if len(e.Suggestions) == 0 {
+		return msg + "; pick the folder that contains them (usually .minecraft)"
+	}
+	quoted := make([]string, len(e.Suggestions))
+	for i, s := range e.Suggestions {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return msg + "; did you mean " + strings.Join(quoted, " or ") + "?"
+}
+
+// checkSource fails fast on a source that would produce an empty backup
+func checkSource(root string) error {
+	if looksLikeMinecraft(root) {
+		return nil
+	}
+	return &NothingFoundError{Path: root, Suggestions: SuggestMinecraftPaths(root)}
+}
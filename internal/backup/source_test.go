@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A source with nothing to back up fails before any folder is made, suggesting the game
+// folders found below it
+func TestNothingFound(t *testing.T) {
+	launcher := t.TempDir()
+	writeTestFile(t, launcher, "instances/Fabric/.minecraft/options.txt", "fov:0.0\n")
+	writeTestFile(t, launcher, "launcher.log", "")
+	dest := t.TempDir()
+
+	_, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: launcher, BackupDest: dest}, nil)
+	var nothing *NothingFoundError
+	if !errors.As(err, &nothing) {
+		t.Fatalf("PerformContext = %v, want a NothingFoundError", err)
+	}
+	want := filepath.Join(launcher, "instances", "Fabric", ".minecraft")
+	if len(nothing.Suggestions) != 1 || nothing.Suggestions[0] != want || !strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("error %q suggests %v, want %s", err, nothing.Suggestions, want)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dest, "backup_*")); len(backups) > 0 {
+		t.Errorf("made %v for an empty source", backups)
+	}
+}
+
+// Folders named .minecraft or minecraft are suggested first; a path that already looks right
+// needs no correction
+func TestCorrectMinecraftPath(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "a-backups/saves/World/level.dat", "")
+	writeTestFile(t, root, "instance/minecraft/mods/sodium.jar", "")
+
+	got := CorrectMinecraftPath(root)
+	if len(got) != 2 || got[0] != filepath.Join(root, "instance", "minecraft") {
+		t.Errorf("CorrectMinecraftPath = %v, want instance/minecraft first", got)
+	}
+	if got := CorrectMinecraftPath(filepath.Join(root, "instance", "minecraft")); got != nil {
+		t.Errorf("CorrectMinecraftPath of a game folder = %v, want nil", got)
+	}
+	if got := CorrectMinecraftPath(filepath.Join(root, "missing")); got != nil {
+		t.Errorf("CorrectMinecraftPath of a missing folder = %v, want nil", got)
+	}
+}
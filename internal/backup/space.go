@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// spaceReserve is left free on the destination so the OS and other programs keep working
+	spaceReserve = 64 << 20 // 64 MB
+	// inodeReserve is the minimum number of free inodes before a backup stops
+	inodeReserve = 100
+	// spaceCheckInterval limits how often the destination volume is queried
+	spaceCheckInterval = time.Second
+)
+
+// DestinationFullError stops a backup when the destination runs out of room
+type DestinationFullError struct {
+	Dest   string
+	Free   uint64
+	Need   int64
+	Inodes bool // Out of inodes rather than bytes
+}
+
+func (e *DestinationFullError) Error() string {
+	if e.Inodes {
+		return fmt.Sprintf("destination %s has run out of inodes (too many files); free some up, then run totem resume", e.Dest)
+	}
+	return fmt.Sprintf("destination %s is full (%s free, next file needs %s); free some space, then run totem resume",
+		e.Dest, formatBytes(int64(e.Free)), formatBytes(e.Need))
+}
+
+// spaceMonitor watches the destination volume while files are copied. Copies running in
+// parallel share one, so each sees the room the others have already taken.
+type spaceMonitor struct {
+	mu      sync.Mutex
+	dest    string
+	free    uint64
+	inodes  uint64
+	checked time.Time
+	full    error // Sticky: once full, every later copy fails the same way
+}
+
+func newSpaceMonitor(dest string) *spaceMonitor {
+	return &spaceMonitor{dest: dest}
+}
+
+// reserve returns an error if a file of size bytes would not fit on the destination
+func (s *spaceMonitor) reserve(size int64) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.full != nil {
+		return s.full
+	}
+
+	// Re-query when the cached figure is stale or the file is a big share of what was left
+	if time.Since(s.checked) > spaceCheckInterval || uint64(size) > s.free/2 {
+		free, inodes, ok := diskFree(existingParent(s.dest))
+		if !ok {
+			return nil
+		}
+		s.free, s.inodes, s.checked = free, inodes, time.Now()
+	}
+
+	switch {
+	case s.inodes < inodeReserve:
+		s.full = &DestinationFullError{Dest: s.dest, Inodes: true}
+	case s.free < uint64(size)+spaceReserve:
+		s.full = &DestinationFullError{Dest: s.dest, Free: s.free, Need: size}
+	default:
+		s.free -= uint64(size)
+		s.inodes--
+		return nil
+	}
+	return s.full
+}
+
+// freeInodes turns a volume's inode total and free count into the figure reserve checks.
+// btrfs, FAT/exFAT and many CIFS mounts don't track inodes and report both as 0, so a
+// volume with no inode total is treated as unlimited, like NTFS.
+func freeInodes(files, ffree uint64) uint64 {
+	if files == 0 {
+		return ^uint64(0)
+	}
+	return ffree
+}
+
+// existingParent returns path or its closest ancestor that exists
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package backup
+
+// diskFree is not supported on this platform; space is never checked
+func diskFree(path string) (bytes, inodes uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,48 @@
+package backup
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Parallel copies sharing a monitor must not all be granted the same free space
+func TestSpaceMonitorShared(t *testing.T) {
+	s := newSpaceMonitor(t.TempDir())
+	s.free, s.inodes, s.checked = spaceReserve+100, 1000, time.Now().Add(time.Hour)
+
+	var granted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.reserve(1) == nil {
+				granted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := granted.Load(); n != 100 {
+		t.Errorf("reserved %d bytes of 100 free", n)
+	}
+	if _, ok := s.full.(*DestinationFullError); !ok {
+		t.Errorf("full = %v, want a DestinationFullError", s.full)
+	}
+}
+
+// Volumes that don't track inodes report none in total and must not look out of inodes
+func TestSpaceMonitorZeroInodeVolume(t *testing.T) {
+	s := newSpaceMonitor(t.TempDir())
+	s.free, s.inodes, s.checked = spaceReserve+100, freeInodes(0, 0), time.Now().Add(time.Hour)
+	if err := s.reserve(1); err != nil {
+		t.Fatalf("reserve on a zero-inode volume: %v", err)
+	}
+
+	s = newSpaceMonitor(t.TempDir())
+	s.free, s.inodes, s.checked = spaceReserve+100, freeInodes(1000, 0), time.Now().Add(time.Hour)
+	if err, ok := s.reserve(1).(*DestinationFullError); !ok || !err.Inodes {
+		t.Errorf("reserve with no free inodes = %v, want an inode DestinationFullError", err)
+	}
+}
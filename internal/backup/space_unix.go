@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package backup
+
+import "syscall"
+
+// diskFree returns the bytes and inodes available to this user on the volume holding path
+func diskFree(path string) (bytes, inodes uint64, ok bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, false
+	}
+	return st.Bavail * uint64(st.Bsize), freeInodes(uint64(st.Files), uint64(st.Ffree)), true
+}
@@ -0,0 +1,17 @@
+package backup
+
+import "golang.org/x/sys/windows"
+
+// diskFree returns the bytes available to this user on the volume holding path.
+// NTFS has no inode limit, so inodes is reported as unlimited.
+func diskFree(path string) (bytes, inodes uint64, ok bool) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, 0, false
+	}
+	return free, ^uint64(0), true
+}
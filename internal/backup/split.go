@@ -0,0 +1,256 @@
+package backup
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MinSplitSize is the smallest part size --split accepts
+const MinSplitSize = 1 << 20
+
+// firstPart is the suffix of a split archive's first part, which stands for the whole backup;
+// later parts count up from it (.002, .003, ...), as 7-Zip and HJSplit name them
+const firstPart = ".001"
+
+// partNumber returns the number of a split archive part from its name, or 0 for anything else
+func partNumber(name string) int {
+	ext := filepath.Ext(name)
+	if len(ext) != len(firstPart) {
+		return 0
+	}
+	n, err := strconv.Atoi(ext[1:])
+	if err != nil || n < 1 {
+		return 0
+	}
+	// Only archives, encrypted or not, are split; a folder named backup_x.001 is not a part
+	name = strings.TrimSuffix(name, ext)
+	for _, suffix := range encryptSuffixes {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	if trimArchiveSuffix(name) == name {
+		return 0
+	}
+	return n
+}
+
+// isLaterPart reports whether name is a part of a split archive other than the first
+func isLaterPart(name string) bool {
+	return partNumber(name) > 1
+}
+
+// unsplitName strips the part suffix from the first part of a split archive
+func unsplitName(name string) string {
+	if partNumber(name) == 1 {
+		return strings.TrimSuffix(name, firstPart)
+	}
+	return name
+}
+
+// SplitParts returns every part of a split archive given its first part, in order, or just
+// path for a backup that is not split
+func SplitParts(path string) []string {
+	if partNumber(filepath.Base(path)) != 1 {
+		return []string{path}
+	}
+	base := strings.TrimSuffix(path, firstPart)
+	var parts []string
+	for n := 1; ; n++ {
+		part := fmt.Sprintf("%s.%03d", base, n)
+		if !exists(part) {
+			return parts
+		}
+		parts = append(parts, part)
+	}
+}
+
+// splitArchive cuts path into parts of at most size bytes next to it, then removes it.
+// It returns the first part. An archive that already fits is left whole.
+func splitArchive(path string, size int64) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() <= size {
+		return path, nil
+	}
+	if (info.Size()+size-1)/size > 999 {
+		return "", fmt.Errorf("%s would need more than 999 parts; split it into larger ones", filepath.Base(path))
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	var written []string
+	fail := func(err error) (string, error) {
+		for _, part := range written {
+			os.Remove(part)
+		}
+		return "", err
+	}
+	for n := 1; int64(n-1)*size < info.Size(); n++ {
+		part := fmt.Sprintf("%s.%03d", path, n)
+		out, err := os.Create(part)
+		if err != nil {
+			return fail(err)
+		}
+		written = append(written, part)
+		_, err = io.CopyN(out, in, size)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil && err != io.EOF {
+			return fail(fmt.Errorf("%s: %w", filepath.Base(part), err))
+		}
+	}
+	in.Close()
+	if err := os.Remove(path); err != nil {
+		return fail(err)
+	}
+	return written[0], nil
+}
+
+// JoinParts writes the parts of a split archive, given its first part, back into one file
+// next to them and returns its path. The parts are kept.
+func JoinParts(firstPartPath string) (string, error) {
+	if partNumber(filepath.Base(firstPartPath)) != 1 {
+		return "", fmt.Errorf("%s is not the first part of a split archive", filepath.Base(firstPartPath))
+	}
+	parts := SplitParts(firstPartPath)
+	dest := strings.TrimSuffix(firstPartPath, firstPart)
+	if exists(dest) {
+		return "", fmt.Errorf("%s already exists", dest)
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	r, closeParts, err := openParts(parts)
+	if err == nil {
+		_, err = io.Copy(out, r)
+		closeParts()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return "", err
+	}
+	return dest, nil
+}
+
+// partsReader reads the parts of a split archive as one file
+type partsReader struct {
+	files   []*os.File
+	offsets []int64 // Where each part starts
+	size    int64
+}
+
+// openParts opens parts as one reader; close closes them all
+func openParts(parts []string) (*io.SectionReader, func() error, error) {
+	pr := &partsReader{}
+	closeAll := func() error {
+		for _, f := range pr.files {
+			f.Close()
+		}
+		return nil
+	}
+	for _, part := range parts {
+		f, err := os.Open(part)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			closeAll()
+			return nil, nil, err
+		}
+		pr.files = append(pr.files, f)
+		pr.offsets = append(pr.offsets, pr.size)
+		pr.size += info.Size()
+	}
+	return io.NewSectionReader(pr, 0, pr.size), closeAll, nil
+}
+
+func (pr *partsReader) ReadAt(b []byte, off int64) (int, error) {
+	n := 0
+	for n < len(b) {
+		pos := off + int64(n)
+		if pos >= pr.size {
+			return n, io.EOF
+		}
+		// The last part starting at or before pos holds it; a read past its end goes on
+		// into the next part on the next turn
+		i := sort.Search(len(pr.offsets), func(i int) bool { return pr.offsets[i] > pos }) - 1
+		m, err := pr.files[i].ReadAt(b[n:], pos-pr.offsets[i])
+		n += m
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		if m == 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+	}
+	return n, nil
+}
+
+// openZipBackup opens a zip backup, split or whole; password-protected entries are read
+// with the password in ZipPasswordEnv
+func openZipBackup(path string) (*zip.Reader, func() error, error) {
+	if partNumber(filepath.Base(path)) != 1 {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		readAESEntries(&zr.Reader)
+		return &zr.Reader, zr.Close, nil
+	}
+	r, closeParts, err := openParts(SplitParts(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		closeParts()
+		return nil, nil, err
+	}
+	readAESEntries(zr)
+	return zr, closeParts, nil
+}
+
+// isZipBackup reports whether path is a zip backup, split or whole
+func isZipBackup(path string) bool {
+	return strings.HasSuffix(strings.ToLower(unsplitName(path)), ".zip")
+}
+
+// archiveSize returns the size of a backup archive, all parts together for a split one
+func archiveSize(path string) int64 {
+	var total int64
+	for _, part := range SplitParts(path) {
+		if info, err := os.Stat(part); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// removeBackup deletes a backup with its parity data, every part for a split one
+func removeBackup(path string) error {
+	for _, part := range SplitParts(path) {
+		if err := os.RemoveAll(part); err != nil {
+			return err
+		}
+		os.Remove(part + ParitySuffix)
+	}
+	return nil
+}
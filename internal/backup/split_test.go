@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A split zip is cut into parts no larger than asked, reads as one backup, and joins back
+// into the archive it was cut from
+func TestSplitArchive(t *testing.T) {
+	mc := t.TempDir()
+	// Random data so the zip can't shrink below a few parts
+	noise := make([]byte, 20<<10)
+	rand.Read(noise)
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", string(noise))
+
+	const size = 8 << 10
+	result, err := PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), IncludeSaves: true, ZipOutput: true, SplitSize: size}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v %v", err, result.Errors)
+	}
+	if filepath.Ext(result.OutputPath) != firstPart {
+		t.Fatalf("OutputPath = %s, want the first part", result.OutputPath)
+	}
+	parts := SplitParts(result.OutputPath)
+	if len(parts) < 3 {
+		t.Fatalf("SplitParts = %v, want at least 3", parts)
+	}
+	var joined []byte
+	for i, part := range parts {
+		data, err := os.ReadFile(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > size || (i < len(parts)-1 && len(data) != size) {
+			t.Errorf("%s is %d bytes, want %d", filepath.Base(part), len(data), size)
+		}
+		joined = append(joined, data...)
+	}
+
+	restored := t.TempDir()
+	if _, err := Restore(result.OutputPath, restored); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(restored, "saves", "World", "level.dat")); !bytes.Equal(data, noise) {
+		t.Error("level.dat did not survive being split")
+	}
+	if report, err := VerifyFiles(result.OutputPath); err != nil || len(report.Missing)+len(report.Corrupted) > 0 {
+		t.Errorf("VerifyFiles = %+v, %v", report, err)
+	}
+
+	whole, err := JoinParts(result.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(whole); filepath.Ext(whole) != ".zip" || !bytes.Equal(data, joined) {
+		t.Errorf("JoinParts wrote %s, not the parts end to end", whole)
+	}
+	if _, err := JoinParts(result.OutputPath); err == nil {
+		t.Error("JoinParts over an existing archive: no error")
+	}
+
+	// One that already fits is left whole
+	small := filepath.Join(t.TempDir(), "backup_2026-01-01_00-00.zip")
+	writeTestZip(t, small, map[string]string{"options.txt": "fov:0.0\n"})
+	if path, err := splitArchive(small, size); err != nil || path != small {
+		t.Errorf("splitArchive of a small zip = %s, %v", path, err)
+	}
+}
+
+// Only archives have parts, numbered from .001
+func TestPartNumber(t *testing.T) {
+	for name, want := range map[string]int{
+		"backup_2026-01-01_00-00.zip.001":     1,
+		"backup_2026-01-01_00-00.tar.zst.012": 12,
+		"backup_2026-01-01_00-00.zip.age.002": 2,
+		"backup_2026-01-01_00-00.zip":         0,
+		"backup_2026-01-01_00-00.001":         0,
+		"backup_2026-01-01_00-00.zip.000":     0,
+		"backup_2026-01-01_00-00.zip.1":       0,
+	} {
+		if got := partNumber(name); got != want {
+			t.Errorf("partNumber(%s) = %d, want %d", name, got, want)
+		}
+	}
+}
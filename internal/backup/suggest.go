@@ -0,0 +1,162 @@
+package backup
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Heuristics for the report's cleanup suggestions
+const (
+	largestListLimit = 10                  // Entries in the largest screenshots and worlds lists
+	staleAfter       = 90 * 24 * time.Hour // Untouched for this long counts as no longer played
+	oldScreenshot    = 365 * 24 * time.Hour
+	suggestMinSize   = 100 << 20 // Smaller items are not worth a suggestion
+)
+
+// folderUsage is a folder's size and the newest modification time inside it
+type folderUsage struct {
+	Size   int64
+	Newest time.Time
+}
+
+// scanUsage measures a folder the way the backup would copy it
+func scanUsage(path string, opts *copyOptions) folderUsage {
+	var u folderUsage
+	walkDirFollow(path, func(p string, d fs.DirEntry, err error) error {
+		if opts.cancelled() {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return nil
+		}
+		if p != path && opts.excluded(p, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			u.Size += info.Size()
+			if info.ModTime().After(u.Newest) {
+				u.Newest = info.ModTime()
+			}
+		}
+		return nil
+	})
+	return u
+}
+
+// cleanupSuggestions looks for large data that has not been used in a while
+func cleanupSuggestions(paths MinecraftPaths, config *tui.Config, opts *copyOptions, now time.Time) []string {
+	var suggestions []string
+	type candidate struct {
+		size int64
+		text string
+	}
+	var found []candidate
+
+	// Worlds nobody has opened in months
+	if config.IncludeSaves && config.ActiveWorldsDays == 0 {
+		if worlds, err := listWorlds(paths.Saves); err == nil {
+			for _, w := range worlds {
+				if w.LastPlayed.IsZero() || now.Sub(w.LastPlayed) < staleAfter {
+					continue
+				}
+				size := getDirSize(filepath.Join(paths.Saves, w.Name), opts)
+				if size >= suggestMinSize {
+					found = append(found, candidate{size, fmt.Sprintf("`saves/%s` was last played %s and is %s — consider backing up active worlds only",
+						w.Name, w.LastPlayed.Format("2006-01-02"), formatBytes(size))})
+				}
+			}
+		}
+	}
+
+	// Xaero keeps one folder per world or server under each of its map kinds
+	if config.IncludeXaero {
+		kinds, _ := os.ReadDir(paths.Xaero)
+		for _, kind := range kinds {
+			if !kind.IsDir() {
+				continue
+			}
+			kindDir := filepath.Join(paths.Xaero, kind.Name())
+			entries, _ := os.ReadDir(kindDir)
+			for _, e := range entries {
+				if !isDirLink(kindDir, e) || opts.excluded(filepath.Join(kindDir, e.Name()), e) {
+					continue
+				}
+				u := scanUsage(filepath.Join(kindDir, e.Name()), opts)
+				if u.Size < suggestMinSize || now.Sub(u.Newest) < staleAfter {
+					continue
+				}
+				found = append(found, candidate{u.Size, fmt.Sprintf("`xaero/%s/%s` has not changed since %s and is %s — likely a world or server you no longer play; consider excluding it",
+					kind.Name(), e.Name(), u.Newest.Format("2006-01-02"), formatBytes(u.Size))})
+			}
+		}
+	}
+
+	// Distant Horizons data is regenerated by the mod if it is missing
+	if config.IncludeDH {
+		if u := scanUsage(paths.DistantHorizons, opts); u.Size >= suggestMinSize && now.Sub(u.Newest) >= staleAfter {
+			found = append(found, candidate{u.Size, fmt.Sprintf("`distant_horizons_server_data/` has not changed since %s and is %s — Distant Horizons can regenerate it",
+				u.Newest.Format("2006-01-02"), formatBytes(u.Size))})
+		}
+	}
+
+	// Old screenshots add up over the years
+	var oldCount int
+	var oldSize int64
+	entries, _ := os.ReadDir(paths.Screenshots)
+	for _, e := range entries {
+		if e.IsDir() || opts.excluded(filepath.Join(paths.Screenshots, e.Name()), e) {
+			continue
+		}
+		if info, err := e.Info(); err == nil && now.Sub(info.ModTime()) >= oldScreenshot {
+			oldCount++
+			oldSize += info.Size()
+		}
+	}
+	if oldSize >= suggestMinSize {
+		found = append(found, candidate{oldSize, fmt.Sprintf("%d screenshots older than a year use %s — consider archiving them elsewhere and using `--screenshots-since`",
+			oldCount, formatBytes(oldSize))})
+	}
+
+	// Biggest savings first
+	sort.SliceStable(found, func(i, j int) bool { return found[i].size > found[j].size })
+	for _, c := range found {
+		suggestions = append(suggestions, c.text)
+	}
+	return suggestions
+}
+
+// renderCleanupSection lists the largest screenshots and suggests what could be left out.
+// Suggestions depend on the current date, so reproducible backups leave them out.
+func renderCleanupSection(paths MinecraftPaths, config *tui.Config, opts *copyOptions) string {
+	var b strings.Builder
+	if largest := getLargestItems(paths.Screenshots, largestListLimit, opts); len(largest) > 0 {
+		b.WriteString("\n## 🖼️ Largest Screenshots\n\n")
+		for _, s := range largest {
+			b.WriteString(fmt.Sprintf("- %s (%s)\n", s.Name, formatBytes(s.Size)))
+		}
+	}
+
+	if config.Deterministic {
+		return b.String()
+	}
+	if suggestions := cleanupSuggestions(paths, config, opts, time.Now()); len(suggestions) > 0 {
+		b.WriteString("\n## 🧹 Cleanup Suggestions\n\n")
+		for _, s := range suggestions {
+			b.WriteString("- " + s + "\n")
+		}
+	}
+	return b.String()
+}
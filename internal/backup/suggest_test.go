@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// sparseFile makes a file of size bytes under root that takes no disk space, dated mtime
+func sparseFile(t *testing.T, root, path string, size int64, mtime time.Time) {
+	t.Helper()
+	writeTestFile(t, root, path, "")
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.Truncate(full, size); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(full, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The largest list keeps the ten biggest entries, biggest first
+func TestGetLargestItems(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 12; i++ {
+		writeTestFile(t, dir, fmt.Sprintf("shot%02d.png", i), strings.Repeat("x", i*10))
+	}
+	largest := getLargestItems(dir, largestListLimit, nil)
+	if len(largest) != 10 || largest[0].Name != "shot12.png" || largest[9].Name != "shot03.png" {
+		t.Errorf("getLargestItems = %+v, want shot12.png down to shot03.png", largest)
+	}
+}
+
+// Big worlds nobody has played in months and piles of old screenshots are suggested for
+// cleanup, biggest first; recent or small ones are not
+func TestCleanupSuggestions(t *testing.T) {
+	mc := t.TempDir()
+	now := time.Now()
+	longAgo := now.AddDate(-2, 0, 0)
+	writeLevelDat(t, mc, "saves/Old/level.dat", map[string]any{"Data": map[string]any{"LastPlayed": now.AddDate(0, -6, 0).UnixMilli()}})
+	sparseFile(t, mc, "saves/Old/region/r.0.0.mca", 300<<20, longAgo)
+	writeLevelDat(t, mc, "saves/Current/level.dat", map[string]any{"Data": map[string]any{"LastPlayed": now.UnixMilli()}})
+	sparseFile(t, mc, "saves/Current/region/r.0.0.mca", 500<<20, now)
+	writeLevelDat(t, mc, "saves/Tiny/level.dat", map[string]any{"Data": map[string]any{"LastPlayed": longAgo.UnixMilli()}})
+	sparseFile(t, mc, "screenshots/a.png", 80<<20, longAgo)
+	sparseFile(t, mc, "screenshots/b.png", 80<<20, longAgo)
+	sparseFile(t, mc, "screenshots/new.png", 80<<20, now)
+
+	config := &tui.Config{MinecraftPath: mc, IncludeSaves: true}
+	got := cleanupSuggestions(buildPaths(mc), config, nil, now)
+	if len(got) != 2 || !strings.HasPrefix(got[0], "`saves/Old` was last played") || !strings.HasPrefix(got[1], "2 screenshots older than a year") {
+		t.Errorf("cleanupSuggestions = %q", got)
+	}
+
+	config.ActiveWorldsDays = 30
+	if got := cleanupSuggestions(buildPaths(mc), config, nil, now); len(got) != 1 {
+		t.Errorf("with active worlds only, cleanupSuggestions = %q; want just the screenshots", got)
+	}
+}
@@ -0,0 +1,174 @@
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/version"
+)
+
+// Logs go into a support bundle trimmed to their last supportLogTail bytes,
+// which keeps the bundle small enough to attach to an issue
+const supportLogTail = 2 << 20
+
+// Logs collected into a support bundle, relative to the instance
+var supportLogs = []string{"logs/latest.log", "logs/debug.log"}
+
+// SupportBundle writes a small zip for mod authors' issue trackers into dest: logs, the newest
+// crash reports, mods.json, options.txt and support.md with system info. Worlds and screenshots
+// are never included. With redact, usernames, server addresses and absolute paths are replaced
+// by placeholders in everything but mods.json. It returns the path of the zip.
+func SupportBundle(instance, dest string, redact bool) (string, error) {
+	if !exists(instance) {
+		return "", fmt.Errorf("minecraft path does not exist: %s", instance)
+	}
+	if err := LocalOnly("a support bundle", dest); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(dest, "support_"+time.Now().Format(backupTimeLayout))
+	path := base + ".zip"
+	for i := 2; exists(path); i++ {
+		path = fmt.Sprintf("%s_%d.zip", base, i)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	var r *redactor
+	if redact {
+		r = newRedactor(instance)
+	}
+	w := zip.NewWriter(f)
+	if err := writeSupportBundle(w, instance, r); err != nil {
+		w.Close()
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	return path, f.Close()
+}
+
+func writeSupportBundle(w *zip.Writer, instance string, r *redactor) error {
+	mods := listModInfo(filepath.Join(instance, "mods"))
+	data, err := json.MarshalIndent(mods, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipEntry(w, "mods.json", data); err != nil {
+		return err
+	}
+	if err := writeZipEntry(w, "support.md", []byte(r.Redact(renderSupportMD(instance, mods)))); err != nil {
+		return err
+	}
+
+	files := []string{"options.txt"}
+	files = append(files, supportLogs...)
+	reports := CrashReports(instance)
+	if len(reports) > recentCrashLimit {
+		reports = reports[len(reports)-recentCrashLimit:]
+	}
+	for _, r := range reports {
+		files = append(files, filepath.ToSlash(filepath.Join(CrashReportsDir, filepath.Base(r))))
+	}
+
+	for _, name := range files {
+		data, err := readTail(filepath.Join(instance, filepath.FromSlash(name)), supportLogTail)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			data = []byte(r.Redact(string(data)))
+		}
+		if err := writeZipEntry(w, name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeZipEntry(w *zip.Writer, name string, data []byte) error {
+	out, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// readTail reads at most the last n bytes of a file
+func readTail(path string, n int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > n {
+		if _, err := f.Seek(info.Size()-n, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(f)
+}
+
+// renderSupportMD formats the bundle's summary for pasting into an issue
+func renderSupportMD(instance string, mods []ModInfo) string {
+	mc := getMinecraftInfo(instance)
+	loader := mc.Loader
+	if mc.LoaderVersion != "Unknown" {
+		loader += " " + mc.LoaderVersion
+	}
+
+	var b strings.Builder
+	b.WriteString("## Environment\n\n")
+	b.WriteString("| Property | Value |\n|----------|-------|\n")
+	b.WriteString(fmt.Sprintf("| Minecraft | %s |\n", mc.Version))
+	b.WriteString(fmt.Sprintf("| Mod loader | %s |\n", loader))
+	b.WriteString(fmt.Sprintf("| Mods | %d |\n", len(mods)))
+	b.WriteString(fmt.Sprintf("| OS | %s |\n", getOSInfo()))
+	b.WriteString(fmt.Sprintf("| CPUs | %d |\n", runtime.NumCPU()))
+	b.WriteString(fmt.Sprintf("| Collected by | Totem v%s |\n", version.Version))
+
+	if crashes := renderCrashSection(instance); crashes != "" {
+		b.WriteString(strings.Replace(crashes, "## 💥 Recent Crashes", "## Recent Crashes", 1))
+	}
+
+	if len(mods) > 0 {
+		b.WriteString("\n<details>\n<summary>Mod list</summary>\n\n")
+		b.WriteString("| Mod | Version | File |\n|-----|---------|------|\n")
+		for _, m := range mods {
+			name := m.Name
+			if name == "" {
+				name = m.ID
+			}
+			if name == "" {
+				name = strings.TrimSuffix(m.File, filepath.Ext(m.File))
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s | `%s` |\n", name, m.Version, m.File))
+		}
+		b.WriteString("\n</details>\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package backup
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readZipEntries returns the contents of every file in the zip at path, by name
+func readZipEntries(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	entries := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		entries[f.Name] = string(data)
+	}
+	return entries
+}
+
+// A support bundle holds logs, the newest crash reports and a summary, but never worlds
+func TestSupportBundle(t *testing.T) {
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "logs/latest.log", "[Render thread/INFO]: Loading Minecraft\n")
+	writeTestFile(t, mc, "logs/debug.log", strings.Repeat("x", supportLogTail)+"the end\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "screenshots/a.png", "png")
+	for i := range recentCrashLimit + 1 {
+		name := fmt.Sprintf("crash-reports/crash-%d.txt", i)
+		writeTestFile(t, mc, name, sampleCrashReport)
+		at := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(filepath.Join(mc, filepath.FromSlash(name)), at, at)
+	}
+
+	path, err := SupportBundle(mc, t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(filepath.Base(path), "support_") || filepath.Ext(path) != ".zip" {
+		t.Errorf("bundle written to %s", path)
+	}
+	entries := readZipEntries(t, path)
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	want := []string{"crash-reports/crash-1.txt", "crash-reports/crash-2.txt", "crash-reports/crash-3.txt",
+		"logs/debug.log", "logs/latest.log", "mods.json", "options.txt", "support.md"}
+	if !slices.Equal(names, want) {
+		t.Errorf("the bundle holds %q, want %q", names, want)
+	}
+
+	if debug := entries["logs/debug.log"]; len(debug) != supportLogTail || !strings.HasSuffix(debug, "the end\n") {
+		t.Errorf("debug.log is %d bytes, want its last %d", len(debug), supportLogTail)
+	}
+	if entries["logs/latest.log"] != "[Render thread/INFO]: Loading Minecraft\n" {
+		t.Errorf("latest.log = %q", entries["logs/latest.log"])
+	}
+	if md := entries["support.md"]; !strings.Contains(md, "## Environment") || !strings.Contains(md, "## Recent Crashes") {
+		t.Errorf("support.md = %s", md)
+	}
+
+	if _, err := SupportBundle(filepath.Join(mc, "missing"), t.TempDir(), false); err == nil {
+		t.Error("bundled an instance that does not exist")
+	}
+}
@@ -0,0 +1,71 @@
+package backup
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// rateBurst is how far ahead of the limit a copy may read after a pause, in time at the
+// limit. Time spent scanning or between steps refills at most this much, so later copies
+// can't spend it all at once at full speed.
+const rateBurst = 250 * time.Millisecond
+
+// rateLimiter is a token bucket: reads spend tokens, which refill at bytesPerSec up to
+// a quarter second's worth. Parallel copies may share one to stay under the limit together.
+type rateLimiter struct {
+	mu          sync.Mutex
+	bytesPerSec int64
+	burst       float64
+	tokens      float64
+	last        time.Time
+}
+
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	burst := float64(bytesPerSec) * rateBurst.Seconds()
+	return &rateLimiter{bytesPerSec: bytesPerSec, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait spends n bytes of tokens, sleeping off any shortfall at the limit. The sleep comes
+// after the lock is released, so each reader sharing the bucket waits for the debt so far.
+func (l *rateLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*float64(l.bytesPerSec), l.burst)
+	l.last = now
+	l.tokens -= float64(n)
+	debt := l.tokens
+	l.mu.Unlock()
+	if debt < 0 {
+		time.Sleep(time.Duration(-debt / float64(l.bytesPerSec) * float64(time.Second)))
+	}
+}
+
+// throttledReader applies a rateLimiter to an underlying reader
+type throttledReader struct {
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	// Keep chunks small so sleeps stay short and smooth
+	if len(p) > 64<<10 {
+		p = p[:64<<10]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.limiter.wait(n)
+	}
+	return n, err
+}
+
+// throttle wraps r with the limiter, or returns r unchanged when there is no limit
+func (l *rateLimiter) throttle(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &throttledReader{r: r, limiter: l}
+}
@@ -0,0 +1,24 @@
+package backup
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// An idle limiter must not let the next copy through at full speed
+func TestRateLimiterAfterIdle(t *testing.T) {
+	const rate = 4 << 20
+	l := newRateLimiter(rate)
+	l.last = l.last.Add(-time.Hour)
+
+	start := time.Now()
+	if _, err := io.Copy(io.Discard, l.throttle(bytes.NewReader(make([]byte, 2<<20)))); err != nil {
+		t.Fatal(err)
+	}
+	// 2 MB at 4 MB/s, less the quarter second burst, takes a quarter second
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("2 MB after an hour idle took %v at 4 MB/s; the burst is not bounded", elapsed)
+	}
+}
@@ -0,0 +1,262 @@
+package backup
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/netguard"
+	"github.com/vaalley/totem/internal/tui"
+	"github.com/vaalley/totem/internal/version"
+)
+
+// Traces are configured with OpenTelemetry's standard variables, so a service unit can
+// point totem at the collector its other services already use. Only OTLP over HTTP with
+// JSON bodies is spoken, which every OpenTelemetry Collector accepts on port 4318.
+const (
+	otlpEndpointEnv       = "OTEL_EXPORTER_OTLP_ENDPOINT"        // Base URL; /v1/traces is appended
+	otlpTracesEndpointEnv = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT" // Full URL, taking precedence
+	otlpHeadersEnv        = "OTEL_EXPORTER_OTLP_HEADERS"         // key=value,... sent with each export
+	otlpProtocolEnv       = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	otelServiceNameEnv    = "OTEL_SERVICE_NAME"
+	otelResourceEnv       = "OTEL_RESOURCE_ATTRIBUTES" // key=value,... describing this host
+	otelDisabledEnv       = "OTEL_SDK_DISABLED"
+	otelTracesExporterEnv = "OTEL_TRACES_EXPORTER"
+	traceParentEnv        = "TRACEPARENT" // W3C trace context of a caller to nest under
+)
+
+// traceExportTimeout bounds how long a backup waits on the collector
+const traceExportTimeout = 10 * time.Second
+
+// OTLP span kinds and status codes
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"` // int64s are strings in OTLP JSON
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func stringAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func intAttr(key string, value int64) otlpAttribute {
+	s := strconv.FormatInt(value, 10)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+// traceEndpoint returns where traces go, or "" when exporting is off
+func traceEndpoint() string {
+	if strings.EqualFold(os.Getenv(otelDisabledEnv), "true") || os.Getenv(otelTracesExporterEnv) == "none" {
+		return ""
+	}
+	if endpoint := os.Getenv(otlpTracesEndpointEnv); endpoint != "" {
+		return endpoint
+	}
+	if base := os.Getenv(otlpEndpointEnv); base != "" {
+		return strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// parseKeyValues reads the key=value,... lists of OTEL_EXPORTER_OTLP_HEADERS and
+// OTEL_RESOURCE_ATTRIBUTES, whose values may be percent-encoded
+func parseKeyValues(list string) map[string]string {
+	values := map[string]string{}
+	for _, pair := range strings.Split(list, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = decoded
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// randomID returns n random bytes in hex, for trace and span ids
+func randomID(n int) string {
+	id := make([]byte, n)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// traceParent reads TRACEPARENT, so a backup started by a traced job nests under its span
+func traceParent() (traceID, spanID string, ok bool) {
+	parts := strings.Split(os.Getenv(traceParentEnv), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(parts[1] + parts[2]); err != nil {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// buildTrace turns a finished backup into one span for the whole backup with a child for
+// each step that ran, from the step timings info.md already reports
+func buildTrace(config *tui.Config, result *Result, started, ended time.Time) otlpTraces {
+	traceID, parentID, nested := traceParent()
+	if !nested {
+		traceID = randomID(16)
+	}
+	nanos := func(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+	root := otlpSpan{
+		TraceID:           traceID,
+		SpanID:            randomID(8),
+		ParentSpanID:      parentID,
+		Name:              "backup",
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: nanos(started),
+		EndTimeUnixNano:   nanos(ended),
+		Attributes: []otlpAttribute{
+			stringAttr("totem.instance", config.MinecraftPath),
+			stringAttr("totem.destination", config.BackupDest),
+			stringAttr("totem.output", result.OutputPath),
+			intAttr("totem.files", int64(result.TotalFiles)),
+			intAttr("totem.health", int64(result.Health)),
+			intAttr("totem.errors", int64(len(result.Errors))),
+		},
+		Status: otlpStatus{Code: statusOK},
+	}
+	if len(result.Errors) > 0 {
+		root.Status = otlpStatus{Code: statusError, Message: strings.Join(result.Errors, "; ")}
+	}
+	spans := []otlpSpan{root}
+	for _, t := range result.Stats.Timings {
+		if t.Start.IsZero() {
+			continue // Timed before a crash by a version that did not record starts
+		}
+		spans = append(spans, otlpSpan{
+			TraceID:           traceID,
+			SpanID:            randomID(8),
+			ParentSpanID:      root.SpanID,
+			Name:              t.Step,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: nanos(t.Start),
+			EndTimeUnixNano:   nanos(t.Start.Add(t.Duration)),
+		})
+	}
+
+	service := os.Getenv(otelServiceNameEnv)
+	resource := parseKeyValues(os.Getenv(otelResourceEnv))
+	if service == "" {
+		service = cmp.Or(resource["service.name"], "totem")
+	}
+	resource["service.name"] = service
+	resource["service.version"] = version.Version
+	var attrs []otlpAttribute
+	for _, key := range slices.Sorted(maps.Keys(resource)) {
+		attrs = append(attrs, stringAttr(key, resource[key]))
+	}
+
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: attrs},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/vaalley/totem/internal/backup", Version: version.Version},
+			Spans: spans,
+		}},
+	}}}
+}
+
+// exportTrace sends the backup's spans to the OTLP endpoint in the environment, if any.
+// A collector that is down costs a warning, never the backup.
+func exportTrace(config *tui.Config, result *Result, started time.Time) error {
+	endpoint := traceEndpoint()
+	if endpoint == "" {
+		return nil
+	}
+	if protocol := os.Getenv(otlpProtocolEnv); protocol != "" && protocol != "http/json" {
+		return fmt.Errorf("%s=%s is not supported; totem exports http/json", otlpProtocolEnv, protocol)
+	}
+	body, err := json.Marshal(buildTrace(config, result, started, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range parseKeyValues(os.Getenv(otlpHeadersEnv)) {
+		req.Header.Set(key, value)
+	}
+	client := netguard.HTTPClient("trace export")
+	client.Timeout = traceExportTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s %s", endpoint, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
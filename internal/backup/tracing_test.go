@@ -0,0 +1,125 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A backup sends one span for itself and one per step to the collector in the environment,
+// nested under the caller's TRACEPARENT and described by the OTEL_* variables
+func TestExportTrace(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []otlpTraces
+		auth     string
+		status   = http.StatusOK
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "wrong endpoint", http.StatusBadRequest)
+			return
+		}
+		var traces otlpTraces
+		json.NewDecoder(r.Body).Decode(&traces)
+		received = append(received, traces)
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(status)
+	}))
+	defer collector.Close()
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	t.Setenv(otlpEndpointEnv, collector.URL+"/")
+	t.Setenv(otlpHeadersEnv, "Authorization=Bearer%20s3cret")
+	t.Setenv(otelServiceNameEnv, "mc-backups")
+	t.Setenv(otelResourceEnv, "host.name=nas, service.name=ignored")
+	t.Setenv(traceParentEnv, "00-"+traceID+"-"+parentID+"-01")
+	mc := t.TempDir()
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	config := &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), ZipOutput: true}
+
+	result, err := PerformContext(context.Background(), config, nil)
+	if err != nil || !result.Success || len(result.Warnings) > 0 {
+		t.Fatalf("PerformContext: %v, %v, warnings %v", err, result.Errors, result.Warnings)
+	}
+	mu.Lock()
+	if len(received) != 1 || auth != "Bearer s3cret" {
+		t.Fatalf("collector got %d exports with Authorization %q", len(received), auth)
+	}
+	rs := received[0].ResourceSpans[0]
+	mu.Unlock()
+	var resource []string
+	for _, a := range rs.Resource.Attributes {
+		if a.Value.StringValue != nil {
+			resource = append(resource, a.Key+"="+*a.Value.StringValue)
+		}
+	}
+	if !slices.Contains(resource, "host.name=nas") || !slices.Contains(resource, "service.name=mc-backups") {
+		t.Errorf("resource = %q", resource)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	root := spans[0]
+	if root.Name != "backup" || root.TraceID != traceID || root.ParentSpanID != parentID || root.Status.Code != statusOK {
+		t.Errorf("root span = %+v", root)
+	}
+	var steps []string
+	for _, s := range spans[1:] {
+		steps = append(steps, s.Name)
+		if s.TraceID != traceID || s.ParentSpanID != root.SpanID || s.StartTimeUnixNano > s.EndTimeUnixNano {
+			t.Errorf("step span = %+v", s)
+		}
+	}
+	if len(steps) != len(result.Stats.Timings) || !slices.Contains(steps, "Zip") {
+		t.Errorf("step spans %q, want one per timed step", steps)
+	}
+
+	// A collector that is down, or a protocol totem doesn't speak, costs a warning only
+	mu.Lock()
+	status = http.StatusServiceUnavailable
+	mu.Unlock()
+	result, err = PerformContext(context.Background(), config, nil)
+	if err != nil || !result.Success || !slices.ContainsFunc(result.Warnings, func(w string) bool { return strings.Contains(w, "Could not export the trace") }) {
+		t.Errorf("with the collector down: %v, success %v, warnings %v", err, result.Success, result.Warnings)
+	}
+	t.Setenv(otlpProtocolEnv, "grpc")
+	if err := exportTrace(config, result, result.Stats.Timings[0].Start); err == nil || !strings.Contains(err.Error(), "grpc") {
+		t.Errorf("exportTrace over grpc = %v", err)
+	}
+
+	t.Setenv(otelDisabledEnv, "true")
+	if endpoint := traceEndpoint(); endpoint != "" {
+		t.Errorf("traceEndpoint with the SDK disabled = %q", endpoint)
+	}
+}
+
+// The traces endpoint wins over the base one, and a malformed TRACEPARENT starts a new trace
+func TestTraceEndpoint(t *testing.T) {
+	t.Setenv(otlpEndpointEnv, "http://collector:4318")
+	if got := traceEndpoint(); got != "http://collector:4318/v1/traces" {
+		t.Errorf("traceEndpoint = %q", got)
+	}
+	t.Setenv(otlpTracesEndpointEnv, "http://traces:4318/custom")
+	if got := traceEndpoint(); got != "http://traces:4318/custom" {
+		t.Errorf("traceEndpoint with a traces endpoint = %q", got)
+	}
+	t.Setenv(otelTracesExporterEnv, "none")
+	if got := traceEndpoint(); got != "" {
+		t.Errorf("traceEndpoint with the exporter off = %q", got)
+	}
+
+	for _, bad := range []string{"", "00-short-00f067aa0ba902b7-01", "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01"} {
+		t.Setenv(traceParentEnv, bad)
+		if _, _, ok := traceParent(); ok {
+			t.Errorf("traceParent accepted %q", bad)
+		}
+	}
+}
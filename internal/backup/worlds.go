@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// World is a single save folder with its last-played time from level.dat
+type World struct {
+	Name       string
+	LastPlayed time.Time // Zero if level.dat is missing or unreadable
+}
+
+// listWorlds returns the worlds in savesDir, most recently played first
+func listWorlds(savesDir string) ([]World, error) {
+	entries, err := os.ReadDir(savesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var worlds []World
+	for _, e := range entries {
+		if !isDirLink(savesDir, e) {
+			continue
+		}
+		w := World{Name: e.Name()}
+		if level, err := readLevelDat(filepath.Join(savesDir, e.Name(), "level.dat")); err == nil {
+			if data := nbtCompound(level, "Data"); data != nil {
+				if ms, ok := data["LastPlayed"].(int64); ok {
+					w.LastPlayed = time.UnixMilli(ms)
+				}
+			}
+		}
+		worlds = append(worlds, w)
+	}
+
+	sort.SliceStable(worlds, func(i, j int) bool {
+		return worlds[i].LastPlayed.After(worlds[j].LastPlayed)
+	})
+	return worlds, nil
+}
+
+// activeWorlds keeps worlds played within the last days days
+func activeWorlds(worlds []World, days int, now time.Time) []World {
+	cutoff := now.AddDate(0, 0, -days)
+	var active []World
+	for _, w := range worlds {
+		if w.LastPlayed.After(cutoff) {
+			active = append(active, w)
+		}
+	}
+	return active
+}
+
+// copySaves copies the saves folder, or only recently played worlds when ActiveWorldsDays is set
+func copySaves(savesDir, dst string, opts *copyOptions, config *tui.Config) (int, error) {
+	if config.ActiveWorldsDays <= 0 {
+		return copyDirSince(savesDir, dst, opts, config.SavesSince)
+	}
+
+	worlds, err := listWorlds(savesDir)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	targets := sameTarget{}
+	for _, w := range activeWorlds(worlds, config.ActiveWorldsDays, time.Now()) {
+		if targets.seen(filepath.Join(savesDir, w.Name)) {
+			continue
+		}
+		n, err := copyDirSince(filepath.Join(savesDir, w.Name), filepath.Join(dst, w.Name), opts, config.SavesSince)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,42 @@
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Worlds are listed most recently played first, with unreadable ones last, and the
+// active-worlds option copies only those played within its window
+func TestActiveWorlds(t *testing.T) {
+	saves := t.TempDir()
+	now := time.Now()
+	writeLevelDat(t, saves, "Old/level.dat", map[string]any{"Data": map[string]any{"LastPlayed": now.AddDate(0, 0, -30).UnixMilli()}})
+	writeLevelDat(t, saves, "Recent/level.dat", map[string]any{"Data": map[string]any{"LastPlayed": now.AddDate(0, 0, -1).UnixMilli()}})
+	writeTestFile(t, saves, "Broken/level.dat", "not nbt")
+
+	worlds, err := listWorlds(saves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, w := range worlds {
+		names = append(names, w.Name)
+	}
+	if len(names) != 3 || names[0] != "Recent" || names[1] != "Old" || names[2] != "Broken" {
+		t.Errorf("listWorlds = %v, want Recent, Old, Broken", names)
+	}
+	if active := activeWorlds(worlds, 7, now); len(active) != 1 || active[0].Name != "Recent" {
+		t.Errorf("activeWorlds(7 days) = %+v, want Recent", active)
+	}
+
+	dst := t.TempDir()
+	if _, err := copySaves(saves, dst, newCopyOptions(&tui.Config{}, nil), &tui.Config{ActiveWorldsDays: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(filepath.Join(dst, "Recent", "level.dat")) || exists(filepath.Join(dst, "Old")) || exists(filepath.Join(dst, "Broken")) {
+		t.Error("copySaves with ActiveWorldsDays did not copy only the recent world")
+	}
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ZstdSuffix is the extension of backups stored as a Zstandard-compressed tar
+const ZstdSuffix = ".tar.zst"
+
+// MaxZstdLevel is the highest level zstd accepts without --ultra
+const MaxZstdLevel = 19
+
+// Window logs for zstd's long-distance matching (--long), which finds repeats far apart,
+// such as the same chunks in several region files. A window of 2^n bytes needs as much
+// memory to compress and to decompress.
+const (
+	MinZstdLong     = 10
+	DefaultZstdLong = 27 // zstd's own default for a bare --long
+	MaxZstdLong     = 31
+)
+
+// archiveSuffixes are the extensions of backups stored as one archive, not a folder
+var archiveSuffixes = []string{".zip", ZstdSuffix}
+
+// trimArchiveSuffix strips a backup archive's extension, and the part number of a split
+// one, from name
+func trimArchiveSuffix(name string) string {
+	name = unsplitName(name)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
+
+// archiveExists reports whether path exists as a folder or any backup archive, split or whole
+func archiveExists(path string) bool {
+	if exists(path) {
+		return true
+	}
+	for _, suffix := range archiveSuffixes {
+		if exists(path+suffix) || exists(path+suffix+firstPart) {
+			return true
+		}
+	}
+	return false
+}
+
+// zstdCommand returns the zstd binary, which Totem runs rather than bundling a compressor
+func zstdCommand() (string, error) {
+	bin, err := exec.LookPath("zstd")
+	if err != nil {
+		return "", fmt.Errorf("zstd is not installed (needed for %s backups)", ZstdSuffix)
+	}
+	return bin, nil
+}
+
+// createTarZst archives srcDir into dest as writeTarZst does
+func createTarZst(srcDir, dest string, level, long int, deterministic bool, progress *Progress) error {
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	err = writeTarZst(out, srcDir, level, long, deterministic, progress)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
+
+// writeTarZst writes srcDir to out as a tar streamed through zstd at level (0 uses zstd's
+// default) on all cores, with long-distance matching over a 2^long byte window unless long
+// is 0. Deterministic archives get fixed timestamps.
+func writeTarZst(out io.Writer, srcDir string, level, long int, deterministic bool, progress *Progress) error {
+	bin, err := zstdCommand()
+	if err != nil {
+		return err
+	}
+	args := []string{"-q", "-c", "-T0"}
+	if level > 0 {
+		args = append(args, fmt.Sprintf("-%d", level))
+	}
+	if long > 0 {
+		args = append(args, fmt.Sprintf("--long=%d", long))
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = out
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(stdin)
+	addFile := func(path, rel string, info fs.FileInfo) error {
+		h := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     filepath.ToSlash(rel),
+			Mode:     0644,
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Format:   tar.FormatPAX,
+		}
+		if deterministic {
+			h.ModTime = time.Unix(0, 0)
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		source, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		_, err = io.Copy(tw, progress.track(source))
+		return err
+	}
+
+	// The manifest goes first, so ReadManifest need not decompress the rest to find it
+	var walkErr error
+	manifest := filepath.Join(srcDir, ManifestName)
+	if info, err := os.Stat(manifest); err == nil {
+		walkErr = addFile(manifest, ManifestName, info)
+	}
+	if walkErr == nil {
+		walkErr = filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || path == manifest {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(srcDir, path)
+			return addFile(path, rel, info)
+		})
+	}
+	if walkErr == nil {
+		walkErr = tw.Close()
+	}
+	stdin.Close()
+	if err := cmd.Wait(); err != nil && walkErr == nil {
+		walkErr = fmt.Errorf("zstd: %s", strings.TrimSpace(stderr.String()+" "+err.Error()))
+	}
+	return walkErr
+}
+
+// zstdReader decompresses a .tar.zst through zstd; Close waits for zstd to exit
+type zstdReader struct {
+	io.ReadCloser
+	cmd        *exec.Cmd
+	closeParts func() error
+}
+
+func (z *zstdReader) Close() error {
+	z.ReadCloser.Close()
+	z.cmd.Wait()
+	if z.closeParts != nil {
+		z.closeParts()
+	}
+	return nil
+}
+
+// zstdReadArgs decompress to stdout. zstd refuses windows over 128 MB unless told to
+// allow them, so --long=MaxZstdLong lets archives made with any --zstd-long be read.
+var zstdReadArgs = []string{"-q", "-d", "-c", fmt.Sprintf("--long=%d", MaxZstdLong)}
+
+// openZstd streams the decompressed contents of a .tar.zst; a split one is fed to zstd
+// part after part
+func openZstd(path string) (io.ReadCloser, error) {
+	bin, err := zstdCommand()
+	if err != nil {
+		return nil, err
+	}
+	z := &zstdReader{cmd: exec.Command(bin, append(zstdReadArgs, path)...)}
+	if partNumber(filepath.Base(path)) == 1 {
+		r, closeParts, err := openParts(SplitParts(path))
+		if err != nil {
+			return nil, err
+		}
+		z.cmd = exec.Command(bin, zstdReadArgs...)
+		z.cmd.Stdin, z.closeParts = r, closeParts
+	}
+	stdout, err := z.cmd.StdoutPipe()
+	if err == nil {
+		err = z.cmd.Start()
+	}
+	if err != nil {
+		if z.closeParts != nil {
+			z.closeParts()
+		}
+		return nil, err
+	}
+	z.ReadCloser = stdout
+	return z, nil
+}
@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// A .tar.zst at a set level and window restores to the files it was made from
+func TestZstdRoundTrip(t *testing.T) {
+	if _, err := zstdCommand(); err != nil {
+		t.Skip(err)
+	}
+	mc := t.TempDir()
+	region := strings.Repeat("chunk data ", 4096)
+	writeTestFile(t, mc, "options.txt", "fov:0.0\n")
+	writeTestFile(t, mc, "saves/World/level.dat", "level")
+	writeTestFile(t, mc, "saves/World/region/r.0.0.mca", region)
+
+	config := &tui.Config{MinecraftPath: mc, BackupDest: t.TempDir(), IncludeSaves: true, Zstd: true, ZstdLevel: MaxZstdLevel, ZstdLong: MinZstdLong}
+	result, err := PerformContext(context.Background(), config, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("PerformContext: %v %v", err, result.Errors)
+	}
+	if !strings.HasSuffix(result.OutputPath, ZstdSuffix) {
+		t.Fatalf("OutputPath = %s, want a %s", result.OutputPath, ZstdSuffix)
+	}
+	info, err := os.Stat(result.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= int64(len(region)) {
+		t.Errorf("the archive is %d bytes, want it smaller than the %d byte region file", info.Size(), len(region))
+	}
+	if name := trimArchiveSuffix(filepath.Base(result.OutputPath)); strings.Contains(name, ".") {
+		t.Errorf("trimArchiveSuffix left %s", name)
+	}
+	if !archiveExists(strings.TrimSuffix(result.OutputPath, ZstdSuffix)) {
+		t.Error("archiveExists does not find the .tar.zst")
+	}
+
+	dest := t.TempDir()
+	if _, err := Restore(result.OutputPath, dest); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "saves", "World", "region", "r.0.0.mca")); string(data) != region {
+		t.Error("the region file did not survive the round trip")
+	}
+	if report, err := VerifyFiles(result.OutputPath); err != nil || len(report.Missing)+len(report.Corrupted) > 0 {
+		t.Errorf("VerifyFiles = %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,163 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vaalley/totem/internal/netguard"
+)
+
+// Source types a fleet server can be read from
+const (
+	SourceLocal  = "local"
+	SourceSFTP   = "sftp"
+	SourceDocker = "docker"
+)
+
+// Server is one entry in a fleet config
+type Server struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`                // local, sftp or docker ("" = local)
+	Path      string `json:"path"`                // Server folder (inside the container for docker)
+	Host      string `json:"host,omitempty"`      // user@host for sftp
+	Port      int    `json:"port,omitempty"`      // SSH port for sftp (0 = default)
+	Container string `json:"container,omitempty"` // Container name or ID for docker
+}
+
+// Config is the fleet manifest passed to `totem backup --fleet`
+type Config struct {
+	Servers []Server `json:"servers"`
+}
+
+// Load reads and validates a fleet config file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i := range cfg.Servers {
+		s := &cfg.Servers[i]
+		if s.Type == "" {
+			s.Type = SourceLocal
+		}
+		switch {
+		case s.Name == "":
+			return nil, fmt.Errorf("%s: server %d has no name", path, i+1)
+		case seen[s.Name]:
+			return nil, fmt.Errorf("%s: duplicate server name %q", path, s.Name)
+		case s.Path == "":
+			return nil, fmt.Errorf("%s: server %q has no path", path, s.Name)
+		case s.Type == SourceSFTP && s.Host == "":
+			return nil, fmt.Errorf("%s: sftp server %q has no host", path, s.Name)
+		case s.Type == SourceDocker && s.Container == "":
+			return nil, fmt.Errorf("%s: docker server %q has no container", path, s.Name)
+		case s.Type != SourceLocal && s.Type != SourceSFTP && s.Type != SourceDocker:
+			return nil, fmt.Errorf("%s: server %q has unknown type %q", path, s.Name, s.Type)
+		}
+		seen[s.Name] = true
+	}
+	return &cfg, nil
+}
+
+// Stage makes the server's files available locally, returning the folder to back up
+// and a cleanup func that removes any temporary copy
+func (s Server) Stage() (string, func(), error) {
+	if s.Type == SourceLocal {
+		return s.Path, func() {}, nil
+	}
+	if s.Type == SourceSFTP {
+		if err := netguard.Allow("sftp server " + s.Name); err != nil {
+			return "", nil, err
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "totem-fleet-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	staged := filepath.Join(tmp, "server")
+
+	var cmd *exec.Cmd
+	switch s.Type {
+	case SourceSFTP:
+		// scp speaks SFTP on modern OpenSSH and reuses the user's keys and known_hosts
+		args := []string{"-r", "-q", "-B"}
+		if s.Port != 0 {
+			args = append(args, "-P", fmt.Sprint(s.Port))
+		}
+		cmd = exec.Command("scp", append(args, s.Host+":"+s.Path, staged)...)
+	case SourceDocker:
+		cmd = exec.Command("docker", "cp", s.Container+":"+s.Path, staged)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", nil, fmt.Errorf("%s: %s", cmd.Args[0], msg)
+	}
+	return staged, cleanup, nil
+}
+
+// Source describes where the server is read from, for reports
+func (s Server) Source() string {
+	switch s.Type {
+	case SourceSFTP:
+		return "sftp://" + s.Host + s.Path
+	case SourceDocker:
+		return "docker://" + s.Container + s.Path
+	}
+	return s.Path
+}
+
+// ReportEntry is one server's outcome in the combined fleet report
+type ReportEntry struct {
+	Server Server
+	Output string
+	Files  int
+	Err    string // Empty on success
+}
+
+// WriteReport writes fleet.md summarizing every server's backup into dest
+func WriteReport(dest string, entries []ReportEntry, elapsed time.Duration) (string, error) {
+	var b strings.Builder
+	b.WriteString("# 🗿 Totem Fleet Backup\n\n")
+	b.WriteString(fmt.Sprintf("> Generated on %s in %s\n\n", time.Now().Format("2006-01-02 15:04:05"), elapsed.Round(time.Millisecond)))
+
+	failed := 0
+	for _, e := range entries {
+		if e.Err != "" {
+			failed++
+		}
+	}
+	b.WriteString(fmt.Sprintf("**%d servers**, %d ok, %d failed\n\n", len(entries), len(entries)-failed, failed))
+
+	b.WriteString("| Server | Source | Status | Files | Output |\n|--------|--------|--------|-------|--------|\n")
+	for _, e := range entries {
+		status, output := "✅ ok", "`"+e.Output+"`"
+		if e.Err != "" {
+			status, output = "❌ failed", e.Err
+		}
+		b.WriteString(fmt.Sprintf("| %s | `%s` | %s | %d | %s |\n", e.Server.Name, e.Server.Source(), status, e.Files, output))
+	}
+
+	path := filepath.Join(dest, "fleet.md")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,77 @@
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, json string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fleet.json")
+	if err := os.WriteFile(path, []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Servers default to local, and each type must say where to find it
+func TestLoad(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"servers":[
+		{"name":"lobby","path":"/srv/lobby"},
+		{"name":"survival","type":"sftp","host":"mc@box","port":2222,"path":"/srv/survival"},
+		{"name":"creative","type":"docker","container":"mc-creative","path":"/data"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Servers) != 3 || cfg.Servers[0].Type != SourceLocal {
+		t.Fatalf("Load = %+v", cfg.Servers)
+	}
+	for i, want := range []string{"/srv/lobby", "sftp://mc@box/srv/survival", "docker://mc-creative/data"} {
+		if got := cfg.Servers[i].Source(); got != want {
+			t.Errorf("Source() of %s = %q, want %q", cfg.Servers[i].Name, got, want)
+		}
+	}
+
+	for _, bad := range []string{
+		`{"servers":[{"path":"/srv"}]}`,
+		`{"servers":[{"name":"a","path":"/a"},{"name":"a","path":"/b"}]}`,
+		`{"servers":[{"name":"a"}]}`,
+		`{"servers":[{"name":"a","type":"sftp","path":"/a"}]}`,
+		`{"servers":[{"name":"a","type":"docker","path":"/a"}]}`,
+		`{"servers":[{"name":"a","type":"ftp","path":"/a"}]}`,
+		`{"servers":`,
+	} {
+		if _, err := Load(writeConfig(t, bad)); err == nil {
+			t.Errorf("Load(%s): no error", bad)
+		}
+	}
+}
+
+// Local servers are backed up in place, with nothing to clean up
+func TestStageLocal(t *testing.T) {
+	dir, cleanup, err := Server{Name: "lobby", Type: SourceLocal, Path: "/srv/lobby"}.Stage()
+	if err != nil || dir != "/srv/lobby" {
+		t.Fatalf("Stage = %s, %v", dir, err)
+	}
+	cleanup()
+}
+
+func TestWriteReport(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "fleet")
+	path, err := WriteReport(dest, []ReportEntry{
+		{Server: Server{Name: "lobby", Type: SourceLocal, Path: "/srv/lobby"}, Output: "backup_1", Files: 12},
+		{Server: Server{Name: "creative", Type: SourceDocker, Container: "mc", Path: "/data"}, Err: "docker: no such container"},
+	}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, _ := os.ReadFile(path)
+	for _, want := range []string{"**2 servers**, 1 ok, 1 failed", "| lobby | `/srv/lobby` | ✅ ok | 12 | `backup_1` |", "❌ failed | 0 | docker: no such container |"} {
+		if !strings.Contains(string(report), want) {
+			t.Errorf("fleet.md lacks %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package instances
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Instance is a Minecraft game directory found on this machine
+type Instance struct {
+	Name     string
+	Launcher string
+	Path     string // Game directory (the folder containing mods/, saves/, ...)
+}
+
+// launcherRoot describes where a launcher keeps its instances
+type launcherRoot struct {
+	launcher string
+	dir      string
+	// Candidate subfolders holding the game directory ("" = the instance folder itself)
+	gameDirs []string
+}
+
+// Detect finds instances from known launchers plus the vanilla .minecraft folder
+func Detect() []Instance {
+	var found []Instance
+	seen := map[string]bool{}
+
+	add := func(inst Instance) {
+		abs, err := filepath.Abs(inst.Path)
+		if err != nil || seen[abs] {
+			return
+		}
+		seen[abs] = true
+		inst.Path = abs
+		found = append(found, inst)
+	}
+
+	if vanilla := vanillaDir(); isGameDir(vanilla) {
+		add(Instance{Name: "vanilla", Launcher: "Minecraft Launcher", Path: vanilla})
+	}
+
+	for _, root := range launcherRoots() {
+		entries, err := os.ReadDir(root.dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			for _, sub := range root.gameDirs {
+				dir := filepath.Join(root.dir, e.Name(), sub)
+				if isGameDir(dir) {
+					add(Instance{Name: e.Name(), Launcher: root.launcher, Path: dir})
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Launcher != found[j].Launcher {
+			return found[i].Launcher < found[j].Launcher
+		}
+		return found[i].Name < found[j].Name
+	})
+	return found
+}
+
+// Launcher is a launcher's whole data folder: instances plus its own files
+type Launcher struct {
+	Name string
+	Path string
+}
+
+// Launchers returns the data folders of installed launchers: the vanilla .minecraft and
+// launchers that keep instances (Prism, MultiMC, Modrinth App, CurseForge, ATLauncher)
+func Launchers() []Launcher {
+	var found []Launcher
+	seen := map[string]bool{}
+	if vanilla := vanillaDir(); isGameDir(vanilla) {
+		seen[vanilla] = true
+		found = append(found, Launcher{Name: "Minecraft Launcher", Path: vanilla})
+	}
+	for _, root := range launcherRoots() {
+		// The instances folder sits directly inside the launcher's data folder
+		dir := filepath.Dir(root.dir)
+		if seen[dir] {
+			continue
+		}
+		if _, err := os.Stat(root.dir); err != nil {
+			continue
+		}
+		seen[dir] = true
+		found = append(found, Launcher{Name: root.launcher, Path: dir})
+	}
+	return found
+}
+
+// FindLauncher returns the installed launcher whose name contains query, ignoring case and spaces
+// ("prism", "curseforge")
+func FindLauncher(query string) (Launcher, bool) {
+	normalize := func(s string) string { return strings.ToLower(strings.ReplaceAll(s, " ", "")) }
+	for _, l := range Launchers() {
+		if strings.Contains(normalize(l.Name), normalize(query)) {
+			return l, true
+		}
+	}
+	return Launcher{}, false
+}
+
+// CanCreate reports whether Create can set up instances for l
+func CanCreate(l Launcher) bool {
+	return l.Name == "Prism Launcher" || l.Name == "MultiMC"
+}
+
+// Create sets up an empty instance named name in l (Prism Launcher or MultiMC) for a
+// Minecraft version, with the instance.cfg and mmc-pack.json the launcher expects, and
+// returns it. The launcher installs the game and adds a mod loader on first launch.
+func Create(l Launcher, name, minecraft string) (Instance, error) {
+	if !CanCreate(l) {
+		return Instance{}, fmt.Errorf("%s instances can't be created by Totem; create one in the launcher", l.Name)
+	}
+	dir := filepath.Join(l.Path, "instances", name)
+	if _, err := os.Stat(dir); err == nil {
+		return Instance{}, fmt.Errorf("%s already has an instance named %q", l.Name, name)
+	}
+	gameDir := filepath.Join(dir, ".minecraft")
+	if err := os.MkdirAll(gameDir, 0755); err != nil {
+		return Instance{}, err
+	}
+
+	cfg := "InstanceType=OneSix\nname=" + name + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "instance.cfg"), []byte(cfg), 0644); err != nil {
+		return Instance{}, err
+	}
+	type component struct {
+		UID       string `json:"uid"`
+		Version   string `json:"version,omitempty"`
+		Important bool   `json:"important,omitempty"`
+	}
+	pack := struct {
+		Components    []component `json:"components"`
+		FormatVersion int         `json:"formatVersion"`
+	}{Components: []component{}, FormatVersion: 1}
+	if minecraft != "" {
+		pack.Components = append(pack.Components, component{UID: "net.minecraft", Version: minecraft, Important: true})
+	}
+	data, err := json.MarshalIndent(pack, "", "    ")
+	if err != nil {
+		return Instance{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mmc-pack.json"), data, 0644); err != nil {
+		return Instance{}, err
+	}
+	return Instance{Name: name, Launcher: l.Name, Path: gameDir}, nil
+}
+
+// Vanilla returns the Minecraft Launcher's game folder, creating it when the launcher has
+// not run on this machine yet
+func Vanilla() (Instance, error) {
+	dir := vanillaDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Instance{}, err
+	}
+	return Instance{Name: "vanilla", Launcher: "Minecraft Launcher", Path: dir}, nil
+}
+
+// isGameDir reports whether dir looks like a Minecraft game directory
+func isGameDir(dir string) bool {
+	for _, marker := range []string{"options.txt", "mods", "saves", "screenshots"} {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// dataDir returns the per-user application data folder for the current OS
+func dataDir() string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return appData
+		}
+		return filepath.Join(home, "AppData", "Roaming")
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support")
+	default:
+		if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+			return xdg
+		}
+		return filepath.Join(home, ".local", "share")
+	}
+}
+
+func vanillaDir() string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(dataDir(), ".minecraft")
+	case "darwin":
+		return filepath.Join(dataDir(), "minecraft")
+	default:
+		return filepath.Join(home, ".minecraft")
+	}
+}
+
+func launcherRoots() []launcherRoot {
+	home, _ := os.UserHomeDir()
+	data := dataDir()
+	mmcDirs := []string{".minecraft", "minecraft"}
+
+	return []launcherRoot{
+		{launcher: "Prism Launcher", dir: filepath.Join(data, "PrismLauncher", "instances"), gameDirs: mmcDirs},
+		{launcher: "MultiMC", dir: filepath.Join(data, "multimc", "instances"), gameDirs: mmcDirs},
+		{launcher: "Modrinth App", dir: filepath.Join(data, "ModrinthApp", "profiles"), gameDirs: []string{""}},
+		{launcher: "Modrinth App", dir: filepath.Join(data, "com.modrinth.theseus", "profiles"), gameDirs: []string{""}},
+		{launcher: "CurseForge", dir: filepath.Join(home, "curseforge", "minecraft", "Instances"), gameDirs: []string{""}},
+		{launcher: "ATLauncher", dir: filepath.Join(data, "ATLauncher", "instances"), gameDirs: []string{""}},
+	}
+}
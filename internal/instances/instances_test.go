@@ -0,0 +1,50 @@
+package instances
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// Detect finds the vanilla folder and launcher instances holding game files, each once,
+// sorted by launcher and name, and skips instance folders with nothing in them
+func TestDetect(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the test lays out folders where Linux launchers keep them")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	data := filepath.Join(home, ".local", "share")
+	t.Setenv("XDG_DATA_HOME", data)
+	for _, p := range []string{
+		filepath.Join(home, ".minecraft", "options.txt"),
+		filepath.Join(data, "PrismLauncher", "instances", "Fabric", ".minecraft", "mods", "sodium.jar"),
+		filepath.Join(data, "PrismLauncher", "instances", "Alpha", "minecraft", "saves", "World", "level.dat"),
+		filepath.Join(data, "ModrinthApp", "profiles", "Pack", "options.txt"),
+		filepath.Join(data, "PrismLauncher", "instances", "Empty", "instance.cfg"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []Instance{
+		{"vanilla", "Minecraft Launcher", filepath.Join(home, ".minecraft")},
+		{"Pack", "Modrinth App", filepath.Join(data, "ModrinthApp", "profiles", "Pack")},
+		{"Alpha", "Prism Launcher", filepath.Join(data, "PrismLauncher", "instances", "Alpha", "minecraft")},
+		{"Fabric", "Prism Launcher", filepath.Join(data, "PrismLauncher", "instances", "Fabric", ".minecraft")},
+	}
+	got := Detect()
+	if len(got) != len(want) {
+		t.Fatalf("Detect = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Detect()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package modapi
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// missTTL is how long a jar no API knew stays unknown before it is asked about again,
+// since it may be uploaded later
+const missTTL = 7 * 24 * time.Hour
+
+// cacheEntry is one answer; a nil Project means no API knew the jar. A hash always
+// names the same file, so found projects never expire.
+type cacheEntry struct {
+	Project *Project  `json:"project,omitempty"`
+	Checked time.Time `json:"checked"`
+}
+
+// cache maps SHA-1 sums to answers, stored as JSON
+type cache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// loadCache reads the cache at path; a missing or unreadable one starts empty
+func loadCache(path string) *cache {
+	c := &cache{path: path, entries: map[string]cacheEntry{}}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &c.entries)
+	}
+	return c
+}
+
+func (c *cache) get(sha string) (*Project, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[sha]
+	if !ok || (e.Project == nil && time.Since(e.Checked) > missTTL) {
+		return nil, false
+	}
+	return e.Project, true
+}
+
+func (c *cache) put(sha string, p *Project) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[sha] = cacheEntry{Project: p, Checked: time.Now()}
+	c.dirty = true
+}
+
+func (c *cache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty || c.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	// Written aside and renamed, so an interrupted save keeps the old cache
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,130 @@
+package modapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	curseforgeAPI = "https://api.curseforge.com/v1"
+	// curseforgeGame is Minecraft's game id on CurseForge
+	curseforgeGame = 432
+	// CurseForge does not publish a limit; this stays well clear of it
+	curseforgeInterval = 250 * time.Millisecond
+)
+
+// Fingerprint is CurseForge's file fingerprint: 32-bit murmur2 with seed 1 over the file
+// with tabs, newlines, carriage returns and spaces removed
+func Fingerprint(data []byte) uint32 {
+	normalized := make([]byte, 0, len(data))
+	for _, b := range data {
+		if b != 9 && b != 10 && b != 13 && b != 32 {
+			normalized = append(normalized, b)
+		}
+	}
+
+	const m = 0x5bd1e995
+	h := 1 ^ uint32(len(normalized))
+	n := len(normalized) &^ 3
+	for i := 0; i < n; i += 4 {
+		k := uint32(normalized[i]) | uint32(normalized[i+1])<<8 | uint32(normalized[i+2])<<16 | uint32(normalized[i+3])<<24
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+	switch tail := normalized[n:]; len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// lookupCurseForge identifies jars by fingerprint, then fetches their mods' names
+func (c *Client) lookupCurseForge(ctx context.Context, jars []Hashes) (map[string]*Project, error) {
+	headers := map[string]string{"x-api-key": c.curseforgeKey}
+	bySum := map[uint32]string{}
+	for _, h := range jars {
+		bySum[h.Fingerprint] = h.SHA1
+	}
+
+	found := map[string]*Project{}
+	byMod := map[int][]*Project{}
+	var modIDs []int
+	for start := 0; start < len(jars); start += batchSize {
+		batch := jars[start:min(start+batchSize, len(jars))]
+		sums := make([]uint32, len(batch))
+		for i, h := range batch {
+			sums[i] = h.Fingerprint
+		}
+		var resp struct {
+			Data struct {
+				ExactMatches []struct {
+					File struct {
+						ID              int    `json:"id"`
+						ModID           int    `json:"modId"`
+						DisplayName     string `json:"displayName"`
+						FileFingerprint uint32 `json:"fileFingerprint"`
+					} `json:"file"`
+				} `json:"exactMatches"`
+			} `json:"data"`
+		}
+		url := fmt.Sprintf("%s/fingerprints/%d", curseforgeAPI, curseforgeGame)
+		if err := c.do(ctx, c.curseforge, "POST", url, headers, map[string]any{"fingerprints": sums}, &resp); err != nil {
+			return nil, fmt.Errorf("curseforge: %w", err)
+		}
+		for _, match := range resp.Data.ExactMatches {
+			sha, ok := bySum[match.File.FileFingerprint]
+			if !ok {
+				continue
+			}
+			p := &Project{
+				Source:    "curseforge",
+				ProjectID: strconv.Itoa(match.File.ModID),
+				VersionID: strconv.Itoa(match.File.ID),
+				Version:   match.File.DisplayName,
+			}
+			found[sha] = p
+			if byMod[match.File.ModID] == nil {
+				modIDs = append(modIDs, match.File.ModID)
+			}
+			byMod[match.File.ModID] = append(byMod[match.File.ModID], p)
+		}
+	}
+
+	for start := 0; start < len(modIDs); start += batchSize {
+		var resp struct {
+			Data []struct {
+				ID    int    `json:"id"`
+				Name  string `json:"name"`
+				Slug  string `json:"slug"`
+				Links struct {
+					WebsiteURL string `json:"websiteUrl"`
+				} `json:"links"`
+			} `json:"data"`
+		}
+		body := map[string]any{"modIds": modIDs[start:min(start+batchSize, len(modIDs))]}
+		if err := c.do(ctx, c.curseforge, "POST", curseforgeAPI+"/mods", headers, body, &resp); err != nil {
+			return nil, fmt.Errorf("curseforge: %w", err)
+		}
+		for _, mod := range resp.Data {
+			for _, p := range byMod[mod.ID] {
+				p.Slug, p.Title, p.URL = mod.Slug, mod.Name, mod.Links.WebsiteURL
+			}
+		}
+	}
+	return found, nil
+}
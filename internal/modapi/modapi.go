@@ -0,0 +1,125 @@
+// Package modapi identifies mod jars on Modrinth and CurseForge by their hashes. Both APIs
+// are queried in batches behind a rate limiter, and answers are cached on disk by hash, so
+// a 400-mod pack is only looked up once and later backups only ask about new jars.
+package modapi
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/vaalley/totem/internal/netguard"
+	"github.com/vaalley/totem/internal/version"
+)
+
+// userAgent identifies Totem to the APIs, which Modrinth requires
+const userAgent = "vaalley/totem/" + version.Version + " (github.com/vaalley/totem)"
+
+// Project is what an API knows about a jar
+type Project struct {
+	Source    string `json:"source"` // "modrinth" or "curseforge"
+	ProjectID string `json:"project_id"`
+	Slug      string `json:"slug,omitempty"`
+	Title     string `json:"title,omitempty"`
+	VersionID string `json:"version_id,omitempty"`
+	Version   string `json:"version,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Hashes are the hashes the APIs identify a jar by
+type Hashes struct {
+	SHA1        string // Modrinth, and the cache key
+	Fingerprint uint32 // CurseForge's murmur2 fingerprint
+}
+
+// HashFile computes the hashes of a jar in one read
+func HashFile(path string) (Hashes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Hashes{}, err
+	}
+	sum := sha1.Sum(data)
+	return Hashes{SHA1: hex.EncodeToString(sum[:]), Fingerprint: Fingerprint(data)}, nil
+}
+
+// Client looks jars up, answering from its cache where it can
+type Client struct {
+	http          *http.Client
+	cache         *cache
+	modrinth      *limiter
+	curseforge    *limiter
+	curseforgeKey string
+}
+
+// New returns a client caching answers in the file at cachePath. CurseForge is only asked
+// when CURSEFORGE_API_KEY is set, since its API needs a key.
+func New(cachePath string) *Client {
+	return &Client{
+		http:          netguard.HTTPClient("mod lookups"),
+		cache:         loadCache(cachePath),
+		modrinth:      newLimiter(modrinthInterval),
+		curseforge:    newLimiter(curseforgeInterval),
+		curseforgeKey: os.Getenv("CURSEFORGE_API_KEY"),
+	}
+}
+
+// Lookup identifies jars, returning projects keyed by SHA-1; jars no API knows are left out.
+// An API that fails does not stop the others from being asked, and whatever was found is
+// returned along with the errors.
+func (c *Client) Lookup(ctx context.Context, jars []Hashes) (map[string]*Project, error) {
+	found := map[string]*Project{}
+	var todo []Hashes
+	for _, h := range jars {
+		if p, ok := c.cache.get(h.SHA1); ok {
+			if p != nil {
+				found[h.SHA1] = p
+			}
+			continue
+		}
+		todo = append(todo, h)
+	}
+	if len(todo) == 0 {
+		return found, nil
+	}
+
+	fromModrinth, modrinthErr := c.lookupModrinth(ctx, todo)
+	for sha, p := range fromModrinth {
+		found[sha] = p
+		c.cache.put(sha, p)
+	}
+
+	var rest []Hashes
+	for _, h := range todo {
+		if found[h.SHA1] == nil {
+			rest = append(rest, h)
+		}
+	}
+	var curseforgeErr error
+	if c.curseforgeKey != "" && len(rest) > 0 {
+		var fromCurseForge map[string]*Project
+		fromCurseForge, curseforgeErr = c.lookupCurseForge(ctx, rest)
+		for sha, p := range fromCurseForge {
+			found[sha] = p
+			c.cache.put(sha, p)
+		}
+	}
+	if modrinthErr != nil || curseforgeErr != nil {
+		return found, errors.Join(modrinthErr, curseforgeErr)
+	}
+
+	// Only jars every API answered for are known to be unknown
+	for _, h := range rest {
+		if found[h.SHA1] == nil {
+			c.cache.put(h.SHA1, nil)
+		}
+	}
+	return found, nil
+}
+
+// Save writes the cache back to disk if anything was added
+func (c *Client) Save() error {
+	return c.cache.save()
+}
@@ -0,0 +1,219 @@
+package modapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// roundTripFunc serves requests in the test, so no lookup leaves the machine
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// fakeAPIs answers as Modrinth and CurseForge would: sodium is on Modrinth, jei only on
+// CurseForge, and nothing else is on either
+type fakeAPIs struct {
+	mu          sync.Mutex
+	requests    []string
+	fingerprint []uint32 // The fingerprints CurseForge was last asked about
+	fail        bool     // Modrinth answers 500
+	limited     int      // Modrinth answers 429 this many times first
+}
+
+var (
+	sodium  = Hashes{SHA1: "5041", Fingerprint: 1}
+	jei     = Hashes{SHA1: "7e1", Fingerprint: 2}
+	mystery = Hashes{SHA1: "0000", Fingerprint: 3}
+)
+
+func (f *fakeAPIs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Host+r.URL.Path)
+	switch {
+	case r.URL.Host == "api.modrinth.com" && f.limited > 0:
+		f.limited--
+		w.Header().Set("X-Ratelimit-Reset", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	case r.URL.Host == "api.modrinth.com" && f.fail:
+		http.Error(w, "down for maintenance", http.StatusInternalServerError)
+	case r.URL.Path == "/v2/version_files":
+		var body struct{ Hashes []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		versions := map[string]any{}
+		if slices.Contains(body.Hashes, sodium.SHA1) {
+			versions[sodium.SHA1] = map[string]string{"id": "v1", "project_id": "AANobbMI", "version_number": "0.5.8"}
+		}
+		json.NewEncoder(w).Encode(versions)
+	case r.URL.Path == "/v2/projects":
+		json.NewEncoder(w).Encode([]map[string]string{{"id": "AANobbMI", "slug": "sodium", "title": "Sodium", "project_type": "mod"}})
+	case r.URL.Path == "/v1/fingerprints/432":
+		var body struct{ Fingerprints []uint32 }
+		json.NewDecoder(r.Body).Decode(&body)
+		f.fingerprint = body.Fingerprints
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"exactMatches": []any{
+			map[string]any{"file": map[string]any{"id": 11, "modId": 238222, "displayName": "jei-15.2.jar", "fileFingerprint": jei.Fingerprint}},
+		}}})
+	case r.URL.Path == "/v1/mods":
+		json.NewEncoder(w).Encode(map[string]any{"data": []any{
+			map[string]any{"id": 238222, "name": "Just Enough Items", "slug": "jei", "links": map[string]string{"websiteUrl": "https://www.curseforge.com/minecraft/mc-mods/jei"}},
+		}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// take returns the requests made since the last call
+func (f *fakeAPIs) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.requests
+	f.requests = nil
+	return r
+}
+
+// newTestClient returns a client caching at cachePath that asks apis without pauses
+func newTestClient(cachePath string, apis *fakeAPIs) *Client {
+	c := New(cachePath)
+	c.http = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		apis.ServeHTTP(rec, r)
+		return rec.Result(), nil
+	})}
+	c.modrinth, c.curseforge = newLimiter(0), newLimiter(0)
+	c.curseforgeKey = "test-key"
+	return c
+}
+
+// Jars are asked about once: found ones and known misses come from the cache afterwards,
+// and CurseForge only hears about jars Modrinth didn't know
+func TestLookupCached(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "mod-lookups.json")
+	apis := &fakeAPIs{}
+	c := newTestClient(cachePath, apis)
+	jars := []Hashes{sodium, jei, mystery}
+
+	found, err := c.Lookup(context.Background(), jars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := found[sodium.SHA1]; p == nil || p.Source != "modrinth" || p.Title != "Sodium" || p.Version != "0.5.8" || p.URL != "https://modrinth.com/mod/sodium" {
+		t.Errorf("sodium = %+v", p)
+	}
+	if p := found[jei.SHA1]; p == nil || p.Source != "curseforge" || p.ProjectID != "238222" || p.URL != "https://www.curseforge.com/minecraft/mc-mods/jei" {
+		t.Errorf("jei = %+v", p)
+	}
+	if _, ok := found[mystery.SHA1]; ok || len(found) != 2 {
+		t.Errorf("found %d projects, want sodium and jei only", len(found))
+	}
+	if got := apis.take(); len(got) != 4 {
+		t.Errorf("requests = %q, want 2 to each API", got)
+	}
+	if !slices.Equal(apis.fingerprint, []uint32{jei.Fingerprint, mystery.Fingerprint}) {
+		t.Errorf("CurseForge was asked about %v, want only Modrinth's misses", apis.fingerprint)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A later run answers from the saved cache without asking
+	c = newTestClient(cachePath, apis)
+	found, err = c.Lookup(context.Background(), jars)
+	if err != nil || len(found) != 2 || found[jei.SHA1].Title != "Just Enough Items" {
+		t.Errorf("cached Lookup = %v, %v", found, err)
+	}
+	if got := apis.take(); len(got) != 0 {
+		t.Errorf("cached Lookup made requests %q", got)
+	}
+
+	// A miss is asked about again once it is old, in case the mod was uploaded since
+	c.cache.entries[mystery.SHA1] = cacheEntry{Checked: time.Now().Add(-missTTL - time.Hour)}
+	c.Lookup(context.Background(), jars)
+	if got := apis.take(); len(got) != 2 || !strings.Contains(got[0], "version_files") {
+		t.Errorf("requests for an expired miss = %q", got)
+	}
+}
+
+// A failing API returns what the others found and leaves the rest unknown, not cached as misses
+func TestLookupFails(t *testing.T) {
+	apis := &fakeAPIs{fail: true}
+	c := newTestClient("", apis)
+	found, err := c.Lookup(context.Background(), []Hashes{sodium, jei, mystery})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Lookup error = %v, want Modrinth's 500", err)
+	}
+	if len(found) != 1 || found[jei.SHA1] == nil {
+		t.Errorf("found %v, want jei from CurseForge", found)
+	}
+	if _, ok := c.cache.get(mystery.SHA1); ok {
+		t.Error("a jar Modrinth never answered for was cached as unknown")
+	}
+	if _, ok := c.cache.get(jei.SHA1); !ok {
+		t.Error("jei was not cached")
+	}
+}
+
+// A 429 pauses the API for as long as it asks, then the request is sent again
+func TestLookupRateLimited(t *testing.T) {
+	apis := &fakeAPIs{limited: 1}
+	c := newTestClient("", apis)
+	c.curseforgeKey = ""
+	start := time.Now()
+	found, err := c.Lookup(context.Background(), []Hashes{sodium})
+	if err != nil || found[sodium.SHA1] == nil {
+		t.Fatalf("Lookup = %v, %v", found, err)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("retried after %v, want the 1s X-Ratelimit-Reset asked for", waited)
+	}
+	if got := apis.take(); len(got) != 3 {
+		t.Errorf("requests = %q, want version_files twice, then projects", got)
+	}
+}
+
+// Requests to one API are spaced out, and a pause holds them until ctx gives up
+func TestLimiterSpacing(t *testing.T) {
+	l := newLimiter(20 * time.Millisecond)
+	start := time.Now()
+	for range 4 {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("4 requests took %v, want at least 60ms", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.pause(time.Hour)
+	if err := l.wait(ctx); err == nil {
+		t.Error("wait during a pause with a cancelled context: no error")
+	}
+}
+
+// CurseForge fingerprints ignore whitespace, so line-ending changes keep a jar's identity
+func TestFingerprint(t *testing.T) {
+	if Fingerprint([]byte("a b\r\n\tc")) != Fingerprint([]byte("abc")) {
+		t.Error("whitespace changed the fingerprint")
+	}
+	if Fingerprint([]byte("abc")) == Fingerprint([]byte("abd")) {
+		t.Error("different contents share a fingerprint")
+	}
+	seen := map[uint32]bool{}
+	for n := range 8 {
+		seen[Fingerprint([]byte("abcdefg"[:n]))] = true
+	}
+	if len(seen) != 8 {
+		t.Errorf("%d distinct fingerprints for the 8 tail lengths", len(seen))
+	}
+}
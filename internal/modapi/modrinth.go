@@ -0,0 +1,77 @@
+package modapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	modrinthAPI = "https://api.modrinth.com/v2"
+	// Modrinth allows 300 requests a minute
+	modrinthInterval = 200 * time.Millisecond
+	// batchSize is how many hashes or ids go in one request
+	batchSize = 100
+)
+
+// lookupModrinth identifies jars by SHA-1, then fetches their projects' names
+func (c *Client) lookupModrinth(ctx context.Context, jars []Hashes) (map[string]*Project, error) {
+	type version struct {
+		ID            string `json:"id"`
+		ProjectID     string `json:"project_id"`
+		VersionNumber string `json:"version_number"`
+	}
+	found := map[string]*Project{}
+	for start := 0; start < len(jars); start += batchSize {
+		batch := jars[start:min(start+batchSize, len(jars))]
+		hashes := make([]string, len(batch))
+		for i, h := range batch {
+			hashes[i] = h.SHA1
+		}
+		var versions map[string]version
+		body := map[string]any{"hashes": hashes, "algorithm": "sha1"}
+		if err := c.do(ctx, c.modrinth, "POST", modrinthAPI+"/version_files", nil, body, &versions); err != nil {
+			return nil, fmt.Errorf("modrinth: %w", err)
+		}
+		for sha, v := range versions {
+			found[sha] = &Project{Source: "modrinth", ProjectID: v.ProjectID, VersionID: v.ID, Version: v.VersionNumber}
+		}
+	}
+	if err := c.modrinthProjects(ctx, found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// modrinthProjects fills in the title, slug and page of each project
+func (c *Client) modrinthProjects(ctx context.Context, found map[string]*Project) error {
+	byID := map[string][]*Project{}
+	var ids []string
+	for _, p := range found {
+		if byID[p.ProjectID] == nil {
+			ids = append(ids, p.ProjectID)
+		}
+		byID[p.ProjectID] = append(byID[p.ProjectID], p)
+	}
+	for start := 0; start < len(ids); start += batchSize {
+		batch, _ := json.Marshal(ids[start:min(start+batchSize, len(ids))])
+		var projects []struct {
+			ID          string `json:"id"`
+			Slug        string `json:"slug"`
+			Title       string `json:"title"`
+			ProjectType string `json:"project_type"`
+		}
+		if err := c.do(ctx, c.modrinth, "GET", modrinthAPI+"/projects?ids="+url.QueryEscape(string(batch)), nil, nil, &projects); err != nil {
+			return fmt.Errorf("modrinth: %w", err)
+		}
+		for _, proj := range projects {
+			for _, p := range byID[proj.ID] {
+				p.Slug, p.Title = proj.Slug, proj.Title
+				p.URL = "https://modrinth.com/" + proj.ProjectType + "/" + proj.Slug
+			}
+		}
+	}
+	return nil
+}
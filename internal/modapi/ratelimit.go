@@ -0,0 +1,115 @@
+package modapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRetries is how often a rate-limited request is retried before giving up
+const maxRetries = 3
+
+// limiter spaces out requests to one API
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(interval time.Duration) *limiter {
+	return &limiter{interval: interval}
+}
+
+// wait blocks until a request may be sent, or ctx is done
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pause holds back every request until d from now, after the API said to slow down
+func (l *limiter) pause(d time.Duration) {
+	l.mu.Lock()
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+	l.mu.Unlock()
+}
+
+// retryAfter reads how long a 429 response asks to wait: Modrinth's X-Ratelimit-Reset or
+// the standard Retry-After, both in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	for _, header := range []string{"X-Ratelimit-Reset", "Retry-After"} {
+		if s, err := strconv.Atoi(resp.Header.Get(header)); err == nil && s > 0 {
+			return time.Duration(s) * time.Second
+		}
+	}
+	return 10 * time.Second
+}
+
+// do sends a JSON request through l and decodes the JSON answer into out, retrying when
+// rate limited. A 404 leaves out untouched.
+func (c *Client) do(ctx context.Context, l *limiter, method, url string, headers map[string]string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		if err := l.wait(ctx); err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
+			l.pause(retryAfter(resp))
+			resp.Body.Close()
+			continue
+		}
+		defer resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return nil
+		case resp.StatusCode != http.StatusOK:
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("%s: %s %s", req.URL.Host, resp.Status, bytes.TrimSpace(msg))
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+}
@@ -0,0 +1,92 @@
+// Package netguard is the only way Totem reaches the network. Every feature that connects
+// out, or listens anywhere but loopback, goes through Dial, HTTPClient, Listen or Allow,
+// naming itself, and all of them refuse while offline mode is on. No other package may
+// dial or import net/http for a client, so backups and restores cannot phone home.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOffline is returned for network features while offline mode is on
+var ErrOffline = errors.New("needs the network, which offline mode turns off")
+
+// dialTimeout bounds how long a connection attempt may take
+const dialTimeout = 30 * time.Second
+
+// offline is set by SetOffline
+var offline bool
+
+// SetOffline turns off every network feature for the rest of the run, for --offline
+func SetOffline() {
+	offline = true
+}
+
+// attempts counts every time a feature asked for the network, whether or not it was let
+var attempts atomic.Int64
+
+// Attempts reports how many times any feature has asked for the network, for tests that
+// assert a code path never does
+func Attempts() int64 {
+	return attempts.Load()
+}
+
+// Offline reports whether offline mode is on
+func Offline() bool {
+	return offline
+}
+
+// Allow reports whether feature may use the network now. Features whose connections are
+// made by another program (scp) call it before starting that program.
+func Allow(feature string) error {
+	attempts.Add(1)
+	if offline {
+		return fmt.Errorf("%s %w", feature, ErrOffline)
+	}
+	return nil
+}
+
+// Dial connects to addr for feature
+func Dial(feature, network, addr string) (net.Conn, error) {
+	return DialContext(context.Background(), feature, network, addr)
+}
+
+// DialContext connects to addr for feature until ctx is done
+func DialContext(ctx context.Context, feature, network, addr string) (net.Conn, error) {
+	if err := Allow(feature); err != nil {
+		return nil, err
+	}
+	d := net.Dialer{Timeout: dialTimeout}
+	return d.DialContext(ctx, network, addr)
+}
+
+// HTTPClient returns a client for feature whose connections all go through DialContext,
+// honouring the usual proxy environment variables
+func HTTPClient(feature string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return DialContext(ctx, feature, network, addr)
+	}
+	return &http.Client{Transport: transport, Timeout: 2 * time.Minute}
+}
+
+// Listen listens on addr for feature. Loopback addresses never leave the machine and are
+// always allowed; any other address, including all interfaces, needs the network.
+func Listen(feature, network, addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		if err := Allow(feature + " on " + addr); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen(network, addr)
+}
@@ -0,0 +1,110 @@
+package netguard
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// networkImports are the packages that reach the network on their own
+var networkImports = map[string]bool{"net": true, "net/http": true, "net/smtp": true}
+
+// allowedImports are the files outside netguard that may import a network package, and
+// which. Each one only uses it with a client or connection netguard made, or to serve on a
+// listener netguard opened; the README lists them too.
+var allowedImports = map[string][]string{
+	"cli.go":                          {"net/http"}, // totem mount's file server
+	"internal/modapi/modapi.go":       {"net/http"},
+	"internal/modapi/ratelimit.go":    {"net/http"},
+	"internal/modapi/modapi_test.go":  {"net/http"}, // Answers as the APIs in memory, without dialing
+	"internal/backup/tracing.go":      {"net/http"},
+	"internal/backup/tracing_test.go": {"net/http"}, // A collector on loopback, reached with netguard's client
+	"internal/backup/digest.go":       {"net/smtp"},
+}
+
+// TestNetworkImports keeps every connection going through netguard: a new import of net,
+// net/http or net/smtp elsewhere fails until it is reviewed and added to allowedImports
+func TestNetworkImports(t *testing.T) {
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasPrefix(rel, "internal/netguard/") {
+			return nil
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, imp := range f.Imports {
+			pkg, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				return err
+			}
+			if networkImports[pkg] && !allowed(rel, pkg) {
+				t.Errorf("%s imports %s; reach the network through internal/netguard instead", rel, pkg)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func allowed(file, pkg string) bool {
+	for _, p := range allowedImports[file] {
+		if p == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOfflineRefuses(t *testing.T) {
+	SetOffline()
+	t.Cleanup(func() { offline = false })
+
+	if _, err := Dial("test", "tcp", "127.0.0.1:1"); !errors.Is(err, ErrOffline) {
+		t.Errorf("Dial: got %v, want ErrOffline", err)
+	}
+	if err := Allow("test"); !errors.Is(err, ErrOffline) {
+		t.Errorf("Allow: got %v, want ErrOffline", err)
+	}
+	if _, err := HTTPClient("test").Get("http://127.0.0.1:1/"); !errors.Is(err, ErrOffline) {
+		t.Errorf("HTTPClient: got %v, want ErrOffline", err)
+	}
+	for _, addr := range []string{"0.0.0.0:0", ":0", "192.0.2.1:0"} {
+		if _, err := Listen("test", "tcp", addr); !errors.Is(err, ErrOffline) {
+			t.Errorf("Listen on %s: got %v, want ErrOffline", addr, err)
+		}
+	}
+
+	// Loopback never leaves the machine
+	l, err := Listen("test", "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen on loopback: %v", err)
+	}
+	l.Close()
+}
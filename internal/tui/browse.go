@@ -0,0 +1,556 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// BackupItem is one backup on the manage screen
+type BackupItem struct {
+	Path        string
+	Name        string
+	Instance    string // Folder it is in, relative to the destination ("." for the destination itself)
+	Time        time.Time
+	Size        int64 // Archive or folder size
+	Incremental bool
+	Meta        map[string]string // Labels given with --meta
+	Minecraft   string            // Version, empty when unknown
+	Loader      string            // Mod loader, empty when none was detected
+	Contents    string            // Summary such as "2 save files, 3 screenshots, 12 mods"
+	Key         string            // Key it is encrypted with, such as "gpg:<fingerprint>"; empty when unknown or none
+}
+
+// BackupFile is one file in a backup
+type BackupFile struct {
+	Path string // Slash-separated, relative to the backup root
+	Size int64
+}
+
+// Browser gives the manage screen its backups and the actions it runs on them
+type Browser struct {
+	Dest      string // Destination whose backups are listed
+	Instance  string // Minecraft folder that restores write into; empty disables restore
+	ExtractTo string // Folder offered for extracts
+	List      func(dest string) ([]BackupItem, error)
+	Files     func(backupPath string) ([]BackupFile, error)
+	Extract   func(backupPath string, paths []string, to string) (int, error)
+	Read      func(backupPath, file string) ([]byte, error) // For screenshot previews
+	Open      func(path string)                             // Opens a file in its default app
+
+	KeepExisting bool // Extract never overwrites files, so restores only bring back missing ones
+}
+
+// browseView is the part of the manage screen being shown
+type browseView int
+
+const (
+	browseList    browseView = iota // Backups in the destination
+	browseTree                      // Contents of one backup
+	browseExtract                   // Asking where to extract the selected item
+	browseRestore                   // Confirming a restore of the selected item
+)
+
+// treeNode is a file or folder in a backup's contents
+type treeNode struct {
+	name     string
+	path     string
+	size     int64 // Folders: total of everything inside
+	files    int
+	children []*treeNode // nil for files
+	open     bool
+}
+
+// treeRow is a node as drawn, with its depth in the tree
+type treeRow struct {
+	node  *treeNode
+	depth int
+}
+
+// browseState is the manage screen's part of the model
+type browseState struct {
+	view    browseView
+	loading bool
+	backups []BackupItem
+	cursor  int
+	backup  BackupItem
+	root    *treeNode
+	rows    []treeRow
+	row     int
+	input   textinput.Model
+	status  string
+	failed  bool
+}
+
+type backupsMsg struct {
+	backups []BackupItem
+	err     error
+}
+
+type backupFilesMsg struct {
+	files []BackupFile
+	err   error
+}
+
+type previewMsg struct {
+	name string
+	data []byte
+	err  error
+}
+
+type browseActionMsg struct {
+	text string
+	err  error
+}
+
+var browseErrorStyle = lipgloss.NewStyle().Foreground(orange).Bold(true)
+
+// openManage switches to the manage screen and loads the destination's backups
+func (m Model) openManage() (Model, tea.Cmd) {
+	m.stage = StageManage
+	m.browse = browseState{view: browseList, loading: true}
+	return m, m.loadBackups()
+}
+
+func (m Model) loadBackups() tea.Cmd {
+	list, dest := m.browser.List, m.browser.Dest
+	return func() tea.Msg {
+		backups, err := list(dest)
+		return backupsMsg{backups: backups, err: err}
+	}
+}
+
+// leaveManage goes back to the options, or quits when the TUI was started on the manage screen
+func (m Model) leaveManage() (tea.Model, tea.Cmd) {
+	if m.manageOnly {
+		m.quitting = true
+		return m, tea.Quit
+	}
+	m.stage = StageOptions
+	return m, nil
+}
+
+func (m Model) updateManageResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	b := &m.browse
+	b.loading = false
+	switch msg := msg.(type) {
+	case backupsMsg:
+		b.backups = msg.backups
+		if msg.err != nil {
+			b.status, b.failed = fmt.Sprintf("Can't read %s: %v", m.browser.Dest, msg.err), true
+		}
+	case backupFilesMsg:
+		if msg.err != nil {
+			b.view = browseList
+			b.status, b.failed = fmt.Sprintf("Can't open %s: %v", b.backup.Name, msg.err), true
+			return m, nil
+		}
+		b.root = buildTree(msg.files)
+		b.root.open = true
+		b.row = 0
+		b.rows = flattenTree(b.root)
+	case previewMsg:
+		if msg.err != nil {
+			b.status, b.failed = msg.err.Error(), true
+			return m, nil
+		}
+		if protocol := imageProtocol(); protocol != protocolNone {
+			preview := &imagePreview{name: msg.name, data: msg.data, protocol: protocol, cols: max(20, min(m.width-4, 120))}
+			return m, tea.Exec(preview, func(err error) tea.Msg { return browseActionMsg{err: err} })
+		}
+		// No inline images in this terminal: hand a copy to the default viewer
+		dir := filepath.Join(os.TempDir(), "totem-preview")
+		file := filepath.Join(dir, path.Base(msg.name))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.status, b.failed = err.Error(), true
+			return m, nil
+		}
+		if err := os.WriteFile(file, msg.data, 0644); err != nil {
+			b.status, b.failed = err.Error(), true
+			return m, nil
+		}
+		m.browser.Open(file)
+		b.status, b.failed = "✓ Opened "+path.Base(msg.name)+" in the default viewer", false
+	case browseActionMsg:
+		b.status, b.failed = msg.text, msg.err != nil
+		if msg.err != nil {
+			b.status = msg.err.Error()
+		}
+	}
+	return m, nil
+}
+
+func (m Model) updateManage(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := &m.browse
+	if b.loading {
+		if msg.String() == "esc" && b.view == browseList {
+			return m.leaveManage()
+		}
+		return m, nil
+	}
+
+	switch b.view {
+	case browseList:
+		switch msg.String() {
+		case "up", "k":
+			if b.cursor > 0 {
+				b.cursor--
+			}
+		case "down", "j":
+			if b.cursor < len(b.backups)-1 {
+				b.cursor++
+			}
+		case "enter", "right", "l":
+			if len(b.backups) == 0 {
+				return m, nil
+			}
+			b.backup = b.backups[b.cursor]
+			b.view = browseTree
+			b.loading = true
+			b.status = ""
+			b.root, b.rows = nil, nil
+			files, backupPath := m.browser.Files, b.backup.Path
+			return m, func() tea.Msg {
+				list, err := files(backupPath)
+				return backupFilesMsg{files: list, err: err}
+			}
+		case "esc", "q":
+			return m.leaveManage()
+		}
+
+	case browseTree:
+		if len(b.rows) == 0 {
+			if msg.String() == "esc" || msg.String() == "backspace" {
+				b.view = browseList
+			}
+			return m, nil
+		}
+		node := b.rows[b.row].node
+		switch msg.String() {
+		case "up", "k":
+			if b.row > 0 {
+				b.row--
+			}
+		case "down", "j":
+			if b.row < len(b.rows)-1 {
+				b.row++
+			}
+		case "enter", " ":
+			if node.children != nil {
+				node.open = !node.open
+				b.rows = flattenTree(b.root)
+			} else if isImage(node.name) {
+				return m.previewImage(node)
+			}
+		case "v":
+			if node.children != nil || !isImage(node.name) {
+				b.status, b.failed = "Only screenshots and other images can be previewed", true
+				return m, nil
+			}
+			return m.previewImage(node)
+		case "right", "l":
+			if node.children != nil && !node.open {
+				node.open = true
+				b.rows = flattenTree(b.root)
+			}
+		case "left", "h":
+			if node.children != nil && node.open {
+				node.open = false
+				b.rows = flattenTree(b.root)
+				break
+			}
+			// Jump to the parent folder
+			for i := b.row - 1; i >= 0; i-- {
+				if b.rows[i].depth < b.rows[b.row].depth {
+					b.row = i
+					break
+				}
+			}
+		case "e":
+			b.view = browseExtract
+			b.status = ""
+			b.input = textinput.New()
+			b.input.CharLimit = 256
+			b.input.Width = 55
+			b.input.PromptStyle = lipgloss.NewStyle().Foreground(orange)
+			b.input.TextStyle = lipgloss.NewStyle().Foreground(sand)
+			b.input.SetValue(m.browser.ExtractTo)
+			return m, b.input.Focus()
+		case "r":
+			if m.browser.Instance == "" {
+				b.status, b.failed = "No Minecraft folder to restore into", true
+				return m, nil
+			}
+			b.view = browseRestore
+			b.status = ""
+		case "esc", "backspace":
+			b.view = browseList
+			b.status = ""
+		}
+
+	case browseExtract:
+		switch msg.String() {
+		case "esc":
+			b.view = browseTree
+			return m, nil
+		case "enter":
+			to := expandHome(strings.TrimSpace(b.input.Value()))
+			if to == "" {
+				return m, nil
+			}
+			b.view, b.loading, b.status = browseTree, true, ""
+			return m, m.runBrowseExtract(to, "Extracted")
+		}
+		var cmd tea.Cmd
+		b.input, cmd = b.input.Update(msg)
+		return m, cmd
+
+	case browseRestore:
+		switch msg.String() {
+		case "y", "enter":
+			b.view, b.loading, b.status = browseTree, true, ""
+			return m, m.runBrowseExtract(m.browser.Instance, "Restored")
+		case "n", "esc":
+			b.view = browseTree
+		}
+	}
+	return m, nil
+}
+
+// previewImage reads an image out of the backup for the screenshot viewer
+func (m Model) previewImage(node *treeNode) (tea.Model, tea.Cmd) {
+	m.browse.loading, m.browse.status = true, ""
+	read, backupPath, name := m.browser.Read, m.browse.backup.Path, node.path
+	return m, func() tea.Msg {
+		data, err := read(backupPath, name)
+		return previewMsg{name: name, data: data, err: err}
+	}
+}
+
+// runBrowseExtract copies the selected item out of the backup into to
+func (m Model) runBrowseExtract(to, verb string) tea.Cmd {
+	b := m.browse
+	extract, backupPath, item := m.browser.Extract, b.backup.Path, b.rows[b.row].node.path
+	return func() tea.Msg {
+		count, err := extract(backupPath, []string{item}, to)
+		if err != nil {
+			return browseActionMsg{err: err}
+		}
+		return browseActionMsg{text: fmt.Sprintf("✓ %s %d files of %s into %s", verb, count, item, to)}
+	}
+}
+
+// expandHome turns a leading ~ into the home folder
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[1:])
+	}
+	return path
+}
+
+// buildTree arranges a backup's files into folders, biggest first
+func buildTree(files []BackupFile) *treeNode {
+	root := &treeNode{children: []*treeNode{}}
+	for _, f := range files {
+		node := root
+		parts := strings.Split(f.Path, "/")
+		for i, part := range parts {
+			node.size += f.Size
+			node.files++
+			if i == len(parts)-1 {
+				node.children = append(node.children, &treeNode{name: part, path: f.Path, size: f.Size, files: 1})
+				break
+			}
+			var next *treeNode
+			for _, c := range node.children {
+				if c.name == part && c.children != nil {
+					next = c
+					break
+				}
+			}
+			if next == nil {
+				next = &treeNode{name: part, path: strings.Join(parts[:i+1], "/"), children: []*treeNode{}}
+				node.children = append(node.children, next)
+			}
+			node = next
+		}
+	}
+	sortTree(root)
+	return root
+}
+
+func sortTree(node *treeNode) {
+	sort.Slice(node.children, func(i, j int) bool {
+		a, b := node.children[i], node.children[j]
+		if a.size != b.size {
+			return a.size > b.size
+		}
+		return a.name < b.name
+	})
+	for _, c := range node.children {
+		if c.children != nil {
+			sortTree(c)
+		}
+	}
+}
+
+// flattenTree lists the nodes under open folders in drawing order
+func flattenTree(root *treeNode) []treeRow {
+	var rows []treeRow
+	var walk func(node *treeNode, depth int)
+	walk = func(node *treeNode, depth int) {
+		for _, c := range node.children {
+			rows = append(rows, treeRow{node: c, depth: depth})
+			if c.children != nil && c.open {
+				walk(c, depth+1)
+			}
+		}
+	}
+	walk(root, 0)
+	return rows
+}
+
+// visibleRange returns the window of n rows to draw so that cursor stays in view
+func visibleRange(cursor, n, height int) (int, int) {
+	if n <= height {
+		return 0, n
+	}
+	start := max(0, min(cursor-height/2, n-height))
+	return start, start + height
+}
+
+func (m Model) renderManage() string {
+	var s strings.Builder
+	b := m.browse
+	rowsShown := max(5, m.height-22)
+
+	var content strings.Builder
+	switch {
+	case b.view == browseList:
+		s.WriteString(sectionStyle.Render("🗂️  Backups in "+m.browser.Dest) + "\n")
+		if b.loading {
+			content.WriteString(descStyle.Render("Loading backups..."))
+		} else if len(b.backups) == 0 {
+			content.WriteString(descStyle.Render("No backups here yet"))
+		}
+		start, end := visibleRange(b.cursor, len(b.backups), rowsShown)
+		for i := start; i < end; i++ {
+			item := b.backups[i]
+			cursor, nameStyle := "  ", optionStyle
+			if i == b.cursor {
+				cursor, nameStyle = cursorActive.Render("▸ "), selectedOptionStyle
+			}
+			kind := "folder"
+			if ext := filepath.Ext(item.Name); ext != "" {
+				kind = ext[1:]
+			}
+			if item.Size > 0 {
+				kind += " " + formatBytes(item.Size)
+			}
+			if item.Incremental {
+				kind += ", incremental"
+			}
+			if game := strings.TrimSpace(item.Minecraft + " " + item.Loader); game != "" {
+				kind += ", " + game
+			}
+			for _, k := range slices.Sorted(maps.Keys(item.Meta)) {
+				kind += fmt.Sprintf(", %s=%s", k, item.Meta[k])
+			}
+			line := cursor + nameStyle.Render(item.Time.Format("2006-01-02 15:04"))
+			if item.Instance != "." {
+				line += "  " + optionStyle.Render(item.Instance)
+			}
+			content.WriteString(line + descStyle.Render("  "+kind) + "\n")
+		}
+		if !b.loading && len(b.backups) > 0 {
+			if contents := b.backups[b.cursor].Contents; contents != "" {
+				content.WriteString("\n" + descStyle.Render(contents) + "\n")
+			}
+		}
+
+	default:
+		s.WriteString(sectionStyle.Render("🗂️  "+b.backup.Name) + "\n")
+		if b.root != nil && !b.loading {
+			content.WriteString(descStyle.Render(fmt.Sprintf("%d files, %s", b.root.files, formatBytes(b.root.size))) + "\n\n")
+		}
+		if b.loading && b.root == nil {
+			content.WriteString(descStyle.Render("Reading backup..."))
+		}
+		start, end := visibleRange(b.row, len(b.rows), rowsShown)
+		for i := start; i < end; i++ {
+			row := b.rows[i]
+			cursor, nameStyle := "  ", optionStyle
+			if i == b.row {
+				cursor, nameStyle = cursorActive.Render("▸ "), selectedOptionStyle
+			}
+			icon := "  📄 "
+			if row.node.children != nil {
+				icon = "▸ 📁 "
+				if row.node.open {
+					icon = "▾ 📁 "
+				}
+			}
+			content.WriteString(cursor + strings.Repeat("  ", row.depth) + icon +
+				nameStyle.Render(row.node.name) + descStyle.Render("  "+formatBytes(row.node.size)) + "\n")
+		}
+	}
+	s.WriteString(optionBoxStyle.Render(strings.TrimRight(content.String(), "\n")))
+	s.WriteString("\n")
+
+	switch b.view {
+	case browseExtract:
+		s.WriteString(inputBoxStyle.Render(inputLabelStyle.Render("Extract "+b.rows[b.row].node.path+" to:") + "\n" + b.input.View()))
+	case browseRestore:
+		effect := "Files with the same name are overwritten."
+		if m.browser.KeepExisting {
+			effect = "Existing files are kept; the restore stops at the first one in the way."
+		}
+		s.WriteString("\n" + warningBadge.Render("RESTORE") + optionStyle.Render(fmt.Sprintf(
+			" Copy %s into %s? %s (y/n)", b.rows[b.row].node.path, m.browser.Instance, effect)))
+	}
+	switch {
+	case b.loading && b.root != nil:
+		s.WriteString("\n" + descStyle.Render("Working..."))
+	case b.status != "" && b.failed:
+		s.WriteString("\n" + browseErrorStyle.Render("✗ "+b.status))
+	case b.status != "":
+		s.WriteString("\n" + checkboxChecked.Render(b.status))
+	}
+
+	back := "back"
+	if m.manageOnly {
+		back = "quit"
+	}
+	switch b.view {
+	case browseList:
+		s.WriteString("\n" + m.renderHelp([]string{"↑↓", "enter", "esc"}, []string{"move", "open", back}))
+	case browseTree:
+		s.WriteString("\n" + m.renderHelp([]string{"↑↓", "enter", "←", "v", "e", "r", "esc"}, []string{"move", "expand", "parent", "view", "extract", "restore", "backups"}))
+	case browseExtract:
+		s.WriteString("\n" + m.renderHelp([]string{"enter", "esc"}, []string{"extract", "cancel"}))
+	case browseRestore:
+		s.WriteString("\n" + m.renderHelp([]string{"y", "n"}, []string{"restore", "cancel"}))
+	}
+	return s.String()
+}
+
+// Browse starts the TUI on the manage screen, for browsing backups without making one
+func Browse(browser Browser) error {
+	m := initialModel()
+	m.browser = &browser
+	m.manageOnly = true
+	m, _ = m.openManage()
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+)
+
+// The tree totals each folder, sorts biggest first, and only shows what is inside open folders
+func TestBuildTree(t *testing.T) {
+	root := buildTree([]BackupFile{
+		{Path: "options.txt", Size: 10},
+		{Path: "saves/World/level.dat", Size: 100},
+		{Path: "saves/World/region/r.0.0.mca", Size: 4000},
+		{Path: "saves/Other/level.dat", Size: 100},
+		{Path: "screenshots/a.png", Size: 500},
+	})
+	if root.size != 4710 || root.files != 5 {
+		t.Errorf("root has %d bytes in %d files, want 4710 in 5", root.size, root.files)
+	}
+
+	rows := flattenTree(root)
+	var names []string
+	for _, r := range rows {
+		names = append(names, r.node.name)
+	}
+	if want := []string{"saves", "screenshots", "options.txt"}; !slices.Equal(names, want) {
+		t.Fatalf("closed tree shows %q, want %q", names, want)
+	}
+	saves := rows[0].node
+	if saves.size != 4200 || saves.files != 3 || saves.path != "saves" {
+		t.Errorf("saves = %+v", saves)
+	}
+
+	saves.open = true
+	saves.children[0].open = true
+	names = nil
+	var depths []int
+	for _, r := range flattenTree(root) {
+		names = append(names, r.node.name)
+		depths = append(depths, r.depth)
+	}
+	if want := []string{"saves", "World", "region", "level.dat", "Other", "screenshots", "options.txt"}; !slices.Equal(names, want) {
+		t.Errorf("open tree shows %q, want %q", names, want)
+	}
+	if depths[2] != 2 || depths[4] != 1 {
+		t.Errorf("depths = %v", depths)
+	}
+}
+
+func TestVisibleRange(t *testing.T) {
+	for _, c := range []struct{ cursor, n, height, start, end int }{
+		{0, 5, 10, 0, 5},
+		{0, 50, 10, 0, 10},
+		{25, 50, 10, 20, 30},
+		{49, 50, 10, 40, 50},
+	} {
+		if start, end := visibleRange(c.cursor, c.n, c.height); start != c.start || end != c.end {
+			t.Errorf("visibleRange(%d, %d, %d) = %d, %d; want %d, %d", c.cursor, c.n, c.height, start, end, c.start, c.end)
+		}
+	}
+}
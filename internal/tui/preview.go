@@ -0,0 +1,206 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	_ "image/jpeg"
+)
+
+// Terminal image protocols the screenshot viewer can draw with
+const (
+	protocolNone   = ""
+	protocolKitty  = "kitty"
+	protocolITerm  = "iterm"
+	protocolSixel  = "sixel"
+	sixelMaxPixels = 960 // Sixel images are scaled down to this width
+)
+
+// imageProtocol guesses which inline image protocol the terminal speaks.
+// TOTEM_IMAGES (kitty, iterm, sixel or none) overrides the guess.
+func imageProtocol() string {
+	switch p := strings.ToLower(os.Getenv("TOTEM_IMAGES")); p {
+	case protocolKitty, protocolITerm, protocolSixel:
+		return p
+	case "none":
+		return protocolNone
+	}
+	term := os.Getenv("TERM")
+	switch {
+	case term == "xterm-kitty" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM_PROGRAM") == "ghostty":
+		return protocolKitty
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("TERM_PROGRAM") == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return protocolITerm
+	case strings.Contains(term, "sixel") || strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm"):
+		return protocolSixel
+	}
+	return protocolNone
+}
+
+// isImage reports whether a file in a backup can be previewed
+func isImage(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+// imagePreview shows an image full screen until Enter is pressed. It runs through tea.Exec,
+// which hands it the terminal, so the escape sequences don't pass through the renderer.
+type imagePreview struct {
+	name     string
+	data     []byte
+	protocol string
+	cols     int
+
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func (p *imagePreview) SetStdin(r io.Reader)  { p.stdin = r }
+func (p *imagePreview) SetStdout(w io.Writer) { p.stdout = w }
+func (p *imagePreview) SetStderr(io.Writer)   {}
+
+func (p *imagePreview) Run() error {
+	w := bufio.NewWriter(p.stdout)
+	fmt.Fprintf(w, "\x1b[2J\x1b[H%s\n\n", sectionStyle.Render("🖼️  "+p.name))
+	if err := writeImage(w, p.data, p.protocol, p.cols); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\n\n%s", helpStyle.Render(keyStyle.Render("enter")+descStyle.Render("back")))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	bufio.NewReader(p.stdin).ReadString('\n')
+	if p.protocol == protocolKitty {
+		// Kitty keeps images until told otherwise
+		fmt.Fprint(p.stdout, "\x1b_Ga=d\x1b\\")
+	}
+	return nil
+}
+
+// writeImage draws an image cols terminal columns wide
+func writeImage(w io.Writer, data []byte, protocol string, cols int) error {
+	switch protocol {
+	case protocolITerm:
+		fmt.Fprintf(w, "\x1b]1337;File=inline=1;size=%d;width=%d;preserveAspectRatio=1:%s\a",
+			len(data), cols, base64.StdEncoding.EncodeToString(data))
+		return nil
+	case protocolKitty:
+		// Kitty takes PNG as is; anything else is converted
+		if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+			return err
+		} else if format != "png" {
+			img, _, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return err
+			}
+			data = buf.Bytes()
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for first := true; len(encoded) > 0; first = false {
+			chunk := encoded[:min(4096, len(encoded))]
+			encoded = encoded[len(chunk):]
+			more := 0
+			if len(encoded) > 0 {
+				more = 1
+			}
+			if first {
+				fmt.Fprintf(w, "\x1b_Gf=100,a=T,c=%d,m=%d;%s\x1b\\", cols, more, chunk)
+			} else {
+				fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+			}
+		}
+		return nil
+	case protocolSixel:
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		return writeSixel(w, img, min(cols*8, sixelMaxPixels))
+	}
+	return fmt.Errorf("no image protocol")
+}
+
+// writeSixel encodes img as sixel graphics at most maxWidth pixels wide, in a 6x6x6 color cube
+func writeSixel(w io.Writer, img image.Image, maxWidth int) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxWidth {
+		height = height * maxWidth / width
+		width = maxWidth
+	}
+	if width == 0 || height == 0 {
+		return fmt.Errorf("empty image")
+	}
+
+	// Nearest-neighbour scale into palette indexes
+	pixels := make([]uint8, width*height)
+	for y := range height {
+		for x := range width {
+			r, g, b, _ := img.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height).RGBA()
+			pixels[y*width+x] = uint8((r*5+0x7fff)/0xffff*36 + (g*5+0x7fff)/0xffff*6 + (b*5+0x7fff)/0xffff)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\x1bPq\"1;1;%d;%d", width, height)
+	for i := range 216 {
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, i/36*20, i/6%6*20, i%6*20)
+	}
+	row := make([]byte, width)
+	for band := 0; band < height; band += 6 {
+		// Which colors appear in this band of six pixel rows
+		var used [216]bool
+		for y := band; y < min(band+6, height); y++ {
+			for _, c := range pixels[y*width : (y+1)*width] {
+				used[c] = true
+			}
+		}
+		for c := range 216 {
+			if !used[c] {
+				continue
+			}
+			for x := range width {
+				bits := byte(0)
+				for dy := range min(6, height-band) {
+					if pixels[(band+dy)*width+x] == uint8(c) {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = '?' + bits
+			}
+			fmt.Fprintf(bw, "#%d", c)
+			// Run-length encode repeated columns
+			for x := 0; x < width; {
+				run := 1
+				for x+run < width && row[x+run] == row[x] {
+					run++
+				}
+				if run > 3 {
+					fmt.Fprintf(bw, "!%d%c", run, row[x])
+				} else {
+					bw.Write(row[x : x+run])
+				}
+				x += run
+			}
+			bw.WriteByte('$')
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestImageProtocol(t *testing.T) {
+	for _, c := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, protocolKitty},
+		{map[string]string{"TERM_PROGRAM": "ghostty"}, protocolKitty},
+		{map[string]string{"TERM_PROGRAM": "WezTerm"}, protocolITerm},
+		{map[string]string{"LC_TERMINAL": "iTerm2"}, protocolITerm},
+		{map[string]string{"TERM": "foot"}, protocolSixel},
+		{map[string]string{"TERM": "xterm-256color"}, protocolNone},
+		{map[string]string{"TERM": "xterm-kitty", "TOTEM_IMAGES": "none"}, protocolNone},
+		{map[string]string{"TERM": "xterm-256color", "TOTEM_IMAGES": "Sixel"}, protocolSixel},
+	} {
+		for _, name := range []string{"TERM", "TERM_PROGRAM", "KITTY_WINDOW_ID", "LC_TERMINAL", "TOTEM_IMAGES"} {
+			t.Setenv(name, c.env[name])
+		}
+		if got := imageProtocol(); got != c.want {
+			t.Errorf("imageProtocol with %v = %q, want %q", c.env, got, c.want)
+		}
+	}
+}
+
+// testImage returns a w×h image, red on the left half and blue on the right
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestWriteImage(t *testing.T) {
+	var pngData, jpegData bytes.Buffer
+	png.Encode(&pngData, testImage(4, 4))
+	jpeg.Encode(&jpegData, testImage(4, 4), nil)
+
+	var out bytes.Buffer
+	if err := writeImage(&out, pngData.Bytes(), protocolITerm, 40); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "\x1b]1337;File=inline=1;") || !strings.Contains(out.String(), "width=40;") {
+		t.Errorf("iTerm2 output = %q", out.String())
+	}
+
+	// Kitty only takes PNG, so the JPEG is converted
+	out.Reset()
+	if err := writeImage(&out, jpegData.Bytes(), protocolKitty, 40); err != nil {
+		t.Fatal(err)
+	}
+	payload, ok := strings.CutPrefix(out.String(), "\x1b_Gf=100,a=T,c=40,m=0;")
+	if !ok {
+		t.Fatalf("kitty output = %q", out.String())
+	}
+	data, _ := base64.StdEncoding.DecodeString(strings.TrimSuffix(payload, "\x1b\\"))
+	if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err != nil || format != "png" {
+		t.Errorf("kitty payload is %s, %v; want png", format, err)
+	}
+
+	if err := writeImage(&out, pngData.Bytes(), protocolNone, 40); err == nil {
+		t.Error("wrote an image without a protocol")
+	}
+	if err := writeImage(&out, []byte("not an image"), protocolSixel, 40); err == nil {
+		t.Error("wrote sixels for data that is not an image")
+	}
+}
+
+// Big images go to kitty in chunks of at most 4096 base64 bytes, all but the last marked m=1
+func TestWriteImageKittyChunks(t *testing.T) {
+	data := bytes.Repeat([]byte{0}, 10000)
+	var header bytes.Buffer
+	png.Encode(&header, testImage(1, 1))
+	data = append(header.Bytes(), data...) // Decodes as a PNG config; the rest is never parsed
+
+	var out bytes.Buffer
+	if err := writeImage(&out, data, protocolKitty, 40); err != nil {
+		t.Fatal(err)
+	}
+	chunks := strings.Split(strings.TrimSuffix(out.String(), "\x1b\\"), "\x1b\\")
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4", len(chunks))
+	}
+	for i, c := range chunks {
+		want := "\x1b_Gm=1;"
+		switch i {
+		case 0:
+			want = "\x1b_Gf=100,a=T,c=40,m=1;"
+		case len(chunks) - 1:
+			want = "\x1b_Gm=0;"
+		}
+		if !strings.HasPrefix(c, want) || len(c)-len(want) > 4096 {
+			t.Errorf("chunk %d starts %q and is %d bytes", i, c[:min(len(c), 24)], len(c))
+		}
+	}
+}
+
+func TestWriteSixel(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeSixel(&out, testImage(4, 6), 100); err != nil {
+		t.Fatal(err)
+	}
+	s := out.String()
+	if !strings.HasPrefix(s, "\x1bPq\"1;1;4;6") || !strings.HasSuffix(s, "\x1b\\") {
+		t.Errorf("sixel output is not framed as one 4×6 image: %q", s[:min(len(s), 20)])
+	}
+	// Red (180) fills the left two columns of the band, blue (5) the right two
+	if !strings.Contains(s, "#180~~??$") || !strings.Contains(s, "#5??~~$") {
+		_, band, _ := strings.Cut(s, "#215;2;100;100;100") // The last palette entry
+		t.Errorf("sixel band = %q", band)
+	}
+
+	out.Reset()
+	if err := writeSixel(&out, testImage(2000, 1000), sixelMaxPixels); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "\x1bPq\"1;1;960;480") {
+		t.Errorf("wide image was not scaled to %d pixels: %q", sixelMaxPixels, out.String()[:20])
+	}
+	if err := writeSixel(&out, image.NewRGBA(image.Rect(0, 0, 0, 0)), 100); err == nil {
+		t.Error("encoded an empty image")
+	}
+}
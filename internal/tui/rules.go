@@ -0,0 +1,258 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Rules are the include/exclude rules saved for an instance: paths relative to it,
+// slash-separated, that every backup copies as they are or leaves out
+type Rules struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// InstanceFolder is a top-level folder of an instance on the rules screen
+type InstanceFolder struct {
+	Name string
+	Size int64
+	Note string // What a backup does with it when no rule applies
+}
+
+// RulesEditor gives the rules screen an instance's folders and saves the user's choices
+type RulesEditor struct {
+	Folders func(instance string) ([]InstanceFolder, error)
+	Load    func(instance string) (Rules, error)
+	Save    func(instance string, rules Rules) error
+}
+
+// Rule states of a folder on the rules screen, in the order space cycles through them
+const (
+	ruleDefault = iota
+	ruleInclude
+	ruleExclude
+)
+
+// rulesState is the rules screen's part of the model
+type rulesState struct {
+	loading bool
+	folders []InstanceFolder
+	states  []int
+	saved   Rules // As loaded; rules for paths not listed are kept on save
+	cursor  int
+	err     error
+}
+
+type rulesLoadedMsg struct {
+	folders []InstanceFolder
+	rules   Rules
+	err     error
+}
+
+type rulesSavedMsg struct {
+	err error
+}
+
+// openRules switches to the rules screen and measures the instance's folders
+func (m Model) openRules() (Model, tea.Cmd) {
+	m.stage = StageRules
+	m.rules = rulesState{loading: true}
+	return m, m.loadRules()
+}
+
+func (m Model) loadRules() tea.Cmd {
+	editor, instance := m.rulesEditor, m.mcPath
+	return func() tea.Msg {
+		folders, err := editor.Folders(instance)
+		if err != nil {
+			return rulesLoadedMsg{err: err}
+		}
+		rules, err := editor.Load(instance)
+		return rulesLoadedMsg{folders: folders, rules: rules, err: err}
+	}
+}
+
+// leaveRules goes back to the confirmation screen, estimating again since the rules change
+// what is copied, or quits when the TUI was started on the rules screen
+func (m Model) leaveRules(changed bool) (tea.Model, tea.Cmd) {
+	if m.rulesOnly {
+		m.quitting = true
+		return m, tea.Quit
+	}
+	m.stage = StageConfirm
+	if changed && m.estimator != nil {
+		m.estimating = true
+		return m, m.runEstimate()
+	}
+	return m, nil
+}
+
+func (m Model) updateRulesResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	r := &m.rules
+	r.loading = false
+	switch msg := msg.(type) {
+	case rulesLoadedMsg:
+		r.err = msg.err
+		r.folders, r.saved = msg.folders, msg.rules
+		r.states = make([]int, len(r.folders))
+		for i, f := range r.folders {
+			switch {
+			case slices.Contains(r.saved.Include, f.Name):
+				r.states[i] = ruleInclude
+			case slices.Contains(r.saved.Exclude, f.Name):
+				r.states[i] = ruleExclude
+			}
+		}
+	case rulesSavedMsg:
+		if msg.err != nil {
+			r.err = msg.err
+			return m, nil
+		}
+		return m.leaveRules(true)
+	}
+	return m, nil
+}
+
+func (m Model) updateRules(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := &m.rules
+	if r.loading {
+		if msg.String() == "esc" {
+			return m.leaveRules(false)
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "up", "k":
+		if r.cursor > 0 {
+			r.cursor--
+		}
+	case "down", "j":
+		if r.cursor < len(r.folders)-1 {
+			r.cursor++
+		}
+	case " ", "x":
+		if len(r.states) > 0 {
+			r.states[r.cursor] = (r.states[r.cursor] + 1) % 3
+		}
+	case "+":
+		if len(r.states) > 0 {
+			r.states[r.cursor] = ruleInclude
+		}
+	case "-":
+		if len(r.states) > 0 {
+			r.states[r.cursor] = ruleExclude
+		}
+	case "enter":
+		if len(r.folders) == 0 {
+			return m.leaveRules(false)
+		}
+		r.loading = true
+		save, instance, rules := m.rulesEditor.Save, m.mcPath, m.chosenRules()
+		return m, func() tea.Msg {
+			return rulesSavedMsg{err: save(instance, rules)}
+		}
+	case "esc":
+		return m.leaveRules(false)
+	}
+	return m, nil
+}
+
+// chosenRules turns the folder states into rules, keeping saved rules for paths not listed
+func (m Model) chosenRules() Rules {
+	r := m.rules
+	listed := map[string]bool{}
+	var rules Rules
+	for i, f := range r.folders {
+		listed[f.Name] = true
+		switch r.states[i] {
+		case ruleInclude:
+			rules.Include = append(rules.Include, f.Name)
+		case ruleExclude:
+			rules.Exclude = append(rules.Exclude, f.Name)
+		}
+	}
+	for _, p := range r.saved.Include {
+		if !listed[p] {
+			rules.Include = append(rules.Include, p)
+		}
+	}
+	for _, p := range r.saved.Exclude {
+		if !listed[p] {
+			rules.Exclude = append(rules.Exclude, p)
+		}
+	}
+	return rules
+}
+
+func (m Model) renderRules() string {
+	var s strings.Builder
+	r := m.rules
+
+	s.WriteString(sectionStyle.Render("📐  Copy Rules") + "\n")
+
+	var content strings.Builder
+	content.WriteString(descStyle.Render(m.mcPath) + "\n\n")
+	switch {
+	case r.loading && r.folders == nil:
+		content.WriteString(descStyle.Render("Measuring folders..."))
+	case len(r.folders) == 0 && r.err == nil:
+		content.WriteString(descStyle.Render("No folders in this instance"))
+	}
+	start, end := visibleRange(r.cursor, len(r.folders), max(5, m.height-22))
+	for i := start; i < end; i++ {
+		f := r.folders[i]
+		cursor, nameStyle := "  ", optionStyle
+		if i == r.cursor {
+			cursor, nameStyle = cursorActive.Render("▸ "), selectedOptionStyle
+		}
+		state, note := checkboxUnchecked.Render("·"), descStyle.Render(f.Note)
+		switch r.states[i] {
+		case ruleInclude:
+			state, note = checkboxChecked.Render("+"), checkboxChecked.Render("Always copied")
+		case ruleExclude:
+			state, note = browseErrorStyle.Render("−"), browseErrorStyle.Render("Never copied")
+		}
+		content.WriteString(fmt.Sprintf("%s%s  %s %s  %s\n", cursor, state,
+			nameStyle.Render(f.Name), descStyle.Render(formatBytes(f.Size)), note))
+	}
+	if n := len(r.saved.Include) + len(r.saved.Exclude) - countRuled(r); n > 0 {
+		noun := "rules"
+		if n == 1 {
+			noun = "rule"
+		}
+		content.WriteString("\n" + descStyle.Render(fmt.Sprintf("%d more %s for nested paths kept", n, noun)))
+	}
+	s.WriteString(optionBoxStyle.Render(strings.TrimRight(content.String(), "\n")))
+
+	if r.err != nil {
+		s.WriteString("\n" + browseErrorStyle.Render("✗ "+r.err.Error()))
+	}
+	s.WriteString("\n" + m.renderHelp([]string{"↑↓", "space", "+", "-", "enter", "esc"},
+		[]string{"move", "cycle", "always", "never", "save", "cancel"}))
+	return s.String()
+}
+
+// countRuled counts the saved rules that name a listed folder
+func countRuled(r rulesState) int {
+	n := 0
+	for _, f := range r.folders {
+		if slices.Contains(r.saved.Include, f.Name) || slices.Contains(r.saved.Exclude, f.Name) {
+			n++
+		}
+	}
+	return n
+}
+
+// EditRules starts the TUI on the rules screen for one instance
+func EditRules(instance string, editor RulesEditor) error {
+	m := initialModel()
+	m.mcPath = instance
+	m.rulesEditor = &editor
+	m.rulesOnly = true
+	m, _ = m.openRules()
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
@@ -0,0 +1,48 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Toggling folders saves their rules and keeps saved rules for paths the screen doesn't list
+func TestRulesScreen(t *testing.T) {
+	var saved Rules
+	m := Model{mcPath: "/mc", rulesOnly: true, rulesEditor: &RulesEditor{Save: func(instance string, rules Rules) error {
+		saved = rules
+		return nil
+	}}}
+	next, _ := m.updateRulesResult(rulesLoadedMsg{
+		folders: []InstanceFolder{{Name: "config"}, {Name: "logs"}, {Name: "saves"}},
+		rules:   Rules{Include: []string{"config", "config/sodium/options.json"}, Exclude: []string{"saves"}},
+	})
+	m = next.(Model)
+	if !slices.Equal(m.rules.states, []int{ruleInclude, ruleDefault, ruleExclude}) {
+		t.Fatalf("states = %v, want the saved rules applied", m.rules.states)
+	}
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyDown},
+		{Type: tea.KeyRunes, Runes: []rune{'-'}},
+		{Type: tea.KeyDown},
+		{Type: tea.KeySpace, Runes: []rune{' '}},
+	} {
+		next, _ = m.updateRules(key)
+		m = next.(Model)
+	}
+	next, cmd := m.updateRules(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("enter did not save")
+	}
+	next, _ = next.(Model).updateRulesResult(cmd())
+
+	want := Rules{Include: []string{"config", "config/sodium/options.json"}, Exclude: []string{"logs"}}
+	if !slices.Equal(saved.Include, want.Include) || !slices.Equal(saved.Exclude, want.Exclude) {
+		t.Errorf("saved %+v, want %+v", saved, want)
+	}
+	if !next.(Model).quitting {
+		t.Error("saving from totem rules did not quit")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,23 +15,103 @@ import (
 
 // Config holds the user's selections
 type Config struct {
-	MinecraftPath string
-	BackupDest    string
-	ZipOutput     bool
-	IncludeSaves  bool
-	IncludeXaero  bool
-	IncludeDH     bool
-	OpenWhenDone  bool
+	MinecraftPath    string
+	BackupDest       string
+	ZipOutput        bool
+	ProtectZip       bool   // Encrypt the zip with AES-256 using ZipPassword
+	ZipPassword      string `json:"-"` // Kept out of the journal; a resumed backup reads it again
+	GPGRecipient     string // Pipe the zip through gpg to this key, into a .zip.gpg
+	IncludeSaves     bool
+	IncludeXaero     bool
+	IncludeDH        bool
+	IncludeCrashes   bool // crash-reports/, summarized in info.md
+	OpenWhenDone     bool
+	WorldConfigOnly  bool   // level.dat and datapacks only, when saves are skipped
+	SkipJunk         bool   // Skip lock files, OS metadata and caches
+	MaxFileSize      int64  // Skip files larger than this many bytes (0 = no cap)
+	LowPriority      bool   // Lower CPU/I/O priority so the game stays smooth
+	RateLimit        int64  // Max copy throughput in bytes per second (0 = unlimited)
+	SignManifest     bool   // Sign manifest.json with the local ed25519 key
+	Parity           bool   // Write a .parity file next to the archive for bit-rot repair
+	ParityBlocks     int    // Damaged blocks per group of 16 that parity can rebuild; 0 means 1
+	Deterministic    bool   // Byte-identical archives for identical inputs
+	Zstd             bool   // Write a .tar.zst instead of a zip or folder
+	ModMetadata      bool   // Look up mods on Modrinth and CurseForge by hash, into mods.json
+	SplitSize        int64  // Split the archive into parts of this many bytes (0 = one file)
+	StreamArchive    bool   // Leave the archive to the sftp upload, which streams it to the server
+	ZstdLevel        int    // zstd compression level, 1-19 (0 = zstd's default)
+	ZstdLong         int    // zstd long-distance matching window log, 10-31 (0 = off)
+	MemoryLimit      int64  // Soft heap limit in bytes for low-RAM machines (0 = none)
+	VerifyCopies     bool   // Re-read each copied file and compare its hash with the source
+	NetworkDest      bool   // Destination is an SMB/NFS share: retry I/O errors, fsync, fewer parallel copies
+	RedactSecrets    bool   // Blank proxy forwarding secrets in the backup
+	RedactReports    bool   // Hide usernames, server addresses and absolute paths in info.md
+	ActiveWorldsDays int    // Only back up worlds played within this many days (0 = all)
+	Modpack          string // .mrpack the instance was installed from; deviations go in modpack.md
+
+	// Differential cutoffs: only copy files modified after these times (zero = everything)
+	ScreenshotsSince time.Time
+	SavesSince       time.Time
+	XaeroSince       time.Time
+	Parent           string // Backup an incremental builds on, by folder name
+	Incremental      bool   // Copy only files whose size or modification time differ from Parent's chain
+	LinkDest         bool   // Hard-link files unchanged since Parent instead of copying them
+
+	// Copy rules, as slash-separated paths relative to the instance; rules saved for the
+	// instance are added to these when the backup runs
+	Include []string // Extra folders and files copied as they are
+	Exclude []string // Folders and files never copied
+
+	// Free-form labels such as a seed, server name or season, recorded in manifest.json
+	// and info.md so `totem list --filter` can find the backup later
+	Meta map[string]string
+}
+
+// SizeEstimate is a predicted backup size, before and after zipping,
+// plus any files the preflight check could not read
+type SizeEstimate struct {
+	Raw        int64
+	Compressed int64
+	Unreadable []string
+	OneDrive   bool   // Source is inside a OneDrive-synced folder
+	CloudOnly  int    // Online-only files, not counted in Raw or Compressed
+	Retention  string // Retention policy pruning the destination afterwards, described; empty for none
+}
+
+// Estimator predicts the size of a backup for the confirmation screen
+type Estimator func(config *Config) (SizeEstimate, error)
+
+// PathSuggester returns the folders the user probably meant when a Minecraft path looks
+// wrong (e.g. a launcher folder), or nil when it looks right
+type PathSuggester func(path string) []string
+
+// sizeEstimateMsg carries the result of an Estimator run
+type sizeEstimateMsg struct {
+	estimate SizeEstimate
+	err      error
 }
 
 // Stage represents the current TUI stage
 type Stage int
 
+// DefaultMaxFileSize is the per-file cap applied by the "Skip huge files" option
+const DefaultMaxFileSize = 2 << 30 // 2 GB
+
+// DefaultActiveDays is the window used by the "Active worlds only" option
+const DefaultActiveDays = 30
+
+// DefaultRateLimit is the copy throughput used by the "Background mode" option
+const DefaultRateLimit = 20 << 20 // 20 MB/s
+
 const (
 	StageOptions Stage = iota
 	StageMCPath
 	StageBackupDest
+	StageConfirm
 	StageDone
+	StageManage   // Browsing existing backups
+	StageRules    // Editing the instance's include/exclude rules
+	StagePassword // Typing the zip password, then again to confirm it
 )
 
 // Option represents a toggleable option
@@ -53,6 +134,27 @@ type Model struct {
 	cancelled  bool
 	width      int
 	height     int
+
+	estimator  Estimator
+	estimating bool
+	estimate   *SizeEstimate
+	estErr     error
+
+	password       string // Typed on the password screen; the first entry while repeating
+	repeating      bool   // The password is being typed the second time
+	passwordFailed bool   // The last two entries did not match
+
+	suggester     PathSuggester
+	suggestions   []string // Corrected Minecraft paths on offer; the last is the path as typed
+	suggestCursor int
+
+	browser    *Browser // Enables the manage screen
+	browse     browseState
+	manageOnly bool // Started on the manage screen, so leaving it quits
+
+	rulesEditor *RulesEditor // Enables the rules screen
+	rules       rulesState
+	rulesOnly   bool // Started on the rules screen, so leaving it quits
 }
 
 // Colors - Stone/Earth palette with orange accent
@@ -175,9 +277,18 @@ func initialModel() Model {
 		options: []Option{
 			{Name: "Compress backup", Desc: "Create a .zip archive", Checked: false, Icon: "📦"},
 			{Name: "Include saves", Desc: "World saves", Checked: false, Icon: "🌍"},
+			{Name: "Active worlds only", Desc: "Played in the last 30 days", Checked: false, Icon: "🕹️"},
 			{Name: "Include Xaero maps", Desc: "Minimap data", Checked: false, Icon: "🗺️"},
 			{Name: "Include Distant Horizons", Desc: "LOD chunks", Checked: false, Icon: "🏔️"},
+			{Name: "Include world configs", Desc: "level.dat + datapacks only", Checked: false, Icon: "🧩"},
+			{Name: "Skip junk files", Desc: "Locks, caches, OS metadata", Checked: true, Icon: "🧹"},
+			{Name: "Skip huge files", Desc: "Files over 2 GB", Checked: false, Icon: "🐘"},
+			{Name: "Background mode", Desc: "Low priority, 20 MB/s", Checked: false, Icon: "🐢"},
+			{Name: "Verify copies", Desc: "Re-read and hash every copied file", Checked: false, Icon: "🔍"},
 			{Name: "Open when done", Desc: "Open in explorer", Checked: true, Icon: "📂"},
+			{Name: "Include crash reports", Desc: "Summarized in info.md", Checked: false, Icon: "💥"},
+			{Name: "Identify mods", Desc: "Look up on Modrinth/CurseForge", Checked: false, Icon: "🔎"},
+			{Name: "Password-protect zip", Desc: "AES-256, asks for a password", Checked: false, Icon: "🔒"},
 		},
 		textInput: ti,
 		width:     80,
@@ -186,6 +297,12 @@ func initialModel() Model {
 }
 
 func (m Model) Init() tea.Cmd {
+	switch m.stage {
+	case StageManage:
+		return m.loadBackups()
+	case StageRules:
+		return m.loadRules()
+	}
 	return textinput.Blink
 }
 
@@ -197,6 +314,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		// Esc steps back out of the manage and rules screens instead of quitting
+		if m.stage == StageManage && msg.String() != "ctrl+c" {
+			return m.updateManage(msg)
+		}
+		if m.stage == StageRules && msg.String() != "ctrl+c" {
+			return m.updateRules(msg)
+		}
 		switch msg.String() {
 		case "ctrl+c", "esc":
 			m.quitting = true
@@ -209,10 +333,32 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateOptions(msg)
 		case StageMCPath, StageBackupDest:
 			return m.updateTextInput(msg)
+		case StagePassword:
+			return m.updatePassword(msg)
+		case StageConfirm:
+			return m.updateConfirm(msg)
 		}
+
+	case sizeEstimateMsg:
+		m.estimating = false
+		m.estimate = &msg.estimate
+		m.estErr = msg.err
+		return m, nil
+
+	case backupsMsg, backupFilesMsg, previewMsg, browseActionMsg:
+		return m.updateManageResult(msg)
+
+	case rulesLoadedMsg, rulesSavedMsg:
+		return m.updateRulesResult(msg)
+	}
+
+	if m.stage == StageManage && m.browse.view == browseExtract {
+		var cmd tea.Cmd
+		m.browse.input, cmd = m.browse.input.Update(msg)
+		return m, cmd
 	}
 
-	if m.stage == StageMCPath || m.stage == StageBackupDest {
+	if m.stage == StageMCPath || m.stage == StageBackupDest || m.stage == StagePassword {
 		var cmd tea.Cmd
 		m.textInput, cmd = m.textInput.Update(msg)
 		return m, cmd
@@ -233,6 +379,13 @@ func (m Model) updateOptions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	case " ", "x":
 		m.options[m.cursor].Checked = !m.options[m.cursor].Checked
+	case "m":
+		if m.browser != nil {
+			return m.openManage()
+		}
+	case "r":
+		// One-key shortcut for "Active worlds only"
+		m.options[2].Checked = !m.options[2].Checked
 	case "a":
 		allChecked := true
 		for _, opt := range m.options {
@@ -253,29 +406,61 @@ func (m Model) updateOptions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) updateTextInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// While corrections are on offer, the arrows pick one and typing edits the path again
+	if len(m.suggestions) > 0 {
+		switch msg.String() {
+		case "up":
+			if m.suggestCursor > 0 {
+				m.suggestCursor--
+			}
+			return m, nil
+		case "down":
+			if m.suggestCursor < len(m.suggestions)-1 {
+				m.suggestCursor++
+			}
+			return m, nil
+		case "enter":
+		default:
+			m.suggestions = nil
+		}
+	}
+
 	switch msg.String() {
 	case "enter":
 		value := m.textInput.Value()
 		if m.stage == StageMCPath {
-			if value == "" {
+			if len(m.suggestions) > 0 {
+				value = m.suggestions[m.suggestCursor]
+				m.suggestions = nil
+			} else if value == "" {
 				return m, nil
+			} else if m.suggester != nil {
+				// Offer the game folder when a launcher folder or instance root was typed
+				if fixed := m.suggester(value); len(fixed) > 0 {
+					m.suggestions = append(fixed, value)
+					m.suggestCursor = 0
+					return m, nil
+				}
 			}
 			m.mcPath = value
 			m.stage = StageBackupDest
 			m.textInput.SetValue("")
-			homeDir, _ := os.UserHomeDir()
-			defaultDest := filepath.Join(homeDir, "TotemBackups")
-			m.textInput.Placeholder = defaultDest
+			m.textInput.Placeholder = backupDestDefault()
 		} else if m.stage == StageBackupDest {
 			if value == "" {
-				homeDir, _ := os.UserHomeDir()
-				m.backupDest = filepath.Join(homeDir, "TotemBackups")
+				m.backupDest = backupDestDefault()
 			} else {
 				m.backupDest = value
 			}
-			m.stage = StageDone
-			m.quitting = true
-			return m, tea.Quit
+			if m.options[13].Checked {
+				m.stage = StagePassword
+				m.textInput.SetValue("")
+				m.textInput.Placeholder = ""
+				m.textInput.EchoMode = textinput.EchoPassword
+				m.textInput.EchoCharacter = '•'
+				return m, nil
+			}
+			return m.inputsDone()
 		}
 	}
 
@@ -284,6 +469,77 @@ func (m Model) updateTextInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updatePassword takes the zip password, then the same again; a mismatch starts over
+func (m Model) updatePassword(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() != "enter" {
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
+	}
+	value := m.textInput.Value()
+	if value == "" {
+		return m, nil
+	}
+	m.textInput.SetValue("")
+	if !m.repeating {
+		m.password, m.repeating, m.passwordFailed = value, true, false
+		return m, nil
+	}
+	m.repeating = false
+	if value != m.password {
+		m.password, m.passwordFailed = "", true
+		return m, nil
+	}
+	m.textInput.EchoMode = textinput.EchoNormal
+	return m.inputsDone()
+}
+
+// inputsDone moves on from the last input screen: to the size estimate, or straight to the backup
+func (m Model) inputsDone() (tea.Model, tea.Cmd) {
+	if m.estimator != nil {
+		m.stage = StageConfirm
+		m.estimating = true
+		return m, m.runEstimate()
+	}
+	m.stage = StageDone
+	m.quitting = true
+	return m, tea.Quit
+}
+
+// runEstimate samples the installation in the background
+func (m Model) runEstimate() tea.Cmd {
+	config := m.GetConfig()
+	estimator := m.estimator
+	return func() tea.Msg {
+		est, err := estimator(config)
+		return sizeEstimateMsg{estimate: est, err: err}
+	}
+}
+
+func (m Model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "z":
+		m.options[0].Checked = !m.options[0].Checked
+	case "f":
+		if m.rulesEditor != nil && !m.estimating {
+			return m.openRules()
+		}
+	case "enter":
+		// A failed estimate means the backup would fail too (e.g. destination inside
+		// the source), so send the user back to pick another destination
+		if m.estErr != nil {
+			m.stage = StageBackupDest
+			m.textInput.SetValue(m.backupDest)
+			m.estErr = nil
+			return m, nil
+		}
+		m.stage = StageDone
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
 func (m Model) View() string {
 	if m.quitting && m.stage == StageDone {
 		return ""
@@ -302,6 +558,14 @@ func (m Model) View() string {
 		s.WriteString(m.renderMCPath())
 	case StageBackupDest:
 		s.WriteString(m.renderBackupDest())
+	case StagePassword:
+		s.WriteString(m.renderPassword())
+	case StageConfirm:
+		s.WriteString(m.renderConfirm())
+	case StageManage:
+		s.WriteString(m.renderManage())
+	case StageRules:
+		s.WriteString(m.renderRules())
 	}
 
 	return containerStyle.Render(s.String())
@@ -368,8 +632,14 @@ func (m Model) renderOptions() string {
 	s.WriteString(optionBoxStyle.Render(optionsContent.String()))
 
 	s.WriteString("\n\n")
-	s.WriteString(m.renderProgress(1, 3))
-	s.WriteString("\n" + m.renderHelp([]string{"↑↓", "space", "a", "enter", "esc"}, []string{"move", "toggle", "all", "next", "quit"}))
+	s.WriteString(m.renderProgress(1, m.totalSteps()))
+	keys := []string{"↑↓", "space", "a", "r", "enter", "esc"}
+	descs := []string{"move", "toggle", "all", "recent worlds", "next", "quit"}
+	if m.browser != nil {
+		keys = append(keys[:5], "m", "esc")
+		descs = append(descs[:5], "backups", "quit")
+	}
+	s.WriteString("\n" + m.renderHelp(keys, descs))
 
 	return s.String()
 }
@@ -384,11 +654,31 @@ func (m Model) renderMCPath() string {
 	inputContent.WriteString(inputLabelStyle.Render("Enter path to .minecraft folder") + "\n")
 	inputContent.WriteString(m.textInput.View())
 
+	if len(m.suggestions) > 0 {
+		inputContent.WriteString("\n\n" + warningBadge.Render("NO MINECRAFT FOLDERS") + "\n")
+		inputContent.WriteString(descStyle.Render("That looks like a launcher or instance folder. Did you mean:"))
+		for i, path := range m.suggestions {
+			label := path
+			if i == len(m.suggestions)-1 {
+				label += " (keep as typed)"
+			}
+			if i == m.suggestCursor {
+				inputContent.WriteString("\n" + cursorActive.Render("▸ ") + selectedOptionStyle.Render(label))
+			} else {
+				inputContent.WriteString("\n  " + optionStyle.Render(label))
+			}
+		}
+	}
+
 	s.WriteString(inputBoxStyle.Render(inputContent.String()))
 
 	s.WriteString("\n\n")
-	s.WriteString(m.renderProgress(2, 3))
-	s.WriteString("\n" + m.renderHelp([]string{"enter", "esc"}, []string{"confirm", "cancel"}))
+	s.WriteString(m.renderProgress(2, m.totalSteps()))
+	if len(m.suggestions) > 0 {
+		s.WriteString("\n" + m.renderHelp([]string{"↑↓", "enter", "esc"}, []string{"choose", "use path", "cancel"}))
+	} else {
+		s.WriteString("\n" + m.renderHelp([]string{"enter", "esc"}, []string{"confirm", "cancel"}))
+	}
 
 	return s.String()
 }
@@ -406,12 +696,133 @@ func (m Model) renderBackupDest() string {
 	s.WriteString(inputBoxStyle.Render(inputContent.String()))
 
 	s.WriteString("\n\n")
-	s.WriteString(m.renderProgress(3, 3))
+	s.WriteString(m.renderProgress(3, m.totalSteps()))
 	s.WriteString("\n" + m.renderHelp([]string{"enter", "esc"}, []string{"start backup", "cancel"}))
 
 	return s.String()
 }
 
+func (m Model) renderPassword() string {
+	var s strings.Builder
+
+	title := sectionStyle.Render("🔒  Zip Password")
+	s.WriteString(title + "\n")
+
+	var inputContent strings.Builder
+	label := "Password for the zip"
+	if m.repeating {
+		label = "Type it again to confirm"
+	}
+	inputContent.WriteString(inputLabelStyle.Render(label) + "\n")
+	inputContent.WriteString(m.textInput.View())
+	if m.passwordFailed {
+		inputContent.WriteString("\n\n" + warningBadge.Render("NO MATCH") + " " + descStyle.Render("The passwords differed; type it again."))
+	}
+	inputContent.WriteString("\n\n" + descStyle.Render("Opens in 7-Zip, WinRAR and WinZip. It is not saved, so keep it somewhere safe."))
+
+	s.WriteString(inputBoxStyle.Render(inputContent.String()))
+
+	s.WriteString("\n\n")
+	s.WriteString(m.renderProgress(4, m.totalSteps()))
+	s.WriteString("\n" + m.renderHelp([]string{"enter", "esc"}, []string{"confirm", "cancel"}))
+
+	return s.String()
+}
+
+func (m Model) renderConfirm() string {
+	var s strings.Builder
+
+	title := sectionStyle.Render("📋  Ready to Back Up")
+	s.WriteString(title + "\n")
+
+	var content strings.Builder
+	content.WriteString(inputLabelStyle.Render("Summary") + "\n")
+	content.WriteString(fmt.Sprintf("%s %s\n", descStyle.Render("From:"), optionStyle.Render(m.mcPath)))
+	content.WriteString(fmt.Sprintf("%s %s\n\n", descStyle.Render("To:  "), optionStyle.Render(m.backupDest)))
+
+	compress := checkboxUnchecked.Render("○")
+	if m.options[0].Checked {
+		compress = checkboxChecked.Render("●")
+	}
+
+	switch {
+	case m.estimating:
+		content.WriteString(descStyle.Render("Estimating size..."))
+	case m.estErr != nil:
+		content.WriteString(warningBadge.Render("CANNOT BACK UP") + "\n")
+		content.WriteString(descStyle.Render(m.estErr.Error()) + "\n")
+		content.WriteString(descStyle.Render("Press enter to choose another destination."))
+	default:
+		content.WriteString(fmt.Sprintf("%s %s\n", descStyle.Render("Estimated size:"), optionStyle.Render(formatBytes(m.estimate.Raw))))
+		content.WriteString(fmt.Sprintf("%s %s  %s",
+			compress,
+			optionStyle.Render("Compressed: ~"+formatBytes(m.estimate.Compressed)),
+			descStyle.Render(fmt.Sprintf("(saves ~%s)", formatBytes(m.estimate.Raw-m.estimate.Compressed)))))
+
+		if m.estimate.CloudOnly > 0 {
+			content.WriteString("\n\n" + warningBadge.Render(fmt.Sprintf("%d ONLINE-ONLY", m.estimate.CloudOnly)) + "\n")
+			content.WriteString(descStyle.Render("  OneDrive will download these during the backup; they are not in the size above.") + "\n")
+			content.WriteString(descStyle.Render("  Pick \"Always keep on this device\" on the folder to avoid surprises."))
+		} else if m.estimate.OneDrive {
+			content.WriteString("\n\n" + descStyle.Render("Source is synced by OneDrive."))
+		}
+
+		if m.estimate.Retention != "" {
+			content.WriteString("\n\n" + descStyle.Render("Afterwards, old backups are pruned: "+m.estimate.Retention))
+		}
+
+		if n := len(m.estimate.Unreadable); n > 0 {
+			content.WriteString("\n\n" + warningBadge.Render(fmt.Sprintf("%d UNREADABLE", n)) + "\n")
+			for i, p := range m.estimate.Unreadable {
+				if i == 3 {
+					content.WriteString(descStyle.Render(fmt.Sprintf("  …and %d more", n-3)))
+					break
+				}
+				content.WriteString(descStyle.Render("  "+p) + "\n")
+			}
+		}
+	}
+
+	s.WriteString(inputBoxStyle.Render(content.String()))
+
+	s.WriteString("\n\n")
+	s.WriteString(m.renderProgress(m.totalSteps(), m.totalSteps()))
+	if m.rulesEditor != nil {
+		s.WriteString("\n" + m.renderHelp([]string{"z", "f", "enter", "esc"}, []string{"toggle zip", "folders", "start backup", "cancel"}))
+	} else {
+		s.WriteString("\n" + m.renderHelp([]string{"z", "enter", "esc"}, []string{"toggle zip", "start backup", "cancel"}))
+	}
+
+	return s.String()
+}
+
+// totalSteps is the number of screens before the backup starts
+func (m Model) totalSteps() int {
+	steps := 3
+	if m.options[13].Checked {
+		steps++
+	}
+	if m.estimator != nil {
+		steps++
+	}
+	return steps
+}
+
+func formatBytes(bytes int64) string {
+	if bytes <= 0 {
+		return "0 B"
+	}
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	k := float64(1024)
+	b := float64(bytes)
+	i := 0
+	for b >= k && i < len(units)-1 {
+		b /= k
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
+
 func (m Model) renderProgress(current, total int) string {
 	var bar strings.Builder
 
@@ -453,20 +864,68 @@ func (m Model) GetConfig() *Config {
 	if m.cancelled {
 		return nil
 	}
+	var maxFileSize int64
+	if m.options[7].Checked {
+		maxFileSize = DefaultMaxFileSize
+	}
+	var activeDays int
+	if m.options[2].Checked {
+		activeDays = DefaultActiveDays
+	}
+	var rateLimit int64
+	if m.options[8].Checked {
+		rateLimit = DefaultRateLimit
+	}
 	return &Config{
-		MinecraftPath: m.mcPath,
-		BackupDest:    m.backupDest,
-		ZipOutput:     m.options[0].Checked,
-		IncludeSaves:  m.options[1].Checked,
-		IncludeXaero:  m.options[2].Checked,
-		IncludeDH:     m.options[3].Checked,
-		OpenWhenDone:  m.options[4].Checked,
+		MinecraftPath:    m.mcPath,
+		BackupDest:       m.backupDest,
+		ZipOutput:        m.options[0].Checked || m.options[13].Checked,
+		ProtectZip:       m.options[13].Checked,
+		ZipPassword:      m.password,
+		IncludeSaves:     m.options[1].Checked,
+		IncludeXaero:     m.options[3].Checked,
+		IncludeDH:        m.options[4].Checked,
+		IncludeCrashes:   m.options[11].Checked,
+		ModMetadata:      m.options[12].Checked,
+		WorldConfigOnly:  m.options[5].Checked,
+		SkipJunk:         m.options[6].Checked,
+		OpenWhenDone:     m.options[10].Checked,
+		VerifyCopies:     m.options[9].Checked,
+		MaxFileSize:      maxFileSize,
+		LowPriority:      m.options[8].Checked,
+		RateLimit:        rateLimit,
+		ActiveWorldsDays: activeDays,
+	}
+}
+
+// defaultDest overrides the backup destination offered when none is typed; see SetDefaultDest
+var defaultDest string
+
+// SetDefaultDest makes the destination prompt default to dir, for portable mode
+func SetDefaultDest(dir string) {
+	defaultDest = dir
+}
+
+// backupDestDefault returns the destination used when none is typed
+func backupDestDefault() string {
+	if defaultDest != "" {
+		return defaultDest
 	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, "TotemBackups")
 }
 
-// Run starts the TUI and returns the user's configuration
-func Run() (*Config, error) {
+// Run starts the TUI and returns the user's configuration.
+// When estimate is set, a confirmation screen shows the predicted backup size.
+// When suggest is set, a Minecraft path that looks wrong gets corrected paths offered.
+// When browse is set, the m key opens the manage screen to browse existing backups.
+// When rules is set, the f key on the confirmation screen edits the instance's copy rules.
+func Run(estimate Estimator, suggest PathSuggester, browse *Browser, rules *RulesEditor) (*Config, error) {
 	m := initialModel()
+	m.estimator = estimate
+	m.suggester = suggest
+	m.browser = browse
+	m.rulesEditor = rules
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	finalModel, err := p.Run()
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/vaalley/totem/internal/backup"
+)
+
+// Kiosk builds (go build -tags kiosk) are for handing restores to a shared server's
+// players: anything that deletes or overwrites existing files is refused, while backing
+// up, browsing and restoring into empty places still work.
+
+// kioskRefuses prints why a command is unavailable in kiosk builds and reports whether it was
+func kioskRefuses(what string) bool {
+	if !kioskMode {
+		return false
+	}
+	fmt.Printf("%s %s is disabled in this kiosk build; ask the server admin\n", errorStyle.Render("✗"), what)
+	return true
+}
+
+// extractFiles is backup.Extract, or in kiosk builds backup.ExtractNew so extracts and
+// restores never overwrite files
+func extractFiles(backupPath string, paths []string, to string) (int, error) {
+	if kioskMode {
+		return backup.ExtractNew(backupPath, paths, to)
+	}
+	return backup.Extract(backupPath, paths, to)
+}
@@ -0,0 +1,6 @@
+//go:build !kiosk
+
+package main
+
+// kioskMode disables commands that delete or overwrite files
+const kioskMode = false
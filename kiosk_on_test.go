@@ -0,0 +1,56 @@
+//go:build kiosk
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vaalley/totem/internal/backup"
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// Kiosk builds refuse to delete anything and only restore or extract where no file is in the way
+func TestKioskMode(t *testing.T) {
+	t.Setenv("TOTEM_CONFIG", t.TempDir())
+	mc := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mc, "options.txt"), []byte("fov:0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	result, err := backup.PerformContext(context.Background(), &tui.Config{MinecraftPath: mc, BackupDest: dest}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, run := range map[string]func([]string) int{"prune": runPrune, "rollback": runRollback} {
+		if code := run([]string{"--dest", dest}); code != 1 {
+			t.Errorf("%s exited %d, want 1", name, code)
+		}
+	}
+	if _, err := os.Stat(result.OutputPath); err != nil {
+		t.Errorf("the backup is gone: %v", err)
+	}
+
+	// options.txt is already in the instance, so neither command may write over it
+	os.WriteFile(filepath.Join(mc, "options.txt"), []byte("fov:1.0\n"), 0644)
+	if code := runRestore([]string{result.OutputPath, "--to", mc}); code != 1 {
+		t.Errorf("restore over existing files exited %d, want 1", code)
+	}
+	if code := runExtract([]string{result.OutputPath, "options.txt", "--to", mc}); code != 1 {
+		t.Errorf("extract over an existing file exited %d, want 1", code)
+	}
+	if data, _ := os.ReadFile(filepath.Join(mc, "options.txt")); string(data) != "fov:1.0\n" {
+		t.Errorf("options.txt was overwritten with %q", data)
+	}
+
+	empty := t.TempDir()
+	if code := runRestore([]string{result.OutputPath, "--to", empty}); code != 0 {
+		t.Errorf("restore into an empty folder exited %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(empty, "options.txt")); err != nil {
+		t.Errorf("options.txt was not restored: %v", err)
+	}
+}
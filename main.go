@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/vaalley/totem/internal/backup"
+	"github.com/vaalley/totem/internal/netguard"
 	"github.com/vaalley/totem/internal/tui"
 	"github.com/vaalley/totem/internal/version"
 )
@@ -72,7 +76,7 @@ func clearScreen() {
 	fmt.Print("\033[H\033[2J")
 }
 
-func showSpinner(message string, done chan bool) {
+func showSpinner(message string, progress *backup.Progress, done chan bool) {
 	i := 0
 	spinnerStyle := lipgloss.NewStyle().Foreground(orange).Bold(true)
 	for {
@@ -80,7 +84,14 @@ func showSpinner(message string, done chan bool) {
 		case <-done:
 			return
 		default:
-			fmt.Printf("\r  %s %s", spinnerStyle.Render(spinnerFrames[i%len(spinnerFrames)]), message)
+			percent := ""
+			if _, total := progress.Bytes(); total > 0 {
+				percent = labelStyle.Render(fmt.Sprintf(" %3.0f%%", progress.Fraction()*100))
+			}
+			if looked, total := progress.Mods(); total > 0 {
+				percent += labelStyle.Render(fmt.Sprintf("  mods %d/%d", looked, total))
+			}
+			fmt.Printf("\r  %s %s%s", spinnerStyle.Render(spinnerFrames[i%len(spinnerFrames)]), message, percent)
 			i++
 			time.Sleep(80 * time.Millisecond)
 		}
@@ -136,6 +147,9 @@ func showSuccessScreen(result *backup.Result) {
 	stats.WriteString(fmt.Sprintf("%s %s\n",
 		labelStyle.Render("Files:"),
 		valueStyle.Render(fmt.Sprintf("%d files copied", result.TotalFiles))))
+	stats.WriteString(fmt.Sprintf("%s %s\n",
+		labelStyle.Render("Health:"),
+		valueStyle.Render(fmt.Sprintf("%d/100", result.Health))))
 
 	// Item breakdown
 	stats.WriteString("\n")
@@ -158,12 +172,59 @@ func showSuccessScreen(result *backup.Result) {
 	if result.Stats.SavesCopied > 0 {
 		stats.WriteString(fmt.Sprintf("  🌍 %d save files\n", result.Stats.SavesCopied))
 	}
+	if result.Stats.WorldConfigsCopied > 0 {
+		stats.WriteString(fmt.Sprintf("  🧩 %d world config files\n", result.Stats.WorldConfigsCopied))
+	}
+	if result.Stats.DatapacksListed > 0 {
+		stats.WriteString(fmt.Sprintf("  📜 %d datapacks listed\n", result.Stats.DatapacksListed))
+	}
 	if result.Stats.XaeroCopied > 0 {
 		stats.WriteString(fmt.Sprintf("  🗺️  %d xaero files\n", result.Stats.XaeroCopied))
 	}
 	if result.Stats.DistantHorizonsCopied > 0 {
 		stats.WriteString(fmt.Sprintf("  🏔️  %d DH files\n", result.Stats.DistantHorizonsCopied))
 	}
+	if result.Stats.ServerFilesCopied > 0 {
+		stats.WriteString(fmt.Sprintf("  🛡️  %d server admin files\n", result.Stats.ServerFilesCopied))
+	}
+	if result.Stats.FilesVerified > 0 {
+		stats.WriteString(fmt.Sprintf("  🔍 %d copies verified\n", result.Stats.FilesVerified))
+	}
+	if result.Stats.ProxyFilesCopied > 0 {
+		stats.WriteString(fmt.Sprintf("  🔀 %d proxy config files\n", result.Stats.ProxyFilesCopied))
+	}
+	if result.Stats.CrashReportsCopied > 0 {
+		stats.WriteString(fmt.Sprintf("  💥 %d crash reports\n", result.Stats.CrashReportsCopied))
+	}
+
+	if len(result.Stats.Timings) > 0 {
+		stats.WriteString("\n" + labelStyle.Render("Timing:") + "\n")
+		for _, t := range result.Stats.Timings {
+			stats.WriteString(fmt.Sprintf("  %-18s %s\n", t.Step, valueStyle.Render(t.Duration.Round(time.Millisecond).String())))
+		}
+	}
+	if result.Stats.StoredUncompressed > 0 {
+		stats.WriteString(fmt.Sprintf("\n%s %d files stored as-is (~%s faster, ~%s larger)\n",
+			labelStyle.Render("Compression:"), result.Stats.StoredUncompressed,
+			result.Stats.StoreTimeSaved.Round(time.Millisecond), formatBytes(max(result.Stats.StoreSizeDelta, 0))))
+	}
+
+	if len(result.Warnings) > 0 {
+		stats.WriteString("\n" + labelStyle.Render("Warnings:") + "\n")
+		for _, w := range result.Warnings {
+			stats.WriteString(fmt.Sprintf("  ⚠️  %s\n", w))
+		}
+	}
+
+	if len(result.Skipped) > 0 {
+		stats.WriteString("\n" + labelStyle.Render("Skipped:") + "\n")
+		stats.WriteString(fmt.Sprintf("  🐘 %d files over the size cap (see info.md)\n", len(result.Skipped)))
+	}
+
+	if len(result.Pruned) > 0 {
+		stats.WriteString("\n" + labelStyle.Render("Pruned:") + "\n")
+		stats.WriteString(fmt.Sprintf("  🧹 %d old backups deleted by the retention policy\n", len(result.Pruned)))
+	}
 
 	fmt.Println(successBoxStyle.Render(stats.String()))
 	fmt.Println()
@@ -205,17 +266,53 @@ func showCancelledScreen() {
 }
 
 func main() {
-	// Run the TUI
-	config, err := tui.Run()
+	// Kiosk builds never delete backups, whatever config.json says
+	if kioskMode {
+		backup.DisablePruning()
+	}
+
+	args, global, err := takeGlobalFlags(os.Args[1:])
+	if global.portable {
+		backup.SetPortable()
+	}
+	if global.offline || os.Getenv("TOTEM_OFFLINE") != "" {
+		netguard.SetOffline()
+	}
+	if err == nil && global.config != "" {
+		err = backup.SetConfigDir(global.config)
+	}
+	if err == nil {
+		err = startProfiles(global.profileCPU, global.profileMem)
+	}
+	if err != nil {
+		fmt.Printf("%s %v\n", errorStyle.Render("✗"), err)
+		os.Exit(2)
+	}
+
+	// Subcommands run headless
+	if len(args) > 0 {
+		exit(runCLI(args))
+	}
+
+	// Run the TUI; the manage screen browses the last destination used
+	instance, dest := "", defaultBackupDest()
+	tui.SetDefaultDest(dest)
+	if profile, err := backup.LoadQuickProfile(); err == nil {
+		instance = profile.MinecraftPath
+		if profile.BackupDest != "" {
+			dest = profile.BackupDest
+		}
+	}
+	config, err := tui.Run(backup.EstimateSize, backup.CorrectMinecraftPath, backupBrowser(instance, dest), rulesEditor())
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// If user cancelled, exit gracefully
 	if config == nil {
 		showCancelledScreen()
-		os.Exit(0)
+		exit(0)
 	}
 
 	// Clear screen and show progress
@@ -226,25 +323,35 @@ func main() {
 
 	// Start spinner in background
 	done := make(chan bool)
-	go showSpinner("Backing up your Minecraft installation...", done)
+	progress := &backup.Progress{}
+	go showSpinner("Backing up your Minecraft installation...", progress, done)
 
-	// Perform the backup (with suppressed output)
-	result, err := backup.PerformQuiet(config)
+	// Perform the backup (with suppressed output); Ctrl+C stops it so it can be resumed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	result, err := backup.PerformContext(ctx, config, progress)
+	stop()
 	
 	// Stop spinner
 	done <- true
-	fmt.Print("\r" + strings.Repeat(" ", 60) + "\r") // Clear spinner line
+	fmt.Print("\r" + strings.Repeat(" ", 80) + "\r") // Clear spinner line
 
+	if errors.Is(err, backup.ErrCancelled) {
+		fmt.Printf("\n  %s\n", labelStyle.Render("Backup cancelled. Run \"totem resume\" to finish it."))
+		exit(130)
+	}
 	if err != nil {
 		fmt.Printf("\n%s %v\n", errorStyle.Render("✗ Backup failed:"), err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Show result screen
 	if result.Success {
+		// Remember this source and destination for `totem quick`
+		backup.SaveQuickProfile(config)
 		showSuccessScreen(result)
+		stopProfiles()
 	} else {
 		showErrorScreen(result)
-		os.Exit(1)
+		exit(1)
 	}
 }
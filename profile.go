@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// stopProfiles finishes the profiles startProfiles began; it does nothing when none were
+var stopProfiles = func() {}
+
+// exit finishes any profiles before exiting, since os.Exit skips deferred calls
+func exit(code int) {
+	stopProfiles()
+	os.Exit(code)
+}
+
+// startProfiles begins a CPU profile into cpuPath and arranges for a heap profile to be
+// written to memPath when the run ends, either path being empty to skip it. They are for
+// performance reports: `go tool pprof` reads both.
+func startProfiles(cpuPath, memPath string) error {
+	if cpuPath == "" && memPath == "" {
+		return nil
+	}
+	var cpuFile *os.File
+	if cpuPath != "" {
+		f, err := os.Create(cpuPath)
+		if err != nil {
+			return fmt.Errorf("CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("CPU profile: %w", err)
+		}
+		cpuFile = f
+	}
+
+	stopProfiles = func() {
+		stopProfiles = func() {}
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuFile.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "error: CPU profile: %v\n", err)
+			} else {
+				fmt.Fprintf(os.Stderr, "CPU profile written to %s\n", cpuPath)
+			}
+		}
+		if memPath != "" {
+			if err := writeHeapProfile(memPath); err != nil {
+				fmt.Fprintf(os.Stderr, "error: heap profile: %v\n", err)
+			} else {
+				fmt.Fprintf(os.Stderr, "Heap profile written to %s\n", memPath)
+			}
+		}
+	}
+	return nil
+}
+
+// writeHeapProfile writes the heap profile, which holds both what is still in use and
+// everything allocated during the run
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// Collect first so in-use figures are up to date
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
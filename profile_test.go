@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Both profiles are written, as gzipped pprof data, once the run ends
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cpu, mem := filepath.Join(dir, "cpu.pprof"), filepath.Join(dir, "mem.pprof")
+	if err := startProfiles(cpu, mem); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(mem); !os.IsNotExist(err) {
+		t.Error("the heap profile was written before the run ended")
+	}
+	stopProfiles()
+	for _, path := range []string{cpu, mem} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+			t.Errorf("%s is not a gzipped profile", filepath.Base(path))
+		}
+	}
+	// Stopping again does nothing, so every way out of main may call it
+	stopProfiles()
+
+	if err := startProfiles(filepath.Join(dir, "missing", "cpu.pprof"), ""); err == nil {
+		t.Error("CPU profile into a missing folder: no error")
+	}
+	if err := startProfiles("", ""); err != nil {
+		t.Errorf("no profiles: %v", err)
+	}
+	stopProfiles()
+}
@@ -0,0 +1,12 @@
+//go:build !tray
+
+package main
+
+import "fmt"
+
+// runTray explains how to get the tray icon: it needs a system tray library that the
+// default build leaves out so the TUI and CLI stay free of cgo and D-Bus
+func runTray(args []string) int {
+	fmt.Printf("%s this build has no tray icon; build with `go build -tags tray`\n", errorStyle.Render("✗"))
+	return 2
+}
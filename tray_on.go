@@ -0,0 +1,202 @@
+//go:build tray
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/systray"
+	"github.com/vaalley/totem/internal/backup"
+	"github.com/vaalley/totem/internal/tui"
+)
+
+// trayRefresh is how often the tray re-reads the destination for the last backup
+const trayRefresh = time.Minute
+
+// runTray keeps Totem in the system tray: the tooltip shows the last backup, "Back up now"
+// runs a quick backup with the saved profile, and --every schedules one
+func runTray(args []string) int {
+	fs := flag.NewFlagSet("tray", flag.ContinueOnError)
+	instance := fs.String("instance", "", "instance to back up (default: from the last TUI backup)")
+	dest := fs.String("dest", "", "backup destination folder (default: from the last TUI backup)")
+	every := fs.Duration("every", 0, "also back up this often, e.g. 2h (0 = only when asked)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !applyQuickProfile(instance, dest) {
+		return 2
+	}
+
+	t := &tray{instance: *instance, dest: *dest, every: *every}
+	systray.Run(t.ready, func() {})
+	return 0
+}
+
+// tray is the state behind the tray icon
+type tray struct {
+	instance string
+	dest     string
+	every    time.Duration
+
+	mu      sync.Mutex
+	running bool
+	failure string // Error of the last backup run from the tray, empty when it went fine
+
+	status *systray.MenuItem
+	now    *systray.MenuItem
+}
+
+func (t *tray) ready() {
+	systray.SetIcon(trayIcon())
+	systray.SetTitle("Totem")
+	t.status = systray.AddMenuItem("", "")
+	t.status.Disable()
+	systray.AddSeparator()
+	t.now = systray.AddMenuItem("Back up now", "Quick backup of saves and configs")
+	open := systray.AddMenuItem("Open backups folder", t.dest)
+	systray.AddSeparator()
+	quit := systray.AddMenuItem("Quit", "Close the tray icon")
+	t.refresh()
+
+	var schedule <-chan time.Time
+	if t.every > 0 {
+		schedule = time.NewTicker(t.every).C
+	}
+	tick := time.NewTicker(trayRefresh)
+	go func() {
+		for {
+			select {
+			case <-t.now.ClickedCh:
+				go t.backup()
+			case <-schedule:
+				go t.backup()
+			case <-open.ClickedCh:
+				backup.OpenFile(t.dest)
+			case <-tick.C:
+				t.refresh()
+			case <-quit.ClickedCh:
+				systray.Quit()
+				return
+			}
+		}
+	}()
+}
+
+// backup runs one quick backup unless one is already running
+func (t *tray) backup() {
+	t.mu.Lock()
+	if t.running {
+		t.mu.Unlock()
+		return
+	}
+	t.running = true
+	t.mu.Unlock()
+	t.now.Disable()
+	t.refresh()
+
+	config := &tui.Config{
+		MinecraftPath: t.instance,
+		BackupDest:    t.dest,
+		IncludeSaves:  true,
+		SkipJunk:      true,
+	}
+	res, err := backup.PerformQuick(config)
+	failure := ""
+	switch {
+	case err != nil:
+		failure = err.Error()
+		notify("Totem quick backup failed", failure)
+	case !res.Success:
+		failure = strings.Join(res.Errors, "; ")
+		notify("Totem quick backup had errors", res.OutputPath)
+	default:
+		notify("Totem quick backup done", fmt.Sprintf("%d files saved to %s", res.TotalFiles, res.OutputPath))
+	}
+
+	t.mu.Lock()
+	t.running = false
+	t.failure = failure
+	t.mu.Unlock()
+	t.now.Enable()
+	t.refresh()
+}
+
+// refresh puts the last backup's age, or the failure of the last tray backup, in the menu and tooltip
+func (t *tray) refresh() {
+	t.mu.Lock()
+	running, failure := t.running, t.failure
+	t.mu.Unlock()
+
+	var status string
+	switch {
+	case running:
+		status = "Backing up…"
+	case failure != "":
+		status = "Last backup failed: " + failure
+	default:
+		status = "No backups yet"
+		if items, err := backup.ListBackups(t.dest); err == nil && len(items) > 0 {
+			last := items[0]
+			for _, item := range items[1:] {
+				if item.Time.After(last.Time) {
+					last = item
+				}
+			}
+			status = fmt.Sprintf("Last backup %s ago (%s)", trayAge(time.Since(last.Time)), last.Name)
+		}
+	}
+	t.status.SetTitle(status)
+	systray.SetTooltip("Totem: " + status)
+}
+
+// trayAge rounds an age to the largest unit that fits
+func trayAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%d min", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d h", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days", int(d.Hours()/24))
+}
+
+// trayIcon draws a small totem: a gold block on a green body. Windows wants an .ico, which
+// may hold a PNG as its only image; everything else takes the PNG.
+func trayIcon() []byte {
+	const size = 32
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	gold := color.NRGBA{0xe8, 0xb8, 0x30, 0xff}
+	green := color.NRGBA{0x3a, 0xa8, 0x5a, 0xff}
+	for y := 2; y < size-2; y++ {
+		for x := 8; x < size-8; x++ {
+			c := green
+			if y < 13 {
+				c = gold
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	if runtime.GOOS != "windows" {
+		return buf.Bytes()
+	}
+
+	var ico bytes.Buffer
+	binary.Write(&ico, binary.LittleEndian, []uint16{0, 1, 1}) // reserved, type icon, one image
+	ico.Write([]byte{size, size, 0, 0})                        // width, height, no palette, reserved
+	binary.Write(&ico, binary.LittleEndian, []uint16{1, 32})   // planes, bits per pixel
+	binary.Write(&ico, binary.LittleEndian, []uint32{uint32(buf.Len()), 6 + 16})
+	ico.Write(buf.Bytes())
+	return ico.Bytes()
+}
@@ -0,0 +1,39 @@
+//go:build tray
+
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestTrayAge(t *testing.T) {
+	cases := map[time.Duration]string{
+		5 * time.Minute: "5 min",
+		3 * time.Hour:   "3 h",
+		30 * time.Hour:  "30 h",
+		72 * time.Hour:  "3 days",
+		240 * time.Hour: "10 days",
+	}
+	for d, want := range cases {
+		if got := trayAge(d); got != want {
+			t.Errorf("trayAge(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestTrayIconDecodes(t *testing.T) {
+	data := trayIcon()
+	if len(data) > 22 && data[2] == 1 && data[0] == 0 {
+		data = data[22:] // The .ico wrapper Windows gets
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 32 || b.Dy() != 32 {
+		t.Errorf("icon is %dx%d, want 32x32", b.Dx(), b.Dy())
+	}
+}